@@ -14,6 +14,10 @@ func NewApplication(o AppOptions) AppModel {
 		o.ctx = context.Background()
 	}
 
+	if o.backend == nil {
+		o.backend = NewExecBackend()
+	}
+
 	return AppModel{
 		Mode:       ModeMainMenu,
 		MenuModel:  NewMenuModel(),
@@ -37,6 +41,11 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, tea.Quit
 	}
 
+	// Запоминаем размер окна для экранов, которые открываются позже
+	if size, ok := msg.(tea.WindowSizeMsg); ok {
+		m.width, m.height = size.Width, size.Height
+	}
+
 	switch m.Mode {
 	case ModeMainMenu:
 		// Обновляем модель меню
@@ -46,34 +55,111 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Проверяем выбор пользователя
 		if m.MenuModel.Choice != "" {
 			switch m.MenuModel.Choice {
+			case ActionBrowseServices:
+				// Переходим к списку сервисов
+				m.Mode = ModeBrowser
+				m.BrowserModel = NewBrowserModel(m.options)
+				if m.width > 0 {
+					m.BrowserModel, _ = UpdateBrowser(m.options.ctx, tea.WindowSizeMsg{Width: m.width, Height: m.height}, m.BrowserModel)
+				}
+				var cmd tea.Cmd
+				m.BrowserModel, cmd = InitBrowser(m.options.ctx, m.BrowserModel)
+				return m, cmd
+
 			case ActionInstallService:
 				// Переключаемся в режим установки сервиса
 				m.Mode = ModeInstallService
 				m.InstallModel = NewInstallModel(m.options)
 				return m, nil
 
+			case ActionInstallTimer:
+				// Установка сервиса, запускаемого по расписанию
+				m.Mode = ModeInstallService
+				m.InstallModel = NewTimerInstallModel(m.options)
+				return m, nil
+
+			case ActionInstallSocket:
+				// Установка сервиса, запускаемого при подключении к сокету
+				m.Mode = ModeInstallService
+				m.InstallModel = NewSocketInstallModel(m.options)
+				return m, nil
+
+			case ActionInstallPath:
+				// Установка сервиса, запускаемого при появлении файлов
+				m.Mode = ModeInstallService
+				m.InstallModel = NewPathInstallModel(m.options)
+				return m, nil
+
+			case ActionEditService:
+				// Переключаемся в режим редактирования существующего сервиса
+				m.Mode = ModeInstallService
+				m.InstallModel = NewEditModel(m.options)
+				return m, nil
+
+			case ActionManageDropIns:
+				// Переходим к управлению drop-in файлами
+				m.Mode = ModeDropIns
+				m.DropInModel = NewDropInModel(m.options)
+				return m, nil
+
 			case ActionStartService:
 				// Переходим к вводу имени сервиса для запуска
 				m.Mode = ModeServiceInput
-				m.ServiceInputModel = NewServiceInputModel(ActionStart)
+				m.ServiceInputModel = NewServiceInputModel(m.options, ActionStart)
 				return m, nil
 
 			case ActionStopService:
 				// Переходим к вводу имени сервиса для остановки
 				m.Mode = ModeServiceInput
-				m.ServiceInputModel = NewServiceInputModel(ActionStop)
+				m.ServiceInputModel = NewServiceInputModel(m.options, ActionStop)
 				return m, nil
 
 			case ActionRestartService:
 				// Переходим к вводу имени сервиса для перезапуска
 				m.Mode = ModeServiceInput
-				m.ServiceInputModel = NewServiceInputModel(ActionRestart)
+				m.ServiceInputModel = NewServiceInputModel(m.options, ActionRestart)
 				return m, nil
 
-			case ActionViewLogs:
-				// Переходим к вводу имени сервиса для просмотра логов
+			case ActionReloadService:
+				// Переходим к вводу имени сервиса для reload
 				m.Mode = ModeServiceInput
-				m.ServiceInputModel = NewServiceInputModel(ActionViewLog)
+				m.ServiceInputModel = NewServiceInputModel(m.options, ActionReload)
+				return m, nil
+
+			case ActionViewLogs:
+				// Переходим к просмотру журнала
+				m.Mode = ModeLogs
+				m.LogViewerModel = NewLogViewerModel(m.options)
+				if m.width > 0 {
+					m.LogViewerModel, _ = UpdateLogViewer(m.options.ctx, tea.WindowSizeMsg{Width: m.width, Height: m.height}, m.LogViewerModel)
+				}
+				return m, nil
+
+			case ActionServiceStatus:
+				// Переходим к экрану статуса сервиса
+				m.Mode = ModeStatus
+				m.StatusModel = NewStatusModel(m.options)
+				return m, nil
+
+			case ActionSecurityAudit:
+				// Переходим к экрану аудита безопасности
+				m.Mode = ModeSecurity
+				m.SecurityModel = NewSecurityModel(m.options)
+				if m.width > 0 {
+					m.SecurityModel, _ = UpdateSecurity(m.options.ctx, tea.WindowSizeMsg{Width: m.width, Height: m.height}, m.SecurityModel)
+				}
+				return m, nil
+
+			case ActionTuneLive:
+				// Переходим к экрану изменения ограничений на лету
+				m.Mode = ModeTune
+				m.TuneModel = NewTuneModel(m.options)
+				return m, nil
+
+			case ActionScaleTemplate:
+				// Переходим к экрану экземпляров шаблона
+				m.Mode = ModeScale
+				m.ScaleModel = NewScaleModel(m.options)
 				return m, nil
 
 			case ActionExit:
@@ -114,9 +200,157 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		return m, cmd
 
+	case ModeBrowser:
+		// Обновляем модель списка сервисов
+		browserModel, cmd := UpdateBrowser(m.options.ctx, msg, m.BrowserModel)
+		m.BrowserModel = browserModel
+
+		// Редактирование выбранного сервиса открываем в мастере
+		if m.BrowserModel.Request == ActionEditService {
+			m.BrowserModel.Request = ""
+			m.Mode = ModeInstallService
+			m.InstallModel, _ = HandleServiceNameInput(NewEditModel(m.options), m.BrowserModel.Selected)
+			m.returnToBrowser = true
+			return m, nil
+		}
+
+		// Журнал выбранного сервиса открываем без ввода имени
+		if m.BrowserModel.Request == ActionViewLogs {
+			m.BrowserModel.Request = ""
+			m.Mode = ModeLogs
+			m.LogViewerModel = NewLogViewerModel(m.options)
+			if m.width > 0 {
+				m.LogViewerModel, _ = UpdateLogViewer(m.options.ctx, tea.WindowSizeMsg{Width: m.width, Height: m.height}, m.LogViewerModel)
+			}
+			var cmd tea.Cmd
+			m.LogViewerModel, cmd = FollowServiceLogs(m.options.ctx, m.LogViewerModel, BrowserLogServices(m.BrowserModel)...)
+			m.returnToBrowser = true
+			return m, cmd
+		}
+
+		// Статус выбранного сервиса показываем без ввода имени
+		if m.BrowserModel.Request == ActionServiceStatus {
+			m.BrowserModel.Request = ""
+			m.Mode = ModeStatus
+			m.StatusModel = LoadStatus(m.options.ctx, NewStatusModel(m.options), m.BrowserModel.Selected)
+			m.returnToBrowser = true
+			return m, nil
+		}
+
+		// Аудит безопасности выбранного сервиса выполняем без ввода имени
+		if m.BrowserModel.Request == ActionSecurityAudit {
+			m.BrowserModel.Request = ""
+			m.Mode = ModeSecurity
+			m.SecurityModel = NewSecurityModel(m.options)
+			if m.width > 0 {
+				m.SecurityModel, _ = UpdateSecurity(m.options.ctx, tea.WindowSizeMsg{Width: m.width, Height: m.height}, m.SecurityModel)
+			}
+			m.SecurityModel = LoadSecurityReport(m.options.ctx, m.SecurityModel, m.BrowserModel.Selected)
+			m.returnToBrowser = true
+			return m, nil
+		}
+
+		// Ограничения выбранного сервиса показываем без ввода имени
+		if m.BrowserModel.Request == ActionTuneLive {
+			m.BrowserModel.Request = ""
+			m.Mode = ModeTune
+			m.TuneModel = LoadTune(m.options.ctx, NewTuneModel(m.options), m.BrowserModel.Selected)
+			m.returnToBrowser = true
+			return m, nil
+		}
+
+		// Экземпляры шаблона выбранного сервиса показываем без ввода имени
+		if m.BrowserModel.Request == ActionScaleTemplate {
+			m.BrowserModel.Request = ""
+			m.Mode = ModeScale
+			template, _ := TemplateOf(m.BrowserModel.Selected)
+			m.ScaleModel = LoadInstances(m.options.ctx, NewScaleModel(m.options), template)
+			m.returnToBrowser = true
+			return m, nil
+		}
+
+		// По завершении возвращаемся в главное меню
+		if m.BrowserModel.Quitting {
+			m.Mode = ModeMainMenu
+			m.MenuModel = NewMenuModel()
+			return m, nil
+		}
+
+		return m, cmd
+
+	case ModeDropIns:
+		// Обновляем модель управления drop-in файлами
+		dropInModel, cmd := UpdateDropIns(m.options.ctx, msg, m.DropInModel)
+		m.DropInModel = dropInModel
+
+		// По завершении возвращаемся в главное меню
+		if m.DropInModel.Quitting {
+			m.Mode = ModeMainMenu
+			m.MenuModel = NewMenuModel()
+			m.Message = m.DropInModel.Message
+			return m, nil
+		}
+
+		return m, cmd
+
+	case ModeStatus:
+		// Обновляем модель экрана статуса
+		statusModel, cmd := UpdateStatus(m.options.ctx, msg, m.StatusModel)
+		m.StatusModel = statusModel
+
+		if m.StatusModel.Quitting {
+			return m.leaveScreen()
+		}
+
+		return m, cmd
+
+	case ModeScale:
+		// Обновляем модель экрана экземпляров шаблона
+		scaleModel, cmd := UpdateScale(m.options.ctx, msg, m.ScaleModel)
+		m.ScaleModel = scaleModel
+
+		if m.ScaleModel.Quitting {
+			return m.leaveScreen()
+		}
+
+		return m, cmd
+
+	case ModeTune:
+		// Обновляем модель экрана изменения ограничений
+		tuneModel, cmd := UpdateTune(m.options.ctx, msg, m.TuneModel)
+		m.TuneModel = tuneModel
+
+		if m.TuneModel.Quitting {
+			return m.leaveScreen()
+		}
+
+		return m, cmd
+
+	case ModeSecurity:
+		// Обновляем модель экрана аудита безопасности
+		securityModel, cmd := UpdateSecurity(m.options.ctx, msg, m.SecurityModel)
+		m.SecurityModel = securityModel
+
+		if m.SecurityModel.Quitting {
+			return m.leaveScreen()
+		}
+
+		return m, cmd
+
+	case ModeLogs:
+		// Обновляем модель просмотра журнала
+		logModel, cmd := UpdateLogViewer(m.options.ctx, msg, m.LogViewerModel)
+		m.LogViewerModel = logModel
+
+		if m.LogViewerModel.Quitting {
+			return m.leaveScreen()
+		}
+
+		return m, cmd
+
 	case ModeInstallService:
 		// Обновляем модель установки
-		installModel, cmd, err := UpdateInstall(msg, m.InstallModel)
+		installModel, cmd, err := UpdateInstall(m.options.ctx, msg, m.InstallModel)
 		m.InstallModel = installModel
 
 		// Обрабатываем ошибку
@@ -126,6 +360,19 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.Quit
 		}
 
+		// Мастер, открытый из списка сервисов, возвращает к списку
+		if m.returnToBrowser && (m.InstallModel.Quitting || m.InstallModel.Aborted) {
+			m.returnToBrowser = false
+			m.Mode = ModeBrowser
+			m.BrowserModel.Message = strings.TrimSpace(m.InstallModel.ResultMsg)
+			if m.InstallModel.Aborted {
+				m.BrowserModel.Message = "Редактирование прервано"
+			}
+			var cmd tea.Cmd
+			m.BrowserModel, cmd = InitBrowser(m.options.ctx, m.BrowserModel)
+			return m, cmd
+		}
+
 		// Если установка завершена
 		if m.InstallModel.Quitting {
 			// Если есть результат операции, выводим его и выходим
@@ -151,6 +398,22 @@ func (m AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// Закрыть экран: открытый из списка сервисов возвращает к списку,
+// остальные - в главное меню
+func (m AppModel) leaveScreen() (AppModel, tea.Cmd) {
+	if m.returnToBrowser {
+		m.returnToBrowser = false
+		m.Mode = ModeBrowser
+		var cmd tea.Cmd
+		m.BrowserModel, cmd = InitBrowser(m.options.ctx, m.BrowserModel)
+		return m, cmd
+	}
+
+	m.Mode = ModeMainMenu
+	m.MenuModel = NewMenuModel()
+	return m, nil
+}
+
 // Отображение интерфейса приложения
 func (m AppModel) View() string {
 	// При фатальной ошибке показываем сообщение об ошибке
@@ -183,6 +446,26 @@ func (m AppModel) View() string {
 	case ModeInstallService:
 		return ViewInstall(m.InstallModel)
 
+	case ModeDropIns:
+		return ViewDropIns(m.DropInModel)
+
+	case ModeBrowser:
+		return ViewBrowser(m.BrowserModel)
+
+	case ModeStatus:
+		return ViewStatus(m.StatusModel)
+
+	case ModeLogs:
+		return ViewLogViewer(m.LogViewerModel)
+
+	case ModeScale:
+		return ViewScale(m.ScaleModel)
+
+	case ModeSecurity:
+		return ViewSecurity(m.SecurityModel)
+	case ModeTune:
+		return ViewTune(m.TuneModel)
+
 	case ModeError:
 		return FormatError(m.Error)
 	}
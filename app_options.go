@@ -5,6 +5,7 @@ import "context"
 type AppOptions struct {
 	serviceName string
 	ctx         context.Context
+	backend     Backend
 }
 
 type AppOption func(*AppOptions)
@@ -20,3 +21,9 @@ func WithContext(ctx context.Context) AppOption {
 		o.ctx = ctx
 	}
 }
+
+func WithBackend(backend Backend) AppOption {
+	return func(o *AppOptions) {
+		o.backend = backend
+	}
+}
@@ -0,0 +1,378 @@
+package sdmanager
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// Ошибка: сервис не найден
+var ErrUnitNotFound = errors.New("сервис не найден")
+
+// Интерфейс управления systemd. Все операции с сервисами выполняются через
+// него, что позволяет подменить systemctl при тестировании.
+type Backend interface {
+	Start(ctx context.Context, serviceName string) error
+	Stop(ctx context.Context, serviceName string) error
+	Restart(ctx context.Context, serviceName string) error
+	// Перечитать конфигурацию сервиса командой ExecReload без перезапуска
+	Reload(ctx context.Context, serviceName string) error
+	Enable(ctx context.Context, serviceName string) error
+	Disable(ctx context.Context, serviceName string) error
+	DaemonReload(ctx context.Context) error
+	Status(ctx context.Context, serviceName string) (UnitInfo, error)
+	Properties(ctx context.Context, serviceName string, names ...string) (map[string]string, error)
+	// Записи журнала одного или нескольких сервисов в порядке времени
+	Logs(ctx context.Context, serviceNames []string, query LogQuery) ([]JournalEntry, error)
+	// Передать fn записи журнала по мере чтения, не накапливая их в памяти.
+	// query.Lines не применяется. Чтение прекращается на первой ошибке fn.
+	StreamLogs(ctx context.Context, serviceNames []string, query LogQuery, fn func(JournalEntry) error) error
+	// Записи журнала по query и далее новые записи по мере появления.
+	// Канал закрывается при отмене ctx или завершении чтения журнала.
+	FollowLogs(ctx context.Context, serviceNames []string, query LogQuery) (<-chan JournalEntry, error)
+	ListUnits(ctx context.Context) ([]UnitInfo, error)
+	// Имена всех unit-файлов с типом (api.service, data.mount)
+	ListUnitFiles(ctx context.Context) ([]string, error)
+	// Аудит безопасности загруженного сервиса (systemd-analyze security)
+	SecurityReport(ctx context.Context, serviceName string) (SecurityReport, error)
+	// Изменить свойства запущенного сервиса (systemctl set-property) без
+	// перезапуска: assignments вида "MemoryMax=1G". runtime - только до
+	// перезагрузки.
+	SetProperty(ctx context.Context, serviceName string, runtime bool, assignments ...string) error
+}
+
+// Реализация Backend через вызов systemctl и journalctl
+type ExecBackend struct{}
+
+// Создать Backend на основе systemctl и journalctl
+func NewExecBackend() *ExecBackend {
+	return &ExecBackend{}
+}
+
+func (b *ExecBackend) Start(ctx context.Context, serviceName string) error {
+	_, err := runSystemctl(ctx, serviceName, "start", serviceName)
+	return err
+}
+
+func (b *ExecBackend) Stop(ctx context.Context, serviceName string) error {
+	_, err := runSystemctl(ctx, serviceName, "stop", serviceName)
+	return err
+}
+
+func (b *ExecBackend) Restart(ctx context.Context, serviceName string) error {
+	_, err := runSystemctl(ctx, serviceName, "restart", serviceName)
+	return err
+}
+
+func (b *ExecBackend) Reload(ctx context.Context, serviceName string) error {
+	_, err := runSystemctl(ctx, serviceName, "reload", serviceName)
+	return err
+}
+
+func (b *ExecBackend) Enable(ctx context.Context, serviceName string) error {
+	_, err := runSystemctl(ctx, serviceName, "enable", serviceName)
+	return err
+}
+
+func (b *ExecBackend) Disable(ctx context.Context, serviceName string) error {
+	_, err := runSystemctl(ctx, serviceName, "disable", serviceName)
+	return err
+}
+
+func (b *ExecBackend) DaemonReload(ctx context.Context) error {
+	_, err := runSystemctl(ctx, "", "daemon-reload")
+	return err
+}
+
+func (b *ExecBackend) Status(ctx context.Context, serviceName string) (UnitInfo, error) {
+	output, err := ExecuteCommand(ctx, "systemctl", "show", serviceName, "--no-pager",
+		"--property=Id,Description,LoadState,ActiveState,SubState,UnitFileState")
+	if err != nil {
+		return UnitInfo{}, err
+	}
+
+	props := ParseProperties(output)
+	if props["LoadState"] == "not-found" {
+		return UnitInfo{}, fmt.Errorf("%s: %w", serviceName, ErrUnitNotFound)
+	}
+
+	return UnitInfo{
+		Name:          strings.TrimSuffix(props["Id"], ".service"),
+		Description:   props["Description"],
+		LoadState:     props["LoadState"],
+		ActiveState:   props["ActiveState"],
+		SubState:      props["SubState"],
+		UnitFileState: props["UnitFileState"],
+	}, nil
+}
+
+// Получить свойства сервиса в формате systemctl show
+func (b *ExecBackend) Properties(ctx context.Context, serviceName string, names ...string) (map[string]string, error) {
+	args := []string{"show", serviceName, "--no-pager"}
+	if len(names) > 0 {
+		args = append(args, "--property="+strings.Join(names, ","))
+	}
+
+	output, err := ExecuteCommand(ctx, "systemctl", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseProperties(output), nil
+}
+
+func (b *ExecBackend) Logs(ctx context.Context, serviceNames []string, query LogQuery) ([]JournalEntry, error) {
+	match, err := query.Matcher()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []JournalEntry
+	err = runJournalctl(ctx, query.JournalctlArgs(serviceNames, false), func(entry JournalEntry) error {
+		if match(entry) {
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return entries, err
+	}
+
+	return LastEntries(entries, query.Lines), nil
+}
+
+func (b *ExecBackend) StreamLogs(ctx context.Context, serviceNames []string, query LogQuery, fn func(JournalEntry) error) error {
+	match, err := query.Matcher()
+	if err != nil {
+		return err
+	}
+
+	query.Lines = 0
+	return runJournalctl(ctx, query.JournalctlArgs(serviceNames, false), func(entry JournalEntry) error {
+		if !match(entry) {
+			return nil
+		}
+		return fn(entry)
+	})
+}
+
+// Запустить journalctl и передать fn записи из его stdout. stderr читается
+// отдельно: туда journalctl пишет подсказки, например пользователю без
+// доступа к системному журналу, и в разбор они попадать не должны.
+func runJournalctl(ctx context.Context, args []string, fn func(JournalEntry) error) error {
+	path, err := exec.LookPath("journalctl")
+	if err != nil {
+		return fmt.Errorf("команда journalctl не найдена: %w", err)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("ошибка запуска journalctl: %w", err)
+	}
+
+	readErr := ScanJournal(stdout, fn)
+	if readErr != nil {
+		// Остаток вывода не нужен, а непрочитанный pipe не даст процессу завершиться
+		_ = cmd.Process.Kill()
+	}
+	waitErr := cmd.Wait()
+
+	if readErr != nil {
+		return readErr
+	}
+	if waitErr != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return fmt.Errorf("%w: %s", waitErr, message)
+		}
+		return fmt.Errorf("ошибка при выполнении команды journalctl: %w", waitErr)
+	}
+
+	return nil
+}
+
+func (b *ExecBackend) FollowLogs(ctx context.Context, serviceNames []string, query LogQuery) (<-chan JournalEntry, error) {
+	match, err := query.Matcher()
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := exec.LookPath("journalctl")
+	if err != nil {
+		return nil, fmt.Errorf("команда journalctl не найдена: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, path, query.JournalctlArgs(serviceNames, true)...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("ошибка запуска journalctl: %w", err)
+	}
+
+	entries := make(chan JournalEntry, 64)
+	go func() {
+		defer close(entries)
+		defer cmd.Wait()
+
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+		for scanner.Scan() {
+			entry, err := ParseJournalEntry(scanner.Bytes())
+			if err != nil || !match(entry) {
+				continue
+			}
+
+			select {
+			case entries <- entry:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return entries, nil
+}
+
+func (b *ExecBackend) ListUnits(ctx context.Context) ([]UnitInfo, error) {
+	output, err := ExecuteCommand(ctx, "systemctl", "list-units", "--type=service", "--all", "--no-legend", "--plain", "--no-pager")
+	if err != nil {
+		return nil, err
+	}
+	units := ParseListUnits(output)
+
+	output, err = ExecuteCommand(ctx, "systemctl", "list-unit-files", "--type=service", "--no-legend", "--no-pager")
+	if err != nil {
+		return nil, err
+	}
+
+	return MergeUnitFileStates(units, ParseListUnitFiles(output)), nil
+}
+
+func (b *ExecBackend) ListUnitFiles(ctx context.Context) ([]string, error) {
+	output, err := ExecuteCommand(ctx, "systemctl", "list-unit-files", "--no-legend", "--no-pager")
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, line := range strings.Split(output, "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			names = append(names, fields[0])
+		}
+	}
+	return names, nil
+}
+
+func (b *ExecBackend) SecurityReport(ctx context.Context, serviceName string) (SecurityReport, error) {
+	unit := strings.TrimSuffix(serviceName, ".service") + ".service"
+
+	output, err := ExecuteCommand(ctx, "systemd-analyze", "security", "--json=short", "--no-pager", unit)
+	if err != nil {
+		return SecurityReport{}, err
+	}
+	findings, err := ParseSecurityFindings(output)
+	if err != nil {
+		return SecurityReport{}, err
+	}
+
+	// Итоговую оценку JSON не содержит
+	output, err = ExecuteCommand(ctx, "systemd-analyze", "security", "--no-pager", unit)
+	if err != nil {
+		return SecurityReport{}, err
+	}
+	exposure, err := parseExposure(output)
+	if err != nil {
+		return SecurityReport{}, err
+	}
+
+	return SecurityReport{Exposure: exposure, Findings: findings}, nil
+}
+
+func (b *ExecBackend) SetProperty(ctx context.Context, serviceName string, runtime bool, assignments ...string) error {
+	args := []string{"set-property"}
+	if runtime {
+		args = append(args, "--runtime")
+	}
+	args = append(args, serviceName)
+
+	_, err := runSystemctl(ctx, serviceName, append(args, assignments...)...)
+	return err
+}
+
+// Выполнить systemctl для unit. Ошибки "unit не найден" и "нет прав"
+// приводятся к ErrUnitNotFound и ErrAccessDenied по сообщению systemctl,
+// как DBusBackend делает это по имени ошибки D-Bus.
+func runSystemctl(ctx context.Context, serviceName string, args ...string) (string, error) {
+	output, err := ExecuteCommand(ctx, "systemctl", args...)
+	if err != nil {
+		return output, convertSystemctlError(serviceName, output, err)
+	}
+	return output, nil
+}
+
+// Сообщения systemctl об отсутствующем unit и недостатке прав
+var (
+	systemctlNotFound     = []string{"not found", "not loaded", "does not exist"}
+	systemctlAccessDenied = []string{"access denied", "permission denied", "authentication required"}
+)
+
+// Преобразовать ошибку systemctl в ошибки пакета, сохранив исходное сообщение
+func convertSystemctlError(serviceName, output string, err error) error {
+	message := strings.ToLower(output)
+	contains := func(patterns []string) bool {
+		return slices.ContainsFunc(patterns, func(pattern string) bool {
+			return strings.Contains(message, pattern)
+		})
+	}
+
+	var target error
+	switch {
+	case contains(systemctlNotFound):
+		target = ErrUnitNotFound
+	case contains(systemctlAccessDenied):
+		target = ErrAccessDenied
+	default:
+		return err
+	}
+
+	if serviceName == "" {
+		return fmt.Errorf("%w: %s", target, output)
+	}
+	return fmt.Errorf("%s: %w: %s", unitName(serviceName), target, output)
+}
+
+// Последние n записей; n <= 0 - все записи
+func LastEntries(entries []JournalEntry, n int) []JournalEntry {
+	if n > 0 && len(entries) > n {
+		return entries[len(entries)-n:]
+	}
+	return entries
+}
+
+// Разбор вывода systemctl show в формате key=value
+func ParseProperties(output string) map[string]string {
+	props := make(map[string]string)
+
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		props[strings.TrimSpace(key)] = value
+	}
+
+	return props
+}
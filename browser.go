@@ -0,0 +1,442 @@
+package sdmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Интервал обновления списка сервисов
+const BrowserRefreshInterval = 5 * time.Second
+
+// Действия панели выбранного сервиса
+var BrowserActions = []struct {
+	Title  string
+	Action string
+}{
+	{Title: "Запустить", Action: ActionStart},
+	{Title: "Остановить", Action: ActionStop},
+	{Title: "Перезапустить", Action: ActionRestart},
+	{Title: "Перечитать конфигурацию (reload)", Action: ActionReload},
+	{Title: "Просмотр логов", Action: ActionViewLog},
+	{Title: "Статус", Action: ActionStatus},
+	{Title: "Аудит безопасности", Action: ActionAudit},
+	{Title: "Ограничения на лету", Action: ActionTune},
+	{Title: "Редактировать", Action: ActionEdit},
+	{Title: "Деактивировать (disable)", Action: ActionDisable},
+}
+
+// Действие панели для шаблонов и их экземпляров
+var BrowserScaleAction = struct {
+	Title  string
+	Action string
+}{Title: "Экземпляры шаблона", Action: ActionScale}
+
+// Действия панели для сервиса: шаблонам и экземплярам доступно
+// масштабирование
+func BrowserPanelActions(serviceName string) []struct {
+	Title  string
+	Action string
+} {
+	if _, ok := TemplateOf(serviceName); ok {
+		return append(slices.Clone(BrowserActions), BrowserScaleAction)
+	}
+	return BrowserActions
+}
+
+// Стили состояний сервисов
+var (
+	ActiveStateStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	FailedStateStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	InactiveStateStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	PanelStyle         = lipgloss.NewStyle().BorderStyle(lipgloss.RoundedBorder()).Padding(0, 1).BorderForeground(lipgloss.Color("62"))
+)
+
+// Сообщение таймера обновления списка
+type browserTickMsg struct {
+	id int64
+}
+
+// Сообщение с обновленным списком сервисов
+type browserUnitsMsg struct {
+	units []UnitInfo
+	err   error
+}
+
+// Результат действия над сервисом из панели
+type browserActionMsg struct {
+	service string
+	result  string
+	err     error
+}
+
+// Действия панели, которые выполняются через Backend
+var browserServiceActions = map[string]func(context.Context, Backend, string) (string, error){
+	ActionStart:   StartService,
+	ActionStop:    StopService,
+	ActionRestart: RestartService,
+	ActionReload:  ReloadService,
+	ActionDisable: DisableService,
+}
+
+// Делегат для отображения сервисов в списке. Отмеченные сервисы
+// помечаются звездочкой.
+type UnitDelegate struct {
+	Marked map[string]bool
+}
+
+func (d UnitDelegate) Height() int                             { return 1 }
+func (d UnitDelegate) Spacing() int                            { return 0 }
+func (d UnitDelegate) Update(_ tea.Msg, _ *list.Model) tea.Cmd { return nil }
+func (d UnitDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
+	unit, ok := listItem.(UnitInfo)
+	if !ok {
+		return
+	}
+
+	mark := "  "
+	if d.Marked[unit.Name] {
+		mark = ServiceStyle(unit.Name).Render("* ")
+	}
+
+	name := fmt.Sprintf("%-36s", truncate(unit.Name, 36))
+	state := StateStyle(unit.ActiveState).Render(fmt.Sprintf("%-10s %-10s", unit.ActiveState, unit.SubState))
+	line := fmt.Sprintf("%s%s %s %-10s %s", mark, name, state, unit.UnitFileState, unit.Description)
+
+	if index == m.Index() {
+		fmt.Fprint(w, SelectedItemStyle.Render("> ")+line)
+		return
+	}
+	fmt.Fprint(w, ItemStyle.Render(line))
+}
+
+// Стиль для отображения состояния сервиса
+func StateStyle(activeState string) lipgloss.Style {
+	switch activeState {
+	case "active", "reloading", "activating":
+		return ActiveStateStyle
+	case "failed":
+		return FailedStateStyle
+	default:
+		return InactiveStateStyle
+	}
+}
+
+// Обрезать строку до заданной длины
+func truncate(s string, n int) string {
+	if len([]rune(s)) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
+
+// Создать модель списка сервисов
+func NewBrowserModel(appOptions AppOptions) BrowserModel {
+	marked := make(map[string]bool)
+	l := list.New(nil, UnitDelegate{Marked: marked}, 100, 20)
+	l.Title = "Сервисы systemd"
+	l.SetShowStatusBar(true)
+	l.SetFilteringEnabled(true)
+	l.Styles.Title = TitleStyle
+	l.Styles.PaginationStyle = PaginationStyle
+	l.Styles.HelpStyle = HelpStyle
+	// Выход из списка обрабатываем сами, чтобы вернуться в меню
+	l.KeyMap.Quit.SetEnabled(false)
+
+	return BrowserModel{
+		List:    l,
+		Marked:  marked,
+		backend: appOptions.backend,
+	}
+}
+
+// Сервисы для просмотра журнала из списка: отмеченные и выбранный.
+// Несколько сервисов показываются в общем журнале.
+func BrowserLogServices(model BrowserModel) []string {
+	services := make([]string, 0, len(model.Marked)+1)
+	for name, marked := range model.Marked {
+		if marked {
+			services = append(services, name)
+		}
+	}
+	slices.Sort(services)
+
+	if model.Selected != "" && !model.Marked[model.Selected] {
+		services = append(services, model.Selected)
+	}
+	return services
+}
+
+// Команды для загрузки списка и запуска периодического обновления. Таймер
+// предыдущего открытия списка после этого игнорируется.
+func InitBrowser(ctx context.Context, model BrowserModel) (BrowserModel, tea.Cmd) {
+	model.tickID = time.Now().UnixNano()
+	return model, tea.Batch(loadUnitsCmd(ctx, model.backend), browserTick(model.tickID))
+}
+
+// Загрузить список сервисов в фоне
+func loadUnitsCmd(ctx context.Context, b Backend) tea.Cmd {
+	return func() tea.Msg {
+		units, err := b.ListUnits(ctx)
+		return browserUnitsMsg{units: units, err: err}
+	}
+}
+
+// Выполнить действие над сервисом в фоне: остановка сервиса может занять
+// до TimeoutStopSec, и интерфейс не должен на это время замирать
+func browserActionCmd(ctx context.Context, b Backend, serviceName string, action func(context.Context, Backend, string) (string, error)) tea.Cmd {
+	return func() tea.Msg {
+		result, err := action(ctx, b, serviceName)
+		return browserActionMsg{service: serviceName, result: result, err: err}
+	}
+}
+
+// Запланировать следующее обновление списка
+func browserTick(id int64) tea.Cmd {
+	return tea.Tick(BrowserRefreshInterval, func(time.Time) tea.Msg {
+		return browserTickMsg{id: id}
+	})
+}
+
+// Обработка событий в списке сервисов
+func UpdateBrowser(ctx context.Context, msg tea.Msg, model BrowserModel) (BrowserModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		model.List.SetSize(msg.Width, msg.Height-8)
+		return model, nil
+
+	case browserTickMsg:
+		// Таймер от предыдущего открытия списка игнорируем
+		if msg.id != model.tickID {
+			return model, nil
+		}
+		return model, tea.Batch(loadUnitsCmd(ctx, model.backend), browserTick(model.tickID))
+
+	case browserUnitsMsg:
+		if msg.err != nil {
+			model.Error = msg.err.Error()
+			return model, nil
+		}
+		return setBrowserUnits(model, msg.units)
+
+	case browserActionMsg:
+		model.Running = ""
+		// Без ExecReload конфигурацию можно применить только перезапуском
+		if errors.Is(msg.err, ErrReloadUnsupported) {
+			model.Selected = msg.service
+			model.PanelOpen = true
+			model.ConfirmRestart = true
+			return model, nil
+		}
+		if msg.err != nil {
+			model.Error = msg.err.Error()
+			return model, nil
+		}
+		model.Message = msg.result
+		model.PanelOpen = false
+		return model, loadUnitsCmd(ctx, model.backend)
+
+	case tea.KeyMsg:
+		if model.ConfirmRestart {
+			return confirmBrowserRestart(ctx, msg, model)
+		}
+		if model.PanelOpen {
+			return updateBrowserPanel(ctx, msg, model)
+		}
+
+		// Во время ввода фильтра все клавиши обрабатывает список
+		if model.List.FilterState() == list.Filtering {
+			break
+		}
+
+		switch msg.String() {
+		case "q", "esc", "ctrl+c":
+			if model.List.FilterState() == list.FilterApplied && msg.String() == "esc" {
+				break
+			}
+			model.Quitting = true
+			return model, nil
+
+		case "enter":
+			unit, ok := model.List.SelectedItem().(UnitInfo)
+			if ok {
+				model.Selected = unit.Name
+				model.PanelOpen = true
+				model.PanelAction = 0
+				model.Error = ""
+				model.Message = ""
+			}
+			return model, nil
+
+		case " ":
+			if unit, ok := model.List.SelectedItem().(UnitInfo); ok {
+				if model.Marked[unit.Name] {
+					delete(model.Marked, unit.Name)
+				} else {
+					model.Marked[unit.Name] = true
+				}
+			}
+			return model, nil
+
+		case "r":
+			return model, loadUnitsCmd(ctx, model.backend)
+		}
+	}
+
+	var cmd tea.Cmd
+	model.List, cmd = model.List.Update(msg)
+	return model, cmd
+}
+
+// Обновить элементы списка, сохранив выбранный сервис
+func setBrowserUnits(model BrowserModel, units []UnitInfo) (BrowserModel, tea.Cmd) {
+	selected := ""
+	if unit, ok := model.List.SelectedItem().(UnitInfo); ok {
+		selected = unit.Name
+	}
+
+	model.Units = units
+	items := make([]list.Item, 0, len(units))
+	for _, unit := range units {
+		items = append(items, unit)
+	}
+	cmd := model.List.SetItems(items)
+
+	for i, item := range model.List.VisibleItems() {
+		if item.(UnitInfo).Name == selected {
+			model.List.Select(i)
+			break
+		}
+	}
+
+	return model, cmd
+}
+
+// Обработка событий панели действий выбранного сервиса
+func updateBrowserPanel(ctx context.Context, msg tea.KeyMsg, model BrowserModel) (BrowserModel, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "q":
+		model.PanelOpen = false
+		return model, nil
+
+	case "ctrl+c":
+		model.Quitting = true
+		return model, nil
+
+	case "up", "k":
+		actions := BrowserPanelActions(model.Selected)
+		model.PanelAction = (model.PanelAction - 1 + len(actions)) % len(actions)
+
+	case "down", "j":
+		model.PanelAction = (model.PanelAction + 1) % len(BrowserPanelActions(model.Selected))
+
+	case "enter":
+		action := BrowserPanelActions(model.Selected)[model.PanelAction]
+		if run, ok := browserServiceActions[action.Action]; ok {
+			// Следующее действие - только после завершения текущего
+			if model.Running != "" {
+				return model, nil
+			}
+			model.Running = fmt.Sprintf("%s: %s", model.Selected, action.Title)
+			model.Error = ""
+			model.Message = ""
+			return model, browserActionCmd(ctx, model.backend, model.Selected, run)
+		}
+
+		switch action.Action {
+		case ActionViewLog:
+			model.Request = ActionViewLogs
+			model.PanelOpen = false
+			return model, nil
+		case ActionEdit:
+			model.Request = ActionEditService
+			model.PanelOpen = false
+			return model, nil
+		case ActionStatus:
+			model.Request = ActionServiceStatus
+			model.PanelOpen = false
+			return model, nil
+		case ActionAudit:
+			model.Request = ActionSecurityAudit
+			model.PanelOpen = false
+			return model, nil
+		case ActionTune:
+			model.Request = ActionTuneLive
+			model.PanelOpen = false
+			return model, nil
+		case ActionScale:
+			model.Request = ActionScaleTemplate
+			model.PanelOpen = false
+			return model, nil
+		}
+	}
+
+	return model, nil
+}
+
+// Обработка ответа на вопрос о перезапуске сервиса вместо reload
+func confirmBrowserRestart(ctx context.Context, msg tea.KeyMsg, model BrowserModel) (BrowserModel, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y":
+		model.ConfirmRestart = false
+		model.Running = model.Selected + ": перезапуск"
+		return model, browserActionCmd(ctx, model.backend, model.Selected, RestartService)
+
+	case "n", "N", "esc", "q":
+		model.ConfirmRestart = false
+	}
+
+	return model, nil
+}
+
+// Отрисовка списка сервисов
+func ViewBrowser(model BrowserModel) string {
+	var s strings.Builder
+
+	if model.PanelOpen {
+		var panel strings.Builder
+		panel.WriteString(TitleStyle.Render(model.Selected) + "\n\n")
+		if services := BrowserLogServices(model); len(services) > 1 {
+			panel.WriteString(LogStatusStyle.Render("Общий журнал: "+strings.Join(services, ", ")) + "\n\n")
+		}
+		for i, action := range BrowserPanelActions(model.Selected) {
+			if i == model.PanelAction {
+				panel.WriteString(SelectedItemStyle.Render("> "+action.Title) + "\n")
+			} else {
+				panel.WriteString("    " + action.Title + "\n")
+			}
+		}
+		if model.ConfirmRestart {
+			panel.WriteString("\nСервис не поддерживает reload (не задан ExecReload). Перезапустить? (y/n)")
+		} else {
+			panel.WriteString("\nEnter - выполнить, Esc - назад")
+		}
+
+		s.WriteString(PanelStyle.Render(panel.String()) + "\n\n")
+	} else {
+		s.WriteString("\n" + model.List.View() + "\n")
+		s.WriteString("Enter - действия, пробел - отметить для общего журнала, / - фильтр, r - обновить, Esc - в меню\n")
+	}
+
+	if model.Running != "" {
+		s.WriteString("\n" + FormatInfo("Выполняется "+model.Running+"...") + "\n")
+	}
+
+	if model.Message != "" {
+		s.WriteString("\n" + FormatInfo(model.Message) + "\n")
+	}
+
+	if model.Error != "" {
+		s.WriteString("\n" + FormatError(model.Error) + "\n")
+	}
+
+	return s.String()
+}
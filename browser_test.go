@@ -0,0 +1,174 @@
+package sdmanager
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Выполнить команду и вернуть сообщения, пришедшие сразу: таймеры
+// обновления списка и мигания курсора не ждем
+func immediateMsgs(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+
+	done := make(chan tea.Msg, 1)
+	go func() { done <- cmd() }()
+
+	select {
+	case msg := <-done:
+		if batch, ok := msg.(tea.BatchMsg); ok {
+			var msgs []tea.Msg
+			for _, cmd := range batch {
+				msgs = append(msgs, immediateMsgs(cmd)...)
+			}
+			return msgs
+		}
+		return []tea.Msg{msg}
+	case <-time.After(100 * time.Millisecond):
+		return nil
+	}
+}
+
+// Передать экрану сообщение и все сообщения, которые сразу вернули его команды
+func updateBrowser(t *testing.T, model BrowserModel, msg tea.Msg) BrowserModel {
+	t.Helper()
+
+	var cmd tea.Cmd
+	model, cmd = UpdateBrowser(context.Background(), msg, model)
+	for _, msg := range immediateMsgs(cmd) {
+		model = updateBrowser(t, model, msg)
+	}
+	return model
+}
+
+func newTestBrowser(t *testing.T, backend Backend) BrowserModel {
+	t.Helper()
+
+	model, cmd := InitBrowser(context.Background(), NewBrowserModel(AppOptions{backend: backend}))
+	for _, msg := range immediateMsgs(cmd) {
+		model = updateBrowser(t, model, msg)
+	}
+	return model
+}
+
+func TestBrowserFilterAndSelect(t *testing.T) {
+	backend := NewFakeBackend(
+		UnitInfo{Name: "api", ActiveState: "active", SubState: "running"},
+		UnitInfo{Name: "nginx", ActiveState: "active", SubState: "running"},
+		UnitInfo{Name: "worker", ActiveState: "failed", SubState: "failed"},
+	)
+	model := newTestBrowser(t, backend)
+	if len(model.List.Items()) != 3 {
+		t.Fatalf("items = %v, error = %q", model.List.Items(), model.Error)
+	}
+
+	// Выбранный сервис сохраняется при обновлении списка
+	model = updateBrowser(t, model, tea.KeyMsg{Type: tea.KeyDown})
+	backend.AddUnit(UnitInfo{Name: "cron"})
+	model = updateBrowser(t, model, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	if unit := model.List.SelectedItem().(UnitInfo); unit.Name != "nginx" || len(model.List.Items()) != 4 {
+		t.Errorf("selected = %s, items = %d", unit.Name, len(model.List.Items()))
+	}
+
+	model = updateBrowser(t, model, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	model = updateBrowser(t, model, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("work")})
+	model = updateBrowser(t, model, tea.KeyMsg{Type: tea.KeyEnter})
+	if visible := model.List.VisibleItems(); len(visible) != 1 || visible[0].(UnitInfo).Name != "worker" {
+		t.Fatalf("visible = %v", visible)
+	}
+
+	// Отмеченные сервисы и выбранный открываются в общем журнале
+	model = updateBrowser(t, model, tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")})
+	model = updateBrowser(t, model, tea.KeyMsg{Type: tea.KeyEnter})
+	if !model.PanelOpen || model.Selected != "worker" {
+		t.Fatalf("panel = %v, selected = %q", model.PanelOpen, model.Selected)
+	}
+	if services := BrowserLogServices(model); !slices.Equal(services, []string{"worker"}) {
+		t.Errorf("log services = %v", services)
+	}
+
+	// Esc сначала закрывает панель, затем сбрасывает фильтр и только потом
+	// возвращает в меню
+	model = updateBrowser(t, model, tea.KeyMsg{Type: tea.KeyEsc})
+	model = updateBrowser(t, model, tea.KeyMsg{Type: tea.KeyEsc})
+	if model.PanelOpen || model.Quitting || len(model.List.VisibleItems()) != 4 {
+		t.Errorf("panel = %v, quitting = %v, visible = %d", model.PanelOpen, model.Quitting, len(model.List.VisibleItems()))
+	}
+	model = updateBrowser(t, model, tea.KeyMsg{Type: tea.KeyEsc})
+	if !model.Quitting {
+		t.Error("esc did not quit the browser")
+	}
+}
+
+func TestBrowserActions(t *testing.T) {
+	backend := NewFakeBackend(UnitInfo{Name: "api", ActiveState: "active", SubState: "running"})
+	model := newTestBrowser(t, backend)
+	model = updateBrowser(t, model, tea.KeyMsg{Type: tea.KeyEnter})
+
+	// Действие выполняется в фоне: Update сразу возвращает команду
+	model = updateBrowser(t, model, tea.KeyMsg{Type: tea.KeyDown})
+	model, cmd := UpdateBrowser(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+	if cmd == nil || model.Running == "" || slices.Contains(backend.Calls(), "stop api") {
+		t.Fatalf("running = %q, calls = %v", model.Running, backend.Calls())
+	}
+	if view := ViewBrowser(model); !strings.Contains(view, "Выполняется api: Остановить") {
+		t.Errorf("view does not show running action:\n%s", view)
+	}
+
+	// Повторное нажатие во время выполнения игнорируется
+	if _, again := UpdateBrowser(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model); again != nil {
+		t.Error("second action started while the first is running")
+	}
+
+	for _, msg := range immediateMsgs(cmd) {
+		model = updateBrowser(t, model, msg)
+	}
+	if unit, _ := backend.Unit("api"); unit.ActiveState != "inactive" {
+		t.Errorf("api state = %s", unit.ActiveState)
+	}
+	if model.Running != "" || model.PanelOpen || !strings.Contains(model.Message, "остановлен") {
+		t.Errorf("running = %q, panel = %v, message = %q", model.Running, model.PanelOpen, model.Message)
+	}
+	if state := model.List.Items()[0].(UnitInfo).ActiveState; state != "inactive" {
+		t.Errorf("list was not refreshed: %s", state)
+	}
+
+	// Без ExecReload панель предлагает перезапуск
+	model = updateBrowser(t, model, tea.KeyMsg{Type: tea.KeyEnter})
+	for range 3 {
+		model = updateBrowser(t, model, tea.KeyMsg{Type: tea.KeyDown})
+	}
+	model = updateBrowser(t, model, tea.KeyMsg{Type: tea.KeyEnter})
+	if !model.ConfirmRestart {
+		t.Fatalf("reload without ExecReload: confirm = %v, error = %q", model.ConfirmRestart, model.Error)
+	}
+	model = updateBrowser(t, model, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	if unit, _ := backend.Unit("api"); unit.ActiveState != "active" || model.ConfirmRestart {
+		t.Errorf("api state = %s, confirm = %v", unit.ActiveState, model.ConfirmRestart)
+	}
+
+	// Переход на другой экран выполняет приложение
+	model = updateBrowser(t, model, tea.KeyMsg{Type: tea.KeyEnter})
+	for range 5 {
+		model = updateBrowser(t, model, tea.KeyMsg{Type: tea.KeyDown})
+	}
+	model = updateBrowser(t, model, tea.KeyMsg{Type: tea.KeyEnter})
+	if model.Request != ActionServiceStatus || model.PanelOpen {
+		t.Errorf("request = %q, panel = %v", model.Request, model.PanelOpen)
+	}
+
+	backend.FailOn("start", "api", ErrUnitNotFound)
+	backend.Stop(context.Background(), "api")
+	model.Request = ""
+	model = updateBrowser(t, model, tea.KeyMsg{Type: tea.KeyEnter})
+	model = updateBrowser(t, model, tea.KeyMsg{Type: tea.KeyEnter})
+	if model.Error == "" || !model.PanelOpen {
+		t.Errorf("failed start: error = %q, panel = %v", model.Error, model.PanelOpen)
+	}
+}
@@ -0,0 +1,501 @@
+package sdmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Сокращенные календарные выражения systemd
+var calendarShorthands = map[string]string{
+	"minutely":     "*-*-* *:*:00",
+	"hourly":       "*-*-* *:00:00",
+	"daily":        "*-*-* 00:00:00",
+	"weekly":       "Mon *-*-* 00:00:00",
+	"monthly":      "*-*-01 00:00:00",
+	"yearly":       "*-01-01 00:00:00",
+	"annually":     "*-01-01 00:00:00",
+	"quarterly":    "*-01,04,07,10-01 00:00:00",
+	"semiannually": "*-01,07-01 00:00:00",
+}
+
+// Дни недели в порядке systemd (с понедельника)
+var calendarWeekdays = []struct {
+	short, long string
+	day         time.Weekday
+}{
+	{"mon", "monday", time.Monday},
+	{"tue", "tuesday", time.Tuesday},
+	{"wed", "wednesday", time.Wednesday},
+	{"thu", "thursday", time.Thursday},
+	{"fri", "friday", time.Friday},
+	{"sat", "saturday", time.Saturday},
+	{"sun", "sunday", time.Sunday},
+}
+
+// Разобранное календарное выражение OnCalendar
+type CalendarSpec struct {
+	weekdays [7]bool
+	years    []int
+	months   []int
+	days     []int
+	hours    []int
+	minutes  []int
+	seconds  []int
+	location *time.Location
+}
+
+// Разобрать календарное выражение в формате systemd.time(7):
+// "[DOW] [[YYYY-]MM-DD] [HH:MM[:SS]] [TZ]" со списками (a,b), диапазонами
+// (a..b) и повторениями (a/b), а также сокращения daily, weekly и т.д.
+// Последние дни месяца (~) не поддерживаются.
+func ParseCalendar(expr string) (CalendarSpec, error) {
+	fields := strings.Fields(expr)
+	if len(fields) == 0 {
+		return CalendarSpec{}, errors.New("пустое календарное выражение")
+	}
+
+	spec := CalendarSpec{location: time.Local}
+
+	// Часовой пояс может стоять последним полем
+	if len(fields) > 1 {
+		loc, err := calendarLocation(fields[len(fields)-1])
+		if err != nil {
+			return CalendarSpec{}, err
+		}
+		if loc != nil {
+			spec.location = loc
+			fields = fields[:len(fields)-1]
+		}
+	}
+
+	if len(fields) == 1 {
+		if full, ok := calendarShorthands[strings.ToLower(fields[0])]; ok {
+			fields = strings.Fields(full)
+		}
+	}
+
+	// День недели начинается с буквы, дата и время - с цифры или *
+	weekdays := ""
+	if first := fields[0]; first[0] >= 'A' && first[0] <= 'z' {
+		weekdays, fields = first, fields[1:]
+	}
+
+	date, clock := "*-*-*", "00:00:00"
+	var haveDate, haveTime bool
+	for _, field := range fields {
+		switch {
+		case strings.Contains(field, ":") && !haveTime:
+			clock, haveTime = field, true
+		case strings.Contains(field, "-") && !haveDate && !haveTime:
+			date, haveDate = field, true
+		default:
+			return CalendarSpec{}, fmt.Errorf("непонятная часть выражения %q", field)
+		}
+	}
+
+	if err := spec.parseWeekdays(weekdays); err != nil {
+		return CalendarSpec{}, err
+	}
+	if err := spec.parseDate(date); err != nil {
+		return CalendarSpec{}, err
+	}
+	if err := spec.parseTime(clock); err != nil {
+		return CalendarSpec{}, err
+	}
+
+	return spec, nil
+}
+
+// Часовой пояс из последнего поля выражения или nil, если это поле даты или
+// времени. Повторение в поле времени (*:0/15) тоже содержит "/", поэтому
+// поясом считается только имя без ":", "*" и ",", начинающееся с буквы.
+func calendarLocation(field string) (*time.Location, error) {
+	if strings.ContainsAny(field, ":*,") || field[0] < 'A' || field[0] > 'z' {
+		return nil, nil
+	}
+	if field == "UTC" {
+		return time.UTC, nil
+	}
+	// В именах IANA встречается "-" (America/Port-au-Prince), а дата
+	// начинается с цифры, поэтому "-" допустим только в имени с "/"
+	if strings.Contains(field, "-") && !strings.Contains(field, "/") {
+		return nil, nil
+	}
+
+	loc, err := time.LoadLocation(field)
+	if err != nil {
+		if strings.Contains(field, "/") {
+			return nil, fmt.Errorf("неизвестный часовой пояс %q", field)
+		}
+		return nil, nil
+	}
+	return loc, nil
+}
+
+// Разобрать список дней недели: Mon,Wed..Fri. Пустой список - все дни.
+func (s *CalendarSpec) parseWeekdays(value string) error {
+	if value == "" {
+		s.weekdays = [7]bool{true, true, true, true, true, true, true}
+		return nil
+	}
+
+	index := func(name string) (int, error) {
+		name = strings.ToLower(name)
+		for i, wd := range calendarWeekdays {
+			if name == wd.short || name == wd.long {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("неизвестный день недели %q", name)
+	}
+
+	for _, item := range strings.Split(value, ",") {
+		from, to, isRange := strings.Cut(item, "..")
+		first, err := index(from)
+		if err != nil {
+			return err
+		}
+		last := first
+		if isRange {
+			if last, err = index(to); err != nil {
+				return err
+			}
+		}
+		if last < first {
+			return fmt.Errorf("некорректный диапазон дней недели %q", item)
+		}
+
+		for i := first; i <= last; i++ {
+			s.weekdays[calendarWeekdays[i].day] = true
+		}
+	}
+
+	return nil
+}
+
+// Разобрать дату: [YYYY-]MM-DD
+func (s *CalendarSpec) parseDate(value string) error {
+	if strings.Contains(value, "~") {
+		return errors.New("последние дни месяца (~) не поддерживаются")
+	}
+
+	parts := strings.Split(value, "-")
+	switch len(parts) {
+	case 2:
+		parts = append([]string{"*"}, parts...)
+	case 3:
+	default:
+		return fmt.Errorf("некорректная дата %q", value)
+	}
+
+	var err error
+	if s.years, err = parseCalendarComponent(parts[0], 1970, 2199, "год"); err != nil {
+		return err
+	}
+	if s.months, err = parseCalendarComponent(parts[1], 1, 12, "месяц"); err != nil {
+		return err
+	}
+	s.days, err = parseCalendarComponent(parts[2], 1, 31, "день")
+	return err
+}
+
+// Разобрать время: HH:MM[:SS]
+func (s *CalendarSpec) parseTime(value string) error {
+	parts := strings.Split(value, ":")
+	switch len(parts) {
+	case 2:
+		parts = append(parts, "00")
+	case 3:
+	default:
+		return fmt.Errorf("некорректное время %q", value)
+	}
+
+	var err error
+	if s.hours, err = parseCalendarComponent(parts[0], 0, 23, "час"); err != nil {
+		return err
+	}
+	if s.minutes, err = parseCalendarComponent(parts[1], 0, 59, "минута"); err != nil {
+		return err
+	}
+	s.seconds, err = parseCalendarComponent(parts[2], 0, 59, "секунда")
+	return err
+}
+
+// Разобрать компонент даты или времени: *, 5, 1,15, 1..5, */10, 0/15
+func parseCalendarComponent(value string, minValue, maxValue int, name string) ([]int, error) {
+	invalid := func() error {
+		return fmt.Errorf("некорректное значение %s: %q (допустимо %d-%d)", name, value, minValue, maxValue)
+	}
+
+	var values []int
+	for _, item := range strings.Split(value, ",") {
+		base, stepText, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n <= 0 {
+				return nil, invalid()
+			}
+			step = n
+		}
+
+		var lo, hi int
+		switch from, to, isRange := strings.Cut(base, ".."); {
+		case base == "*":
+			lo, hi = minValue, maxValue
+		case isRange:
+			var errFrom, errTo error
+			lo, errFrom = strconv.Atoi(from)
+			hi, errTo = strconv.Atoi(to)
+			if errFrom != nil || errTo != nil || lo > hi {
+				return nil, invalid()
+			}
+		default:
+			n, err := strconv.Atoi(base)
+			if err != nil {
+				return nil, invalid()
+			}
+			lo, hi = n, n
+			// "a/b" повторяется от a до конца диапазона
+			if hasStep {
+				hi = maxValue
+			}
+		}
+
+		if lo < minValue || hi > maxValue {
+			return nil, invalid()
+		}
+		for v := lo; v <= hi; v += step {
+			values = append(values, v)
+		}
+	}
+
+	slices.Sort(values)
+	return slices.Compact(values), nil
+}
+
+// Ближайшее срабатывание строго после after
+func (s CalendarSpec) Next(after time.Time) (time.Time, bool) {
+	from := after.In(s.location)
+
+	for _, y := range s.years {
+		if y < from.Year() {
+			continue
+		}
+		for _, m := range s.months {
+			if y == from.Year() && m < int(from.Month()) {
+				continue
+			}
+			for _, d := range s.days {
+				if d > daysIn(y, time.Month(m)) {
+					continue
+				}
+				sameMonth := y == from.Year() && m == int(from.Month())
+				if sameMonth && d < from.Day() {
+					continue
+				}
+				if !s.weekdays[time.Date(y, time.Month(m), d, 0, 0, 0, 0, s.location).Weekday()] {
+					continue
+				}
+
+				sameDay := sameMonth && d == from.Day()
+				if t, ok := s.nextOnDay(y, time.Month(m), d, after, sameDay); ok {
+					return t, true
+				}
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// Первое подходящее время в течение дня строго после after
+func (s CalendarSpec) nextOnDay(year int, month time.Month, day int, after time.Time, sameDay bool) (time.Time, bool) {
+	from := after.In(s.location)
+
+	for _, h := range s.hours {
+		if sameDay && h < from.Hour() {
+			continue
+		}
+		for _, mi := range s.minutes {
+			if sameDay && h == from.Hour() && mi < from.Minute() {
+				continue
+			}
+			for _, sec := range s.seconds {
+				t := time.Date(year, month, day, h, mi, sec, 0, s.location)
+				if t.After(after) {
+					return t, true
+				}
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// Количество дней в месяце
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// Ближайшие n срабатываний календарного выражения после from. Выражения,
+// которые не разбирает ParseCalendar, проверяются через systemd-analyze
+// calendar, если он установлен.
+func CalendarNextElapses(ctx context.Context, expr string, from time.Time, n int) ([]time.Time, error) {
+	spec, err := ParseCalendar(expr)
+	if err != nil {
+		if _, lookErr := exec.LookPath("systemd-analyze"); lookErr != nil {
+			return nil, err
+		}
+		return systemdAnalyzeCalendar(ctx, expr, n)
+	}
+
+	var elapses []time.Time
+	for len(elapses) < n {
+		next, ok := spec.Next(from)
+		if !ok {
+			break
+		}
+		elapses = append(elapses, next)
+		from = next
+	}
+
+	if len(elapses) == 0 {
+		return nil, fmt.Errorf("выражение %q больше не сработает", expr)
+	}
+	return elapses, nil
+}
+
+// Строки "Next elapse:" и "Iter. #N:" вывода systemd-analyze calendar
+var calendarElapseLine = regexp.MustCompile(`^\s*(?:Next elapse|Iter\. #\d+):\s*(.+)$`)
+
+// Ближайшие срабатывания по данным systemd-analyze calendar
+func systemdAnalyzeCalendar(ctx context.Context, expr string, n int) ([]time.Time, error) {
+	output, err := ExecuteCommand(ctx, "systemd-analyze", "calendar", "--iterations="+strconv.Itoa(n), expr)
+	if err != nil {
+		return nil, fmt.Errorf("некорректное календарное выражение %q: %w", expr, err)
+	}
+
+	return parseCalendarElapses(output)
+}
+
+// Разобрать время срабатываний из вывода systemd-analyze calendar
+func parseCalendarElapses(output string) ([]time.Time, error) {
+	var elapses []time.Time
+	for _, line := range strings.Split(output, "\n") {
+		match := calendarElapseLine.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		t, err := time.ParseInLocation(systemdTimestampLayout, strings.TrimSpace(match[1]), time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка разбора времени %q: %w", match[1], err)
+		}
+		elapses = append(elapses, t)
+	}
+
+	if len(elapses) == 0 {
+		return nil, errors.New("systemd-analyze не вернул время срабатывания")
+	}
+	return elapses, nil
+}
+
+// Единицы интервалов времени systemd.time(7)
+var timespanUnits = map[string]time.Duration{
+	"usec": time.Microsecond, "us": time.Microsecond, "µs": time.Microsecond,
+	"msec": time.Millisecond, "ms": time.Millisecond,
+	"seconds": time.Second, "second": time.Second, "sec": time.Second, "s": time.Second, "": time.Second,
+	"minutes": time.Minute, "minute": time.Minute, "min": time.Minute, "m": time.Minute,
+	"hours": time.Hour, "hour": time.Hour, "hr": time.Hour, "h": time.Hour,
+	"days": 24 * time.Hour, "day": 24 * time.Hour, "d": 24 * time.Hour,
+	"weeks": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "w": 7 * 24 * time.Hour,
+	"months": 2629800 * time.Second, "month": 2629800 * time.Second, "M": 2629800 * time.Second,
+	"years": 31557600 * time.Second, "year": 31557600 * time.Second, "y": 31557600 * time.Second,
+}
+
+// Одна часть интервала: число и единица
+var timespanPart = regexp.MustCompile(`^\s*(\d+(?:\.\d+)?)\s*([a-zA-Zµ]*)`)
+
+// Разобрать интервал времени systemd: "30", "5min", "1h 30min", "2d"
+func ParseTimespan(value string) (time.Duration, error) {
+	rest := strings.TrimSpace(value)
+	if rest == "" {
+		return 0, errors.New("пустой интервал времени")
+	}
+
+	var total time.Duration
+	for rest != "" {
+		match := timespanPart.FindStringSubmatch(rest)
+		if match == nil {
+			return 0, fmt.Errorf("некорректный интервал времени %q", value)
+		}
+
+		unit, ok := timespanUnits[match[2]]
+		if !ok {
+			return 0, fmt.Errorf("неизвестная единица времени %q в %q", match[2], value)
+		}
+		number, _ := strconv.ParseFloat(match[1], 64)
+		total += time.Duration(number * float64(unit))
+
+		rest = strings.TrimSpace(rest[len(match[0]):])
+	}
+
+	return total, nil
+}
+
+// Единицы интервала времени systemd в микросекундах, от больших к меньшим
+var timespanFormatUnits = []struct {
+	Suffix string
+	USec   uint64
+}{
+	{"y", 31557600 * 1e6},
+	{"month", 2629800 * 1e6},
+	{"w", 7 * 24 * 3600 * 1e6},
+	{"d", 24 * 3600 * 1e6},
+	{"h", 3600 * 1e6},
+	{"min", 60 * 1e6},
+	{"s", 1e6},
+	{"ms", 1e3},
+	{"us", 1},
+}
+
+// Интервал в микросекундах в формате systemctl show (format_timespan в
+// systemd): "1min 30s", "500ms", дробные секунды до минуты - "1.500000s"
+func FormatTimespan(usec uint64) string {
+	if usec == math.MaxUint64 {
+		return "infinity"
+	}
+	if usec == 0 {
+		return "0"
+	}
+
+	var parts []string
+	for _, unit := range timespanFormatUnits {
+		if usec == 0 {
+			break
+		}
+		if usec < unit.USec {
+			continue
+		}
+
+		whole, rest := usec/unit.USec, usec%unit.USec
+		if usec < 60*1e6 && rest > 0 && unit.USec > 1 {
+			digits := len(strconv.FormatUint(unit.USec, 10)) - 1
+			parts = append(parts, fmt.Sprintf("%d.%0*d%s", whole, digits, rest, unit.Suffix))
+			break
+		}
+
+		parts = append(parts, fmt.Sprintf("%d%s", whole, unit.Suffix))
+		usec = rest
+	}
+
+	return strings.Join(parts, " ")
+}
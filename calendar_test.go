@@ -0,0 +1,113 @@
+package sdmanager
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendarNext(t *testing.T) {
+	// Суббота 2024-06-15 10:30 UTC
+	from := time.Date(2024, 6, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"daily UTC", []string{"2024-06-16 00:00:00", "2024-06-17 00:00:00"}},
+		{"hourly UTC", []string{"2024-06-15 11:00:00", "2024-06-15 12:00:00"}},
+		{"Mon..Fri 09:00 UTC", []string{"2024-06-17 09:00:00", "2024-06-18 09:00:00"}},
+		{"Sat,Sun *-*-* 10,11:00 UTC", []string{"2024-06-15 11:00:00", "2024-06-16 10:00:00"}},
+		{"*:0/15 UTC", []string{"2024-06-15 10:45:00", "2024-06-15 11:00:00"}},
+		{"*-*-* *:0/15:00 UTC", []string{"2024-06-15 10:45:00", "2024-06-15 11:00:00"}},
+		{"*-*-* 0/2:0/15 Europe/Berlin", []string{"2024-06-15 10:45:00", "2024-06-15 12:00:00"}},
+		{"*-*-01 03:00 UTC", []string{"2024-07-01 03:00:00", "2024-08-01 03:00:00"}},
+		{"*-02-29 UTC", []string{"2028-02-29 00:00:00", "2032-02-29 00:00:00"}},
+		{"2024-06-15 10:30:00 UTC", nil},
+	}
+
+	for _, tt := range tests {
+		spec, err := ParseCalendar(tt.expr)
+		if err != nil {
+			t.Errorf("ParseCalendar(%q): %v", tt.expr, err)
+			continue
+		}
+
+		var got []string
+		after := from
+		for range 2 {
+			next, ok := spec.Next(after)
+			if !ok {
+				break
+			}
+			got = append(got, next.UTC().Format(time.DateTime))
+			after = next
+		}
+		if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+			t.Errorf("%q: next = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	// Повторение в последнем поле - время, а не часовой пояс
+	for _, expr := range []string{"*:0/15", "*-*-* *:0/15:00", "Mon..Fri 0/2:0/15", "*-*-* 0/15:00"} {
+		spec, err := ParseCalendar(expr)
+		if err != nil || spec.location != time.Local {
+			t.Errorf("ParseCalendar(%q): %v", expr, err)
+		}
+	}
+
+	for _, expr := range []string{"", "Foo 09:00", "25:00", "*-13-01", "*:0/0", "Mon~Fri", "09:00 Europe/Nowhere"} {
+		if _, err := ParseCalendar(expr); err == nil {
+			t.Errorf("ParseCalendar(%q) succeeded", expr)
+		}
+	}
+
+	// Прошедшая дата больше не срабатывает
+	if _, err := CalendarNextElapses(context.Background(), "2024-06-15 10:30:00 UTC", from, 1); err == nil {
+		t.Error("expected error for elapsed date")
+	}
+}
+
+func TestParseCalendarElapses(t *testing.T) {
+	elapses, err := parseCalendarElapses(readFixture(t, "calendar.txt"))
+	if err != nil {
+		t.Fatalf("parseCalendarElapses: %v", err)
+	}
+
+	want := []string{"2026-10-19 09:00:00", "2026-10-20 09:00:00", "2026-10-21 09:00:00"}
+	if len(elapses) != len(want) {
+		t.Fatalf("elapses = %v", elapses)
+	}
+	for i, elapse := range elapses {
+		if got := elapse.UTC().Format(time.DateTime); got != want[i] {
+			t.Errorf("elapse #%d = %s, want %s", i+1, got, want[i])
+		}
+	}
+}
+
+func TestParseTimespan(t *testing.T) {
+	tests := map[string]time.Duration{
+		"30":         30 * time.Second,
+		"5min":       5 * time.Minute,
+		"1h 30min":   90 * time.Minute,
+		"1h30m":      90 * time.Minute,
+		"2d":         48 * time.Hour,
+		"1.5s":       1500 * time.Millisecond,
+		"500ms":      500 * time.Millisecond,
+		"1w 1d":      8 * 24 * time.Hour,
+		"10 minutes": 10 * time.Minute,
+	}
+	for value, want := range tests {
+		got, err := ParseTimespan(value)
+		if err != nil || got != want {
+			t.Errorf("ParseTimespan(%q) = %s, %v; want %s", value, got, err, want)
+		}
+	}
+
+	for _, value := range []string{"", "abc", "5 parsecs", "-1s"} {
+		if _, err := ParseTimespan(value); err == nil {
+			t.Errorf("ParseTimespan(%q) succeeded", value)
+		}
+	}
+}
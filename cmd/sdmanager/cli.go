@@ -0,0 +1,833 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sxwebdev/sdmanager"
+)
+
+// Коды завершения
+const (
+	exitOK           = 0
+	exitError        = 1
+	exitUsage        = 2
+	exitInactive     = 3
+	exitNotFound     = 4
+	exitAccessDenied = 5
+)
+
+// Ошибка: сервис не активен (для команды status, как в systemctl)
+var errInactive = errors.New("сервис не активен")
+
+// Ошибка в аргументах командной строки
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string { return e.err.Error() }
+func (e usageError) Unwrap() error { return e.err }
+
+// Подкоманда CLI
+type command struct {
+	name        string
+	args        string
+	description string
+	run         func(ctx context.Context, b sdmanager.Backend, args []string) error
+}
+
+var commands = []command{
+	{name: "start", args: "<name>", description: "запустить сервис", run: runStart},
+	{name: "stop", args: "<name>", description: "остановить сервис", run: runStop},
+	{name: "restart", args: "<name>", description: "перезапустить сервис", run: runRestart},
+	{name: "reload", args: "<name> [--or-restart]", description: "перечитать конфигурацию сервиса (ExecReload)", run: runReload},
+	{name: "status", args: "<name>", description: "показать состояние сервиса", run: runStatus},
+	{name: "security", args: "<name> [--write] [--restart]", description: "аудит безопасности сервиса (systemd-analyze security)", run: runSecurity},
+	{name: "tune", args: "<name> [--memory-max v ...] [--runtime] [--revert]", description: "изменить ограничения запущенного сервиса без перезапуска (systemctl set-property)", run: runTune},
+	{name: "logs", args: "<name>... [-n lines] [-f] [filters]", description: "показать логи одного или нескольких сервисов", run: runLogs},
+	{name: "export", args: "<name>... [-o file] [--format fmt] [--gzip] [filters]", description: "выгрузить логи сервиса в файл", run: runExport},
+	{name: "install", args: "<name> --exec-start <cmd> [flags]", description: "установить сервис", run: runInstall},
+	{name: "uninstall", args: "<name> [--unit-dir dir]", description: "удалить сервис", run: runUninstall},
+	{name: "scale", args: "<name> <count>", description: "изменить количество экземпляров шаблона <name>@.service", run: runScale},
+	{name: "list", args: "[filter]", description: "показать список сервисов", run: runList},
+	{name: "plan", args: "-f <manifest>", description: "показать изменения, которые внесет манифест", run: runPlan},
+	{name: "apply", args: "-f <manifest>", description: "применить манифест", run: runApply},
+}
+
+// Разобрать аргументы и выполнить команду. Без подкоманды запускается TUI.
+func run(ctx context.Context, args []string, stderr io.Writer) int {
+	global := flag.NewFlagSet("sdmanager", flag.ContinueOnError)
+	global.SetOutput(stderr)
+	showVersion := global.Bool("version", false, "показать версию и выйти")
+	backendName := global.String("backend", "exec", "способ управления systemd: exec (systemctl) или dbus")
+	global.Usage = func() { printUsage(global) }
+
+	if err := global.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	if *showVersion {
+		PrintVersion()
+		return exitOK
+	}
+
+	backend, closeBackend, err := newBackend(*backendName)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %s\n", err)
+		return exitCode(err)
+	}
+	defer closeBackend()
+
+	if global.NArg() == 0 {
+		err := sdmanager.RunSystemdManager(sdmanager.WithContext(ctx), sdmanager.WithBackend(backend))
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %s\n", err)
+			return exitError
+		}
+		return exitOK
+	}
+
+	name := global.Arg(0)
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+
+		if err := cmd.run(ctx, backend, global.Args()[1:]); err != nil {
+			if !errors.Is(err, errInactive) && !errors.Is(err, flag.ErrHelp) {
+				fmt.Fprintf(stderr, "Error: %s\n", err)
+			}
+			return exitCode(err)
+		}
+		return exitOK
+	}
+
+	fmt.Fprintf(stderr, "Error: неизвестная команда %q\n\n", name)
+	global.Usage()
+	return exitUsage
+}
+
+// Код завершения для ошибки
+func exitCode(err error) int {
+	var usageErr usageError
+
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.As(err, &usageErr):
+		return exitUsage
+	case errors.Is(err, errInactive):
+		return exitInactive
+	case errors.Is(err, sdmanager.ErrUnitNotFound):
+		return exitNotFound
+	case errors.Is(err, sdmanager.ErrAccessDenied):
+		return exitAccessDenied
+	default:
+		return exitError
+	}
+}
+
+// Создать Backend по имени. В тестах заменяется на FakeBackend.
+var newBackend = func(name string) (sdmanager.Backend, func(), error) {
+	switch name {
+	case "exec":
+		return sdmanager.NewExecBackend(), func() {}, nil
+	case "dbus":
+		b, err := sdmanager.ConnectDBusBackend()
+		if err != nil {
+			return nil, nil, err
+		}
+		return b, func() { b.Close() }, nil
+	default:
+		return nil, nil, usageError{fmt.Errorf("неизвестный backend %q: ожидается exec или dbus", name)}
+	}
+}
+
+// Вывести справку по командам
+func printUsage(global *flag.FlagSet) {
+	out := global.Output()
+
+	fmt.Fprintf(out, "Использование:\n  sdmanager [flags]                 интерактивный режим\n  sdmanager [flags] <command> [args]\n\nКоманды:\n")
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %s %s\t%s\n", cmd.name, cmd.args, cmd.description)
+	}
+	w.Flush()
+
+	fmt.Fprintf(out, "\nФлаги:\n")
+	global.PrintDefaults()
+}
+
+// Разобрать флаги подкоманды. Имя сервиса может стоять как до, так и после флагов.
+func parseArgs(fs *flag.FlagSet, args []string) (string, error) {
+	var name string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return "", err
+		}
+		return "", usageError{err}
+	}
+
+	rest := fs.Args()
+	if name == "" && len(rest) > 0 {
+		name, rest = rest[0], rest[1:]
+	}
+	if len(rest) > 0 {
+		return "", usageError{fmt.Errorf("лишние аргументы: %s", strings.Join(rest, " "))}
+	}
+
+	return name, nil
+}
+
+// Разобрать аргументы команды, принимающей только имя сервиса
+func serviceNameArg(cmdName string, args []string) (string, error) {
+	fs := flag.NewFlagSet(cmdName, flag.ContinueOnError)
+	name, err := parseArgs(fs, args)
+	if err != nil {
+		return "", err
+	}
+
+	if err := sdmanager.IsValidServiceName(name); err != nil {
+		return "", usageError{err}
+	}
+
+	return name, nil
+}
+
+func runStart(ctx context.Context, b sdmanager.Backend, args []string) error {
+	name, err := serviceNameArg("start", args)
+	if err != nil {
+		return err
+	}
+
+	result, err := sdmanager.StartService(ctx, b, name)
+	if err != nil {
+		return err
+	}
+	fmt.Println(result)
+	return nil
+}
+
+func runStop(ctx context.Context, b sdmanager.Backend, args []string) error {
+	name, err := serviceNameArg("stop", args)
+	if err != nil {
+		return err
+	}
+
+	result, err := sdmanager.StopService(ctx, b, name)
+	if err != nil {
+		return err
+	}
+	fmt.Println(result)
+	return nil
+}
+
+func runRestart(ctx context.Context, b sdmanager.Backend, args []string) error {
+	name, err := serviceNameArg("restart", args)
+	if err != nil {
+		return err
+	}
+
+	result, err := sdmanager.RestartService(ctx, b, name)
+	if err != nil {
+		return err
+	}
+	fmt.Println(result)
+	return nil
+}
+
+func runReload(ctx context.Context, b sdmanager.Backend, args []string) error {
+	fs := flag.NewFlagSet("reload", flag.ContinueOnError)
+	orRestart := fs.Bool("or-restart", false, "перезапустить сервис, если он не поддерживает reload")
+
+	name, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := sdmanager.IsValidServiceName(name); err != nil {
+		return usageError{err}
+	}
+
+	result, err := sdmanager.ReloadService(ctx, b, name)
+	if errors.Is(err, sdmanager.ErrReloadUnsupported) && *orRestart {
+		result, err = sdmanager.RestartService(ctx, b, name)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(result)
+	return nil
+}
+
+func runStatus(ctx context.Context, b sdmanager.Backend, args []string) error {
+	name, err := serviceNameArg("status", args)
+	if err != nil {
+		return err
+	}
+
+	status, err := sdmanager.GetServiceStatus(ctx, b, name)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Сервис:\t%s\n", status.Name)
+	for _, row := range status.Rows(time.Now()) {
+		if row[0] != "" {
+			row[0] += ":"
+		}
+		fmt.Fprintf(w, "%s\t%s\n", row[0], row[1])
+	}
+	w.Flush()
+
+	if status.ActiveState != "active" {
+		return errInactive
+	}
+	return nil
+}
+
+// Флаги отбора записей журнала, общие для logs и export
+func logQueryFlags(fs *flag.FlagSet, lines int) *sdmanager.LogQuery {
+	query := &sdmanager.LogQuery{}
+	fs.IntVar(&query.Lines, "n", lines, "количество записей (0 - все)")
+	fs.StringVar(&query.Since, "since", "", "начало интервала, например \"2024-01-15 10:00\", today, -1h")
+	fs.StringVar(&query.Until, "until", "", "конец интервала")
+	fs.StringVar(&query.Priority, "p", "", "максимальный приоритет: emerg, alert, crit, err, warning, notice, info, debug или 0-7")
+	fs.StringVar(&query.Priority, "priority", "", "то же, что -p")
+	fs.StringVar(&query.Boot, "b", "", "загрузка: 0 - текущая, -1 - предыдущая или ID загрузки")
+	fs.StringVar(&query.Boot, "boot", "", "то же, что -b")
+	fs.StringVar(&query.Grep, "grep", "", "регулярное выражение для отбора по тексту сообщения")
+	return query
+}
+
+// Разобрать имена сервисов и проверить параметры выборки журнала. Имена
+// могут стоять до и после флагов, через пробел или запятую.
+func logQueryArgs(fs *flag.FlagSet, args []string, query *sdmanager.LogQuery) ([]string, error) {
+	var names []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		names, args = append(names, args[0]), args[1:]
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, err
+		}
+		return nil, usageError{err}
+	}
+	names = append(names, fs.Args()...)
+
+	serviceNames, err := sdmanager.ParseServiceNames(strings.Join(names, " "))
+	if err != nil {
+		return nil, usageError{err}
+	}
+	if err := query.Validate(); err != nil {
+		return nil, usageError{err}
+	}
+	return serviceNames, nil
+}
+
+// Вывести запись журнала; в общем журнале с префиксом сервиса
+func printJournalEntry(entry sdmanager.JournalEntry, prefixWidth int) {
+	if prefixWidth > 0 {
+		fmt.Print(sdmanager.ServicePrefix(entry.ServiceName(), prefixWidth))
+	}
+	fmt.Println(sdmanager.RenderJournalEntry(entry))
+}
+
+func runSecurity(ctx context.Context, b sdmanager.Backend, args []string) error {
+	fs := flag.NewFlagSet("security", flag.ContinueOnError)
+	write := fs.Bool("write", false, "записать рекомендации в drop-in и выполнить daemon-reload")
+	restart := fs.Bool("restart", false, "перезапустить сервис после записи рекомендаций")
+
+	name, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := sdmanager.IsValidServiceName(name); err != nil {
+		return usageError{err}
+	}
+
+	report, err := b.SecurityReport(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "ВКЛАД\tПРОВЕРКА\tРЕКОМЕНДАЦИЯ\tОПИСАНИЕ\n")
+	for _, finding := range report.Findings {
+		recommendation := finding.Recommendation()
+		if recommendation == "" {
+			recommendation = "-"
+		}
+		fmt.Fprintf(w, "%.1f\t%s\t%s\t%s\n", finding.Exposure, finding.Name, recommendation, finding.Description)
+	}
+	w.Flush()
+	fmt.Printf("\nОценка: %s\n", report.Exposure)
+
+	if !*write {
+		return nil
+	}
+
+	result, err := sdmanager.ApplySecurityRecommendations(ctx, b, name, report.Findings)
+	fmt.Println(strings.TrimSpace(result))
+	if err != nil || !*restart {
+		return err
+	}
+
+	result, err = sdmanager.RestartService(ctx, b, name)
+	if err != nil {
+		return err
+	}
+	fmt.Println(result)
+	return nil
+}
+
+func runTune(ctx context.Context, b sdmanager.Backend, args []string) error {
+	fs := flag.NewFlagSet("tune", flag.ContinueOnError)
+	values := map[string]*string{
+		"MemoryMax":   fs.String("memory-max", "", "MemoryMax: 512M, 1.5G, 50%, число МБ или infinity"),
+		"MemoryHigh":  fs.String("memory-high", "", "MemoryHigh: 512M, 1.5G, 50%, число МБ или infinity"),
+		"CPUQuota":    fs.String("cpu-quota", "", "CPUQuota в процентах"),
+		"AllowedCPUs": fs.String("allowed-cpus", "", "AllowedCPUs: ядра, например 0-1"),
+		"TasksMax":    fs.String("tasks-max", "", "TasksMax: число задач, процент или infinity"),
+		"IOWeight":    fs.String("io-weight", "", "IOWeight: от 1 до 10000"),
+	}
+	runtime := fs.Bool("runtime", false, "изменить только до перезагрузки, без drop-in в /etc/systemd/system.control")
+	revert := fs.Bool("revert", false, "удалить drop-in файлы set-property и вернуть значения unit-файла")
+
+	name, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := sdmanager.IsValidServiceName(name); err != nil {
+		return usageError{err}
+	}
+
+	if *revert {
+		result, err := sdmanager.RevertLiveResources(ctx, b, name)
+		fmt.Println(strings.TrimSpace(result))
+		return err
+	}
+
+	resources, err := sdmanager.LoadLiveResources(ctx, b, name)
+	if err != nil {
+		return err
+	}
+	changed := false
+	for i := range resources {
+		resources[i].New = *values[resources[i].Key]
+		changed = changed || resources[i].New != ""
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "ОГРАНИЧЕНИЕ\tSYSTEMD\tCGROUP\tНОВОЕ\n")
+	for _, resource := range resources {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", resource.Key, resource.Unit, resource.Cgroup, cmp.Or(resource.New, "-"))
+	}
+	w.Flush()
+
+	dropIns, err := sdmanager.LiveDropIns(name)
+	if err != nil {
+		return err
+	}
+	for _, dropIn := range dropIns {
+		fmt.Printf("Drop-in set-property: %s\n", dropIn.Path)
+	}
+
+	if !changed {
+		return nil
+	}
+
+	result, err := sdmanager.ApplyLiveResources(ctx, b, name, resources, *runtime)
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+	fmt.Println(result)
+	return nil
+}
+
+func runLogs(ctx context.Context, b sdmanager.Backend, args []string) error {
+	fs := flag.NewFlagSet("logs", flag.ContinueOnError)
+	query := logQueryFlags(fs, 50)
+	follow := fs.Bool("f", false, "следить за новыми записями")
+
+	names, err := logQueryArgs(fs, args, query)
+	if err != nil {
+		return err
+	}
+	prefixWidth := sdmanager.ServicePrefixWidth(names)
+
+	if *follow {
+		entries, err := b.FollowLogs(ctx, names, *query)
+		if err != nil {
+			return err
+		}
+		for entry := range entries {
+			printJournalEntry(entry, prefixWidth)
+		}
+		return nil
+	}
+
+	entries, err := b.Logs(ctx, names, *query)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		printJournalEntry(entry, prefixWidth)
+	}
+	return nil
+}
+
+func runExport(ctx context.Context, b sdmanager.Backend, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	query := logQueryFlags(fs, 0)
+	output := fs.String("o", "", "файл для выгрузки; формат и сжатие определяются по расширению (.log, .jsonl, .csv, .gz)")
+	format := fs.String("format", "", "формат: text, jsonl или csv")
+	gzip := fs.Bool("gzip", false, "сжать файл gzip")
+
+	names, err := logQueryArgs(fs, args, query)
+	if err != nil {
+		return err
+	}
+
+	opts := sdmanager.ExportOptionsFromPath(*output)
+	if *format != "" {
+		if opts.Format, err = sdmanager.ParseExportFormat(*format); err != nil {
+			return usageError{err}
+		}
+	}
+	opts.Gzip = opts.Gzip || *gzip
+
+	path := *output
+	if path == "" {
+		path = sdmanager.DefaultExportFileName(strings.Join(names, "+"), opts, time.Now())
+	}
+
+	count, err := sdmanager.ExportLogs(ctx, b, names, *query, path, opts, nil)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Экспортировано записей: %d, файл %s\n", count, path)
+	return nil
+}
+
+func runInstall(ctx context.Context, b sdmanager.Backend, args []string) error {
+	fs := flag.NewFlagSet("install", flag.ContinueOnError)
+	config := sdmanager.ServiceConfig{}
+	fs.StringVar(&config.ExecStart, "exec-start", "", "команда запуска (ExecStart), обязательно")
+	fs.Var((*stringList)(&config.ExecStartPre), "exec-start-pre", "ExecStartPre: команда перед запуском, можно указать несколько раз")
+	fs.Var((*stringList)(&config.ExecStartPost), "exec-start-post", "ExecStartPost: команда после запуска, можно указать несколько раз")
+	fs.Var((*stringList)(&config.ExecReload), "exec-reload", "ExecReload: команда для systemctl reload, можно указать несколько раз")
+	fs.Var((*stringList)(&config.ExecStop), "exec-stop", "ExecStop: команда остановки, можно указать несколько раз")
+	fs.Var((*stringList)(&config.ExecStopPost), "exec-stop-post", "ExecStopPost: команда после остановки, можно указать несколько раз")
+	fs.StringVar(&config.WorkingDirectory, "workdir", sdmanager.GetCurrentDir(), "рабочая директория (WorkingDirectory)")
+	fs.StringVar(&config.UserName, "user", "", "пользователь (User)")
+	fs.StringVar(&config.StandardOutput, "stdout", "", "StandardOutput")
+	fs.StringVar(&config.StandardError, "stderr", "", "StandardError")
+	fs.StringVar(&config.SyslogIdentifier, "syslog-identifier", "", "SyslogIdentifier")
+	fs.StringVar((*string)(&config.MemoryHigh), "memory-high", "", "MemoryHigh: 512M, 1.5G, 50% или число МБ")
+	fs.StringVar((*string)(&config.MemoryMax), "memory-max", "", "MemoryMax: 512M, 1.5G, 50% или число МБ; не больше памяти машины")
+	fs.StringVar((*string)(&config.MemorySwapMax), "memory-swap-max", "", "MemorySwapMax: размер swap, 0M - без swap")
+	fs.Float64Var(&config.CPUQuota, "cpu-quota", 0, "CPUQuota в процентах, например 50 или 12.5 (0 - без ограничений)")
+	fs.IntVar(&config.CPUWeight, "cpu-weight", 0, "CPUWeight: от 1 до 10000")
+	fs.StringVar(&config.AllowedCPUs, "allowed-cpus", "", "AllowedCPUs: ядра, например 0-1 или 0,2")
+	fs.StringVar(&config.AllowedMemoryNodes, "allowed-memory-nodes", "", "AllowedMemoryNodes: узлы NUMA, например 0-1")
+	fs.StringVar(&config.TasksMax, "tasks-max", "", "TasksMax: число задач, процент или infinity")
+	fs.IntVar(&config.IOWeight, "io-weight", 0, "IOWeight: от 1 до 10000")
+	fs.Var((*stringList)(&config.IODeviceReadBandwidthMax), "io-read-bandwidth-max", "IODeviceReadBandwidthMax: «/dev/sda 10M», можно указать несколько раз")
+	fs.Var((*stringList)(&config.IODeviceWriteBandwidthMax), "io-write-bandwidth-max", "IODeviceWriteBandwidthMax: «/dev/sda 10M», можно указать несколько раз")
+	fs.StringVar(&config.LimitNOFILE, "limit-nofile", "", "LimitNOFILE: число, infinity или soft:hard")
+	fs.StringVar(&config.LimitNPROC, "limit-nproc", "", "LimitNPROC: число, infinity или soft:hard")
+	fs.StringVar(&config.LimitCORE, "limit-core", "", "LimitCORE: размер core dump, например 0 или infinity")
+	fs.Func("nice", "Nice: приоритет от -20 до 19", func(value string) error {
+		nice, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		config.Nice = &nice
+		return nil
+	})
+	fs.StringVar(&config.Hardening, "hardening", "", "профиль защиты: none, basic или strict")
+	fs.StringVar(&config.UnitFilePath, "unit-dir", sdmanager.DefaultUnitDir, "каталог для unit-файла")
+	var env, secrets stringList
+	fs.Var(&env, "env", "переменная окружения KEY=value, можно указать несколько раз")
+	fs.Var((*stringList)(&config.EnvironmentFiles), "env-file", "EnvironmentFile, «-» перед путем - файл может отсутствовать; можно указать несколько раз")
+	fs.Var(&secrets, "secret", "секрет KEY=value: записывается в файл с правами 0600, а не в unit-файл; можно указать несколько раз")
+	fs.StringVar(&config.Type, "type", "", "Type: simple, exec, forking, oneshot, notify или idle")
+	fs.StringVar(&config.Restart, "restart", "", "Restart: no, on-success, on-failure, on-abnormal, on-watchdog, on-abort или always (по умолчанию always)")
+	fs.StringVar(&config.RestartSec, "restart-sec", "", "RestartSec: пауза перед перезапуском (по умолчанию 10)")
+	fs.StringVar(&config.StartLimitIntervalSec, "start-limit-interval-sec", "", "StartLimitIntervalSec: окно ограничения частоты запусков")
+	fs.IntVar(&config.StartLimitBurst, "start-limit-burst", 0, "StartLimitBurst: число запусков в окне")
+	fs.StringVar(&config.TimeoutStartSec, "timeout-start-sec", "", "TimeoutStartSec")
+	fs.StringVar(&config.TimeoutStopSec, "timeout-stop-sec", "", "TimeoutStopSec")
+	fs.StringVar(&config.KillMode, "kill-mode", "", "KillMode: control-group, mixed, process или none")
+	fs.StringVar(&config.KillSignal, "kill-signal", "", "KillSignal, например SIGINT")
+	fs.BoolVar(&config.RemainAfterExit, "remain-after-exit", false, "RemainAfterExit: для Type=oneshot")
+	fs.IntVar(&config.Instances, "instances", 0, "создать шаблон <name>@.service и запустить N экземпляров (имя вида worker@ - 1 экземпляр)")
+	noReload := fs.Bool("no-reload", false, "не выполнять daemon-reload")
+	noEnable := fs.Bool("no-enable", false, "не активировать (enable) сервис")
+	noStart := fs.Bool("no-start", false, "не запускать (start) сервис")
+	overwrite := fs.Bool("overwrite", false, "перезаписать существующий unit-файл")
+
+	// Таймер: при любом из этих флагов сервис создается как oneshot с .timer
+	timer := sdmanager.TimerConfig{}
+	fs.Var((*stringList)(&timer.OnCalendar), "on-calendar", "OnCalendar, можно указать несколько раз")
+	fs.StringVar(&timer.OnBootSec, "on-boot-sec", "", "OnBootSec: запуск после загрузки")
+	fs.StringVar(&timer.OnUnitActiveSec, "on-unit-active-sec", "", "OnUnitActiveSec: повтор после предыдущего запуска")
+	fs.StringVar(&timer.RandomizedDelaySec, "randomized-delay-sec", "", "RandomizedDelaySec")
+	fs.StringVar(&timer.AccuracySec, "accuracy-sec", "", "AccuracySec")
+	fs.BoolVar(&timer.Persistent, "persistent", false, "Persistent: запускать пропущенные срабатывания")
+
+	// Сокет: при любом из этих флагов сервис запускается через .socket
+	socket := sdmanager.SocketConfig{}
+	fs.Var((*stringList)(&socket.ListenStream), "listen-stream", "ListenStream: порт, адрес:порт или путь, можно указать несколько раз")
+	fs.Var((*stringList)(&socket.ListenDatagram), "listen-datagram", "ListenDatagram, можно указать несколько раз")
+	fs.BoolVar(&socket.Accept, "accept", false, "Accept: экземпляр сервиса на каждое подключение")
+	fs.StringVar(&socket.SocketUser, "socket-user", "", "SocketUser")
+	fs.StringVar(&socket.SocketMode, "socket-mode", "", "SocketMode, например 0660")
+	fs.StringVar(&socket.BindIPv6Only, "bind-ipv6-only", "", "BindIPv6Only: default, both или ipv6-only")
+
+	// Зависимости: можно указать несколько раз или несколько unit через пробел
+	fs.Var((*stringList)(&config.After), "after", "After: запускать после unit (по умолчанию network.target)")
+	fs.Var((*stringList)(&config.Before), "before", "Before: запускать до unit")
+	fs.Var((*stringList)(&config.Wants), "wants", "Wants: желательные зависимости")
+	fs.Var((*stringList)(&config.Requires), "requires", "Requires: обязательные зависимости")
+	fs.Var((*stringList)(&config.BindsTo), "binds-to", "BindsTo: остановить сервис вместе с unit")
+	fs.Var((*stringList)(&config.PartOf), "part-of", "PartOf: останавливать и перезапускать вместе с unit")
+	fs.Var((*stringList)(&config.Conflicts), "conflicts", "Conflicts: не запускать одновременно с unit")
+	fs.Var((*stringList)(&config.WantedBy), "wanted-by", "WantedBy: цели для enable (по умолчанию multi-user.target)")
+	fs.Var((*stringList)(&config.RequiredBy), "required-by", "RequiredBy: цели, для которых сервис обязателен")
+
+	// Path-unit: при любом из этих флагов сервис создается как oneshot с .path
+	path := sdmanager.PathConfig{}
+	fs.Var((*stringList)(&path.PathExists), "path-exists", "PathExists: запуск при появлении пути, можно указать несколько раз")
+	fs.Var((*stringList)(&path.PathChanged), "path-changed", "PathChanged: запуск после записи файла, можно указать несколько раз")
+	fs.Var((*stringList)(&path.PathModified), "path-modified", "PathModified: запуск при любой записи, можно указать несколько раз")
+	fs.Var((*stringList)(&path.DirectoryNotEmpty), "directory-not-empty", "DirectoryNotEmpty: запуск при появлении файлов в каталоге, можно указать несколько раз")
+	fs.BoolVar(&path.MakeDirectory, "make-directory", false, "MakeDirectory: создать отслеживаемые каталоги")
+
+	name, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	config.ServiceName = name
+	config = sdmanager.NormalizeTemplateConfig(config)
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "on-calendar", "on-boot-sec", "on-unit-active-sec", "randomized-delay-sec", "accuracy-sec", "persistent":
+			config.Timer = &timer
+		case "listen-stream", "listen-datagram", "accept", "socket-user", "socket-mode", "bind-ipv6-only":
+			config.Socket = &socket
+		case "path-exists", "path-changed", "path-modified", "directory-not-empty", "make-directory":
+			config.Path = &path
+		}
+	})
+
+	if config.Environment, err = parseEnvFlags(env); err != nil {
+		return usageError{fmt.Errorf("--env: %w", err)}
+	}
+	if config.Secrets, err = parseEnvFlags(secrets); err != nil {
+		return usageError{fmt.Errorf("--secret: %w", err)}
+	}
+
+	for _, units := range []*[]string{
+		&config.After, &config.Before, &config.Wants, &config.Requires, &config.BindsTo,
+		&config.PartOf, &config.Conflicts, &config.WantedBy, &config.RequiredBy,
+	} {
+		if len(*units) > 0 {
+			*units = strings.Fields(strings.Join(*units, " "))
+		}
+	}
+
+	if err := sdmanager.ValidateServiceConfig(config); err != nil {
+		return usageError{err}
+	}
+
+	// Несуществующие unit не мешают установке: они могут появиться позже
+	if missing, err := sdmanager.MissingDependencies(ctx, b, config); err == nil && len(missing) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: unit-файлы не найдены: %s\n", strings.Join(missing, ", "))
+	}
+
+	actions := sdmanager.UserActions{
+		Overwrite:     *overwrite,
+		ReloadDaemon:  !*noReload,
+		EnableService: !*noEnable,
+		StartService:  !*noStart,
+	}
+
+	result, err := sdmanager.InstallService(ctx, b, config, actions)
+	fmt.Println(strings.TrimSpace(result))
+	if err != nil {
+		return err
+	}
+
+	// Оценка защищенности: без systemd-analyze просто пропускается
+	if config.Hardening == "basic" || config.Hardening == "strict" {
+		if units, err := sdmanager.GenerateUnits(config); err == nil {
+			if exposure, err := sdmanager.AnalyzeUnitSecurity(ctx, units[0]); err == nil && exposure != "" {
+				fmt.Printf("Оценка systemd-analyze security: %s\n", exposure)
+			}
+		}
+	}
+	return nil
+}
+
+// Флаг, который можно указать несколько раз
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, "; ") }
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func runUninstall(ctx context.Context, b sdmanager.Backend, args []string) error {
+	fs := flag.NewFlagSet("uninstall", flag.ContinueOnError)
+	unitDir := fs.String("unit-dir", sdmanager.DefaultUnitDir, "каталог unit-файла")
+
+	name, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := sdmanager.IsValidServiceName(name); err != nil {
+		return usageError{err}
+	}
+
+	result, err := sdmanager.UninstallService(ctx, b, *unitDir, name)
+	if result != "" {
+		fmt.Println(result)
+	}
+	return err
+}
+
+// Разобрать повторяющийся флаг KEY=value в словарь переменных
+func parseEnvFlags(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	env := make(map[string]string, len(values))
+	for _, value := range values {
+		name, val, err := sdmanager.ParseEnvAssignment(value)
+		if err != nil {
+			return nil, err
+		}
+		env[name] = val
+	}
+	return env, nil
+}
+
+func runScale(ctx context.Context, b sdmanager.Backend, args []string) error {
+	if len(args) != 2 {
+		return usageError{errors.New("использование: scale <name> <count>")}
+	}
+
+	name, _ := sdmanager.ParseTemplateName(args[0])
+	if err := sdmanager.IsValidServiceName(name); err != nil {
+		return usageError{err}
+	}
+	count, err := strconv.Atoi(args[1])
+	if err != nil {
+		return usageError{fmt.Errorf("количество экземпляров: %w", err)}
+	}
+	if err := sdmanager.ValidateInstanceCount(count); err != nil {
+		return usageError{err}
+	}
+
+	result, err := sdmanager.ScaleTemplate(ctx, b, name, count)
+	if result != "" {
+		fmt.Println(result)
+	}
+	return err
+}
+
+func runList(ctx context.Context, b sdmanager.Backend, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	filter, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	units, err := b.ListUnits(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tACTIVE\tSUB\tUNIT FILE\tDESCRIPTION")
+	for _, unit := range units {
+		if filter != "" && !strings.Contains(unit.FilterValue(), filter) {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", unit.Name, unit.ActiveState, unit.SubState, unit.UnitFileState, unit.Description)
+	}
+	return w.Flush()
+}
+
+// Разобрать аргументы команд plan и apply и построить план
+func manifestPlan(cmdName string, args []string) (sdmanager.Plan, error) {
+	fs := flag.NewFlagSet(cmdName, flag.ContinueOnError)
+	file := fs.String("f", "", "файл манифеста (YAML или JSON, - для stdin)")
+
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return sdmanager.Plan{}, err
+	}
+	if rest != "" {
+		return sdmanager.Plan{}, usageError{fmt.Errorf("лишние аргументы: %s", rest)}
+	}
+	if *file == "" {
+		return sdmanager.Plan{}, usageError{errors.New("не указан файл манифеста (-f)")}
+	}
+
+	manifest, err := sdmanager.LoadManifest(*file)
+	if err != nil {
+		return sdmanager.Plan{}, usageError{err}
+	}
+
+	return sdmanager.BuildPlan(manifest)
+}
+
+func runPlan(ctx context.Context, b sdmanager.Backend, args []string) error {
+	plan, err := manifestPlan("plan", args)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(plan.Render())
+	return plan.Conflicts()
+}
+
+func runApply(ctx context.Context, b sdmanager.Backend, args []string) error {
+	plan, err := manifestPlan("apply", args)
+	if err != nil {
+		return err
+	}
+
+	result, err := sdmanager.ApplyPlan(ctx, b, plan)
+	if result != "" {
+		fmt.Println(result)
+	}
+	return err
+}
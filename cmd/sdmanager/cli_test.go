@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sxwebdev/sdmanager"
+)
+
+// Выполнить команду CLI с заданным Backend. Возвращает код завершения,
+// stdout и stderr.
+func runCLI(t *testing.T, backend sdmanager.Backend, args ...string) (int, string, string) {
+	t.Helper()
+
+	if backend != nil {
+		saved := newBackend
+		defer func() { newBackend = saved }()
+		newBackend = func(string) (sdmanager.Backend, func(), error) {
+			return backend, func() {}, nil
+		}
+	}
+
+	// Команды пишут результат в os.Stdout
+	stdout, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdout.Close()
+	savedStdout := os.Stdout
+	os.Stdout = stdout
+	defer func() { os.Stdout = savedStdout }()
+
+	var stderr bytes.Buffer
+	code := run(context.Background(), args, &stderr)
+
+	output, err := os.ReadFile(stdout.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code, string(output), stderr.String()
+}
+
+func TestRunExitCodes(t *testing.T) {
+	backend := sdmanager.NewFakeBackend(
+		sdmanager.UnitInfo{Name: "api", ActiveState: "active", SubState: "running"},
+		sdmanager.UnitInfo{Name: "worker"},
+		sdmanager.UnitInfo{Name: "locked"},
+	)
+	backend.FailOn("start", "locked", sdmanager.ErrAccessDenied)
+
+	tests := []struct {
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{[]string{"start", "worker"}, exitOK, "успешно запущен", ""},
+		{[]string{"start", "missing"}, exitNotFound, "", "сервис не найден"},
+		{[]string{"start", "locked"}, exitAccessDenied, "", "недостаточно прав"},
+		{[]string{"status", "api"}, exitOK, "Сервис:", ""},
+		{[]string{"status", "missing"}, exitNotFound, "", "сервис не найден"},
+		{[]string{"reload", "api"}, exitError, "", "не поддерживает reload"},
+		{[]string{"reload", "api", "--or-restart"}, exitOK, "перезапущен", ""},
+		{[]string{"list"}, exitOK, "worker", ""},
+
+		// Ошибки в аргументах
+		{[]string{"start"}, exitUsage, "", "Error:"},
+		{[]string{"start", "api", "worker"}, exitUsage, "", "лишние аргументы: worker"},
+		{[]string{"start", "bad/name"}, exitUsage, "", "Error:"},
+		{[]string{"reload", "--unknown", "api"}, exitUsage, "", "flag provided but not defined"},
+		{[]string{"scale", "worker"}, exitUsage, "", "scale <name> <count>"},
+		{[]string{"scale", "worker", "many"}, exitUsage, "", "количество экземпляров"},
+		{[]string{"logs", "api", "-p", "loud"}, exitUsage, "", "Error:"},
+		{[]string{"install", "api"}, exitUsage, "", "ExecStart"},
+		{[]string{"frobnicate"}, exitUsage, "", "неизвестная команда"},
+		{[]string{"--frobnicate"}, exitUsage, "", "flag provided but not defined"},
+		{[]string{"start", "-h"}, exitOK, "", ""},
+	}
+
+	for _, tt := range tests {
+		code, stdout, stderr := runCLI(t, backend, tt.args...)
+		if code != tt.code {
+			t.Errorf("%v: code = %d, want %d (stderr: %s)", tt.args, code, tt.code, stderr)
+		}
+		if !strings.Contains(stdout, tt.stdout) || !strings.Contains(stderr, tt.stderr) {
+			t.Errorf("%v: stdout = %q, stderr = %q", tt.args, stdout, stderr)
+		}
+	}
+
+	// Неактивный сервис: код 3 без сообщения об ошибке, как systemctl status
+	backend.Stop(context.Background(), "api")
+	if code, _, stderr := runCLI(t, backend, "status", "api"); code != exitInactive || stderr != "" {
+		t.Errorf("status inactive: code = %d, stderr = %q", code, stderr)
+	}
+
+	if code, _, stderr := runCLI(t, nil, "--backend", "soap", "list"); code != exitUsage || !strings.Contains(stderr, "soap") {
+		t.Errorf("unknown backend: code = %d, stderr = %q", code, stderr)
+	}
+}
+
+func TestRunExecBackendErrors(t *testing.T) {
+	// systemctl, который отвечает так же, как настоящий, для отсутствующего
+	// unit и при нехватке прав
+	dir := t.TempDir()
+	script := `#!/bin/sh
+case "$2" in
+missing) echo "Failed to $1 missing.service: Unit missing.service not found." >&2; exit 5 ;;
+locked) echo "Failed to $1 locked.service: Access denied" >&2; exit 4 ;;
+broken) echo "Job for broken.service failed because the control process exited with error code." >&2; exit 1 ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, "systemctl"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	for args, want := range map[string]int{
+		"start missing": exitNotFound,
+		"stop missing":  exitNotFound,
+		"start locked":  exitAccessDenied,
+		"start broken":  exitError,
+		"start api":     exitOK,
+	} {
+		code, _, stderr := runCLI(t, nil, append([]string{"--backend", "exec"}, strings.Fields(args)...)...)
+		if code != want {
+			t.Errorf("%s: code = %d, want %d (stderr: %s)", args, code, want, stderr)
+		}
+	}
+}
@@ -6,8 +6,6 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-
-	"github.com/sxwebdev/sdmanager"
 )
 
 var (
@@ -24,11 +22,9 @@ func PrintVersion() {
 
 func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGKILL)
-	defer cancel()
 
-	err := sdmanager.RunSystemdManager(sdmanager.WithContext(ctx))
-	if err != nil {
-		fmt.Printf("Error: %s\n", err)
-		os.Exit(1)
-	}
+	code := run(ctx, os.Args[1:], os.Stderr)
+	cancel()
+
+	os.Exit(code)
 }
@@ -0,0 +1,440 @@
+package sdmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Имена D-Bus API systemd
+const (
+	systemdBusName          = "org.freedesktop.systemd1"
+	systemdObjectPath       = dbus.ObjectPath("/org/freedesktop/systemd1")
+	systemdManagerInterface = "org.freedesktop.systemd1.Manager"
+	systemdUnitInterface    = "org.freedesktop.systemd1.Unit"
+	systemdServiceInterface = "org.freedesktop.systemd1.Service"
+	dbusPropertiesInterface = "org.freedesktop.DBus.Properties"
+)
+
+// Ошибка: недостаточно прав для выполнения операции
+var ErrAccessDenied = errors.New("недостаточно прав")
+
+// Ошибка выполнения задания systemd (job), завершившегося не с результатом "done"
+type JobError struct {
+	Unit   string
+	Job    dbus.ObjectPath
+	Result string
+}
+
+func (e *JobError) Error() string {
+	return fmt.Sprintf("задание %s для %s завершилось с результатом %s", e.Job, e.Unit, e.Result)
+}
+
+// Реализация Backend через D-Bus API systemd (org.freedesktop.systemd1).
+// Журнал и аудит безопасности D-Bus не предоставляет, поэтому они
+// выполняются через journalctl и systemd-analyze. Изменение свойств тоже
+// выполняется через systemctl set-property: он переводит записи вида
+// "MemoryMax=1G" в типы D-Bus.
+type DBusBackend struct {
+	*ExecBackend
+
+	conn    *dbus.Conn
+	manager dbus.BusObject
+	signals chan *dbus.Signal
+
+	mu   sync.Mutex
+	jobs map[dbus.ObjectPath]chan string
+}
+
+// Подключиться к системной шине и создать DBusBackend
+func ConnectDBusBackend() (*DBusBackend, error) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка подключения к системной шине D-Bus: %w", err)
+	}
+
+	b, err := NewDBusBackend(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Создать DBusBackend поверх установленного соединения и подписаться на
+// сигналы JobRemoved для отслеживания результата заданий
+func NewDBusBackend(conn *dbus.Conn) (*DBusBackend, error) {
+	b := &DBusBackend{
+		ExecBackend: NewExecBackend(),
+		conn:        conn,
+		manager:     conn.Object(systemdBusName, systemdObjectPath),
+		signals:     make(chan *dbus.Signal, 64),
+		jobs:        make(map[dbus.ObjectPath]chan string),
+	}
+
+	err := conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(systemdObjectPath),
+		dbus.WithMatchInterface(systemdManagerInterface),
+		dbus.WithMatchMember("JobRemoved"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка подписки на сигналы systemd: %w", err)
+	}
+
+	// Без Subscribe systemd не рассылает сигналы о заданиях
+	if err := b.manager.Call(systemdManagerInterface+".Subscribe", 0).Err; err != nil {
+		return nil, convertDBusError("", err)
+	}
+
+	conn.Signal(b.signals)
+	go b.dispatchSignals()
+
+	return b, nil
+}
+
+// Закрыть соединение с D-Bus
+func (b *DBusBackend) Close() error {
+	b.conn.RemoveSignal(b.signals)
+	return b.conn.Close()
+}
+
+// Передать результат завершенного задания ожидающему его вызову
+func (b *DBusBackend) dispatchSignals() {
+	for signal := range b.signals {
+		if signal.Name != systemdManagerInterface+".JobRemoved" || len(signal.Body) < 4 {
+			continue
+		}
+
+		job, _ := signal.Body[1].(dbus.ObjectPath)
+		result, _ := signal.Body[3].(string)
+
+		b.mu.Lock()
+		if ch, ok := b.jobs[job]; ok {
+			delete(b.jobs, job)
+			ch <- result
+		}
+		b.mu.Unlock()
+	}
+}
+
+// Запустить задание и дождаться его завершения
+func (b *DBusBackend) runJob(ctx context.Context, method, serviceName string) error {
+	unit := unitName(serviceName)
+
+	// Блокировка удерживается до регистрации задания, чтобы сигнал
+	// JobRemoved не был обработан раньше, чем мы начнем его ждать
+	b.mu.Lock()
+	var job dbus.ObjectPath
+	err := b.manager.CallWithContext(ctx, systemdManagerInterface+"."+method, 0, unit, "replace").Store(&job)
+	if err != nil {
+		b.mu.Unlock()
+		return convertDBusError(unit, err)
+	}
+
+	done := make(chan string, 1)
+	b.jobs[job] = done
+	b.mu.Unlock()
+
+	select {
+	case result := <-done:
+		if result != "done" {
+			return &JobError{Unit: unit, Job: job, Result: result}
+		}
+		return nil
+
+	case <-ctx.Done():
+		b.mu.Lock()
+		delete(b.jobs, job)
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+func (b *DBusBackend) Start(ctx context.Context, serviceName string) error {
+	return b.runJob(ctx, "StartUnit", serviceName)
+}
+
+func (b *DBusBackend) Stop(ctx context.Context, serviceName string) error {
+	return b.runJob(ctx, "StopUnit", serviceName)
+}
+
+func (b *DBusBackend) Restart(ctx context.Context, serviceName string) error {
+	return b.runJob(ctx, "RestartUnit", serviceName)
+}
+
+func (b *DBusBackend) Reload(ctx context.Context, serviceName string) error {
+	return b.runJob(ctx, "ReloadUnit", serviceName)
+}
+
+func (b *DBusBackend) Enable(ctx context.Context, serviceName string) error {
+	unit := unitName(serviceName)
+
+	// Как и systemctl enable, не заменяем чужие символические ссылки (force=false)
+	err := b.manager.CallWithContext(ctx, systemdManagerInterface+".EnableUnitFiles", 0, []string{unit}, false, false).Err
+	if err != nil {
+		return convertDBusError(unit, err)
+	}
+
+	// systemctl enable после изменения символических ссылок выполняет reload
+	return b.DaemonReload(ctx)
+}
+
+func (b *DBusBackend) Disable(ctx context.Context, serviceName string) error {
+	unit := unitName(serviceName)
+
+	err := b.manager.CallWithContext(ctx, systemdManagerInterface+".DisableUnitFiles", 0, []string{unit}, false).Err
+	if err != nil {
+		return convertDBusError(unit, err)
+	}
+
+	return b.DaemonReload(ctx)
+}
+
+func (b *DBusBackend) DaemonReload(ctx context.Context) error {
+	if err := b.manager.CallWithContext(ctx, systemdManagerInterface+".Reload", 0).Err; err != nil {
+		return convertDBusError("", err)
+	}
+	return nil
+}
+
+func (b *DBusBackend) Status(ctx context.Context, serviceName string) (UnitInfo, error) {
+	props, err := b.unitProperties(ctx, serviceName, systemdUnitInterface)
+	if err != nil {
+		return UnitInfo{}, err
+	}
+
+	info := UnitInfo{
+		Name:          strings.TrimSuffix(variantString(props["Id"]), ".service"),
+		Description:   variantString(props["Description"]),
+		LoadState:     variantString(props["LoadState"]),
+		ActiveState:   variantString(props["ActiveState"]),
+		SubState:      variantString(props["SubState"]),
+		UnitFileState: variantString(props["UnitFileState"]),
+	}
+
+	if info.LoadState == "not-found" {
+		return UnitInfo{}, fmt.Errorf("%s: %w", serviceName, ErrUnitNotFound)
+	}
+
+	return info, nil
+}
+
+// Получить свойства сервиса в формате systemctl show. Свойства читаются из
+// интерфейсов Unit и Service и приводятся к строкам так же, как это делает systemctl.
+func (b *DBusBackend) Properties(ctx context.Context, serviceName string, names ...string) (map[string]string, error) {
+	props := make(map[string]string)
+
+	for _, iface := range []string{systemdUnitInterface, systemdServiceInterface} {
+		values, err := b.unitProperties(ctx, serviceName, iface)
+		if err != nil {
+			return nil, err
+		}
+
+		for name, value := range values {
+			if len(names) > 0 && !slices.Contains(names, name) {
+				continue
+			}
+			if s, ok := formatDBusProperty(name, value); ok {
+				props[name] = s
+			}
+		}
+	}
+
+	return props, nil
+}
+
+// Получить свойства unit через org.freedesktop.DBus.Properties.GetAll
+func (b *DBusBackend) unitProperties(ctx context.Context, serviceName, iface string) (map[string]dbus.Variant, error) {
+	unit := unitName(serviceName)
+
+	var path dbus.ObjectPath
+	if err := b.manager.CallWithContext(ctx, systemdManagerInterface+".LoadUnit", 0, unit).Store(&path); err != nil {
+		return nil, convertDBusError(unit, err)
+	}
+
+	var props map[string]dbus.Variant
+	err := b.conn.Object(systemdBusName, path).CallWithContext(ctx, dbusPropertiesInterface+".GetAll", 0, iface).Store(&props)
+	if err != nil {
+		return nil, convertDBusError(unit, err)
+	}
+
+	return props, nil
+}
+
+func (b *DBusBackend) ListUnits(ctx context.Context) ([]UnitInfo, error) {
+	var loaded []struct {
+		Name        string
+		Description string
+		LoadState   string
+		ActiveState string
+		SubState    string
+		Following   string
+		Path        dbus.ObjectPath
+		JobID       uint32
+		JobType     string
+		JobPath     dbus.ObjectPath
+	}
+	if err := b.manager.CallWithContext(ctx, systemdManagerInterface+".ListUnits", 0).Store(&loaded); err != nil {
+		return nil, convertDBusError("", err)
+	}
+
+	var units []UnitInfo
+	for _, unit := range loaded {
+		if !strings.HasSuffix(unit.Name, ".service") {
+			continue
+		}
+		units = append(units, UnitInfo{
+			Name:        strings.TrimSuffix(unit.Name, ".service"),
+			Description: unit.Description,
+			LoadState:   unit.LoadState,
+			ActiveState: unit.ActiveState,
+			SubState:    unit.SubState,
+		})
+	}
+
+	var files []struct {
+		Path  string
+		State string
+	}
+	if err := b.manager.CallWithContext(ctx, systemdManagerInterface+".ListUnitFiles", 0).Store(&files); err != nil {
+		return nil, convertDBusError("", err)
+	}
+
+	fileStates := make(map[string]string)
+	for _, file := range files {
+		name := filepath.Base(file.Path)
+		if strings.HasSuffix(name, ".service") {
+			fileStates[strings.TrimSuffix(name, ".service")] = file.State
+		}
+	}
+
+	return MergeUnitFileStates(units, fileStates), nil
+}
+
+func (b *DBusBackend) ListUnitFiles(ctx context.Context) ([]string, error) {
+	var files []struct {
+		Path  string
+		State string
+	}
+	if err := b.manager.CallWithContext(ctx, systemdManagerInterface+".ListUnitFiles", 0).Store(&files); err != nil {
+		return nil, convertDBusError("", err)
+	}
+
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, filepath.Base(file.Path))
+	}
+	return names, nil
+}
+
+// Полное имя unit: без явного типа считаем, что это сервис
+func unitName(serviceName string) string {
+	if slices.Contains(unitTypeSuffixes, filepath.Ext(serviceName)) {
+		return serviceName
+	}
+	return serviceName + ".service"
+}
+
+// Строковое значение свойства D-Bus
+func variantString(v dbus.Variant) string {
+	s, _ := v.Value().(string)
+	return s
+}
+
+// Привести значение свойства D-Bus к формату systemctl show. Сложные
+// структуры (списки ExecStart и т.п.) не поддерживаются.
+func formatDBusProperty(name string, v dbus.Variant) (string, bool) {
+	switch value := v.Value().(type) {
+	case string:
+		return value, true
+	case dbus.ObjectPath:
+		return string(value), true
+	case []string:
+		return strings.Join(value, " "), true
+	case bool:
+		if value {
+			return "yes", true
+		}
+		return "no", true
+	case uint64:
+		// Временные метки передаются в микросекундах от начала эпохи
+		if strings.HasSuffix(name, "Timestamp") {
+			if value == 0 {
+				return "", true
+			}
+			return time.UnixMicro(int64(value)).Format(systemdTimestampLayout), true
+		}
+		// Интервалы systemctl show выводит в виде "1min 30s"
+		if strings.HasSuffix(name, "USec") {
+			return FormatTimespan(value), true
+		}
+		return strconv.FormatUint(value, 10), true
+	case int32, uint32, int64, uint16, int16, byte:
+		return fmt.Sprint(value), true
+	case []byte:
+		// Маски CPU и узлов NUMA systemctl show выводит списком "0-1 3"
+		if strings.HasSuffix(name, "CPUs") || strings.HasSuffix(name, "MemoryNodes") {
+			return formatCPUSet(value), true
+		}
+		return "", false
+	default:
+		return "", false
+	}
+}
+
+// Список номеров из битовой маски: бит j байта i - номер i*8+j
+func formatCPUSet(mask []byte) string {
+	var ranges []string
+	start := -1
+	for i := 0; i <= len(mask)*8; i++ {
+		set := i < len(mask)*8 && mask[i/8]&(1<<(i%8)) != 0
+		switch {
+		case set && start < 0:
+			start = i
+		case !set && start >= 0:
+			if start == i-1 {
+				ranges = append(ranges, strconv.Itoa(start))
+			} else {
+				ranges = append(ranges, fmt.Sprintf("%d-%d", start, i-1))
+			}
+			start = -1
+		}
+	}
+	return strings.Join(ranges, " ")
+}
+
+// Преобразовать ошибку D-Bus в ошибки пакета, сохранив исходное сообщение
+func convertDBusError(unit string, err error) error {
+	var dbusErr dbus.Error
+	if !errors.As(err, &dbusErr) {
+		var dbusErrPtr *dbus.Error
+		if !errors.As(err, &dbusErrPtr) {
+			return err
+		}
+		dbusErr = *dbusErrPtr
+	}
+
+	var target error
+	switch dbusErr.Name {
+	case "org.freedesktop.systemd1.NoSuchUnit", "org.freedesktop.systemd1.LoadFailed":
+		target = ErrUnitNotFound
+	case "org.freedesktop.DBus.Error.AccessDenied", "org.freedesktop.DBus.Error.InteractiveAuthorizationRequired":
+		target = ErrAccessDenied
+	default:
+		return err
+	}
+
+	if unit == "" {
+		return fmt.Errorf("%w: %s", target, dbusErr.Error())
+	}
+	return fmt.Errorf("%s: %w: %s", unit, target, dbusErr.Error())
+}
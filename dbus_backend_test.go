@@ -0,0 +1,382 @@
+package sdmanager
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// Конфигурация частной шины для тестов
+const testBusConfig = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>custom</type>
+  <listen>unix:path=BUS_SOCKET</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+
+// Заглушка org.freedesktop.systemd1.Manager
+type stubManager struct {
+	conn *dbus.Conn
+
+	mu      sync.Mutex
+	units   map[string]*UnitInfo
+	results map[string]string
+	jobs    uint32
+	reloads int
+	denied  bool
+	// Последний EnableUnitFiles был вызван с force
+	forced bool
+}
+
+// Завершить задание: сигнал отправляется после ответа на вызов, как в systemd
+func (m *stubManager) job(name string, apply func(*UnitInfo)) (dbus.ObjectPath, *dbus.Error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.denied {
+		return "", dbus.NewError("org.freedesktop.DBus.Error.AccessDenied", []interface{}{"Access denied"})
+	}
+
+	unit, ok := m.units[name]
+	if !ok {
+		return "", dbus.NewError("org.freedesktop.systemd1.NoSuchUnit", []interface{}{"Unit " + name + " not found."})
+	}
+
+	m.jobs++
+	id := m.jobs
+	path := dbus.ObjectPath(fmt.Sprintf("/org/freedesktop/systemd1/job/%d", id))
+
+	result := m.results[name]
+	if result == "" {
+		result = "done"
+		apply(unit)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		m.conn.Emit(systemdObjectPath, systemdManagerInterface+".JobRemoved", id, path, name, result)
+	}()
+
+	return path, nil
+}
+
+func (m *stubManager) Subscribe() *dbus.Error { return nil }
+
+func (m *stubManager) StartUnit(name, mode string) (dbus.ObjectPath, *dbus.Error) {
+	return m.job(name, func(u *UnitInfo) { u.ActiveState, u.SubState = "active", "running" })
+}
+
+func (m *stubManager) StopUnit(name, mode string) (dbus.ObjectPath, *dbus.Error) {
+	return m.job(name, func(u *UnitInfo) { u.ActiveState, u.SubState = "inactive", "dead" })
+}
+
+func (m *stubManager) RestartUnit(name, mode string) (dbus.ObjectPath, *dbus.Error) {
+	return m.job(name, func(u *UnitInfo) { u.ActiveState, u.SubState = "active", "running" })
+}
+
+func (m *stubManager) Reload() *dbus.Error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reloads++
+	return nil
+}
+
+// Элемент списка изменений EnableUnitFiles с сигнатурой (sss)
+type stubUnitFileChange struct {
+	Type, Filename, Destination string
+}
+
+func (m *stubManager) EnableUnitFiles(files []string, runtime, force bool) (bool, []stubUnitFileChange, *dbus.Error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.forced = force
+	for _, file := range files {
+		unit, ok := m.units[file]
+		if !ok {
+			return false, nil, dbus.NewError("org.freedesktop.systemd1.NoSuchUnit", []interface{}{file})
+		}
+		unit.UnitFileState = "enabled"
+	}
+	return true, nil, nil
+}
+
+func (m *stubManager) LoadUnit(name string) (dbus.ObjectPath, *dbus.Error) {
+	return dbus.ObjectPath("/org/freedesktop/systemd1/unit/" + strings.ReplaceAll(name, ".", "_2e")), nil
+}
+
+// Элемент ответа ListUnits с сигнатурой (ssssssouso)
+type stubListedUnit struct {
+	Name, Description, LoadState, ActiveState, SubState, Following string
+	Path                                                           dbus.ObjectPath
+	JobID                                                          uint32
+	JobType                                                        string
+	JobPath                                                        dbus.ObjectPath
+}
+
+// Элемент ответа ListUnitFiles с сигнатурой (ss)
+type stubListedUnitFile struct {
+	Path, State string
+}
+
+func (m *stubManager) ListUnits() ([]stubListedUnit, *dbus.Error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var units []stubListedUnit
+	for name, u := range m.units {
+		units = append(units, stubListedUnit{
+			Name:        name,
+			Description: u.Description,
+			LoadState:   u.LoadState,
+			ActiveState: u.ActiveState,
+			SubState:    u.SubState,
+			Path:        "/",
+			JobPath:     "/",
+		})
+	}
+	return units, nil
+}
+
+func (m *stubManager) ListUnitFiles() ([]stubListedUnitFile, *dbus.Error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var files []stubListedUnitFile
+	for name, u := range m.units {
+		files = append(files, stubListedUnitFile{Path: "/etc/systemd/system/" + name, State: u.UnitFileState})
+	}
+	return files, nil
+}
+
+// Заглушка org.freedesktop.DBus.Properties для объектов unit
+type stubUnitProperties struct {
+	manager *stubManager
+	name    string
+}
+
+func (p *stubUnitProperties) GetAll(iface string) (map[string]dbus.Variant, *dbus.Error) {
+	p.manager.mu.Lock()
+	defer p.manager.mu.Unlock()
+
+	unit, ok := p.manager.units[p.name]
+	if !ok {
+		return map[string]dbus.Variant{"Id": dbus.MakeVariant(p.name), "LoadState": dbus.MakeVariant("not-found")}, nil
+	}
+
+	return map[string]dbus.Variant{
+		"Id":            dbus.MakeVariant(p.name),
+		"Description":   dbus.MakeVariant(unit.Description),
+		"LoadState":     dbus.MakeVariant(unit.LoadState),
+		"ActiveState":   dbus.MakeVariant(unit.ActiveState),
+		"SubState":      dbus.MakeVariant(unit.SubState),
+		"UnitFileState": dbus.MakeVariant(unit.UnitFileState),
+	}, nil
+}
+
+// Запустить частную шину с заглушкой systemd и вернуть подключенный DBusBackend
+func newTestDBusBackend(t *testing.T) (*DBusBackend, *stubManager) {
+	t.Helper()
+
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon не установлен")
+	}
+
+	dir := t.TempDir()
+	config := filepath.Join(dir, "bus.conf")
+	socket := filepath.Join(dir, "bus")
+	if err := os.WriteFile(config, []byte(strings.ReplaceAll(testBusConfig, "BUS_SOCKET", socket)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(daemon, "--config-file="+config, "--nofork", "--print-address")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Skipf("не удалось запустить dbus-daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("dbus-daemon address: %v", err)
+	}
+	address = strings.TrimSpace(address)
+
+	// Соединение заглушки systemd
+	serverConn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { serverConn.Close() })
+
+	manager := &stubManager{
+		conn: serverConn,
+		units: map[string]*UnitInfo{
+			"api.service":    {Description: "API", LoadState: "loaded", ActiveState: "inactive", SubState: "dead", UnitFileState: "disabled"},
+			"broken.service": {Description: "Broken", LoadState: "loaded", ActiveState: "inactive", SubState: "dead", UnitFileState: "disabled"},
+		},
+		results: map[string]string{"broken.service": "failed"},
+	}
+
+	if err := serverConn.Export(manager, systemdObjectPath, systemdManagerInterface); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"api.service", "broken.service", "missing.service"} {
+		path, _ := manager.LoadUnit(name)
+		if err := serverConn.Export(&stubUnitProperties{manager: manager, name: name}, path, dbusPropertiesInterface); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := serverConn.RequestName(systemdBusName, dbus.NameFlagDoNotQueue); err != nil {
+		t.Fatal(err)
+	}
+
+	clientConn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend, err := NewDBusBackend(clientConn)
+	if err != nil {
+		t.Fatalf("NewDBusBackend: %v", err)
+	}
+	t.Cleanup(func() { backend.Close() })
+
+	return backend, manager
+}
+
+func TestDBusBackendJobs(t *testing.T) {
+	backend, manager := newTestDBusBackend(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := backend.Start(ctx, "api"); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	status, err := backend.Status(ctx, "api")
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if status.Name != "api" || status.ActiveState != "active" || status.SubState != "running" {
+		t.Errorf("status = %+v", status)
+	}
+
+	props, err := backend.Properties(ctx, "api", "ActiveState", "Description")
+	if err != nil {
+		t.Fatalf("Properties: %v", err)
+	}
+	if len(props) != 2 || props["ActiveState"] != "active" || props["Description"] != "API" {
+		t.Errorf("props = %v", props)
+	}
+
+	if err := backend.Restart(ctx, "api"); err != nil {
+		t.Errorf("Restart: %v", err)
+	}
+	if err := backend.Stop(ctx, "api.service"); err != nil {
+		t.Errorf("Stop: %v", err)
+	}
+
+	var jobErr *JobError
+	if err := backend.Start(ctx, "broken"); !errors.As(err, &jobErr) || jobErr.Result != "failed" {
+		t.Errorf("Start(broken) = %v, want JobError with result failed", err)
+	}
+
+	if err := backend.Start(ctx, "missing"); !errors.Is(err, ErrUnitNotFound) {
+		t.Errorf("Start(missing) = %v, want ErrUnitNotFound", err)
+	}
+	if _, err := backend.Status(ctx, "missing"); !errors.Is(err, ErrUnitNotFound) {
+		t.Errorf("Status(missing) = %v, want ErrUnitNotFound", err)
+	}
+
+	manager.mu.Lock()
+	manager.denied = true
+	manager.mu.Unlock()
+
+	if err := backend.Start(ctx, "api"); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("Start(denied) = %v, want ErrAccessDenied", err)
+	}
+}
+
+func TestDBusBackendUnitFiles(t *testing.T) {
+	backend, manager := newTestDBusBackend(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := backend.Enable(ctx, "api"); err != nil {
+		t.Fatalf("Enable: %v", err)
+	}
+	manager.mu.Lock()
+	reloads, forced := manager.reloads, manager.forced
+	manager.mu.Unlock()
+	if reloads != 1 || forced {
+		t.Errorf("reloads = %d, forced = %v; want 1 reload without force", reloads, forced)
+	}
+
+	units, err := backend.ListUnits(ctx)
+	if err != nil {
+		t.Fatalf("ListUnits: %v", err)
+	}
+	if len(units) != 2 || units[0].Name != "api" || units[0].UnitFileState != "enabled" {
+		t.Errorf("units = %+v", units)
+	}
+}
+
+func TestFormatDBusProperty(t *testing.T) {
+	// Свойства в типах D-Bus должны выглядеть так же, как в systemctl show
+	want := ParseProperties(readFixture(t, "show_running.txt"))
+	variants := map[string]dbus.Variant{
+		"Id":                 dbus.MakeVariant("nginx.service"),
+		"MainPID":            dbus.MakeVariant(uint32(1234)),
+		"NRestarts":          dbus.MakeVariant(uint32(2)),
+		"MemoryCurrent":      dbus.MakeVariant(uint64(15728640)),
+		"CPUUsageNSec":       dbus.MakeVariant(uint64(1234567890)),
+		"CPUQuotaPerSecUSec": dbus.MakeVariant(uint64(500000)),
+		"TimeoutStopUSec":    dbus.MakeVariant(uint64(90000000)),
+		"RestartUSec":        dbus.MakeVariant(uint64(100000)),
+		"RuntimeMaxUSec":     dbus.MakeVariant(uint64(math.MaxUint64)),
+		"WatchdogUSec":       dbus.MakeVariant(uint64(0)),
+		"DropInPaths": dbus.MakeVariant([]string{
+			"/etc/systemd/system/nginx.service.d/50-sdmanager.conf",
+			"/etc/systemd/system/nginx.service.d/override.conf",
+		}),
+	}
+
+	for name, variant := range variants {
+		got, ok := formatDBusProperty(name, variant)
+		if !ok || got != want[name] {
+			t.Errorf("%s = %q, want %q", name, got, want[name])
+		}
+	}
+
+	for usec, want := range map[uint64]string{1500000: "1.500000s", 3723000000: "1h 2min 3s", 2500: "2.500ms"} {
+		if got := FormatTimespan(usec); got != want {
+			t.Errorf("FormatTimespan(%d) = %q, want %q", usec, got, want)
+		}
+	}
+}
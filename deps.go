@@ -0,0 +1,183 @@
+package sdmanager
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Типы unit, на которые может ссылаться сервис
+var unitTypeSuffixes = []string{
+	".service", ".socket", ".device", ".mount", ".automount", ".swap",
+	".target", ".path", ".timer", ".slice", ".scope",
+}
+
+// Допустимые символы имени unit
+var unitNamePattern = regexp.MustCompile(`^[A-Za-z0-9:_.\\@-]+$`)
+
+// Цель, после которой сервис запускается по умолчанию
+const DefaultAfter = "network.target"
+
+// Цель, в которую сервис включается по умолчанию
+const DefaultWantedBy = "multi-user.target"
+
+// Директивы зависимостей и порядка запуска одного типа
+type dependency struct {
+	Section string
+	Key     string
+	Units   []string
+}
+
+// Директивы зависимостей в порядке их записи в unit-файл
+func dependencies(config ServiceConfig) []dependency {
+	return []dependency{
+		{Section: "Unit", Key: "After", Units: config.After},
+		{Section: "Unit", Key: "Before", Units: config.Before},
+		{Section: "Unit", Key: "Wants", Units: config.Wants},
+		{Section: "Unit", Key: "Requires", Units: config.Requires},
+		{Section: "Unit", Key: "BindsTo", Units: config.BindsTo},
+		{Section: "Unit", Key: "PartOf", Units: config.PartOf},
+		{Section: "Unit", Key: "Conflicts", Units: config.Conflicts},
+		{Section: "Install", Key: "WantedBy", Units: config.WantedBy},
+		{Section: "Install", Key: "RequiredBy", Units: config.RequiredBy},
+	}
+}
+
+// Итоговый After: без явного значения сервис ждет сеть
+func effectiveAfter(config ServiceConfig) []string {
+	if config.After != nil {
+		return config.After
+	}
+	return []string{DefaultAfter}
+}
+
+// Итоговый WantedBy. Без явных целей сервис включается в multi-user.target,
+// кроме запускаемого таймером, сокетом или path-unit.
+func effectiveWantedBy(config ServiceConfig) []string {
+	if config.WantedBy != nil || config.RequiredBy != nil || triggerCount(config) > 0 {
+		return config.WantedBy
+	}
+	return []string{DefaultWantedBy}
+}
+
+// Непустые директивы зависимостей секции unit-файла с учетом значений по
+// умолчанию
+func unitDependencies(config ServiceConfig, section string) []dependency {
+	config.After = effectiveAfter(config)
+	config.WantedBy = effectiveWantedBy(config)
+
+	var deps []dependency
+	for _, dep := range dependencies(config) {
+		if dep.Section == section && len(dep.Units) > 0 {
+			deps = append(deps, dep)
+		}
+	}
+	return deps
+}
+
+// Проверить имя unit: допустимые символы и известный тип
+func ValidateUnitName(name string) error {
+	if !unitNamePattern.MatchString(name) {
+		return fmt.Errorf("некорректное имя unit: %s", name)
+	}
+	if !slices.Contains(unitTypeSuffixes, filepath.Ext(name)) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("имя unit должно содержать тип (%s): %s", strings.Join(unitTypeSuffixes, ", "), name)
+	}
+	return nil
+}
+
+// Разобрать список unit, разделенных пробелами. Повторы убираются.
+func ParseUnitList(value string) ([]string, error) {
+	var units []string
+	for _, name := range strings.Fields(value) {
+		if err := ValidateUnitName(name); err != nil {
+			return nil, err
+		}
+		if !slices.Contains(units, name) {
+			units = append(units, name)
+		}
+	}
+	return units, nil
+}
+
+// Проверить зависимости сервиса: имена unit и противоречивые сочетания
+func ValidateDependencies(config ServiceConfig) error {
+	self := config.ServiceName + ".service"
+	for _, dep := range dependencies(config) {
+		for _, name := range dep.Units {
+			if err := ValidateUnitName(name); err != nil {
+				return fmt.Errorf("%s: %w", dep.Key, err)
+			}
+			if name == self {
+				return fmt.Errorf("%s: сервис не может ссылаться на себя", dep.Key)
+			}
+		}
+	}
+
+	for _, name := range config.After {
+		if slices.Contains(config.Before, name) {
+			return fmt.Errorf("%s указан одновременно в After и Before", name)
+		}
+	}
+
+	for _, name := range config.Conflicts {
+		for _, deps := range [][]string{config.Wants, config.Requires, config.BindsTo, config.PartOf} {
+			if slices.Contains(deps, name) {
+				return fmt.Errorf("%s указан одновременно в Conflicts и в зависимостях", name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Unit из списка, для которых нет unit-файла. Экземпляр шаблона считается
+// существующим, если есть сам шаблон.
+func missingUnits(names, known []string) []string {
+	var missing []string
+	for _, name := range names {
+		if slices.Contains(known, name) {
+			continue
+		}
+		if prefix, rest, ok := strings.Cut(name, "@"); ok {
+			if slices.Contains(known, prefix+"@"+filepath.Ext(rest)) {
+				continue
+			}
+		}
+		missing = append(missing, name)
+	}
+	return missing
+}
+
+// Unit, на которые ссылается сервис, но которых нет в systemctl
+// list-unit-files
+func MissingDependencies(ctx context.Context, b Backend, config ServiceConfig) ([]string, error) {
+	known, err := b.ListUnitFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, dep := range dependencies(config) {
+		for _, name := range missingUnits(dep.Units, known) {
+			if !slices.Contains(missing, name) {
+				missing = append(missing, name)
+			}
+		}
+	}
+	return missing, nil
+}
+
+// Варианты автодополнения для списка unit: к уже введенным именам
+// добавляется каждое известное имя
+func unitSuggestions(value string, known []string) []string {
+	head := value[:strings.LastIndex(value, " ")+1]
+	suggestions := make([]string, 0, len(known))
+	for _, name := range known {
+		suggestions = append(suggestions, head+name)
+	}
+	return suggestions
+}
@@ -0,0 +1,60 @@
+package sdmanager
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestValidateDependencies(t *testing.T) {
+	valid := []ServiceConfig{
+		{ServiceName: "api", After: []string{"network-online.target", "postgresql.service", "data.mount"}, Requires: []string{"postgresql.service"}},
+		{ServiceName: "api", BindsTo: []string{"dev-sdb1.device"}, WantedBy: []string{"graphical.target"}},
+		{ServiceName: "api", PartOf: []string{"app.target"}, Conflicts: []string{"legacy-api.service"}},
+	}
+	for _, config := range valid {
+		if err := ValidateDependencies(config); err != nil {
+			t.Errorf("ValidateDependencies(%+v): %v", config, err)
+		}
+	}
+
+	invalid := []ServiceConfig{
+		{ServiceName: "api", After: []string{"postgresql"}},
+		{ServiceName: "api", Wants: []string{"bad/name.service"}},
+		{ServiceName: "api", Requires: []string{"api.service"}},
+		{ServiceName: "api", After: []string{"db.service"}, Before: []string{"db.service"}},
+		{ServiceName: "api", Wants: []string{"old.service"}, Conflicts: []string{"old.service"}},
+	}
+	for _, config := range invalid {
+		if err := ValidateDependencies(config); err == nil {
+			t.Errorf("ValidateDependencies(%+v) succeeded", config)
+		}
+	}
+
+	known := []string{"postgresql.service", "getty@.service", "data.mount"}
+	missing := missingUnits([]string{"postgresql.service", "getty@tty1.service", "redis.service"}, known)
+	if !slices.Equal(missing, []string{"redis.service"}) {
+		t.Errorf("missingUnits = %q", missing)
+	}
+
+	if suggestions := unitSuggestions("postgresql.service da", known); suggestions[2] != "postgresql.service data.mount" {
+		t.Errorf("unitSuggestions = %q", suggestions)
+	}
+}
+
+func TestMissingDependencies(t *testing.T) {
+	backend := NewFakeBackend(UnitInfo{Name: "postgresql"}, UnitInfo{Name: "data.mount"})
+	config := ServiceConfig{
+		ServiceName: "api",
+		After:       []string{"postgresql.service", "data.mount", "redis.service"},
+		Wants:       []string{"redis.service"},
+	}
+
+	missing, err := MissingDependencies(context.Background(), backend, config)
+	if err != nil {
+		t.Fatalf("MissingDependencies: %v", err)
+	}
+	if !slices.Equal(missing, []string{"redis.service"}) {
+		t.Errorf("missing = %q", missing)
+	}
+}
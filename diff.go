@@ -0,0 +1,111 @@
+package sdmanager
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Тип строки в diff
+type DiffOp int
+
+const (
+	DiffEqual DiffOp = iota
+	DiffInsert
+	DiffDelete
+)
+
+// Строка результата сравнения
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+}
+
+// Стили для отображения diff
+var (
+	DiffInsertStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	DiffDeleteStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	DiffEqualStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+)
+
+// Построчное сравнение двух текстов на основе наибольшей общей подпоследовательности
+func DiffLines(oldText, newText string) []DiffLine {
+	a := splitLines(oldText)
+	b := splitLines(newText)
+
+	// lcs[i][j] - длина общей подпоследовательности для a[i:] и b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var result []DiffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			result = append(result, DiffLine{Op: DiffEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			result = append(result, DiffLine{Op: DiffDelete, Text: a[i]})
+			i++
+		default:
+			result = append(result, DiffLine{Op: DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		result = append(result, DiffLine{Op: DiffDelete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		result = append(result, DiffLine{Op: DiffInsert, Text: b[j]})
+	}
+
+	return result
+}
+
+// Разбить текст на строки без учета завершающего перевода строки
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// Проверить, есть ли в diff изменения
+func HasChanges(diff []DiffLine) bool {
+	for _, line := range diff {
+		if line.Op != DiffEqual {
+			return true
+		}
+	}
+	return false
+}
+
+// Отрисовка цветного diff
+func RenderDiff(oldText, newText string) string {
+	var sb strings.Builder
+
+	for _, line := range DiffLines(oldText, newText) {
+		switch line.Op {
+		case DiffInsert:
+			sb.WriteString(DiffInsertStyle.Render("+ "+line.Text) + "\n")
+		case DiffDelete:
+			sb.WriteString(DiffDeleteStyle.Render("- "+line.Text) + "\n")
+		default:
+			sb.WriteString(DiffEqualStyle.Render("  "+line.Text) + "\n")
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,266 @@
+package sdmanager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Каталог для unit-файлов и drop-in, создаваемых администратором
+const DefaultUnitDir = "/etc/systemd/system"
+
+// Приоритет drop-in файла sdmanager по умолчанию
+const DefaultDropInPriority = 50
+
+// Каталоги поиска unit-файлов в порядке приоритета systemd
+var UnitSearchPaths = []string{
+	DefaultUnitDir,
+	"/run/systemd/system",
+	"/usr/local/lib/systemd/system",
+	"/usr/lib/systemd/system",
+	"/lib/systemd/system",
+}
+
+// Каталоги администратора. Drop-in из остальных каталогов поиска
+// принадлежат пакетам дистрибутива и доступны только для чтения.
+var AdminUnitDirs = []string{DefaultUnitDir, "/run/systemd/system"}
+
+// Первая строка файлов, которые создает sdmanager
+const createdHeader = "# Создано sdmanager"
+
+// Drop-in файл сервиса
+type DropIn struct {
+	Name    string
+	Path    string
+	Content string
+}
+
+// Проверить, создан ли drop-in утилитой sdmanager
+func (d DropIn) IsSDManager() bool {
+	return strings.HasSuffix(d.Name, "-sdmanager.conf")
+}
+
+// Проверить, можно ли удалить drop-in
+func (d DropIn) Removable() bool {
+	return removableDropIn(d.Path, d.Content)
+}
+
+// Drop-in можно удалить, если он лежит в каталоге администратора (включая
+// каталоги systemctl set-property) или создан sdmanager
+func removableDropIn(path, content string) bool {
+	unitDir := filepath.Dir(filepath.Dir(filepath.Clean(path)))
+	return slices.Contains(ControlDirs, unitDir) || slices.Contains(AdminUnitDirs, unitDir) ||
+		strings.HasPrefix(content, createdHeader)
+}
+
+// Найти unit-файл сервиса в каталогах systemd
+func FindUnitFile(serviceName string) (string, error) {
+	for _, dir := range UnitSearchPaths {
+		path := filepath.Join(dir, serviceName+".service")
+		if FileExists(path) {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("unit-файл %s.service не найден", serviceName)
+}
+
+// Путь к каталогу drop-in файлов сервиса
+func DropInDir(unitDir, serviceName string) string {
+	return filepath.Join(unitDir, serviceName+".service.d")
+}
+
+// Имя drop-in файла sdmanager с заданным приоритетом
+func DropInFileName(priority int) string {
+	return fmt.Sprintf("%02d-sdmanager.conf", priority)
+}
+
+// Получить список drop-in файлов сервиса из всех каталогов systemd, включая
+// созданные systemctl set-property. Файлы с одинаковым именем перекрываются
+// каталогом с большим приоритетом, результат отсортирован по имени - в этом
+// порядке их применяет systemd.
+func ListDropIns(serviceName string) ([]DropIn, error) {
+	seen := make(map[string]bool)
+	var dropIns []DropIn
+
+	for _, dir := range append(slices.Clone(ControlDirs), UnitSearchPaths...) {
+		entries, err := os.ReadDir(DropInDir(dir, serviceName))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("ошибка чтения каталога drop-in: %w", err)
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".conf") || seen[entry.Name()] {
+				continue
+			}
+			seen[entry.Name()] = true
+
+			path := filepath.Join(DropInDir(dir, serviceName), entry.Name())
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("ошибка чтения drop-in файла: %w", err)
+			}
+
+			dropIns = append(dropIns, DropIn{Name: entry.Name(), Path: path, Content: string(content)})
+		}
+	}
+
+	slices.SortFunc(dropIns, func(a, b DropIn) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return dropIns, nil
+}
+
+// Записать drop-in файл sdmanager для сервиса
+func WriteDropIn(unitDir, serviceName string, priority int, content string) (string, error) {
+	dir := DropInDir(unitDir, serviceName)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("ошибка при создании каталога drop-in: %w", err)
+	}
+
+	path := filepath.Join(dir, DropInFileName(priority))
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return "", fmt.Errorf("ошибка при записи drop-in файла: %w", err)
+	}
+
+	return path, nil
+}
+
+// Удалить drop-in файл. Пустой каталог drop-in удаляется вместе с ним.
+// Drop-in пакетов дистрибутива не удаляются.
+func RemoveDropIn(path string) error {
+	dir := filepath.Dir(path)
+	if !strings.HasSuffix(dir, ".d") || !strings.HasSuffix(path, ".conf") {
+		return fmt.Errorf("%s не является drop-in файлом", path)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("ошибка чтения drop-in файла: %w", err)
+	}
+	if !removableDropIn(path, string(content)) {
+		return fmt.Errorf("%s принадлежит пакету и доступен только для чтения", path)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("ошибка при удалении drop-in файла: %w", err)
+	}
+
+	// Ошибку игнорируем: в каталоге могут остаться другие файлы
+	_ = os.Remove(dir)
+
+	return nil
+}
+
+// Сформировать содержимое drop-in только из директив, отличающихся от базовой
+// конфигурации. Удаленные значения сбрасываются пустым присваиванием, а
+// списки (ExecStart, Environment) дополнительно очищаются перед новыми
+// значениями, как того требует systemd. Зависимости сбросить нельзя, поэтому
+// в drop-in попадают только добавленные unit.
+func GenerateDropIn(base, config ServiceConfig) (string, error) {
+	baseDirectives := serviceDirectives(base)
+
+	// Строки по секциям в том же порядке, что и в unit-файле
+	sections := []string{"Unit", "Service", "Install"}
+	lines := make(map[string][]string)
+	for i, directive := range serviceDirectives(config) {
+		if directive.equal(baseDirectives[i].Values) {
+			continue
+		}
+
+		section := directive.section()
+		if directive.Units {
+			baseUnits := unitWords(baseDirectives[i].Values)
+			var removed []string
+			for _, name := range baseUnits {
+				if !slices.Contains(directive.Values, name) {
+					removed = append(removed, name)
+				}
+			}
+			if len(removed) > 0 {
+				return "", fmt.Errorf("%s: drop-in не может удалить зависимость от %s, измените сам unit-файл", directive.Key, strings.Join(removed, ", "))
+			}
+			for _, name := range directive.Values {
+				if !slices.Contains(baseUnits, name) {
+					lines[section] = append(lines[section], directive.Key+"="+name)
+				}
+			}
+			continue
+		}
+
+		if len(directive.Values) == 0 || directive.Reset {
+			lines[section] = append(lines[section], directive.Key+"=")
+		}
+		for _, value := range directive.Values {
+			lines[section] = append(lines[section], directive.Key+"="+value)
+		}
+	}
+
+	if len(lines) == 0 {
+		return "", errors.New("нет изменений относительно исходного unit-файла")
+	}
+
+	var blocks []string
+	for _, section := range sections {
+		if len(lines[section]) > 0 {
+			blocks = append(blocks, "["+section+"]\n"+strings.Join(lines[section], "\n")+"\n")
+		}
+	}
+
+	return createdHeader + "\n" + strings.Join(blocks, "\n"), nil
+}
+
+// Загрузить конфигурацию сервиса для правки через drop-in. Возвращает базовую
+// конфигурацию (unit-файл и чужие drop-in) и итоговую с учетом drop-in sdmanager.
+func LoadDropInConfig(serviceName string) (base ServiceConfig, effective ServiceConfig, err error) {
+	unitPath, err := FindUnitFile(serviceName)
+	if err != nil {
+		return base, effective, err
+	}
+
+	content, err := os.ReadFile(unitPath)
+	if err != nil {
+		return base, effective, fmt.Errorf("ошибка при чтении unit-файла: %w", err)
+	}
+
+	dropIns, err := ListDropIns(serviceName)
+	if err != nil {
+		return base, effective, err
+	}
+
+	// Drop-in применяются поверх unit-файла в порядке имен, поэтому
+	// достаточно склеить тексты: GetAll учитывает повторяющиеся секции и
+	// сброс пустым присваиванием. Чужой drop-in с большим номером
+	// перекрывает drop-in sdmanager, как и в systemd.
+	baseText := string(content)
+	effectiveText := string(content)
+	for _, dropIn := range dropIns {
+		effectiveText += "\n" + dropIn.Content
+		if !dropIn.IsSDManager() {
+			baseText += "\n" + dropIn.Content
+		}
+	}
+
+	parse := func(text string) (ServiceConfig, error) {
+		unit, err := ParseUnitFile(strings.NewReader(text))
+		if err != nil {
+			return ServiceConfig{}, err
+		}
+		return ServiceConfigFromUnit(unit, serviceName, filepath.Dir(unitPath))
+	}
+
+	if base, err = parse(baseText); err != nil {
+		return base, effective, fmt.Errorf("%s: %w", unitPath, err)
+	}
+	if effective, err = parse(effectiveText); err != nil {
+		return base, effective, fmt.Errorf("%s: %w", unitPath, err)
+	}
+
+	return base, effective, nil
+}
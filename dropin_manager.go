@@ -0,0 +1,191 @@
+package sdmanager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Инициализация модели управления drop-in файлами
+func NewDropInModel(appOptions AppOptions) DropInModel {
+	ti := textinput.New()
+	ti.Placeholder = appOptions.serviceName
+	ti.Focus()
+	ti.CharLimit = 255
+	ti.Width = 80
+
+	vp := viewport.New(78, 15)
+	vp.Style = ViewportStyle
+
+	return DropInModel{
+		State:    DropInStateServiceName,
+		Input:    ti,
+		Viewport: vp,
+		Message:  "Введите имя сервиса для просмотра drop-in файлов:",
+		backend:  appOptions.backend,
+	}
+}
+
+// Загрузить список drop-in файлов сервиса
+func loadDropIns(model DropInModel) DropInModel {
+	dropIns, err := ListDropIns(model.ServiceName)
+	if err != nil {
+		model.Error = err.Error()
+		return model
+	}
+
+	model.DropIns = dropIns
+	model.Current = min(model.Current, max(len(dropIns)-1, 0))
+	model.State = DropInStateList
+	model.Message = fmt.Sprintf("Drop-in файлы сервиса %s:", model.ServiceName)
+
+	if len(dropIns) > 0 {
+		model.Viewport.SetContent(dropIns[model.Current].Content)
+	}
+
+	return model
+}
+
+// Обработка событий экрана drop-in файлов
+func UpdateDropIns(ctx context.Context, msg tea.Msg, model DropInModel) (DropInModel, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		var cmd tea.Cmd
+		model.Input, cmd = model.Input.Update(msg)
+		return model, cmd
+	}
+
+	if keyMsg.Type == tea.KeyCtrlC || keyMsg.Type == tea.KeyEsc {
+		model.Message = ""
+		model.Quitting = true
+		return model, nil
+	}
+
+	switch model.State {
+	case DropInStateServiceName:
+		switch keyMsg.Type {
+		case tea.KeyEnter:
+			if model.Error != "" {
+				model.Error = ""
+				return model, nil
+			}
+
+			serviceName := model.Input.Value()
+			if err := IsValidServiceName(serviceName); err != nil {
+				model.Error = err.Error()
+				return model, nil
+			}
+
+			model.ServiceName = strings.TrimSuffix(serviceName, ".service")
+			return loadDropIns(model), nil
+
+		case tea.KeyTab:
+			// Автозаполнение из placeholder если поле пустое
+			if model.Input.Value() == "" && model.Input.Placeholder != "" {
+				model.Input.SetValue(model.Input.Placeholder)
+			}
+		}
+
+		var cmd tea.Cmd
+		model.Input, cmd = model.Input.Update(msg)
+		return model, cmd
+
+	case DropInStateList:
+		if len(model.DropIns) == 0 {
+			return model, nil
+		}
+
+		switch keyMsg.String() {
+		case "up", "k":
+			model.Current = (model.Current - 1 + len(model.DropIns)) % len(model.DropIns)
+			model.Viewport.SetContent(model.DropIns[model.Current].Content)
+		case "down", "j":
+			model.Current = (model.Current + 1) % len(model.DropIns)
+			model.Viewport.SetContent(model.DropIns[model.Current].Content)
+		case "d", "delete":
+			dropIn := model.DropIns[model.Current]
+			if !dropIn.Removable() {
+				model.Error = fmt.Sprintf("%s принадлежит пакету и доступен только для чтения", dropIn.Path)
+				return model, nil
+			}
+			model.Error = ""
+			model.State = DropInStateConfirmDelete
+		}
+
+	case DropInStateConfirmDelete:
+		switch keyMsg.String() {
+		case "y", "Y":
+			path := model.DropIns[model.Current].Path
+			if err := RemoveDropIn(path); err != nil {
+				model.Error = err.Error()
+				model.State = DropInStateList
+				return model, nil
+			}
+
+			if err := model.backend.DaemonReload(ctx); err != nil {
+				model.Error = err.Error()
+			}
+
+			model = loadDropIns(model)
+			model.Message = fmt.Sprintf("Drop-in %s удален. Drop-in файлы сервиса %s:", path, model.ServiceName)
+		default:
+			model.State = DropInStateList
+		}
+	}
+
+	return model, nil
+}
+
+// Отрисовка экрана drop-in файлов
+func ViewDropIns(model DropInModel) string {
+	var s strings.Builder
+
+	s.WriteString(model.Message + "\n\n")
+
+	switch model.State {
+	case DropInStateServiceName:
+		s.WriteString(model.Input.View() + "\n\n")
+
+	case DropInStateList, DropInStateConfirmDelete:
+		if len(model.DropIns) == 0 {
+			s.WriteString(FormatInfo("Drop-in файлы не найдены") + "\n\n")
+			break
+		}
+
+		for i, dropIn := range model.DropIns {
+			line := dropIn.Path
+			if dropIn.IsSDManager() {
+				line += " (sdmanager)"
+			} else if !dropIn.Removable() {
+				line += " (только чтение)"
+			}
+
+			if i == model.Current {
+				s.WriteString(SelectedItemStyle.Render("> "+line) + "\n")
+			} else {
+				s.WriteString("    " + line + "\n")
+			}
+		}
+
+		s.WriteString("\n" + model.Viewport.View() + "\n\n")
+
+		if model.State == DropInStateConfirmDelete {
+			s.WriteString(fmt.Sprintf("Удалить %s и перезагрузить systemd daemon? (y/n)\n\n", model.DropIns[model.Current].Path))
+		} else {
+			s.WriteString("Используйте стрелки ↑/↓ для навигации, d для удаления\n")
+		}
+	}
+
+	// Отображаем ошибку на отдельной строке, если она есть
+	if model.Error != "" {
+		s.WriteString(FormatError(model.Error) + "\n\n")
+	}
+
+	s.WriteString("Esc (Ctrl+C) для возврата в меню\n")
+
+	return s.String()
+}
@@ -0,0 +1,142 @@
+package sdmanager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Каталоги systemd во временном каталоге: control, etc и run - каталоги
+// администратора, usr - каталог пакетов
+func newDropInDirs(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+
+	searchPaths, adminDirs, controlDirs := UnitSearchPaths, AdminUnitDirs, ControlDirs
+	t.Cleanup(func() { UnitSearchPaths, AdminUnitDirs, ControlDirs = searchPaths, adminDirs, controlDirs })
+	ControlDirs = []string{filepath.Join(root, "control")}
+	AdminUnitDirs = []string{filepath.Join(root, "etc"), filepath.Join(root, "run")}
+	UnitSearchPaths = []string{AdminUnitDirs[0], AdminUnitDirs[1], filepath.Join(root, "usr")}
+
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return root
+}
+
+func TestListDropIns(t *testing.T) {
+	root := newDropInDirs(t, map[string]string{
+		"control/api.service.d/50-MemoryMax.conf": "[Service]\nMemoryMax=1G\n",
+		"etc/api.service.d/50-sdmanager.conf":     "# Создано sdmanager\n[Service]\nUser=www\n",
+		"etc/api.service.d/10-override.conf":      "[Service]\nNice=5\n",
+		"run/api.service.d/10-override.conf":      "[Service]\nNice=10\n",
+		"usr/api.service.d/05-vendor.conf":        "[Service]\nLimitNOFILE=1024\n",
+		"usr/api.service.d/50-MemoryMax.conf":     "[Service]\nMemoryMax=2G\n",
+		"usr/api.service.d/README":                "не drop-in",
+	})
+
+	dropIns, err := ListDropIns("api")
+	if err != nil {
+		t.Fatalf("ListDropIns: %v", err)
+	}
+
+	// Порядок применения systemd - по имени; одноименный файл берется из
+	// каталога с большим приоритетом
+	want := []struct {
+		path      string
+		removable bool
+	}{
+		{"usr/api.service.d/05-vendor.conf", false},
+		{"etc/api.service.d/10-override.conf", true},
+		{"control/api.service.d/50-MemoryMax.conf", true},
+		{"etc/api.service.d/50-sdmanager.conf", true},
+	}
+	if len(dropIns) != len(want) {
+		t.Fatalf("dropIns = %+v", dropIns)
+	}
+	for i, dropIn := range dropIns {
+		if dropIn.Path != filepath.Join(root, want[i].path) || dropIn.Removable() != want[i].removable {
+			t.Errorf("dropIns[%d] = %s, removable %v, want %s, removable %v",
+				i, dropIn.Path, dropIn.Removable(), want[i].path, want[i].removable)
+		}
+	}
+}
+
+func TestLoadDropInConfig(t *testing.T) {
+	newDropInDirs(t, map[string]string{
+		"etc/api.service":                     "[Service]\nExecStart=/usr/bin/api\nNice=1\n",
+		"etc/api.service.d/10-override.conf":  "[Service]\nUser=www\n",
+		"etc/api.service.d/50-sdmanager.conf": "# Создано sdmanager\n[Service]\nNice=5\nCPUWeight=200\n",
+		"usr/api.service.d/05-vendor.conf":    "[Service]\nCPUWeight=50\n",
+		"usr/api.service.d/90-vendor.conf":    "[Service]\nNice=10\n",
+	})
+
+	base, effective, err := LoadDropInConfig("api")
+	if err != nil {
+		t.Fatalf("LoadDropInConfig: %v", err)
+	}
+
+	// Без drop-in sdmanager
+	if base.UserName != "www" || base.CPUWeight != 50 || base.Nice == nil || *base.Nice != 10 {
+		t.Errorf("base = %+v", base)
+	}
+
+	// Drop-in применяются по имени: 90-vendor.conf перекрывает Nice из
+	// 50-sdmanager.conf, а тот - CPUWeight из 05-vendor.conf
+	if effective.UserName != "www" || effective.CPUWeight != 200 || effective.Nice == nil || *effective.Nice != 10 {
+		t.Errorf("effective = %+v", effective)
+	}
+}
+
+func TestRemoveDropIn(t *testing.T) {
+	root := newDropInDirs(t, map[string]string{
+		"etc/api.service.d/10-override.conf": "[Service]\nNice=5\n",
+		"usr/api.service.d/05-vendor.conf":   "[Service]\nLimitNOFILE=1024\n",
+		"usr/api.service.d/60-sdmanager.conf": "# Создано sdmanager по результатам systemd-analyze security\n" +
+			"[Service]\nNoNewPrivileges=yes\n",
+	})
+
+	vendor := filepath.Join(root, "usr/api.service.d/05-vendor.conf")
+	if err := RemoveDropIn(vendor); err == nil || !FileExists(vendor) {
+		t.Errorf("vendor drop-in removed: %v", err)
+	}
+	if err := RemoveDropIn(filepath.Join(root, "etc/api.service.d/api.service")); err == nil {
+		t.Error("non-drop-in file accepted")
+	}
+
+	// Drop-in sdmanager удаляется в любом каталоге, как и файлы администратора
+	for _, name := range []string{"usr/api.service.d/60-sdmanager.conf", "etc/api.service.d/10-override.conf"} {
+		path := filepath.Join(root, name)
+		if err := RemoveDropIn(path); err != nil || FileExists(path) {
+			t.Errorf("RemoveDropIn(%s): %v", name, err)
+		}
+	}
+	if FileExists(filepath.Join(root, "etc/api.service.d")) {
+		t.Error("empty drop-in directory was not removed")
+	}
+
+	// На экране drop-in файлы пакетов доступны только для чтения
+	model := NewDropInModel(AppOptions{backend: NewFakeBackend()})
+	model.Input.SetValue("api")
+	model, _ = UpdateDropIns(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+	if model.State != DropInStateList || len(model.DropIns) != 1 {
+		t.Fatalf("state = %d, dropIns = %+v, error = %q", model.State, model.DropIns, model.Error)
+	}
+	if view := ViewDropIns(model); !strings.Contains(view, "(только чтение)") {
+		t.Errorf("view does not mark vendor drop-in:\n%s", view)
+	}
+	model, _ = UpdateDropIns(context.Background(), tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")}, model)
+	if model.State != DropInStateList || model.Error == "" {
+		t.Errorf("state = %d, error = %q", model.State, model.Error)
+	}
+}
@@ -0,0 +1,224 @@
+package sdmanager
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Каталог файлов с секретами сервисов. Файлы доступны только владельцу
+// (0600), поэтому секреты не попадают в общедоступный unit-файл.
+var SecretsDir = "/etc/sdmanager"
+
+// Допустимое имя переменной окружения
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Символы, из-за которых значение нужно взять в кавычки
+const envQuoteChars = " \t\"'\\"
+
+// Путь к файлу секретов сервиса
+func SecretsFilePath(serviceName string) string {
+	return filepath.Join(SecretsDir, serviceName+".env")
+}
+
+// Проверить переменные окружения: имена из букв, цифр и "_", значения без
+// переводов строк
+func ValidateEnvironment(env map[string]string) error {
+	for _, name := range slices.Sorted(maps.Keys(env)) {
+		if !envNamePattern.MatchString(name) {
+			return fmt.Errorf("некорректное имя переменной %q: допустимы буквы, цифры и _", name)
+		}
+		if strings.ContainsAny(env[name], "\n\r\x00") {
+			return fmt.Errorf("значение переменной %s содержит перевод строки", name)
+		}
+	}
+	return nil
+}
+
+// Проверить путь EnvironmentFile. Префикс "-" означает, что файл может
+// отсутствовать.
+func ValidateEnvironmentFile(path string) error {
+	path = strings.TrimPrefix(path, "-")
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("путь EnvironmentFile должен быть абсолютным: %s", path)
+	}
+	return checkPathChars(path)
+}
+
+// Разобрать присваивание "KEY=value"
+func ParseEnvAssignment(value string) (string, string, error) {
+	name, val, ok := strings.Cut(value, "=")
+	if !ok {
+		return "", "", fmt.Errorf("ожидается KEY=value, получено %q", value)
+	}
+	name = strings.TrimSpace(name)
+	if !envNamePattern.MatchString(name) {
+		return "", "", fmt.Errorf("некорректное имя переменной %q: допустимы буквы, цифры и _", name)
+	}
+	return name, val, nil
+}
+
+// Значение в кавычках с экранированием, если без них systemd разберет его
+// иначе
+func quoteEnvValue(value string) string {
+	if !strings.ContainsAny(value, envQuoteChars) {
+		return value
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// Значения директив Environment= по одной переменной в строке, в порядке
+// имен. Значения записываются как есть: спецификаторы (%i, %n) раскрывает
+// systemd, знак процента задается как "%%".
+func FormatEnvironment(env map[string]string) []string {
+	var values []string
+	for _, name := range slices.Sorted(maps.Keys(env)) {
+		values = append(values, quoteEnvValue(name+"="+env[name]))
+	}
+	return values
+}
+
+// Разобрать значения директив Environment=. В одной строке может быть
+// несколько присваиваний, разделенных пробелами; кавычки и "\" снимаются,
+// спецификаторы остаются как есть.
+func ParseEnvironment(values []string) (map[string]string, error) {
+	env := make(map[string]string)
+	for _, value := range values {
+		words, err := splitQuoted(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, value)
+		}
+		for _, word := range words {
+			name, val, err := ParseEnvAssignment(word)
+			if err != nil {
+				return nil, err
+			}
+			env[name] = val
+		}
+	}
+	if len(env) == 0 {
+		return nil, nil
+	}
+	return env, nil
+}
+
+// Содержимое файла переменных окружения в формате EnvironmentFile
+func FormatEnvironmentFile(env map[string]string) string {
+	var sb strings.Builder
+	sb.WriteString("# Создано sdmanager\n")
+	for _, name := range slices.Sorted(maps.Keys(env)) {
+		sb.WriteString(name + "=" + quoteEnvValue(env[name]) + "\n")
+	}
+	return sb.String()
+}
+
+// Разобрать файл переменных окружения: строки KEY=value, комментарии и
+// пустые строки пропускаются
+func ParseEnvironmentFile(content string) (map[string]string, error) {
+	env := make(map[string]string)
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		name, value, err := ParseEnvAssignment(line)
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: %w", i+1, err)
+		}
+		if words, err := splitQuoted(value); err == nil && len(words) == 1 && value != "" {
+			value = words[0]
+		}
+		env[name] = value
+	}
+	return env, nil
+}
+
+// Записать файл секретов сервиса с правами 0600. Если у сервиса задан
+// пользователь, файл принадлежит ему.
+func WriteSecretsFile(config ServiceConfig) (string, error) {
+	if err := os.MkdirAll(SecretsDir, 0o755); err != nil {
+		return "", fmt.Errorf("ошибка при создании каталога секретов: %w", err)
+	}
+
+	path := SecretsFilePath(config.ServiceName)
+	if err := os.WriteFile(path, []byte(FormatEnvironmentFile(config.Secrets)), 0o600); err != nil {
+		return "", fmt.Errorf("ошибка при записи файла секретов: %w", err)
+	}
+	// WriteFile не меняет права уже существующего файла
+	if err := os.Chmod(path, 0o600); err != nil {
+		return "", fmt.Errorf("ошибка при изменении прав файла секретов: %w", err)
+	}
+
+	if config.UserName != "" {
+		if err := chownToUser(path, config.UserName); err != nil {
+			return "", err
+		}
+	}
+
+	return path, nil
+}
+
+// Сменить владельца файла на пользователя и его основную группу
+func chownToUser(path, userName string) error {
+	u, err := user.Lookup(userName)
+	if err != nil {
+		return fmt.Errorf("пользователь %s не найден: %w", userName, err)
+	}
+
+	uid, errUID := strconv.Atoi(u.Uid)
+	gid, errGID := strconv.Atoi(u.Gid)
+	if errUID != nil || errGID != nil {
+		return fmt.Errorf("некорректный uid/gid пользователя %s", userName)
+	}
+
+	if err := os.Chown(path, uid, gid); err != nil {
+		return fmt.Errorf("ошибка при смене владельца файла секретов: %w", err)
+	}
+	return nil
+}
+
+// Загрузить секреты сервиса, если unit-файл ссылается на его файл секретов.
+// Путь к файлу убирается из EnvironmentFiles: он добавляется при генерации.
+func LoadSecrets(config ServiceConfig) (ServiceConfig, error) {
+	path := SecretsFilePath(config.ServiceName)
+	if !slices.Contains(config.EnvironmentFiles, path) {
+		return config, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return config, nil
+		}
+		return config, fmt.Errorf("ошибка при чтении файла секретов: %w", err)
+	}
+
+	secrets, err := ParseEnvironmentFile(string(content))
+	if err != nil {
+		return config, fmt.Errorf("%s: %w", path, err)
+	}
+
+	config.Secrets = secrets
+	config.EnvironmentFiles = slices.DeleteFunc(slices.Clone(config.EnvironmentFiles), func(file string) bool {
+		return file == path
+	})
+	return config, nil
+}
+
+// Пути EnvironmentFile для unit-файла: заданные и файл секретов
+func environmentFiles(config ServiceConfig) []string {
+	files := config.EnvironmentFiles
+	if len(config.Secrets) > 0 && !slices.Contains(files, SecretsFilePath(config.ServiceName)) {
+		files = append(slices.Clone(files), SecretsFilePath(config.ServiceName))
+	}
+	return files
+}
@@ -0,0 +1,111 @@
+package sdmanager
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnvironmentQuoting(t *testing.T) {
+	env := map[string]string{
+		"PORT":     "8080",
+		"GREETING": `say "hi" \ bye`,
+		"EMPTY":    "",
+		"PROGRESS": "100%% done",
+		"INSTANCE": "%i",
+	}
+	// Спецификаторы и "%%" записываются как есть и раскрываются systemd
+	values := FormatEnvironment(env)
+	want := []string{"EMPTY=", `"GREETING=say \"hi\" \\ bye"`, "INSTANCE=%i", "PORT=8080", `"PROGRESS=100%% done"`}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("FormatEnvironment = %q", values)
+	}
+
+	parsed, err := ParseEnvironment(values)
+	if err != nil {
+		t.Fatalf("ParseEnvironment: %v", err)
+	}
+	if !reflect.DeepEqual(parsed, env) {
+		t.Errorf("round trip = %q", parsed)
+	}
+
+	// В EnvironmentFile спецификаторы не раскрываются
+	if content := FormatEnvironmentFile(map[string]string{"PROGRESS": "100%"}); !strings.Contains(content, "\nPROGRESS=100%\n") {
+		t.Errorf("FormatEnvironmentFile = %q", content)
+	}
+
+	// Несколько присваиваний в одной директиве
+	parsed, err = ParseEnvironment([]string{`A=1 "B=two words"`})
+	if err != nil || parsed["A"] != "1" || parsed["B"] != "two words" {
+		t.Errorf("ParseEnvironment = %q, %v", parsed, err)
+	}
+
+	if err := ValidateEnvironment(map[string]string{"1BAD": "x"}); err == nil {
+		t.Error("invalid name accepted")
+	}
+	if err := ValidateEnvironment(map[string]string{"A": "line\nbreak"}); err == nil {
+		t.Error("newline accepted")
+	}
+	for path, ok := range map[string]bool{"/etc/api.env": true, "-/etc/api.env": true, "api.env": false, "-/etc/a;b.env": false} {
+		if err := ValidateEnvironmentFile(path); (err == nil) != ok {
+			t.Errorf("ValidateEnvironmentFile(%q) = %v", path, err)
+		}
+	}
+}
+
+func TestWriteSecretsFile(t *testing.T) {
+	oldDir := SecretsDir
+	SecretsDir = t.TempDir()
+	t.Cleanup(func() { SecretsDir = oldDir })
+	config := ServiceConfig{
+		ServiceName:      "api",
+		UnitFilePath:     DefaultUnitDir,
+		ExecStart:        "/usr/bin/api",
+		EnvironmentFiles: []string{"-/etc/default/api"},
+		Secrets:          map[string]string{"TOKEN": "s3cret"},
+	}
+	if err := ValidateServiceConfig(config); err != nil {
+		t.Fatalf("ValidateServiceConfig: %v", err)
+	}
+
+	// В unit-файл попадает только путь к файлу секретов
+	content, err := GenerateUnitPreview(config)
+	if err != nil {
+		t.Fatalf("GenerateUnitPreview: %v", err)
+	}
+	secretsPath := filepath.Join(SecretsDir, "api.env")
+	if !strings.Contains(content, "EnvironmentFile=-/etc/default/api\nEnvironmentFile="+secretsPath+"\n") || strings.Contains(content, "s3cret") {
+		t.Errorf("unit:\n%s", content)
+	}
+
+	path, err := WriteSecretsFile(config)
+	if err != nil {
+		t.Fatalf("WriteSecretsFile: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v", info.Mode().Perm())
+	}
+
+	// Секреты читаются из файла, путь к нему не дублируется в EnvironmentFiles
+	unit, err := ParseUnitFile(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("ParseUnitFile: %v", err)
+	}
+	loaded, err := ServiceConfigFromUnit(unit, "api", "")
+	if err != nil {
+		t.Fatalf("ServiceConfigFromUnit: %v", err)
+	}
+	loaded, err = LoadSecrets(loaded)
+	if err != nil {
+		t.Fatalf("LoadSecrets: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.EnvironmentFiles, config.EnvironmentFiles) || !reflect.DeepEqual(loaded.Secrets, config.Secrets) {
+		t.Errorf("loaded = %+v", loaded)
+	}
+}
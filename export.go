@@ -0,0 +1,273 @@
+package sdmanager
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Формат файла экспорта журнала
+type ExportFormat string
+
+const (
+	// Текст в формате journalctl -o short-iso-precise
+	ExportText ExportFormat = "text"
+	// Одна запись JSON на строку
+	ExportJSONL ExportFormat = "jsonl"
+	// CSV с заголовком
+	ExportCSV ExportFormat = "csv"
+)
+
+// Поддерживаемые форматы экспорта
+var ExportFormats = []ExportFormat{ExportText, ExportJSONL, ExportCSV}
+
+// Формат времени в текстовом экспорте
+const exportTimeLayout = "2006-01-02T15:04:05.000000Z07:00"
+
+// Через сколько записей сообщать о прогрессе экспорта
+const exportProgressStep = 1000
+
+// Колонки CSV экспорта
+var exportCSVHeader = []string{"timestamp", "priority", "hostname", "unit", "syslog_identifier", "pid", "message"}
+
+// Параметры экспорта журнала
+type ExportOptions struct {
+	Format ExportFormat
+	Gzip   bool
+}
+
+// Разобрать название формата экспорта
+func ParseExportFormat(value string) (ExportFormat, error) {
+	switch strings.ToLower(value) {
+	case "text", "txt", "log":
+		return ExportText, nil
+	case "jsonl", "json":
+		return ExportJSONL, nil
+	case "csv":
+		return ExportCSV, nil
+	}
+	return "", fmt.Errorf("неизвестный формат %q: ожидается text, jsonl или csv", value)
+}
+
+// Определить формат и сжатие по расширению файла: .log, .txt, .jsonl,
+// .json, .csv, с суффиксом .gz для сжатия. По умолчанию - текст.
+func ExportOptionsFromPath(path string) ExportOptions {
+	opts := ExportOptions{Format: ExportText}
+
+	name := strings.ToLower(filepath.Base(path))
+	if strings.HasSuffix(name, ".gz") {
+		opts.Gzip = true
+		name = strings.TrimSuffix(name, ".gz")
+	}
+
+	if format, err := ParseExportFormat(strings.TrimPrefix(filepath.Ext(name), ".")); err == nil {
+		opts.Format = format
+	}
+
+	return opts
+}
+
+// Имя файла экспорта по умолчанию: <сервис>-<время>.<расширение>
+func DefaultExportFileName(serviceName string, opts ExportOptions, now time.Time) string {
+	ext := ".log"
+	switch opts.Format {
+	case ExportJSONL:
+		ext = ".jsonl"
+	case ExportCSV:
+		ext = ".csv"
+	}
+	if opts.Gzip {
+		ext += ".gz"
+	}
+	return serviceName + "-" + now.Format("20060102-150405") + ext
+}
+
+// Записать записи журнала в w. progress, если задан, вызывается каждые
+// exportProgressStep записей и в конце с количеством записанных записей.
+func WriteJournalEntries(w io.Writer, entries []JournalEntry, opts ExportOptions, progress func(done int)) error {
+	_, err := writeJournal(w, opts, func(fn func(JournalEntry) error) error {
+		for _, entry := range entries {
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, progress)
+	return err
+}
+
+// Записать в w записи, которые read передает по мере чтения журнала.
+// Возвращает количество записанных записей.
+func writeJournal(w io.Writer, opts ExportOptions, read func(fn func(JournalEntry) error) error, progress func(done int)) (int, error) {
+	var zw *gzip.Writer
+	if opts.Gzip {
+		zw = gzip.NewWriter(w)
+		w = zw
+	}
+
+	write, flush, err := journalEncoder(w, opts.Format)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = read(func(entry JournalEntry) error {
+		if err := write(entry); err != nil {
+			return fmt.Errorf("ошибка записи: %w", err)
+		}
+		count++
+		if progress != nil && count%exportProgressStep == 0 {
+			progress(count)
+		}
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+
+	if err := flush(); err != nil {
+		return count, fmt.Errorf("ошибка записи: %w", err)
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return count, err
+		}
+	}
+	if progress != nil {
+		progress(count)
+	}
+
+	return count, nil
+}
+
+// Функции записи одной записи журнала в формате format и завершения записи
+func journalEncoder(w io.Writer, format ExportFormat) (write func(JournalEntry) error, flush func() error, err error) {
+	flush = func() error { return nil }
+
+	switch format {
+	case ExportText:
+		write = func(e JournalEntry) error {
+			_, err := io.WriteString(w, e.format(exportTimeLayout)+"\n")
+			return err
+		}
+
+	case ExportJSONL:
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		write = func(e JournalEntry) error { return enc.Encode(e) }
+
+	case ExportCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(exportCSVHeader); err != nil {
+			return nil, nil, fmt.Errorf("ошибка записи: %w", err)
+		}
+		write = func(e JournalEntry) error {
+			pid := ""
+			if e.PID > 0 {
+				pid = strconv.Itoa(e.PID)
+			}
+			return cw.Write([]string{
+				e.Timestamp.Format(time.RFC3339Nano),
+				PriorityNames[e.Priority],
+				e.Hostname,
+				e.Unit,
+				e.SyslogIdentifier,
+				pid,
+				e.Message,
+			})
+		}
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+
+	default:
+		return nil, nil, fmt.Errorf("неизвестный формат %q", format)
+	}
+
+	return write, flush, nil
+}
+
+// Выгрузить журнал сервисов по запросу в файл. Файл сначала пишется во
+// временный файл рядом с целевым, поэтому прерванный экспорт не оставляет
+// неполных файлов. progress получает количество записанных записей и общее
+// количество, если оно известно заранее, иначе 0. Возвращает количество
+// выгруженных записей.
+func ExportLogs(ctx context.Context, b Backend, serviceNames []string, query LogQuery, path string, opts ExportOptions, progress func(done, total int)) (int, error) {
+	if _, err := query.Matcher(); err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return 0, fmt.Errorf("ошибка создания файла: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	// Без ограничения lines журнал пишется в файл по мере чтения и в памяти
+	// не накапливается. Последние lines записей известны только после
+	// чтения, поэтому с ограничением записи сначала читаются через Logs.
+	total := 0
+	read := func(fn func(JournalEntry) error) error {
+		return b.StreamLogs(ctx, serviceNames, query, fn)
+	}
+	if query.Lines > 0 {
+		entries, err := b.Logs(ctx, serviceNames, query)
+		if err != nil {
+			tmp.Close()
+			return 0, err
+		}
+		total = len(entries)
+		read = func(fn func(JournalEntry) error) error {
+			for _, entry := range entries {
+				if err := fn(entry); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	if progress != nil {
+		progress(0, total)
+	}
+
+	count, err := writeJournal(contextWriter{ctx: ctx, w: tmp}, opts, read, func(done int) {
+		if progress != nil {
+			progress(done, total)
+		}
+	})
+	if closeErr := tmp.Close(); err == nil && closeErr != nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, fmt.Errorf("ошибка сохранения файла: %w", err)
+	}
+
+	return count, nil
+}
+
+// Writer, который перестает писать после отмены контекста
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (cw contextWriter) Write(p []byte) (int, error) {
+	if err := cw.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cw.w.Write(p)
+}
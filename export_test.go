@@ -0,0 +1,217 @@
+package sdmanager
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestExportOptionsFromPath(t *testing.T) {
+	tests := map[string]ExportOptions{
+		"api.log":             {Format: ExportText},
+		"api.jsonl":           {Format: ExportJSONL},
+		"/tmp/api.CSV":        {Format: ExportCSV},
+		"api.jsonl.gz":        {Format: ExportJSONL, Gzip: true},
+		"incident-42":         {Format: ExportText},
+		"logs.d/api.txt.gz":   {Format: ExportText, Gzip: true},
+		"archive.tar.gz/file": {Format: ExportText},
+	}
+
+	for path, want := range tests {
+		if got := ExportOptionsFromPath(path); got != want {
+			t.Errorf("ExportOptionsFromPath(%q) = %+v, want %+v", path, got, want)
+		}
+	}
+
+	if _, err := ParseExportFormat("xml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestWriteJournalEntries(t *testing.T) {
+	entries := readJournalFixture(t)
+
+	var text bytes.Buffer
+	if err := WriteJournalEntries(&text, entries, ExportOptions{Format: ExportText}, nil); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(text.String(), "\n"), "\n")
+	if len(lines) != 4 || lines[2] != entries[2].Timestamp.Format(exportTimeLayout)+" api[1234]: database timeout" {
+		t.Errorf("text = %q", lines)
+	}
+
+	var jsonl bytes.Buffer
+	if err := WriteJournalEntries(&jsonl, entries, ExportOptions{Format: ExportJSONL}, nil); err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(&jsonl)
+	for i := range entries {
+		var entry JournalEntry
+		if err := dec.Decode(&entry); err != nil {
+			t.Fatalf("decode %d: %v", i, err)
+		}
+		if !entry.Timestamp.Equal(entries[i].Timestamp) || entry.Message != entries[i].Message || entry.Priority != entries[i].Priority {
+			t.Errorf("jsonl[%d] = %+v, want %+v", i, entry, entries[i])
+		}
+	}
+
+	// CSV со сжатием: заголовок и по строке на запись
+	var compressed bytes.Buffer
+	var progress []int
+	err := WriteJournalEntries(&compressed, entries, ExportOptions{Format: ExportCSV, Gzip: true}, func(done int) {
+		progress = append(progress, done)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := gzip.NewReader(&compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(zr).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 5 || strings.Join(records[0], ",") != strings.Join(exportCSVHeader, ",") {
+		t.Fatalf("records = %q", records)
+	}
+	if row := records[3]; row[1] != "err" || row[5] != "1234" || row[6] != "database timeout" {
+		t.Errorf("row = %q", row)
+	}
+	if len(progress) != 1 || progress[0] != 4 {
+		t.Errorf("progress = %v", progress)
+	}
+}
+
+func TestExportLogs(t *testing.T) {
+	backend := NewFakeBackend(UnitInfo{Name: "api"})
+	backend.SetLogEntries("api", readJournalFixture(t)...)
+
+	path := filepath.Join(t.TempDir(), "api.jsonl.gz")
+	count, err := ExportLogs(context.Background(), backend, []string{"api"}, LogQuery{Priority: "warning"}, path, ExportOptionsFromPath(path), nil)
+	if err != nil || count != 2 {
+		t.Fatalf("ExportLogs = %d, %v", count, err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "\n"); n != 2 {
+		t.Errorf("lines = %d", n)
+	}
+
+	// Прерванный экспорт не оставляет файлов
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	path = filepath.Join(t.TempDir(), "api.log")
+	if _, err := ExportLogs(ctx, backend, []string{"api"}, LogQuery{}, path, ExportOptionsFromPath(path), nil); err == nil {
+		t.Error("expected error for cancelled export")
+	}
+	if files, _ := os.ReadDir(filepath.Dir(path)); len(files) != 0 {
+		t.Errorf("files left after cancel: %v", files)
+	}
+}
+
+func TestExportLogsStream(t *testing.T) {
+	// journalctl, который выдает 2500 записей и сохраняет аргументы
+	dir := t.TempDir()
+	script := `#!/bin/sh
+echo "$@" > "$(dirname "$0")/args"
+i=0
+while [ $i -lt 2500 ]; do
+	echo '{"__REALTIME_TIMESTAMP":"1705314600000000","PRIORITY":"6","_SYSTEMD_UNIT":"api.service","MESSAGE":"request '$i'"}'
+	i=$((i+1))
+done
+`
+	if err := os.WriteFile(filepath.Join(dir, "journalctl"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	// Без ограничения lines записи пишутся по мере чтения, общее
+	// количество заранее неизвестно
+	var progress [][2]int
+	path := filepath.Join(t.TempDir(), "api.log")
+	count, err := ExportLogs(context.Background(), NewExecBackend(), []string{"api"}, LogQuery{}, path, ExportOptionsFromPath(path), func(done, total int) {
+		progress = append(progress, [2]int{done, total})
+	})
+	if err != nil || count != 2500 {
+		t.Fatalf("ExportLogs = %d, %v", count, err)
+	}
+	want := [][2]int{{0, 0}, {1000, 0}, {2000, 0}, {2500, 0}}
+	if fmt.Sprint(progress) != fmt.Sprint(want) {
+		t.Errorf("progress = %v, want %v", progress, want)
+	}
+	if args, _ := os.ReadFile(filepath.Join(dir, "args")); slices.Contains(strings.Fields(string(args)), "-n") {
+		t.Errorf("journalctl args = %s", args)
+	}
+	if data, _ := os.ReadFile(path); strings.Count(string(data), "\n") != 2500 {
+		t.Errorf("lines = %d", strings.Count(string(data), "\n"))
+	}
+
+	// С ограничением lines общее количество известно заранее
+	progress = nil
+	if _, err := ExportLogs(context.Background(), NewFakeBackend(UnitInfo{Name: "api"}), []string{"api"}, LogQuery{Lines: 10}, path, ExportOptionsFromPath(path), func(done, total int) {
+		progress = append(progress, [2]int{done, total})
+	}); err != nil || len(progress) == 0 {
+		t.Errorf("ExportLogs with lines: %v, progress = %v", err, progress)
+	}
+}
+
+func TestLogViewerExport(t *testing.T) {
+	backend := NewFakeBackend(UnitInfo{Name: "api"})
+	backend.SetLogs("api", "first", "second")
+
+	model := NewLogViewerModel(AppOptions{backend: backend})
+	model, _ = FollowServiceLogs(context.Background(), model, "api")
+	defer StopFollowing(model)
+
+	model, _ = UpdateLogViewer(context.Background(), tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")}, model)
+	if model.State != LogStateExport || !strings.HasPrefix(model.ExportInput.Value(), "api-"+time.Now().Format("20060102")) {
+		t.Fatalf("state = %d, file = %q", model.State, model.ExportInput.Value())
+	}
+
+	path := filepath.Join(t.TempDir(), "api.csv")
+	model.ExportInput.SetValue(path)
+	model, cmd := UpdateLogViewer(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+	if !model.Exporting {
+		t.Fatal("export should be started")
+	}
+
+	for model.Exporting {
+		model, cmd = UpdateLogViewer(context.Background(), cmd(), model)
+	}
+	if model.Error != "" || !strings.Contains(model.Message, "2") {
+		t.Errorf("error = %q, message = %q", model.Error, model.Message)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "second") {
+		t.Errorf("csv = %q", data)
+	}
+}
@@ -0,0 +1,518 @@
+package sdmanager
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Backend, хранящий состояние сервисов в памяти. Используется в тестах
+// вместо systemctl и journalctl.
+type FakeBackend struct {
+	// Каталог unit-файлов: при DaemonReload найденные в нем сервисы
+	// становятся известными, как это делает systemd
+	UnitDir string
+
+	mu       sync.Mutex
+	units    map[string]*UnitInfo
+	logs     map[string][]JournalEntry
+	props    map[string]map[string]string
+	security map[string]SecurityReport
+	follows  map[string][]chan JournalEntry
+	failures map[string]error
+	calls    []string
+	reloads  int
+}
+
+// Создать FakeBackend с заданным набором сервисов
+func NewFakeBackend(units ...UnitInfo) *FakeBackend {
+	b := &FakeBackend{
+		units:    make(map[string]*UnitInfo),
+		logs:     make(map[string][]JournalEntry),
+		props:    make(map[string]map[string]string),
+		security: make(map[string]SecurityReport),
+		follows:  make(map[string][]chan JournalEntry),
+		failures: make(map[string]error),
+	}
+
+	for _, unit := range units {
+		b.AddUnit(unit)
+	}
+
+	return b
+}
+
+// Добавить сервис. Незаданные состояния заполняются значениями
+// остановленного сервиса.
+func (b *FakeBackend) AddUnit(unit UnitInfo) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if unit.LoadState == "" {
+		unit.LoadState = "loaded"
+	}
+	if unit.ActiveState == "" {
+		unit.ActiveState = "inactive"
+		unit.SubState = "dead"
+	}
+	if unit.UnitFileState == "" {
+		unit.UnitFileState = "disabled"
+	}
+
+	b.units[unit.Name] = &unit
+}
+
+// Задать строки журнала сервиса с приоритетом info
+func (b *FakeBackend) SetLogs(serviceName string, messages ...string) {
+	entries := make([]JournalEntry, 0, len(messages))
+	for _, message := range messages {
+		entries = append(entries, JournalEntry{Priority: PriorityInfo, Message: message})
+	}
+	b.SetLogEntries(serviceName, entries...)
+}
+
+// Задать записи журнала сервиса
+func (b *FakeBackend) SetLogEntries(serviceName string, entries ...JournalEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.logs[serviceName] = entries
+}
+
+// Добавить запись в журнал сервиса и передать ее подписчикам FollowLogs.
+// Подписчики отбирают записи сами, как это делает journalctl.
+func (b *FakeBackend) PushLog(serviceName string, entry JournalEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.logs[serviceName] = append(b.logs[serviceName], entry)
+	for _, ch := range b.follows[serviceName] {
+		ch <- entry
+	}
+}
+
+// Задать дополнительные свойства сервиса, возвращаемые Properties
+func (b *FakeBackend) SetProperties(serviceName string, props map[string]string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.props[serviceName] = props
+}
+
+// Задать результат аудита безопасности сервиса
+func (b *FakeBackend) SetSecurityReport(serviceName string, report SecurityReport) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.security[serviceName] = report
+}
+
+// Задать ошибку, которую вернет операция над сервисом, например
+// FailOn("start", "api", err). Пустое имя сервиса подходит для DaemonReload.
+func (b *FakeBackend) FailOn(operation, serviceName string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures[operation+" "+serviceName] = err
+}
+
+// Получить текущее состояние сервиса
+func (b *FakeBackend) Unit(serviceName string) (UnitInfo, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	unit, ok := b.units[serviceName]
+	if !ok {
+		return UnitInfo{}, false
+	}
+	return *unit, true
+}
+
+// Получить список выполненных операций в формате "operation name"
+func (b *FakeBackend) Calls() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return slices.Clone(b.calls)
+}
+
+// Получить количество выполненных daemon-reload
+func (b *FakeBackend) Reloads() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.reloads
+}
+
+// Зафиксировать вызов и найти сервис. Вызывается под блокировкой.
+func (b *FakeBackend) call(operation, serviceName string) (*UnitInfo, error) {
+	b.calls = append(b.calls, strings.TrimSpace(operation+" "+serviceName))
+
+	if err := b.failures[operation+" "+serviceName]; err != nil {
+		return nil, err
+	}
+
+	if serviceName == "" {
+		return nil, nil
+	}
+
+	name := strings.TrimSuffix(serviceName, ".service")
+	unit, ok := b.units[name]
+	if !ok {
+		// Экземпляр загруженного шаблона создается при первом обращении
+		template, instance, found := strings.Cut(name, "@")
+		if _, loaded := b.units[template+"@"]; !found || instance == "" || !loaded {
+			return nil, fmt.Errorf("%s: %w", serviceName, ErrUnitNotFound)
+		}
+		unit = &UnitInfo{
+			Name:          name,
+			LoadState:     "loaded",
+			ActiveState:   "inactive",
+			SubState:      "dead",
+			UnitFileState: "disabled",
+		}
+		b.units[name] = unit
+	}
+
+	return unit, nil
+}
+
+func (b *FakeBackend) Start(_ context.Context, serviceName string) error {
+	return b.setActive("start", serviceName, "active", "running")
+}
+
+func (b *FakeBackend) Stop(_ context.Context, serviceName string) error {
+	return b.setActive("stop", serviceName, "inactive", "dead")
+}
+
+func (b *FakeBackend) Restart(_ context.Context, serviceName string) error {
+	return b.setActive("restart", serviceName, "active", "running")
+}
+
+// Reload не меняет состояние сервиса; как и systemd, фейк не перечитывает
+// конфигурацию остановленного сервиса
+func (b *FakeBackend) Reload(_ context.Context, serviceName string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	unit, err := b.call("reload", serviceName)
+	if err != nil {
+		return err
+	}
+	if unit.ActiveState != "active" {
+		return fmt.Errorf("%s: сервис не запущен, reload невозможен", serviceName)
+	}
+	return nil
+}
+
+func (b *FakeBackend) Enable(_ context.Context, serviceName string) error {
+	return b.setFileState("enable", serviceName, "enabled")
+}
+
+func (b *FakeBackend) Disable(_ context.Context, serviceName string) error {
+	return b.setFileState("disable", serviceName, "disabled")
+}
+
+// Типы unit-файлов, которые фейк подхватывает из UnitDir при daemon-reload
+var fakeUnitSuffixes = []string{".service", ".timer", ".socket", ".path"}
+
+func (b *FakeBackend) DaemonReload(_ context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, err := b.call("daemon-reload", ""); err != nil {
+		return err
+	}
+	b.reloads++
+
+	if b.UnitDir == "" {
+		return nil
+	}
+
+	var paths []string
+	for _, suffix := range fakeUnitSuffixes {
+		matches, err := filepath.Glob(filepath.Join(b.UnitDir, "*"+suffix))
+		if err != nil {
+			return err
+		}
+		paths = append(paths, matches...)
+	}
+
+	for _, path := range paths {
+		// Сервисы хранятся без суффикса, остальные unit - с типом (api.timer)
+		name := strings.TrimSuffix(filepath.Base(path), ".service")
+		if _, ok := b.units[name]; ok {
+			continue
+		}
+		b.units[name] = &UnitInfo{
+			Name:          name,
+			LoadState:     "loaded",
+			ActiveState:   "inactive",
+			SubState:      "dead",
+			UnitFileState: "disabled",
+		}
+	}
+
+	return nil
+}
+
+func (b *FakeBackend) Status(_ context.Context, serviceName string) (UnitInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	unit, err := b.call("status", serviceName)
+	if err != nil {
+		return UnitInfo{}, err
+	}
+	return *unit, nil
+}
+
+func (b *FakeBackend) Properties(_ context.Context, serviceName string, names ...string) (map[string]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	unit, err := b.call("show", serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	props := map[string]string{
+		"Id":            unit.Name + ".service",
+		"Description":   unit.Description,
+		"LoadState":     unit.LoadState,
+		"ActiveState":   unit.ActiveState,
+		"SubState":      unit.SubState,
+		"UnitFileState": unit.UnitFileState,
+	}
+	for key, value := range b.props[unit.Name] {
+		props[key] = value
+	}
+
+	if len(names) == 0 {
+		return props, nil
+	}
+
+	selected := make(map[string]string, len(names))
+	for _, name := range names {
+		if value, ok := props[name]; ok {
+			selected[name] = value
+		}
+	}
+	return selected, nil
+}
+
+func (b *FakeBackend) Logs(_ context.Context, serviceNames []string, query LogQuery) ([]JournalEntry, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, name := range serviceNames {
+		if _, err := b.call("logs", name); err != nil {
+			return nil, err
+		}
+	}
+
+	return b.selectLogs(serviceNames, query)
+}
+
+func (b *FakeBackend) StreamLogs(ctx context.Context, serviceNames []string, query LogQuery, fn func(JournalEntry) error) error {
+	query.Lines = 0
+	entries, err := b.Logs(ctx, serviceNames, query)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *FakeBackend) FollowLogs(ctx context.Context, serviceNames []string, query LogQuery) (<-chan JournalEntry, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, name := range serviceNames {
+		if _, err := b.call("follow", name); err != nil {
+			return nil, err
+		}
+	}
+
+	entries, err := b.selectLogs(serviceNames, query)
+	if err != nil {
+		return nil, err
+	}
+	match, _ := query.Matcher()
+
+	// Записи из PushLog проходят через промежуточный канал, чтобы отбор
+	// не выполнялся под блокировкой
+	in := make(chan JournalEntry, 64)
+	out := make(chan JournalEntry, len(entries)+64)
+	for _, entry := range entries {
+		out <- entry
+	}
+	for _, name := range serviceNames {
+		b.follows[name] = append(b.follows[name], in)
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		for _, name := range serviceNames {
+			b.follows[name] = slices.DeleteFunc(b.follows[name], func(c chan JournalEntry) bool { return c == in })
+		}
+		close(in)
+	}()
+
+	go func() {
+		defer close(out)
+		for entry := range in {
+			if !match(entry) {
+				continue
+			}
+			select {
+			case out <- entry:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// Отобрать записи журнала по query. Записи нескольких сервисов сливаются
+// по времени. Вызывается под блокировкой.
+func (b *FakeBackend) selectLogs(serviceNames []string, query LogQuery) ([]JournalEntry, error) {
+	match, err := query.Matcher()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []JournalEntry
+	for _, name := range serviceNames {
+		for _, entry := range b.logs[name] {
+			if match(entry) {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	slices.SortStableFunc(entries, func(a, b JournalEntry) int {
+		return a.Timestamp.Compare(b.Timestamp)
+	})
+
+	return LastEntries(entries, query.Lines), nil
+}
+
+func (b *FakeBackend) ListUnits(_ context.Context) ([]UnitInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, err := b.call("list-units", ""); err != nil {
+		return nil, err
+	}
+
+	units := make([]UnitInfo, 0, len(b.units))
+	for _, unit := range b.units {
+		info := *unit
+		// Как и в systemd, list-unit-files не показывает экземпляры
+		// шаблонов, их автозапуск известен только из свойств экземпляра
+		if _, instance, found := strings.Cut(info.Name, "@"); found && instance != "" {
+			info.UnitFileState = ""
+		}
+		units = append(units, info)
+	}
+
+	slices.SortFunc(units, func(a, b UnitInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return units, nil
+}
+
+func (b *FakeBackend) ListUnitFiles(_ context.Context) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, err := b.call("list-unit-files", ""); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(b.units))
+	for name := range b.units {
+		names = append(names, unitName(name))
+	}
+	slices.Sort(names)
+
+	return names, nil
+}
+
+func (b *FakeBackend) SecurityReport(_ context.Context, serviceName string) (SecurityReport, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	unit, err := b.call("security", serviceName)
+	if err != nil {
+		return SecurityReport{}, err
+	}
+	return b.security[unit.Name], nil
+}
+
+// Изменение свойств записывается в операции "set-property" или
+// "set-property-runtime", а новые значения возвращает Properties
+func (b *FakeBackend) SetProperty(_ context.Context, serviceName string, runtime bool, assignments ...string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	operation := "set-property"
+	if runtime {
+		operation += "-runtime"
+	}
+	unit, err := b.call(operation, serviceName)
+	if err != nil {
+		return err
+	}
+
+	if b.props[unit.Name] == nil {
+		b.props[unit.Name] = make(map[string]string)
+	}
+	for _, assignment := range assignments {
+		key, value, _ := strings.Cut(assignment, "=")
+		b.props[unit.Name][key] = value
+	}
+	return nil
+}
+
+func (b *FakeBackend) setActive(operation, serviceName, activeState, subState string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	unit, err := b.call(operation, serviceName)
+	if err != nil {
+		return err
+	}
+
+	unit.ActiveState = activeState
+	unit.SubState = subState
+	return nil
+}
+
+func (b *FakeBackend) setFileState(operation, serviceName, state string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	unit, err := b.call(operation, serviceName)
+	if err != nil {
+		return err
+	}
+
+	unit.UnitFileState = state
+	return nil
+}
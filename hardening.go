@@ -0,0 +1,204 @@
+package sdmanager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Профили защиты сервиса: none - без ограничений, basic - изоляция, которая
+// подходит почти любому сервису, strict - песочница для сетевых сервисов
+var HardeningProfiles = []string{"none", "basic", "strict"}
+
+// Директива защиты со значениями в профилях и пояснением для предпросмотра
+type hardeningKey struct {
+	Key         string
+	Basic       string
+	Strict      string
+	Description string
+	// Список: в drop-in его нужно сначала очистить, иначе значения
+	// объединяются
+	List bool
+}
+
+// Директивы профилей в порядке записи в unit-файл. ReadWritePaths
+// заполняется из WorkingDirectory.
+var hardeningKeys = []hardeningKey{
+	{Key: "NoNewPrivileges", Basic: "yes", Strict: "yes", Description: "процесс не может получить новые привилегии через setuid или capabilities"},
+	{Key: "ProtectSystem", Basic: "full", Strict: "strict", Description: "full - /usr, /boot и /etc только для чтения; strict - вся файловая система, кроме ReadWritePaths"},
+	{Key: "ProtectHome", Basic: "read-only", Strict: "yes", Description: "домашние каталоги только для чтения (read-only) или недоступны (yes)"},
+	{Key: "PrivateTmp", Basic: "yes", Strict: "yes", Description: "собственные /tmp и /var/tmp, недоступные другим сервисам"},
+	{Key: "PrivateDevices", Basic: "yes", Strict: "yes", Description: "нет доступа к физическим устройствам в /dev"},
+	{Key: "ProtectKernelTunables", Basic: "yes", Strict: "yes", Description: "параметры ядра в /proc/sys и /sys только для чтения"},
+	{Key: "ProtectKernelModules", Basic: "yes", Strict: "yes", Description: "запрещена загрузка модулей ядра"},
+	{Key: "ProtectControlGroups", Basic: "yes", Strict: "yes", Description: "иерархия cgroup только для чтения"},
+	{Key: "ProtectKernelLogs", Strict: "yes", Description: "нет доступа к журналу ядра"},
+	{Key: "ProtectClock", Strict: "yes", Description: "запрещено менять системное время"},
+	{Key: "ProtectHostname", Strict: "yes", Description: "запрещено менять имя хоста"},
+	{Key: "RestrictAddressFamilies", Strict: "AF_UNIX AF_INET AF_INET6", Description: "только unix-сокеты и IPv4/IPv6", List: true},
+	{Key: "RestrictNamespaces", Strict: "yes", Description: "запрещено создавать пространства имен"},
+	{Key: "RestrictRealtime", Strict: "yes", Description: "запрещен планировщик реального времени"},
+	{Key: "RestrictSUIDSGID", Strict: "yes", Description: "запрещено создавать файлы с setuid и setgid"},
+	{Key: "LockPersonality", Strict: "yes", Description: "запрещено менять домен исполнения (personality)"},
+	{Key: "CapabilityBoundingSet", Strict: "CAP_NET_BIND_SERVICE", Description: "из capabilities остается только право занимать порты ниже 1024", List: true},
+	{Key: "SystemCallFilter", Strict: "@system-service", Description: "разрешены только системные вызовы, нужные обычным сервисам", List: true},
+	{Key: "SystemCallArchitectures", Strict: "native", Description: "системные вызовы только родной архитектуры", List: true},
+	{Key: "UMask", Strict: "0027", Description: "создаваемые файлы недоступны остальным пользователям"},
+	{Key: "ReadWritePaths", Description: "рабочий каталог остается доступным для записи", List: true},
+}
+
+// Директива защиты с итоговым значением
+type hardeningDirective struct {
+	Key         string
+	Value       string
+	Description string
+}
+
+// Значение директивы защиты в профиле сервиса; пустое - директива не
+// записывается
+func hardeningValue(config ServiceConfig, key hardeningKey) string {
+	if config.Hardening != "basic" && config.Hardening != "strict" {
+		return ""
+	}
+	if key.Key == "ReadWritePaths" {
+		return config.WorkingDirectory
+	}
+	if config.Hardening == "strict" {
+		return key.Strict
+	}
+	return key.Basic
+}
+
+// Директивы защиты, которые профиль сервиса добавляет в unit-файл
+func hardeningDirectives(config ServiceConfig) []hardeningDirective {
+	var directives []hardeningDirective
+	for _, key := range hardeningKeys {
+		if value := hardeningValue(config, key); value != "" {
+			directives = append(directives, hardeningDirective{Key: key.Key, Value: value, Description: key.Description})
+		}
+	}
+	return directives
+}
+
+// Проверить профиль защиты
+func ValidateHardening(profile string) error {
+	if profile != "" && !slices.Contains(HardeningProfiles, profile) {
+		return fmt.Errorf("профиль защиты: допустимые значения: %s", strings.Join(HardeningProfiles, ", "))
+	}
+	return nil
+}
+
+// Каталоги, которые ProtectHome=yes делает недоступными сервису
+var protectedHomeDirs = []string{"/home", "/root", "/run/user"}
+
+// Проверить, что профиль strict не закрывает сервису его собственные файлы:
+// ProtectHome=yes скрывает домашние каталоги целиком, и ReadWritePaths не
+// открывает их обратно
+func validateHardeningPaths(config ServiceConfig) error {
+	if config.Hardening != "strict" {
+		return nil
+	}
+
+	paths := []struct{ what, path string }{{"рабочий каталог", config.WorkingDirectory}}
+	_, rest := splitExecPrefix(strings.TrimSpace(config.ExecStart))
+	if words, err := splitQuoted(rest); err == nil && len(words) > 0 {
+		paths = append(paths, struct{ what, path string }{"исполняемый файл", words[0]})
+	}
+
+	for _, p := range paths {
+		if !filepath.IsAbs(p.path) {
+			continue
+		}
+		path := filepath.Clean(p.path)
+		for _, dir := range protectedHomeDirs {
+			if path == dir || strings.HasPrefix(path, dir+"/") {
+				return fmt.Errorf("профиль strict: %s %s недоступен при ProtectHome=yes, выберите профиль basic или перенесите сервис из %s", p.what, p.path, dir)
+			}
+		}
+	}
+	return nil
+}
+
+// Определить профиль защиты по директивам unit-файла. Если директивы не
+// совпадают ни с одним профилем, возвращается пустая строка: такие
+// настройки sdmanager не меняет.
+func detectHardening(unit *UnitFile, config ServiceConfig) string {
+	for _, profile := range []string{"strict", "basic"} {
+		config.Hardening = profile
+		matches := true
+		for _, key := range hardeningKeys {
+			value, _ := unit.Get("Service", key.Key)
+			if strings.Join(strings.Fields(value), " ") != hardeningValue(config, key) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return profile
+		}
+	}
+	return ""
+}
+
+// Итоговая строка systemd-analyze security: "→ Overall exposure level for
+// api.service: 3.9 OK 🙂"
+var exposurePattern = regexp.MustCompile(`Overall exposure level for \S+: ([0-9.]+) (\S+)`)
+
+// Оценить защищенность unit-файла командой systemd-analyze security без
+// обращения к systemd. Возвращает оценку вида "3.9 OK" или пустую строку,
+// если systemd-analyze недоступен.
+func AnalyzeUnitSecurity(ctx context.Context, unit GeneratedUnit) (string, error) {
+	if _, err := exec.LookPath("systemd-analyze"); err != nil {
+		return "", nil
+	}
+
+	dir, err := os.MkdirTemp("", "sdmanager-security-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, filepath.Base(unit.Path))
+	if err := os.WriteFile(path, []byte(unit.Content), 0o644); err != nil {
+		return "", err
+	}
+
+	output, err := ExecuteCommand(ctx, "systemd-analyze", "security", "--offline=true", "--no-pager", path)
+	if err != nil {
+		return "", err
+	}
+
+	return parseExposure(output)
+}
+
+// Пояснения к директивам защиты и оценка systemd-analyze security для
+// предпросмотра
+func HardeningPreview(ctx context.Context, config ServiceConfig, unit GeneratedUnit) string {
+	var sb strings.Builder
+
+	sb.WriteString("Профиль защиты " + config.Hardening + ":\n")
+	for _, directive := range hardeningDirectives(config) {
+		sb.WriteString(fmt.Sprintf("  %s=%s - %s\n", directive.Key, directive.Value, directive.Description))
+	}
+
+	exposure, err := AnalyzeUnitSecurity(ctx, unit)
+	switch {
+	case err != nil:
+		sb.WriteString("Оценка systemd-analyze security недоступна: " + firstLine(err.Error()) + "\n")
+	case exposure != "":
+		sb.WriteString("Оценка systemd-analyze security (0 - лучше, 10 - хуже): " + exposure + "\n")
+	}
+
+	return sb.String()
+}
+
+// Первая строка многострочного текста
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,102 @@
+package sdmanager
+
+import (
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestValidateHardening(t *testing.T) {
+	for _, profile := range []string{"", "none", "basic", "strict"} {
+		if err := ValidateHardening(profile); err != nil {
+			t.Errorf("ValidateHardening(%q): %v", profile, err)
+		}
+	}
+	if err := ValidateHardening("paranoid"); err == nil {
+		t.Error("unknown profile accepted")
+	}
+
+	// ProtectHome=yes в strict скрывает домашние каталоги, и ReadWritePaths
+	// их не открывает
+	tests := []struct {
+		hardening, workingDirectory, execStart string
+		ok                                     bool
+	}{
+		{"strict", "/srv/api", "/usr/bin/api", true},
+		{"strict", "/home/dev/api", "/usr/bin/api", false},
+		{"strict", "/srv/api", "-/root/bin/api --port 80", false},
+		{"strict", "/run/user/1000/api", "/usr/bin/api", false},
+		{"strict", "/homework/api", "/usr/bin/api", true},
+		{"strict", "", "api", true},
+		{"basic", "/home/dev/api", "/home/dev/api/api", true},
+	}
+	for _, tt := range tests {
+		config := ServiceConfig{Hardening: tt.hardening, WorkingDirectory: tt.workingDirectory, ExecStart: tt.execStart}
+		if err := validateHardeningPaths(config); (err == nil) != tt.ok {
+			t.Errorf("%s %s %q: %v", tt.hardening, tt.workingDirectory, tt.execStart, err)
+		}
+	}
+}
+
+func TestApplyServiceConfigKeepsCustomHardening(t *testing.T) {
+	content := "[Service]\nExecStart=/usr/bin/api\nProtectSystem=full\nPrivateTmp=yes\n"
+	unit, err := ParseUnitFile(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("ParseUnitFile: %v", err)
+	}
+	config, err := ServiceConfigFromUnit(unit, "api", "")
+	if err != nil {
+		t.Fatalf("ServiceConfigFromUnit: %v", err)
+	}
+	if config.Hardening != "" {
+		t.Fatalf("Hardening = %q, want empty for custom directives", config.Hardening)
+	}
+
+	// Без профиля директивы, заданные вручную, не меняются
+	ApplyServiceConfig(unit, config)
+	if got := unit.String(); got != content {
+		t.Errorf("unit changed:\n%s", got)
+	}
+
+	// Профиль none убирает их
+	config.Hardening = "none"
+	ApplyServiceConfig(unit, config)
+	if got := unit.String(); got != "[Service]\nExecStart=/usr/bin/api\n" {
+		t.Errorf("unit:\n%s", got)
+	}
+}
+
+func TestAnalyzeUnitSecurity(t *testing.T) {
+	if _, err := exec.LookPath("systemd-analyze"); err != nil {
+		t.Skip("systemd-analyze не установлен")
+	}
+
+	config := ServiceConfig{ServiceName: "api", ExecStart: "/usr/bin/api", UnitFilePath: DefaultUnitDir}
+	plain, err := GenerateUnits(config)
+	if err != nil {
+		t.Fatalf("GenerateUnits: %v", err)
+	}
+	config.Hardening = "strict"
+	strict, err := GenerateUnits(config)
+	if err != nil {
+		t.Fatalf("GenerateUnits: %v", err)
+	}
+
+	plainExposure, err := AnalyzeUnitSecurity(context.Background(), plain[0])
+	if err != nil {
+		t.Skipf("systemd-analyze security --offline недоступен: %v", err)
+	}
+	strictExposure, err := AnalyzeUnitSecurity(context.Background(), strict[0])
+	if err != nil {
+		t.Fatalf("AnalyzeUnitSecurity: %v", err)
+	}
+	score := func(exposure string) float64 {
+		value, _ := strconv.ParseFloat(strings.Fields(exposure)[0], 64)
+		return value
+	}
+	if score(strictExposure) >= score(plainExposure) {
+		t.Errorf("strict exposure %s is not lower than %s", strictExposure, plainExposure)
+	}
+}
@@ -0,0 +1,120 @@
+package sdmanager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Префиксы команд Exec*: "-" - код возврата не учитывается, "@" - второй
+// аргумент передается как argv[0], ":" - без подстановки переменных,
+// "+", "!" и "!!" - запуск с полными привилегиями или без смены пользователя
+const execPrefixChars = "-@:+!"
+
+// Команды жизненного цикла сервиса одного типа
+type execHook struct {
+	Key      string
+	Commands []string
+}
+
+// Команды жизненного цикла сервиса, кроме ExecStart, в порядке выполнения
+func execHooks(config ServiceConfig) []execHook {
+	return []execHook{
+		{Key: "ExecStartPre", Commands: config.ExecStartPre},
+		{Key: "ExecStartPost", Commands: config.ExecStartPost},
+		{Key: "ExecReload", Commands: config.ExecReload},
+		{Key: "ExecStop", Commands: config.ExecStop},
+		{Key: "ExecStopPost", Commands: config.ExecStopPost},
+	}
+}
+
+// Отделить префиксы от команды
+func splitExecPrefix(command string) (string, string) {
+	rest := strings.TrimLeft(command, execPrefixChars)
+	return command[:len(command)-len(rest)], rest
+}
+
+// Проверить сочетание префиксов: каждый не более одного раза, "+" и "!"
+// взаимоисключающие
+func validateExecPrefix(prefix string) error {
+	for _, char := range []string{"-", "@", ":", "+"} {
+		if strings.Count(prefix, char) > 1 {
+			return fmt.Errorf("префикс %s указан несколько раз", char)
+		}
+	}
+
+	bangs := strings.Count(prefix, "!")
+	if bangs > 2 || (bangs == 2 && !strings.Contains(prefix, "!!")) {
+		return errors.New("допустимы префиксы ! и !!")
+	}
+	if bangs > 0 && strings.Contains(prefix, "+") {
+		return errors.New("префиксы + и ! нельзя совмещать")
+	}
+	return nil
+}
+
+// Проверить команду Exec*: префиксы и исполняемый файл, который должен
+// существовать и быть исполняемым
+func ValidateExecCommand(command string) error {
+	prefix, rest := splitExecPrefix(strings.TrimSpace(command))
+	if rest == "" {
+		return errors.New("команда не может быть пустой")
+	}
+	if err := validateExecPrefix(prefix); err != nil {
+		return err
+	}
+
+	words, err := splitQuoted(rest)
+	if err != nil {
+		return err
+	}
+	if len(words) == 0 {
+		return errors.New("команда не может быть пустой")
+	}
+
+	// Спецификаторы (%i) и переменные подставляет systemd при запуске
+	if strings.ContainsAny(words[0], "%$") {
+		return nil
+	}
+	return checkExecutable(words[0])
+}
+
+// Проверить, что файл существует и его можно запустить. Имя без пути ищется
+// в PATH, как это делает systemd.
+func checkExecutable(path string) error {
+	if !filepath.IsAbs(path) {
+		if _, err := exec.LookPath(path); err != nil {
+			return fmt.Errorf("исполняемый файл %s не найден в PATH", path)
+		}
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("исполняемый файл %s не найден", path)
+	}
+	if info.IsDir() || info.Mode().Perm()&0o111 == 0 {
+		return fmt.Errorf("файл %s не является исполняемым", path)
+	}
+	return nil
+}
+
+// Проверить все команды жизненного цикла сервиса
+func ValidateExecHooks(config ServiceConfig) error {
+	for _, command := range config.ExecStartNext {
+		if err := ValidateExecCommand(command); err != nil {
+			return fmt.Errorf("ExecStart: %w", err)
+		}
+	}
+	for _, hook := range execHooks(config) {
+		for _, command := range hook.Commands {
+			if err := ValidateExecCommand(command); err != nil {
+				return fmt.Errorf("%s: %w", hook.Key, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package sdmanager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateExecCommand(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "migrate.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	data := filepath.Join(dir, "data.txt")
+	if err := os.WriteFile(data, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	valid := []string{
+		script,
+		script + " --dry-run",
+		"-" + script,
+		"+" + script,
+		"!!" + script,
+		"-@" + script + " migrate",
+		"sh -c 'echo ready'",
+		"/opt/%i/bin/worker",
+	}
+	for _, command := range valid {
+		if err := ValidateExecCommand(command); err != nil {
+			t.Errorf("ValidateExecCommand(%q): %v", command, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"-",
+		filepath.Join(dir, "missing"),
+		data,
+		dir,
+		"+!" + script,
+		"--" + script,
+		"!!!" + script,
+		"no-such-command-sdmanager",
+	}
+	for _, command := range invalid {
+		if err := ValidateExecCommand(command); err == nil {
+			t.Errorf("ValidateExecCommand(%q) succeeded", command)
+		}
+	}
+}
@@ -1,10 +1,17 @@
 package sdmanager
 
 import (
+	"cmp"
+	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
@@ -37,11 +44,11 @@ func NewInstallModel(appOptions AppOptions) InstallModel {
 			ServiceName:      serviceName,
 			WorkingDirectory: currentDir,
 			ExecStart:        execPath,
-			MemoryHigh:       0,
-			MemoryMax:        0,
+			MemoryHigh:       "",
+			MemoryMax:        "",
 			CPUQuota:         0,
 			AllowedCPUs:      "",
-			UnitFilePath:     "/etc/systemd/system",
+			UnitFilePath:     DefaultUnitDir,
 		},
 		Actions: UserActions{
 			Overwrite:     false,
@@ -64,200 +71,1520 @@ func NewInstallModel(appOptions AppOptions) InstallModel {
 			{Name: "Запустить (start) сервис", Selected: true},
 		},
 		CurrentOption: 0,
+		backend:       appOptions.backend,
 	}
 }
 
+// Инициализация модели установки сервиса, запускаемого таймером. Сервис
+// создается как oneshot, а активируется и запускается таймер.
+func NewTimerInstallModel(appOptions AppOptions) InstallModel {
+	model := NewInstallModel(appOptions)
+	model.Config.Timer = &TimerConfig{}
+	model.Options = []Option{
+		{Name: "Перезагрузить systemd daemon", Selected: true},
+		{Name: "Активировать (enable) таймер", Selected: true},
+		{Name: "Запустить (start) таймер", Selected: true},
+	}
+
+	return model
+}
+
+// Что активируется при установке: сервис или запускающий его таймер, сокет или path-unit
+func activationTarget(config ServiceConfig) string {
+	switch {
+	case config.Timer != nil:
+		return "таймер"
+	case config.Socket != nil:
+		return "сокет"
+	case config.Path != nil:
+		return "path-unit"
+	case config.Instances > 0:
+		return "экземпляры"
+	default:
+		return "сервис"
+	}
+}
+
+// Инициализация модели установки сервиса с активацией через сокет. Сервис
+// запускается при первом подключении, поэтому активируется сокет.
+func NewSocketInstallModel(appOptions AppOptions) InstallModel {
+	model := NewInstallModel(appOptions)
+	model.Config.Socket = &SocketConfig{}
+	model.Options = []Option{
+		{Name: "Перезагрузить systemd daemon", Selected: true},
+		{Name: "Активировать (enable) сокет", Selected: true},
+		{Name: "Запустить (start) сокет", Selected: true},
+	}
+
+	return model
+}
+
+// Инициализация модели установки сервиса, запускаемого path-unit при
+// появлении или изменении файлов. Сервис создается как oneshot.
+func NewPathInstallModel(appOptions AppOptions) InstallModel {
+	model := NewInstallModel(appOptions)
+	model.Config.Path = &PathConfig{}
+	model.Options = []Option{
+		{Name: "Перезагрузить systemd daemon", Selected: true},
+		{Name: "Активировать (enable) path-unit", Selected: true},
+		{Name: "Запустить (start) path-unit", Selected: true},
+	}
+
+	return model
+}
+
+// Инициализация модели редактирования существующего сервиса
+func NewEditModel(appOptions AppOptions) InstallModel {
+	model := NewInstallModel(appOptions)
+	model.EditMode = true
+	model.Input.Placeholder = appOptions.serviceName
+	model.Config.ServiceName = appOptions.serviceName
+	model.Message = "Введите название сервиса для редактирования:"
+	model.Actions = UserActions{
+		Overwrite:      true,
+		ReloadDaemon:   true,
+		RestartService: true,
+	}
+	model.Options = []Option{
+		{Name: "Перезагрузить systemd daemon", Selected: true},
+		{Name: "Перезапустить (restart) сервис", Selected: true},
+		{Name: "Записать изменения в drop-in (не изменять основной файл)", Selected: false},
+	}
+
+	return model
+}
+
+// Значение поля с учетом текущего: пустой ввод оставляет текущее значение,
+// "-" очищает его
+func inputOrCurrent(input, current string) string {
+	switch input {
+	case "":
+		return current
+	case "-":
+		return ""
+	}
+	return input
+}
+
 // Обработка события ввода имени сервиса
 func HandleServiceNameInput(model InstallModel, input string) (InstallModel, error) {
 	if input == "" {
-		input = model.Config.ServiceName // Используем значение по умолчанию
+		input = model.Config.ServiceName // Используем значение по умолчанию
+	}
+
+	if err := IsValidServiceName(input); err != nil {
+		model.ErrorMsg = err.Error()
+		return model, nil
+	}
+
+	// В режиме редактирования загружаем текущую конфигурацию из unit-файла
+	if model.EditMode {
+		var err error
+		if model, err = loadServiceForEdit(model, input); err != nil {
+			model.ErrorMsg = err.Error()
+			return model, nil
+		}
+	}
+
+	// Имя вида "worker@" создает шаблон worker@.service с экземплярами
+	if base, ok := ParseTemplateName(input); ok && !model.EditMode {
+		if triggerCount(model.Config) > 0 {
+			model.ErrorMsg = "шаблон с экземплярами нельзя запускать таймером, сокетом или path-unit"
+			return model, nil
+		}
+		input = base
+		model.Config.Instances = max(model.Config.Instances, 1)
+		model.Options[1].Name = "Активировать (enable) экземпляры"
+		model.Options[2].Name = "Запустить (start) экземпляры"
+	}
+
+	model.Config.ServiceName = input
+	model.State = StateUserName
+	model.Message = "Введите имя юзера (оционально):"
+	model.Input.SetValue("")
+	model.Input.Placeholder = model.Config.UserName
+
+	return model, nil
+}
+
+// Загрузить существующий сервис для редактирования. Если unit-файл лежит вне
+// каталога администратора (например, установлен пакетом), изменения по
+// умолчанию записываются в drop-in.
+func loadServiceForEdit(model InstallModel, serviceName string) (InstallModel, error) {
+	unitDir := model.Config.UnitFilePath
+	unitPath := filepath.Join(unitDir, serviceName+".service")
+	if !FileExists(unitPath) {
+		var err error
+		if unitPath, err = FindUnitFile(serviceName); err != nil {
+			return model, err
+		}
+	}
+
+	config, unit, err := LoadServiceConfig(filepath.Dir(unitPath), serviceName)
+	if err != nil {
+		return model, err
+	}
+
+	base, effective, err := LoadDropInConfig(serviceName)
+	if err != nil {
+		return model, err
+	}
+
+	model.Unit = unit
+	model.OriginalContent = unit.String()
+	model.BaseConfig = base
+	model.DropInMode = filepath.Dir(unitPath) != unitDir
+	model.Options[2].Selected = model.DropInMode
+
+	// Для drop-in показываем итоговые значения с учетом уже записанного drop-in
+	model.Config = config
+	if model.DropInMode {
+		model.Config = effective
+	}
+	// Полная копия чужого unit-файла сохраняется в каталог администратора
+	model.Config.UnitFilePath = unitDir
+
+	// Секреты показываются в редакторе окружения, а не путем к их файлу
+	if model.Config, err = LoadSecrets(model.Config); err != nil {
+		return model, err
+	}
+
+	return model, nil
+}
+
+// Обработка события ввода юзера
+func HandleUserNameInput(model InstallModel, input string) (InstallModel, error) {
+	if err := IsValidUserName(input); err != nil {
+		model.ErrorMsg = err.Error()
+		return model, nil
+	}
+
+	model.Config.UserName = inputOrCurrent(input, model.Config.UserName)
+	model.State = StateWorkingDirectory
+	model.Message = "Введите рабочую директорию сервиса (по умолчанию: текущая директория):"
+	if model.Config.Instances > 0 {
+		model.Message = "Введите рабочую директорию сервиса, %i - имя экземпляра (по умолчанию: текущая директория):"
+	}
+	model.Input.SetValue("")
+	model.Input.Placeholder = model.Config.WorkingDirectory
+
+	return model, nil
+}
+
+// Обработка события ввода рабочей директории
+func HandleWorkingDirectoryInput(model InstallModel, input string) (InstallModel, error) {
+	if input != "" {
+		if err := IsValidPath(input); err != nil {
+			model.ErrorMsg = err.Error()
+			return model, nil
+		}
+		model.Config.WorkingDirectory = input
+	}
+	// Если ввод пустой, оставляем текущую директорию по умолчанию
+
+	// Переход к вводу команды запуска
+	model.State = StateExecStart
+	model.Message = "Введите команду ExecStart (по умолчанию: текущий исполняемый файл):"
+	if model.Config.Instances > 0 {
+		model.Message = "Введите команду ExecStart, %i - имя экземпляра, %I - без экранирования (по умолчанию: текущий исполняемый файл):"
+	}
+	model.Input.SetValue("")
+	model.Input.Placeholder = model.Config.ExecStart
+
+	return model, nil
+}
+
+// Обработка события ввода команды запуска
+func HandleExecStartInput(model InstallModel, input string) (InstallModel, error) {
+	if input != "" {
+		model.Config.ExecStart = input
+	}
+	// Если ввод пустой, оставляем значение по умолчанию
+
+	// Остальные команды жизненного цикла настраиваются по желанию
+	model.State = StateExecHooks
+	model.Message = fmt.Sprintf("Настроить команды ExecStartPre, ExecStartPost, ExecReload, ExecStop, ExecStopPost? Сейчас: %s (y/n):", hooksSummary(model.Config))
+	model.Input.SetValue("")
+	model.Input.Placeholder = "n"
+
+	return model, nil
+}
+
+// Краткое описание заданных команд жизненного цикла
+func hooksSummary(config ServiceConfig) string {
+	var parts []string
+	for _, hook := range execHooks(config) {
+		if len(hook.Commands) > 0 {
+			parts = append(parts, fmt.Sprintf("%s: %d", hook.Key, len(hook.Commands)))
+		}
+	}
+	if len(parts) == 0 {
+		return "не заданы"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Обработка ответа на вопрос о командах жизненного цикла
+func HandleExecHooksInput(model InstallModel, input string) (InstallModel, error) {
+	if len(input) == 0 || (input[0] != 'y' && input[0] != 'Y') {
+		return toServicePolicy(model), nil
+	}
+	return toExecHook(model, StateExecStartPre), nil
+}
+
+// Команды, которые редактируются в состоянии мастера
+func execHookField(config *ServiceConfig, state int) (string, *[]string) {
+	switch state {
+	case StateExecStartPre:
+		return "ExecStartPre", &config.ExecStartPre
+	case StateExecStartPost:
+		return "ExecStartPost", &config.ExecStartPost
+	case StateExecReload:
+		return "ExecReload", &config.ExecReload
+	case StateExecStop:
+		return "ExecStop", &config.ExecStop
+	default:
+		return "ExecStopPost", &config.ExecStopPost
+	}
+}
+
+// Переход к редактору команд одного типа
+func toExecHook(model InstallModel, state int) InstallModel {
+	key, _ := execHookField(&model.Config, state)
+	model.State = state
+	model.Message = fmt.Sprintf("Команды %s: ввод добавляет команду (префиксы -, +, !), «-» - удалить все, пустой Enter - продолжить:", key)
+	model.Input.SetValue("")
+	model.Input.Placeholder = ""
+
+	return model
+}
+
+// Обработка ввода в редакторе команд. Каждая команда добавляется в конец
+// списка, пустой ввод переходит к следующему типу команд.
+func HandleExecHookInput(model InstallModel, input string) (InstallModel, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		if model.State == StateExecStopPost {
+			return toServicePolicy(model), nil
+		}
+		return toExecHook(model, model.State+1), nil
+	}
+
+	_, commands := execHookField(&model.Config, model.State)
+	if input == "-" {
+		*commands = nil
+	} else {
+		if err := ValidateExecCommand(input); err != nil {
+			model.ErrorMsg = err.Error()
+			return model, nil
+		}
+		// Список может разделять память с исходной конфигурацией
+		*commands = append(slices.Clip(*commands), input)
+	}
+	model.Input.SetValue("")
+
+	return model, nil
+}
+
+// Переход к вопросу о типе сервиса и перезапуске
+func toServicePolicy(model InstallModel) InstallModel {
+	model.State = StateServicePolicy
+	model.Message = fmt.Sprintf("Настроить тип сервиса, перезапуск и таймауты? Сейчас: %s (y/n):", policySummary(model.Config))
+	model.Input.SetValue("")
+	model.Input.Placeholder = "n"
+
+	return model
+}
+
+// Краткое описание типа и политики перезапуска сервиса
+func policySummary(config ServiceConfig) string {
+	summary := "Type=" + cmp.Or(effectiveType(config), "simple")
+	summary += ", Restart=" + cmp.Or(effectiveRestart(config), "no")
+	if restartSec := effectiveRestartSec(config); restartSec != "" {
+		summary += ", RestartSec=" + restartSec
+	}
+	return summary
+}
+
+// Переход к редактору переменных окружения
+func toEnvironment(model InstallModel) InstallModel {
+	model.State = StateEnvironment
+	model.Message = "Переменные окружения: KEY=value - добавить, !KEY=value - секрет в файл 0600, -KEY - удалить, пустой Enter - продолжить:"
+	model.Input.SetValue("")
+	model.Input.Placeholder = "KEY=value"
+
+	return model
+}
+
+// Обработка события ввода в редакторе переменных окружения. Каждое
+// присваивание добавляет или удаляет одну переменную, пустой ввод
+// завершает редактирование.
+func HandleEnvironmentInput(model InstallModel, input string) (InstallModel, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		model.State = StateEnvironmentFiles
+		model.Message = "Введите пути EnvironmentFile через пробел, «-» перед путем - файл может отсутствовать (опционально):"
+		model.Input.SetValue("")
+		model.Input.Placeholder = strings.Join(model.Config.EnvironmentFiles, " ")
+		return model, nil
+	}
+
+	// Изменяем копии, чтобы не затронуть исходную конфигурацию
+	env := maps.Clone(model.Config.Environment)
+	secrets := maps.Clone(model.Config.Secrets)
+
+	if name, ok := strings.CutPrefix(input, "-"); ok {
+		_, inEnv := env[name]
+		_, inSecrets := secrets[name]
+		if !inEnv && !inSecrets {
+			model.ErrorMsg = fmt.Sprintf("Переменная %s не задана", name)
+			return model, nil
+		}
+		delete(env, name)
+		delete(secrets, name)
+	} else {
+		assignment, secret := strings.CutPrefix(input, "!")
+		name, value, err := ParseEnvAssignment(assignment)
+		if err != nil {
+			model.ErrorMsg = err.Error()
+			return model, nil
+		}
+
+		// Переменная хранится либо в unit-файле, либо в файле секретов
+		if secret {
+			delete(env, name)
+			secrets = setEnv(secrets, name, value)
+		} else {
+			delete(secrets, name)
+			env = setEnv(env, name, value)
+		}
+	}
+
+	model.Config.Environment = emptyToNil(env)
+	model.Config.Secrets = emptyToNil(secrets)
+	model.Input.SetValue("")
+
+	return model, nil
+}
+
+// Задать переменную, создав словарь при необходимости
+func setEnv(env map[string]string, name, value string) map[string]string {
+	if env == nil {
+		env = make(map[string]string)
+	}
+	env[name] = value
+	return env
+}
+
+// Пустой словарь заменяется на nil, как после разбора unit-файла
+func emptyToNil(env map[string]string) map[string]string {
+	if len(env) == 0 {
+		return nil
+	}
+	return env
+}
+
+// Обработка события ввода путей EnvironmentFile
+func HandleEnvironmentFilesInput(model InstallModel, input string) (InstallModel, error) {
+	files := strings.Fields(inputOrCurrent(input, strings.Join(model.Config.EnvironmentFiles, " ")))
+	for _, file := range files {
+		if err := ValidateEnvironmentFile(file); err != nil {
+			model.ErrorMsg = err.Error()
+			return model, nil
+		}
+	}
+
+	model.Config.EnvironmentFiles = files
+
+	return toDependencies(model), nil
+}
+
+// Переход к вопросу о зависимостях и порядке запуска
+func toDependencies(model InstallModel) InstallModel {
+	model.State = StateDependencies
+	model.Message = fmt.Sprintf("Настроить зависимости и порядок запуска (After, Requires, WantedBy и др.)? Сейчас: %s (y/n):", dependenciesSummary(model.Config))
+	model.Input.SetValue("")
+	model.Input.Placeholder = "n"
+
+	return model
+}
+
+// Краткое описание зависимостей с учетом значений по умолчанию
+func dependenciesSummary(config ServiceConfig) string {
+	var parts []string
+	for _, dep := range append(unitDependencies(config, "Unit"), unitDependencies(config, "Install")...) {
+		parts = append(parts, dep.Key+"="+strings.Join(dep.Units, " "))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Обработка ответа на вопрос о зависимостях. Имена unit-файлов загружаются
+// один раз для автодополнения и проверки.
+func HandleDependenciesInput(ctx context.Context, model InstallModel, input string) (InstallModel, error) {
+	if len(input) == 0 || (input[0] != 'y' && input[0] != 'Y') {
+		return toStandardOutput(model), nil
+	}
+
+	// Без списка unit-файлов мастер работает без автодополнения
+	if names, err := model.backend.ListUnitFiles(ctx); err == nil {
+		model.UnitNames = names
+	}
+
+	return toDependency(model, StateAfter), nil
+}
+
+// Описания директив зависимостей для подсказки мастера
+var dependencyHints = map[int]string{
+	StateAfter:      "запускать после",
+	StateBefore:     "запускать до",
+	StateWants:      "желательно запустить вместе с сервисом",
+	StateRequires:   "обязательно запустить вместе с сервисом",
+	StateBindsTo:    "остановить сервис, если остановлены",
+	StatePartOf:     "останавливать и перезапускать вместе с",
+	StateConflicts:  "не может работать одновременно с",
+	StateWantedBy:   "включать в цели при enable",
+	StateRequiredBy: "обязателен для целей при enable",
+}
+
+// Директива зависимостей, которая редактируется в состоянии мастера
+func dependencyKey(state int) string {
+	return []string{"After", "Before", "Wants", "Requires", "BindsTo", "PartOf", "Conflicts", "WantedBy", "RequiredBy"}[state-StateAfter]
+}
+
+// Переход к вводу одной директивы зависимостей
+func toDependency(model InstallModel, state int) InstallModel {
+	key := dependencyKey(state)
+	current := *dependencyField(&model.Config, key)
+	switch key {
+	case "After":
+		current = effectiveAfter(model.Config)
+	case "WantedBy":
+		current = effectiveWantedBy(model.Config)
+	}
+
+	model.State = state
+	model.Message = fmt.Sprintf("%s (%s): unit через пробел, Tab - дополнить, ↑/↓ - варианты:", key, dependencyHints[state])
+	model.Input.SetValue("")
+	model.Input.Placeholder = strings.Join(current, " ")
+	model.Input.ShowSuggestions = true
+	model.Input.SetSuggestions(nil)
+
+	return model
+}
+
+// Обработка ввода директивы зависимостей. Пустой ввод оставляет текущее
+// значение, «-» очищает его; unit, которых нет в системе, не мешают
+// продолжить, но о них выводится предупреждение.
+func HandleDependencyInput(model InstallModel, input string) (InstallModel, error) {
+	key := dependencyKey(model.State)
+	field := dependencyField(&model.Config, key)
+
+	if input != "" {
+		units, err := ParseUnitList(inputOrCurrent(input, ""))
+		if err != nil {
+			model.ErrorMsg = err.Error()
+			return model, nil
+		}
+
+		config := model.Config
+		*dependencyField(&config, key) = units
+		if err := ValidateDependencies(config); err != nil {
+			model.ErrorMsg = err.Error()
+			return model, nil
+		}
+		*field = units
+
+		if missing := missingUnits(units, model.UnitNames); model.UnitNames != nil && len(missing) > 0 {
+			model.WarningMsg = fmt.Sprintf("%s: unit-файлы не найдены: %s", key, strings.Join(missing, ", "))
+		}
+	}
+
+	model.Input.ShowSuggestions = false
+	if model.State == StateRequiredBy {
+		return toStandardOutput(model), nil
+	}
+	return toDependency(model, model.State+1), nil
+}
+
+// Переход к вводу StandardOutput
+func toStandardOutput(model InstallModel) InstallModel {
+	model.State = StateStandardOutput
+	model.Message = "Введите значение для StandardOutput (опционально):"
+	model.Input.SetValue("")
+	model.Input.Placeholder = model.Config.StandardOutput
+
+	return model
+}
+
+// Обработка события ответа на вопрос о настройке типа и перезапуска
+func HandleServicePolicyInput(model InstallModel, input string) (InstallModel, error) {
+	if len(input) == 0 || (input[0] != 'y' && input[0] != 'Y') {
+		return toEnvironment(model), nil
+	}
+
+	model.State = StateServiceType
+	model.Message = fmt.Sprintf("Введите Type (%s):", strings.Join(ServiceTypes, ", "))
+	model.Input.SetValue("")
+	model.Input.Placeholder = cmp.Or(effectiveType(model.Config), "simple")
+
+	return model, nil
+}
+
+// Проверить политику сервиса с учетом версии systemd на машине
+func validatePolicyInput(config ServiceConfig) error {
+	return ValidateServicePolicy(config, LocalSystemdVersion())
+}
+
+// Обработка события ввода Type
+func HandleServiceTypeInput(model InstallModel, input string) (InstallModel, error) {
+	config := model.Config
+	config.Type = inputOrCurrent(input, config.Type)
+	// Тип по умолчанию не записываем, чтобы unit-файл не менялся
+	if config.Type == "simple" && model.Config.Type == "" && effectiveType(model.Config) == "" {
+		config.Type = ""
+	}
+	if config.Type != "" && !slices.Contains(ServiceTypes, config.Type) {
+		model.ErrorMsg = fmt.Sprintf("Type: допустимые значения: %s", strings.Join(ServiceTypes, ", "))
+		return model, nil
+	}
+
+	model.Config = config
+	model.State = StateRestart
+	model.Message = fmt.Sprintf("Введите Restart (%s):", strings.Join(RestartPolicies, ", "))
+	model.Input.SetValue("")
+	model.Input.Placeholder = cmp.Or(effectiveRestart(model.Config), "no")
+
+	return model, nil
+}
+
+// Обработка события ввода Restart. Несовместимые сочетания, например
+// Type=oneshot с Restart=always, отклоняются сразу.
+func HandleRestartInput(model InstallModel, input string) (InstallModel, error) {
+	config := model.Config
+	config.Restart = inputOrCurrent(input, config.Restart)
+	if config.Restart == effectiveRestart(model.Config) && model.Config.Restart == "" {
+		config.Restart = ""
+	}
+	// RestartSec без перезапуска не нужен
+	if !restartEnabled(effectiveRestart(config)) {
+		config.RestartSec = ""
+	}
+	if err := validatePolicyInput(config); err != nil {
+		model.ErrorMsg = err.Error()
+		return model, nil
+	}
+
+	model.Config = config
+	if !restartEnabled(effectiveRestart(config)) {
+		return toStartLimitInterval(model), nil
+	}
+
+	model.State = StateRestartSec
+	model.Message = "Введите паузу перед перезапуском RestartSec, например 10 или 500ms:"
+	model.Input.SetValue("")
+	model.Input.Placeholder = effectiveRestartSec(model.Config)
+
+	return model, nil
+}
+
+// Обработка события ввода RestartSec
+func HandleRestartSecInput(model InstallModel, input string) (InstallModel, error) {
+	value := inputOrCurrent(input, model.Config.RestartSec)
+	if value == DefaultRestartSec && model.Config.RestartSec == "" {
+		value = ""
+	}
+	if err := validateTimespanInput("RestartSec", value); err != nil {
+		model.ErrorMsg = err.Error()
+		return model, nil
+	}
+
+	model.Config.RestartSec = value
+
+	return toStartLimitInterval(model), nil
+}
+
+// Переход к вводу StartLimitIntervalSec
+func toStartLimitInterval(model InstallModel) InstallModel {
+	model.State = StateStartLimitInterval
+	model.Message = "Введите StartLimitIntervalSec - окно ограничения частоты запусков, например 5min (опционально):"
+	model.Input.SetValue("")
+	model.Input.Placeholder = model.Config.StartLimitIntervalSec
+
+	return model
+}
+
+// Проверить интервал, который может быть равен infinity
+func validateTimeoutInput(name, input string) error {
+	if input == "infinity" {
+		return nil
+	}
+	return validateTimespanInput(name, input)
+}
+
+// Обработка события ввода StartLimitIntervalSec
+func HandleStartLimitIntervalInput(model InstallModel, input string) (InstallModel, error) {
+	value := inputOrCurrent(input, model.Config.StartLimitIntervalSec)
+	if err := validateTimeoutInput("StartLimitIntervalSec", value); err != nil {
+		model.ErrorMsg = err.Error()
+		return model, nil
+	}
+
+	model.Config.StartLimitIntervalSec = value
+	model.State = StateStartLimitBurst
+	model.Message = "Введите StartLimitBurst - сколько запусков допускается в этом окне (0 - по умолчанию):"
+	model.Input.SetValue("")
+	model.Input.Placeholder = strconv.Itoa(model.Config.StartLimitBurst)
+
+	return model, nil
+}
+
+// Обработка события ввода StartLimitBurst
+func HandleStartLimitBurstInput(model InstallModel, input string) (InstallModel, error) {
+	val, err := ParseIntValue(inputOrCurrent(input, strconv.Itoa(model.Config.StartLimitBurst)), 0)
+	if err != nil {
+		model.ErrorMsg = err.Error()
+		return model, nil
+	}
+
+	model.Config.StartLimitBurst = val
+	model.State = StateTimeoutStartSec
+	model.Message = "Введите TimeoutStartSec - время на запуск, например 90 или infinity (опционально):"
+	model.Input.SetValue("")
+	model.Input.Placeholder = model.Config.TimeoutStartSec
+
+	return model, nil
+}
+
+// Обработка события ввода TimeoutStartSec
+func HandleTimeoutStartSecInput(model InstallModel, input string) (InstallModel, error) {
+	value := inputOrCurrent(input, model.Config.TimeoutStartSec)
+	if err := validateTimeoutInput("TimeoutStartSec", value); err != nil {
+		model.ErrorMsg = err.Error()
+		return model, nil
+	}
+
+	model.Config.TimeoutStartSec = value
+	model.State = StateTimeoutStopSec
+	model.Message = "Введите TimeoutStopSec - время на остановку до SIGKILL, например 30s (опционально):"
+	model.Input.SetValue("")
+	model.Input.Placeholder = model.Config.TimeoutStopSec
+
+	return model, nil
+}
+
+// Обработка события ввода TimeoutStopSec
+func HandleTimeoutStopSecInput(model InstallModel, input string) (InstallModel, error) {
+	value := inputOrCurrent(input, model.Config.TimeoutStopSec)
+	if err := validateTimeoutInput("TimeoutStopSec", value); err != nil {
+		model.ErrorMsg = err.Error()
+		return model, nil
+	}
+
+	model.Config.TimeoutStopSec = value
+	model.State = StateKillMode
+	model.Message = fmt.Sprintf("Введите KillMode (%s, опционально):", strings.Join(KillModes, ", "))
+	model.Input.SetValue("")
+	model.Input.Placeholder = model.Config.KillMode
+
+	return model, nil
+}
+
+// Обработка события ввода KillMode
+func HandleKillModeInput(model InstallModel, input string) (InstallModel, error) {
+	value := inputOrCurrent(input, model.Config.KillMode)
+	if value != "" && !slices.Contains(KillModes, value) {
+		model.ErrorMsg = fmt.Sprintf("KillMode: допустимые значения: %s", strings.Join(KillModes, ", "))
+		return model, nil
+	}
+
+	model.Config.KillMode = value
+	model.State = StateKillSignal
+	model.Message = "Введите KillSignal - сигнал остановки, например SIGTERM или SIGINT (опционально):"
+	model.Input.SetValue("")
+	model.Input.Placeholder = model.Config.KillSignal
+
+	return model, nil
+}
+
+// Обработка события ввода KillSignal. RemainAfterExit спрашиваем только для
+// oneshot.
+func HandleKillSignalInput(model InstallModel, input string) (InstallModel, error) {
+	value := inputOrCurrent(input, model.Config.KillSignal)
+	if err := ValidateKillSignal(value); err != nil {
+		model.ErrorMsg = "KillSignal: " + err.Error()
+		return model, nil
+	}
+
+	model.Config.KillSignal = value
+	if effectiveType(model.Config) != "oneshot" {
+		model.Config.RemainAfterExit = false
+		return toEnvironment(model), nil
+	}
+
+	model.State = StateRemainAfterExit
+	model.Message = "Считать сервис активным после завершения команды (RemainAfterExit)? (y/n):"
+	model.Input.SetValue("")
+	model.Input.Placeholder = "n"
+	if model.Config.RemainAfterExit {
+		model.Input.Placeholder = "y"
+	}
+
+	return model, nil
+}
+
+// Обработка события ответа на вопрос о RemainAfterExit
+func HandleRemainAfterExitInput(model InstallModel, input string) (InstallModel, error) {
+	if input != "" {
+		model.Config.RemainAfterExit = input[0] == 'y' || input[0] == 'Y'
+	}
+
+	return toEnvironment(model), nil
+}
+
+// Обработка события ввода StandardOutput
+func HandleStandardOutputInput(model InstallModel, input string) (InstallModel, error) {
+	model.Config.StandardOutput = inputOrCurrent(input, model.Config.StandardOutput)
+
+	// Переход к вводу StandardError
+	model.State = StateStandardError
+	model.Message = "Введите значение для StandardError (опционально):"
+	model.Input.SetValue("")
+	model.Input.Placeholder = model.Config.StandardError
+
+	return model, nil
+}
+
+// Обработка события ввода StandardError
+func HandleStandardErrorInput(model InstallModel, input string) (InstallModel, error) {
+	model.Config.StandardError = inputOrCurrent(input, model.Config.StandardError)
+
+	// Переход к вводу SyslogIdentifier
+	model.State = StateSyslogIdentifier
+	model.Message = "Введите SyslogIdentifier (опционально):"
+	model.Input.SetValue("")
+	model.Input.Placeholder = model.Config.SyslogIdentifier
+
+	return model, nil
+}
+
+// Обработка события ввода SyslogIdentifier
+func HandleSyslogIdentifierInput(model InstallModel, input string) (InstallModel, error) {
+	model.Config.SyslogIdentifier = inputOrCurrent(input, model.Config.SyslogIdentifier)
+
+	// Переход к вводу MemoryHigh
+	model.State = StateMemoryHigh
+	model.Message = "Введите ограничение MemoryHigh (512M, 1.5G, 50% или число МБ; 0 - не использовать):"
+	model.Input.SetValue("")
+	model.Input.Placeholder = string(model.Config.MemoryHigh)
+
+	return model, nil
+}
+
+// Ввод размера памяти: пустой ввод оставляет текущее значение, 0 и «-»
+// убирают ограничение
+func memoryInput(input string, current MemorySize) MemorySize {
+	value := strings.TrimSpace(inputOrCurrent(input, string(current)))
+	if value == "0" {
+		return ""
+	}
+	return MemorySize(value)
+}
+
+// Обработка события ввода MemoryHigh
+func HandleMemoryHighInput(model InstallModel, input string) (InstallModel, error) {
+	size := memoryInput(input, model.Config.MemoryHigh)
+	if _, err := validateMemorySize("MemoryHigh", size, LocalHostResources()); err != nil {
+		model.ErrorMsg = err.Error()
+		return model, nil
+	}
+
+	model.Config.MemoryHigh = size
+
+	// Переход к вводу MemoryMax
+	model.State = StateMemoryMax
+	model.Message = "Введите ограничение MemoryMax (512M, 1.5G, 50% или число МБ; 0 - не использовать):"
+	model.Input.SetValue("")
+	model.Input.Placeholder = string(model.Config.MemoryMax)
+
+	return model, nil
+}
+
+// Обработка события ввода MemoryMax
+func HandleMemoryMaxInput(model InstallModel, input string) (InstallModel, error) {
+	config := model.Config
+	config.MemoryMax = memoryInput(input, model.Config.MemoryMax)
+
+	// Проверка логики ограничений памяти
+	if err := validateMemoryLimits(config, LocalHostResources()); err != nil {
+		model.ErrorMsg = "Ошибка: " + err.Error()
+		return model, nil
+	}
+
+	model.Config = config
+
+	// Переход к вводу CPU quota limit
+	model.State = StateCPUQuota
+	model.Message = "Введите максимально разрешенную нагрузку на ядро в процентах если 0 то нет ограничений:"
+	model.Input.SetValue("")
+	model.Input.Placeholder = strconv.FormatFloat(model.Config.CPUQuota, 'f', -1, 64)
+
+	return model, nil
+}
+
+// Обработка события ввода ProcessUsageLimit
+func HandleCPULimitQuota(model InstallModel, input string) (InstallModel, error) {
+	val, err := ParseCPUQuota(inputOrCurrent(input, strconv.FormatFloat(model.Config.CPUQuota, 'f', -1, 64)))
+	if err != nil {
+		model.ErrorMsg = err.Error()
+		return model, nil
+	}
+
+	model.Config.CPUQuota = val
+
+	host := LocalHostResources()
+	model.State = StateAllowedCPUs
+	model.Message = fmt.Sprintf("Введите разрешенные к использованию ядра, например 0-1 или 0,2 (на машине ядер: %d, пусто - все):", host.CPUs)
+	model.Input.SetValue("")
+	model.Input.Placeholder = model.Config.AllowedCPUs
+
+	return model, nil
+}
+
+func HandleCPUCoresUsage(model InstallModel, input string) (InstallModel, error) {
+	cpus := strings.TrimSpace(inputOrCurrent(input, model.Config.AllowedCPUs))
+	if err := validateIndexList("AllowedCPUs", cpus, LocalHostResources().CPUs, "ядра"); err != nil {
+		model.ErrorMsg = err.Error()
+		return model, nil
+	}
+	model.Config.AllowedCPUs = cpus
+
+	model.State = StateResources
+	model.Message = "Настроить дополнительные ограничения (swap, задачи, вес CPU и IO, Limit*, Nice)? (y/N):"
+	model.Input.SetValue("")
+	model.Input.Placeholder = ""
+
+	return model, nil
+}
+
+// Обработка ответа на вопрос о дополнительных ограничениях ресурсов
+func HandleResourcesInput(model InstallModel, input string) (InstallModel, error) {
+	if len(input) == 0 || (input[0] != 'y' && input[0] != 'Y') {
+		return toHardening(model), nil
+	}
+	return toResource(model, StateMemorySwapMax), nil
+}
+
+// Дополнительное ограничение ресурсов, которое вводится в мастере
+type resourceInput struct {
+	Key  string
+	Hint string
+	// Текущее значение в виде строки ввода
+	Get func(config ServiceConfig) string
+	// Записать введенное значение; пустое значение убирает ограничение
+	Set func(config *ServiceConfig, value string) error
+}
+
+// Ввод целого числа; пустое значение - 0
+func intInput(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.New("введено не число")
+	}
+	return number, nil
+}
+
+// Ввод списка через запятую
+func listInput(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Дополнительные ограничения ресурсов по состояниям мастера
+var resourceInputs = map[int]resourceInput{
+	StateMemorySwapMax: {
+		Key:  "MemorySwapMax",
+		Hint: "размер swap, например 256M или 0 - без swap",
+		Get:  func(config ServiceConfig) string { return string(config.MemorySwapMax) },
+		Set: func(config *ServiceConfig, value string) error {
+			// Для swap 0 - запрет, а не отсутствие ограничения
+			if value == "0" {
+				value = "0M"
+			}
+			config.MemorySwapMax = MemorySize(value)
+			return nil
+		},
+	},
+	StateCPUWeight: {
+		Key:  "CPUWeight",
+		Hint: "доля CPU относительно других сервисов, от 1 до 10000, по умолчанию 100",
+		Get:  func(config ServiceConfig) string { return formatPositive(config.CPUWeight) },
+		Set: func(config *ServiceConfig, value string) (err error) {
+			config.CPUWeight, err = intInput(value)
+			return err
+		},
+	},
+	StateAllowedMemoryNodes: {
+		Key:  "AllowedMemoryNodes",
+		Hint: "узлы NUMA, например 0-1",
+		Get:  func(config ServiceConfig) string { return config.AllowedMemoryNodes },
+		Set: func(config *ServiceConfig, value string) error {
+			config.AllowedMemoryNodes = value
+			return nil
+		},
+	},
+	StateTasksMax: {
+		Key:  "TasksMax",
+		Hint: "число процессов и потоков, процент или infinity",
+		Get:  func(config ServiceConfig) string { return config.TasksMax },
+		Set: func(config *ServiceConfig, value string) error {
+			config.TasksMax = value
+			return nil
+		},
+	},
+	StateIOWeight: {
+		Key:  "IOWeight",
+		Hint: "доля ввода-вывода, от 1 до 10000, по умолчанию 100",
+		Get:  func(config ServiceConfig) string { return formatPositive(config.IOWeight) },
+		Set: func(config *ServiceConfig, value string) (err error) {
+			config.IOWeight, err = intInput(value)
+			return err
+		},
+	},
+	StateIODeviceReadBandwidthMax: {
+		Key:  "IODeviceReadBandwidthMax",
+		Hint: "чтение: «устройство скорость» через запятую, например /dev/sda 10M",
+		Get:  func(config ServiceConfig) string { return strings.Join(config.IODeviceReadBandwidthMax, ", ") },
+		Set: func(config *ServiceConfig, value string) error {
+			config.IODeviceReadBandwidthMax = listInput(value)
+			return nil
+		},
+	},
+	StateIODeviceWriteBandwidthMax: {
+		Key:  "IODeviceWriteBandwidthMax",
+		Hint: "запись: «устройство скорость» через запятую, например /dev/sda 10M",
+		Get:  func(config ServiceConfig) string { return strings.Join(config.IODeviceWriteBandwidthMax, ", ") },
+		Set: func(config *ServiceConfig, value string) error {
+			config.IODeviceWriteBandwidthMax = listInput(value)
+			return nil
+		},
+	},
+	StateLimitNOFILE: {
+		Key:  "LimitNOFILE",
+		Hint: "открытые файлы: число, infinity или soft:hard",
+		Get:  func(config ServiceConfig) string { return config.LimitNOFILE },
+		Set: func(config *ServiceConfig, value string) error {
+			config.LimitNOFILE = value
+			return nil
+		},
+	},
+	StateLimitNPROC: {
+		Key:  "LimitNPROC",
+		Hint: "процессы пользователя: число, infinity или soft:hard",
+		Get:  func(config ServiceConfig) string { return config.LimitNPROC },
+		Set: func(config *ServiceConfig, value string) error {
+			config.LimitNPROC = value
+			return nil
+		},
+	},
+	StateLimitCORE: {
+		Key:  "LimitCORE",
+		Hint: "размер core dump, например 0 или infinity",
+		Get:  func(config ServiceConfig) string { return config.LimitCORE },
+		Set: func(config *ServiceConfig, value string) error {
+			config.LimitCORE = value
+			return nil
+		},
+	},
+	StateNice: {
+		Key:  "Nice",
+		Hint: "приоритет от -20 (высокий) до 19 (низкий)",
+		Get:  func(config ServiceConfig) string { return formatNice(config.Nice) },
+		Set: func(config *ServiceConfig, value string) error {
+			if value == "" {
+				config.Nice = nil
+				return nil
+			}
+			nice, err := intInput(value)
+			config.Nice = &nice
+			return err
+		},
+	},
+}
+
+// Переход к вводу одного ограничения ресурсов
+func toResource(model InstallModel, state int) InstallModel {
+	resource := resourceInputs[state]
+	model.State = state
+	model.Message = fmt.Sprintf("%s (%s), «-» - убрать, пустой Enter - оставить:", resource.Key, resource.Hint)
+	model.Input.SetValue("")
+	model.Input.Placeholder = resource.Get(model.Config)
+
+	return model
+}
+
+// Обработка ввода ограничения ресурсов. Значение сразу проверяется с
+// учетом памяти, ядер и узлов NUMA машины.
+func HandleResourceInput(model InstallModel, input string) (InstallModel, error) {
+	if input = strings.TrimSpace(input); input != "" {
+		config := model.Config
+		if err := resourceInputs[model.State].Set(&config, inputOrCurrent(input, "")); err != nil {
+			model.ErrorMsg = fmt.Sprintf("%s: %v", resourceInputs[model.State].Key, err)
+			return model, nil
+		}
+		if err := ValidateResources(config, LocalHostResources()); err != nil {
+			model.ErrorMsg = err.Error()
+			return model, nil
+		}
+		model.Config = config
+	}
+
+	if model.State == StateNice {
+		return toHardening(model), nil
+	}
+	return toResource(model, model.State+1), nil
+}
+
+// Переход к выбору профиля защиты
+func toHardening(model InstallModel) InstallModel {
+	model.State = StateHardening
+	model.Message = "Выберите профиль защиты (none - без ограничений, basic - базовая изоляция, strict - песочница):"
+	model.Input.SetValue("")
+	model.Input.Placeholder = model.Config.Hardening
+	if model.Input.Placeholder == "" {
+		model.Input.Placeholder = "none"
+	}
+
+	return model
+}
+
+// Обработка события ввода профиля защиты
+func HandleHardeningInput(model InstallModel, input string) (InstallModel, error) {
+	profile := inputOrCurrent(strings.TrimSpace(input), model.Config.Hardening)
+	if err := ValidateHardening(profile); err != nil {
+		model.ErrorMsg = err.Error()
+		return model, nil
+	}
+	config := model.Config
+	config.Hardening = profile
+	if err := validateHardeningPaths(config); err != nil {
+		model.ErrorMsg = err.Error()
+		return model, nil
+	}
+	model.Config.Hardening = profile
+
+	// При редактировании файл уже существует, поэтому сразу переходим к опциям
+	if model.EditMode {
+		model.State = StateOptionsSelect
+		model.Message = "Выберите опции (пробел для переключения, Enter для подтверждения):"
+		model.Input.SetValue("")
+		return model, nil
+	}
+
+	// Для сервиса с таймером сначала настраиваем расписание
+	if model.Config.Timer != nil {
+		model.State = StateTimerOnCalendar
+		model.Message = "Введите OnCalendar, несколько выражений через «;» (например: Mon..Fri 09:00; daily):"
+		model.Input.SetValue("")
+		model.Input.Placeholder = strings.Join(model.Config.Timer.OnCalendar, "; ")
+		return model, nil
+	}
+
+	// Для сервиса с сокетом - адреса и параметры сокета
+	if model.Config.Socket != nil {
+		model.State = StateSocketListenStream
+		model.Message = "Введите ListenStream - TCP-порты, адреса или пути unix-сокетов через пробел (например: 8080 [::1]:9000 /run/app.sock):"
+		model.Input.SetValue("")
+		model.Input.Placeholder = strings.Join(model.Config.Socket.ListenStream, " ")
+		return model, nil
+	}
+
+	// Для сервиса с path-unit - отслеживаемые пути
+	if model.Config.Path != nil {
+		model.State = StatePathExists
+		model.Message = "Введите PathExists - пути, появление которых запускает сервис, через пробел (опционально):"
+		model.Input.SetValue("")
+		model.Input.Placeholder = strings.Join(model.Config.Path.PathExists, " ")
+		return model, nil
+	}
+
+	// Для шаблона - количество запускаемых экземпляров
+	if model.Config.Instances > 0 {
+		model.State = StateInstances
+		model.Message = fmt.Sprintf("Введите количество экземпляров %s@ (от 1 до %d):", model.Config.ServiceName, MaxTemplateInstances)
+		model.Input.SetValue("")
+		model.Input.Placeholder = strconv.Itoa(model.Config.Instances)
+		return model, nil
+	}
+
+	return toUnitLocation(model), nil
+}
+
+// Обработка события ввода количества экземпляров шаблона
+func HandleInstancesInput(model InstallModel, input string) (InstallModel, error) {
+	count, err := ParseIntValue(inputOrCurrent(input, strconv.Itoa(model.Config.Instances)), 1)
+	if err == nil && count == 0 {
+		err = errors.New("шаблону нужен хотя бы один экземпляр")
+	}
+	if err == nil {
+		err = ValidateInstanceCount(count)
+	}
+	if err != nil {
+		model.ErrorMsg = err.Error()
+		return model, nil
+	}
+
+	model.Config.Instances = count
+
+	return toUnitLocation(model), nil
+}
+
+// Переход к вводу пути unit-файла
+func toUnitLocation(model InstallModel) InstallModel {
+	model.State = StateUnitLocation
+	model.Message = "Введите путь для сохранения unit-файла (по умолчанию: /etc/systemd/system):"
+	model.Input.SetValue("")
+	model.Input.Placeholder = DefaultUnitDir
+
+	return model
+}
+
+// Обработка события ввода OnCalendar
+func HandleTimerOnCalendarInput(ctx context.Context, model InstallModel, input string) (InstallModel, error) {
+	exprs := ParseCalendarList(input)
+	for _, expr := range exprs {
+		if _, err := CalendarNextElapses(ctx, expr, time.Now(), 1); err != nil {
+			model.ErrorMsg = fmt.Sprintf("OnCalendar: %s", err)
+			return model, nil
+		}
+	}
+
+	model.Config.Timer.OnCalendar = exprs
+	model.State = StateTimerOnBootSec
+	model.Message = "Введите OnBootSec - запуск после загрузки, например 5min (опционально):"
+	model.Input.SetValue("")
+	model.Input.Placeholder = model.Config.Timer.OnBootSec
+
+	return model, nil
+}
+
+// Проверить интервал в формате systemd; пустое значение допустимо
+func validateTimespanInput(name, input string) error {
+	if input == "" {
+		return nil
 	}
+	if _, err := ParseTimespan(input); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
 
-	if err := IsValidServiceName(input); err != nil {
+// Обработка события ввода OnBootSec
+func HandleTimerOnBootSecInput(model InstallModel, input string) (InstallModel, error) {
+	if err := validateTimespanInput("OnBootSec", input); err != nil {
 		model.ErrorMsg = err.Error()
 		return model, nil
 	}
 
-	model.Config.ServiceName = input
-	model.State = StateUserName
-	model.Message = "Введите имя юзера (оционально):"
+	model.Config.Timer.OnBootSec = input
+	model.State = StateTimerOnUnitActiveSec
+	model.Message = "Введите OnUnitActiveSec - повтор после предыдущего запуска, например 1h (опционально):"
 	model.Input.SetValue("")
-	model.Input.Placeholder = model.Config.UserName
+	model.Input.Placeholder = model.Config.Timer.OnUnitActiveSec
 
 	return model, nil
 }
 
-// Обработка события ввода юзера
-func HandleUserNameInput(model InstallModel, input string) (InstallModel, error) {
-	if err := IsValidUserName(input); err != nil {
+// Обработка события ввода OnUnitActiveSec. Это последний триггер, поэтому
+// здесь проверяем, что задан хотя бы один.
+func HandleTimerOnUnitActiveSecInput(model InstallModel, input string) (InstallModel, error) {
+	if err := validateTimespanInput("OnUnitActiveSec", input); err != nil {
 		model.ErrorMsg = err.Error()
 		return model, nil
 	}
 
-	model.Config.UserName = input
-	model.State = StateWorkingDirectory
-	model.Message = "Введите рабочую директорию сервиса (по умолчанию: текущая директория):"
+	timer := model.Config.Timer
+	if len(timer.OnCalendar) == 0 && timer.OnBootSec == "" && input == "" {
+		model.ErrorMsg = "Не задано ни одного триггера: укажите OnCalendar, OnBootSec или OnUnitActiveSec"
+		model.State = StateTimerOnCalendar
+		model.Message = "Введите OnCalendar, несколько выражений через «;» (например: Mon..Fri 09:00; daily):"
+		model.Input.SetValue("")
+		return model, nil
+	}
+
+	timer.OnUnitActiveSec = input
+	model.State = StateTimerRandomizedDelay
+	model.Message = "Введите RandomizedDelaySec - случайная задержка запуска (опционально):"
 	model.Input.SetValue("")
-	model.Input.Placeholder = model.Config.WorkingDirectory
+	model.Input.Placeholder = timer.RandomizedDelaySec
 
 	return model, nil
 }
 
-// Обработка события ввода рабочей директории
-func HandleWorkingDirectoryInput(model InstallModel, input string) (InstallModel, error) {
-	if input != "" {
-		if err := IsValidPath(input); err != nil {
-			model.ErrorMsg = err.Error()
-			return model, nil
-		}
-		model.Config.WorkingDirectory = input
+// Обработка события ввода RandomizedDelaySec
+func HandleTimerRandomizedDelayInput(model InstallModel, input string) (InstallModel, error) {
+	if err := validateTimespanInput("RandomizedDelaySec", input); err != nil {
+		model.ErrorMsg = err.Error()
+		return model, nil
 	}
-	// Если ввод пустой, оставляем текущую директорию по умолчанию
 
-	// Переход к вводу команды запуска
-	model.State = StateExecStart
-	model.Message = "Введите команду ExecStart (по умолчанию: текущий исполняемый файл):"
+	model.Config.Timer.RandomizedDelaySec = input
+	model.State = StateTimerAccuracy
+	model.Message = "Введите AccuracySec - точность срабатывания, по умолчанию 1min (опционально):"
 	model.Input.SetValue("")
-	model.Input.Placeholder = model.Config.ExecStart
+	model.Input.Placeholder = model.Config.Timer.AccuracySec
 
 	return model, nil
 }
 
-// Обработка события ввода команды запуска
-func HandleExecStartInput(model InstallModel, input string) (InstallModel, error) {
-	if input != "" {
-		model.Config.ExecStart = input
+// Обработка события ввода AccuracySec
+func HandleTimerAccuracyInput(model InstallModel, input string) (InstallModel, error) {
+	if err := validateTimespanInput("AccuracySec", input); err != nil {
+		model.ErrorMsg = err.Error()
+		return model, nil
 	}
-	// Если ввод пустой, оставляем значение по умолчанию
 
-	// Переход к вводу StandardOutput
-	model.State = StateStandardOutput
-	model.Message = "Введите значение для StandardOutput (опционально):"
+	model.Config.Timer.AccuracySec = input
+
+	// Persistent имеет смысл только для календарного расписания
+	if len(model.Config.Timer.OnCalendar) == 0 {
+		model.Config.Timer.Persistent = false
+		return toUnitLocation(model), nil
+	}
+
+	model.State = StateTimerPersistent
+	model.Message = "Запускать пропущенные срабатывания после включения машины (Persistent)? (y/n):"
 	model.Input.SetValue("")
-	model.Input.Placeholder = ""
+	model.Input.Placeholder = "n"
 
 	return model, nil
 }
 
-// Обработка события ввода StandardOutput
-func HandleStandardOutputInput(model InstallModel, input string) (InstallModel, error) {
-	model.Config.StandardOutput = input
+// Обработка события ответа на вопрос о Persistent
+func HandleTimerPersistentInput(model InstallModel, input string) (InstallModel, error) {
+	model.Config.Timer.Persistent = len(input) > 0 && (input[0] == 'y' || input[0] == 'Y')
 
-	// Переход к вводу StandardError
-	model.State = StateStandardError
-	model.Message = "Введите значение для StandardError (опционально):"
+	return toUnitLocation(model), nil
+}
+
+// Разобрать и проверить список адресов сокета
+func parseListenInput(name, input string) ([]string, error) {
+	addresses := ParseListenList(input)
+	for _, address := range addresses {
+		if err := ValidateListenAddress(address); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return addresses, nil
+}
+
+// Обработка события ввода ListenStream
+func HandleSocketListenStreamInput(model InstallModel, input string) (InstallModel, error) {
+	addresses, err := parseListenInput("ListenStream", input)
+	if err != nil {
+		model.ErrorMsg = err.Error()
+		return model, nil
+	}
+
+	model.Config.Socket.ListenStream = addresses
+	model.State = StateSocketListenDatagram
+	model.Message = "Введите ListenDatagram - UDP-порты или адреса через пробел (опционально):"
 	model.Input.SetValue("")
-	model.Input.Placeholder = ""
+	model.Input.Placeholder = strings.Join(model.Config.Socket.ListenDatagram, " ")
 
 	return model, nil
 }
 
-// Обработка события ввода StandardError
-func HandleStandardErrorInput(model InstallModel, input string) (InstallModel, error) {
-	model.Config.StandardError = input
+// Обработка события ввода ListenDatagram. Это последний список адресов,
+// поэтому здесь проверяем, что задан хотя бы один.
+func HandleSocketListenDatagramInput(model InstallModel, input string) (InstallModel, error) {
+	addresses, err := parseListenInput("ListenDatagram", input)
+	if err != nil {
+		model.ErrorMsg = err.Error()
+		return model, nil
+	}
 
-	// Переход к вводу SyslogIdentifier
-	model.State = StateSyslogIdentifier
-	model.Message = "Введите SyslogIdentifier (опционально):"
+	socket := model.Config.Socket
+	if len(socket.ListenStream) == 0 && len(addresses) == 0 {
+		model.ErrorMsg = "Не задано ни одного адреса: укажите ListenStream или ListenDatagram"
+		model.State = StateSocketListenStream
+		model.Message = "Введите ListenStream - TCP-порты, адреса или пути unix-сокетов через пробел (например: 8080 [::1]:9000 /run/app.sock):"
+		model.Input.SetValue("")
+		return model, nil
+	}
+
+	socket.ListenDatagram = addresses
+	model.State = StateSocketAccept
+	model.Message = "Запускать отдельный экземпляр сервиса на каждое подключение (Accept)? (y/n):"
 	model.Input.SetValue("")
-	model.Input.Placeholder = ""
+	model.Input.Placeholder = "n"
 
 	return model, nil
 }
 
-// Обработка события ввода SyslogIdentifier
-func HandleSyslogIdentifierInput(model InstallModel, input string) (InstallModel, error) {
-	model.Config.SyslogIdentifier = input
+// Обработка события ответа на вопрос об Accept
+func HandleSocketAcceptInput(model InstallModel, input string) (InstallModel, error) {
+	model.Config.Socket.Accept = len(input) > 0 && (input[0] == 'y' || input[0] == 'Y')
 
-	// Переход к вводу MemoryHigh
-	model.State = StateMemoryHigh
-	model.Message = "Введите ограничение MemoryHigh в МБ (0 - не использовать):"
+	model.State = StateSocketUser
+	model.Message = "Введите владельца файла сокета SocketUser (опционально):"
 	model.Input.SetValue("")
-	model.Input.Placeholder = "0"
+	model.Input.Placeholder = model.Config.Socket.SocketUser
 
 	return model, nil
 }
 
-// Обработка события ввода MemoryHigh
-func HandleMemoryHighInput(model InstallModel, input string) (InstallModel, error) {
-	val, err := ParseIntValue(input, 0)
-	if err != nil {
+// Обработка события ввода SocketUser
+func HandleSocketUserInput(model InstallModel, input string) (InstallModel, error) {
+	if err := IsValidUserName(input); err != nil {
 		model.ErrorMsg = err.Error()
 		return model, nil
 	}
 
-	model.Config.MemoryHigh = val
+	model.Config.Socket.SocketUser = input
+	model.State = StateSocketMode
+	model.Message = "Введите права доступа к файлу сокета SocketMode, например 0660 (опционально):"
+	model.Input.SetValue("")
+	model.Input.Placeholder = model.Config.Socket.SocketMode
 
-	// Переход к вводу MemoryMax
-	model.State = StateMemoryMax
-	model.Message = "Введите ограничение MemoryMax в МБ (0 - не использовать):"
+	return model, nil
+}
+
+// Обработка события ввода SocketMode
+func HandleSocketModeInput(model InstallModel, input string) (InstallModel, error) {
+	if input != "" && !socketModePattern.MatchString(input) {
+		model.ErrorMsg = "SocketMode: ожидаются восьмеричные права доступа, например 0660"
+		return model, nil
+	}
+
+	model.Config.Socket.SocketMode = input
+	model.State = StateSocketBindIPv6Only
+	model.Message = fmt.Sprintf("Введите BindIPv6Only (%s, опционально):", strings.Join(BindIPv6OnlyValues, ", "))
 	model.Input.SetValue("")
-	model.Input.Placeholder = "0"
+	model.Input.Placeholder = model.Config.Socket.BindIPv6Only
 
 	return model, nil
 }
 
-// Переход к вводу MemoryMax
+// Обработка события ввода BindIPv6Only
+func HandleSocketBindIPv6OnlyInput(model InstallModel, input string) (InstallModel, error) {
+	if input != "" && !slices.Contains(BindIPv6OnlyValues, input) {
+		model.ErrorMsg = fmt.Sprintf("BindIPv6Only: допустимые значения: %s", strings.Join(BindIPv6OnlyValues, ", "))
+		return model, nil
+	}
 
-// Обработка события ввода MemoryMax
-func HandleMemoryMaxInput(model InstallModel, input string) (InstallModel, error) {
-	val, err := ParseIntValue(input, 0)
+	model.Config.Socket.BindIPv6Only = input
+
+	return toUnitLocation(model), nil
+}
+
+// Разобрать и проверить список отслеживаемых путей
+func parsePathInput(name, input string) ([]string, error) {
+	paths := ParsePathList(input)
+	for _, path := range paths {
+		if err := ValidateWatchPath(path); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return paths, nil
+}
+
+// Обработка события ввода PathExists
+func HandlePathExistsInput(model InstallModel, input string) (InstallModel, error) {
+	paths, err := parsePathInput("PathExists", input)
 	if err != nil {
 		model.ErrorMsg = err.Error()
 		return model, nil
 	}
 
-	model.Config.MemoryMax = val
+	model.Config.Path.PathExists = paths
+	model.State = StatePathChanged
+	model.Message = "Введите PathChanged - файлы, запись в которые завершена, через пробел (опционально):"
+	model.Input.SetValue("")
+	model.Input.Placeholder = strings.Join(model.Config.Path.PathChanged, " ")
 
-	// Проверка логики ограничений памяти
-	if model.Config.MemoryHigh > 0 && model.Config.MemoryMax > 0 &&
-		model.Config.MemoryHigh >= model.Config.MemoryMax {
-		model.ErrorMsg = "Ошибка: MemoryHigh должен быть меньше MemoryMax"
+	return model, nil
+}
+
+// Обработка события ввода PathChanged
+func HandlePathChangedInput(model InstallModel, input string) (InstallModel, error) {
+	paths, err := parsePathInput("PathChanged", input)
+	if err != nil {
+		model.ErrorMsg = err.Error()
 		return model, nil
 	}
 
-	// Переход к вводу CPU quota limit
-	model.State = StateCPUQuota
-	model.Message = "Введите максимально разрешенную нагрузку на ядро в процентах если 0 то нет ограничений:"
+	model.Config.Path.PathChanged = paths
+	model.State = StatePathModified
+	model.Message = "Введите PathModified - файлы, любая запись в которые запускает сервис, через пробел (опционально):"
 	model.Input.SetValue("")
-	model.Input.Placeholder = "0"
+	model.Input.Placeholder = strings.Join(model.Config.Path.PathModified, " ")
 
 	return model, nil
 }
 
-// Обработка события ввода ProcessUsageLimit
-func HandleCPULimitQuota(model InstallModel, input string) (InstallModel, error) {
-	val, err := ParseIntValue(input, 0)
+// Обработка события ввода PathModified
+func HandlePathModifiedInput(model InstallModel, input string) (InstallModel, error) {
+	paths, err := parsePathInput("PathModified", input)
 	if err != nil {
 		model.ErrorMsg = err.Error()
 		return model, nil
 	}
 
-	model.Config.CPUQuota = val
-
-	model.State = StateAllowedCPUs
-	model.Message = "Введите разрешенные к использованию ядра в формате 0,1,1,0 где 0 отключение ядра:"
+	model.Config.Path.PathModified = paths
+	model.State = StatePathDirectoryNotEmpty
+	model.Message = "Введите DirectoryNotEmpty - каталоги, появление файлов в которых запускает сервис (например: /var/spool/ingest):"
 	model.Input.SetValue("")
-	model.Input.Placeholder = ""
+	model.Input.Placeholder = strings.Join(model.Config.Path.DirectoryNotEmpty, " ")
 
 	return model, nil
 }
 
-func HandleCPUCoresUsage(model InstallModel, input string) (InstallModel, error) {
-	model.Config.AllowedCPUs = input
+// Обработка события ввода DirectoryNotEmpty. Это последний список путей,
+// поэтому здесь проверяем, что задан хотя бы один.
+func HandlePathDirectoryNotEmptyInput(model InstallModel, input string) (InstallModel, error) {
+	paths, err := parsePathInput("DirectoryNotEmpty", input)
+	if err != nil {
+		model.ErrorMsg = err.Error()
+		return model, nil
+	}
 
-	model.State = StateUnitLocation
-	model.Message = "Введите путь для сохранения unit-файла (по умолчанию: /etc/systemd/system):"
+	path := model.Config.Path
+	path.DirectoryNotEmpty = paths
+	if len(path.directives()) == 0 {
+		model.ErrorMsg = "Не задано ни одного пути: укажите PathExists, PathChanged, PathModified или DirectoryNotEmpty"
+		model.State = StatePathExists
+		model.Message = "Введите PathExists - пути, появление которых запускает сервис, через пробел (опционально):"
+		model.Input.SetValue("")
+		return model, nil
+	}
+
+	model.State = StatePathMakeDirectory
+	model.Message = "Создавать отслеживаемые каталоги, если их нет (MakeDirectory)? (y/n):"
 	model.Input.SetValue("")
-	model.Input.Placeholder = "/etc/systemd/system"
+	model.Input.Placeholder = "n"
 
 	return model, nil
 }
 
+// Обработка события ответа на вопрос о MakeDirectory
+func HandlePathMakeDirectoryInput(model InstallModel, input string) (InstallModel, error) {
+	model.Config.Path.MakeDirectory = len(input) > 0 && (input[0] == 'y' || input[0] == 'Y')
+
+	return toUnitLocation(model), nil
+}
+
 // Обработка события ввода пути unit-файла
 func HandleUnitLocationInput(model InstallModel, input string) (InstallModel, error) {
 	if input == "" {
-		model.Config.UnitFilePath = "/etc/systemd/system"
+		model.Config.UnitFilePath = DefaultUnitDir
 	} else {
 		if err := IsValidPath(input); err != nil {
 			model.ErrorMsg = err.Error()
@@ -266,19 +1593,26 @@ func HandleUnitLocationInput(model InstallModel, input string) (InstallModel, er
 		model.Config.UnitFilePath = input
 	}
 
-	// Проверка существования файла
-	unitFilePath := filepath.Join(model.Config.UnitFilePath, model.Config.ServiceName+".service")
-	if FileExists(unitFilePath) {
-		model.State = StateOverwrite
-		model.Message = fmt.Sprintf("Файл %s уже существует. Перезаписать? (y/n):", unitFilePath)
-		model.Input.SetValue("")
-	} else {
-		// Переходим к выбору опций
-		model.State = StateOptionsSelect
-		model.Message = "Выберите опции (пробел для переключения, Enter для подтверждения):"
-		model.Input.SetValue("")
+	// Проверка существования файлов (сервиса и таймера)
+	units, err := GenerateUnits(model.Config)
+	if err != nil {
+		model.ErrorMsg = err.Error()
+		return model, nil
+	}
+	for _, unit := range units {
+		if FileExists(unit.Path) {
+			model.State = StateOverwrite
+			model.Message = fmt.Sprintf("Файл %s уже существует. Перезаписать? (y/n):", unit.Path)
+			model.Input.SetValue("")
+			return model, nil
+		}
 	}
 
+	// Переходим к выбору опций
+	model.State = StateOptionsSelect
+	model.Message = "Выберите опции (пробел для переключения, Enter для подтверждения):"
+	model.Input.SetValue("")
+
 	return model, nil
 }
 
@@ -299,29 +1633,103 @@ func HandleOverwriteInput(model InstallModel, input string) (InstallModel, error
 }
 
 // Обработка события выбора опций
-func HandleOptionsSelect(model InstallModel) (InstallModel, error) {
+func HandleOptionsSelect(ctx context.Context, model InstallModel) (InstallModel, error) {
+	if model.EditMode {
+		return handleEditOptionsSelect(model)
+	}
+
 	// Применяем выбранные опции
 	model.Actions.ReloadDaemon = model.Options[0].Selected
 	model.Actions.EnableService = model.Options[1].Selected
 	model.Actions.StartService = model.Options[2].Selected
 
 	// Генерируем предпросмотр
-	preview, err := GenerateUnitPreview(model.Config)
+	units, err := GenerateUnits(model.Config)
 	if err != nil {
 		model.ErrorMsg = fmt.Sprintf("Ошибка при генерации предпросмотра: %s", err)
 		return model, nil
 	}
 
-	model.PreviewContent = preview
-	model.Viewport.SetContent(preview)
+	model.PreviewContent = GenerateUnitsPreview(units)
 	model.State = StatePreviewUnit
 	model.Message = "Предпросмотр unit-файла (Enter - сохранить, Esc - отменить):"
+	if len(units) > 1 {
+		model.Message = "Предпросмотр unit-файлов (Enter - сохранить, Esc - отменить):"
+	}
+
+	// Несколько файлов показываем рядом, для таймера - еще и ближайшие
+	// срабатывания, для профиля защиты - пояснения к директивам и оценку
+	content := RenderUnitsSideBySide(units, model.Viewport.Width-ViewportStyle.GetHorizontalFrameSize())
+	if model.Config.Timer != nil {
+		content += "\n\n" + TimerElapsesPreview(ctx, *model.Config.Timer, time.Now())
+	}
+	if len(hardeningDirectives(model.Config)) > 0 {
+		content += "\n\n" + HardeningPreview(ctx, model.Config, units[0])
+	}
+	model.Viewport.SetContent(content)
+
+	return model, nil
+}
+
+// Обработка выбора опций в режиме редактирования: применяем конфигурацию к
+// исходному unit-файлу и показываем diff с файлом на диске
+func handleEditOptionsSelect(model InstallModel) (InstallModel, error) {
+	model.Actions.ReloadDaemon = model.Options[0].Selected
+	model.Actions.RestartService = model.Options[1].Selected
+	model.DropInMode = model.Options[2].Selected
+
+	if model.DropInMode {
+		return handleDropInPreview(model)
+	}
+
+	unit, err := ParseUnitFile(strings.NewReader(model.OriginalContent))
+	if err != nil {
+		model.ErrorMsg = fmt.Sprintf("Ошибка при разборе unit-файла: %s", err)
+		return model, nil
+	}
+	ApplyServiceConfig(unit, model.Config)
+	model.Unit = unit
+
+	model.PreviewContent = unit.String()
+	model.State = StatePreviewUnit
+
+	if !HasChanges(DiffLines(model.OriginalContent, model.PreviewContent)) {
+		model.Viewport.SetContent(model.PreviewContent)
+		model.Message = "Изменений нет (Enter - все равно сохранить, Esc - отменить):"
+		return model, nil
+	}
+
+	model.Viewport.SetContent(RenderDiff(model.OriginalContent, model.PreviewContent))
+	model.Message = "Изменения unit-файла (Enter - сохранить, Esc - отменить):"
+
+	return model, nil
+}
+
+// Предпросмотр drop-in файла только с измененными директивами
+func handleDropInPreview(model InstallModel) (InstallModel, error) {
+	content, err := GenerateDropIn(model.BaseConfig, model.Config)
+	if err != nil {
+		model.ErrorMsg = err.Error()
+		return model, nil
+	}
+
+	model.DropInPath = filepath.Join(DropInDir(DefaultUnitDir, model.Config.ServiceName), DropInFileName(DefaultDropInPriority))
+	model.PreviewContent = content
+	model.State = StatePreviewUnit
+
+	// Если drop-in уже существует, показываем изменения относительно него
+	previous := ""
+	if data, err := os.ReadFile(model.DropInPath); err == nil {
+		previous = string(data)
+	}
+	model.Viewport.SetContent(RenderDiff(previous, content))
+	model.Message = fmt.Sprintf("Drop-in %s (Enter - сохранить, Esc - отменить):", model.DropInPath)
 
 	return model, nil
 }
 
 // Обработка события подтверждения установки в режиме предпросмотра
-func HandlePreviewConfirmation(model InstallModel) (InstallModel, error) {
+func HandlePreviewConfirmation(ctx context.Context, model InstallModel) (InstallModel, error) {
 	// Если уже есть ошибка, очищаем её
 	if model.ErrorMsg != "" {
 		model.ErrorMsg = ""
@@ -329,7 +1737,17 @@ func HandlePreviewConfirmation(model InstallModel) (InstallModel, error) {
 	}
 
 	// Выполняем установку сервиса
-	result, err := InstallService(model.Config, model.Actions)
+	var (
+		result string
+		err    error
+	)
+	if model.EditMode && model.DropInMode {
+		result, err = ApplyDropIn(ctx, model.backend, model.Config, model.PreviewContent, model.Actions)
+	} else if model.EditMode {
+		result, err = UpdateService(ctx, model.backend, model.Config, model.PreviewContent, model.Actions)
+	} else {
+		result, err = InstallService(ctx, model.backend, model.Config, model.Actions)
+	}
 	if err != nil {
 		model.ErrorMsg = err.Error()
 		return model, nil
@@ -343,7 +1761,7 @@ func HandlePreviewConfirmation(model InstallModel) (InstallModel, error) {
 }
 
 // Обработка сообщений для установки сервиса
-func UpdateInstall(msg tea.Msg, model InstallModel) (InstallModel, tea.Cmd, error) {
+func UpdateInstall(ctx context.Context, msg tea.Msg, model InstallModel) (InstallModel, tea.Cmd, error) {
 	var err error
 
 	switch msg := msg.(type) {
@@ -355,6 +1773,7 @@ func UpdateInstall(msg tea.Msg, model InstallModel) (InstallModel, tea.Cmd, erro
 				model.ErrorMsg = ""
 				return model, nil, nil
 			}
+			model.WarningMsg = ""
 
 			// Обработка Enter в зависимости от текущего состояния
 			switch model.State {
@@ -366,6 +1785,40 @@ func UpdateInstall(msg tea.Msg, model InstallModel) (InstallModel, tea.Cmd, erro
 				model, err = HandleWorkingDirectoryInput(model, model.Input.Value())
 			case StateExecStart:
 				model, err = HandleExecStartInput(model, model.Input.Value())
+			case StateExecHooks:
+				model, err = HandleExecHooksInput(model, model.Input.Value())
+			case StateExecStartPre, StateExecStartPost, StateExecReload, StateExecStop, StateExecStopPost:
+				model, err = HandleExecHookInput(model, model.Input.Value())
+			case StateServicePolicy:
+				model, err = HandleServicePolicyInput(model, model.Input.Value())
+			case StateServiceType:
+				model, err = HandleServiceTypeInput(model, model.Input.Value())
+			case StateRestart:
+				model, err = HandleRestartInput(model, model.Input.Value())
+			case StateRestartSec:
+				model, err = HandleRestartSecInput(model, model.Input.Value())
+			case StateStartLimitInterval:
+				model, err = HandleStartLimitIntervalInput(model, model.Input.Value())
+			case StateStartLimitBurst:
+				model, err = HandleStartLimitBurstInput(model, model.Input.Value())
+			case StateTimeoutStartSec:
+				model, err = HandleTimeoutStartSecInput(model, model.Input.Value())
+			case StateTimeoutStopSec:
+				model, err = HandleTimeoutStopSecInput(model, model.Input.Value())
+			case StateKillMode:
+				model, err = HandleKillModeInput(model, model.Input.Value())
+			case StateKillSignal:
+				model, err = HandleKillSignalInput(model, model.Input.Value())
+			case StateRemainAfterExit:
+				model, err = HandleRemainAfterExitInput(model, model.Input.Value())
+			case StateEnvironment:
+				model, err = HandleEnvironmentInput(model, model.Input.Value())
+			case StateEnvironmentFiles:
+				model, err = HandleEnvironmentFilesInput(model, model.Input.Value())
+			case StateDependencies:
+				model, err = HandleDependenciesInput(ctx, model, model.Input.Value())
+			case StateAfter, StateBefore, StateWants, StateRequires, StateBindsTo, StatePartOf, StateConflicts, StateWantedBy, StateRequiredBy:
+				model, err = HandleDependencyInput(model, model.Input.Value())
 			case StateStandardOutput:
 				model, err = HandleStandardOutputInput(model, model.Input.Value())
 			case StateStandardError:
@@ -380,17 +1833,60 @@ func UpdateInstall(msg tea.Msg, model InstallModel) (InstallModel, tea.Cmd, erro
 				model, err = HandleCPULimitQuota(model, model.Input.Value())
 			case StateAllowedCPUs:
 				model, err = HandleCPUCoresUsage(model, model.Input.Value())
+			case StateResources:
+				model, err = HandleResourcesInput(model, model.Input.Value())
+			case StateMemorySwapMax, StateCPUWeight, StateAllowedMemoryNodes, StateTasksMax, StateIOWeight,
+				StateIODeviceReadBandwidthMax, StateIODeviceWriteBandwidthMax, StateLimitNOFILE, StateLimitNPROC, StateLimitCORE, StateNice:
+				model, err = HandleResourceInput(model, model.Input.Value())
+			case StateHardening:
+				model, err = HandleHardeningInput(model, model.Input.Value())
+			case StateTimerOnCalendar:
+				model, err = HandleTimerOnCalendarInput(ctx, model, model.Input.Value())
+			case StateTimerOnBootSec:
+				model, err = HandleTimerOnBootSecInput(model, model.Input.Value())
+			case StateTimerOnUnitActiveSec:
+				model, err = HandleTimerOnUnitActiveSecInput(model, model.Input.Value())
+			case StateTimerRandomizedDelay:
+				model, err = HandleTimerRandomizedDelayInput(model, model.Input.Value())
+			case StateTimerAccuracy:
+				model, err = HandleTimerAccuracyInput(model, model.Input.Value())
+			case StateTimerPersistent:
+				model, err = HandleTimerPersistentInput(model, model.Input.Value())
+			case StateSocketListenStream:
+				model, err = HandleSocketListenStreamInput(model, model.Input.Value())
+			case StateSocketListenDatagram:
+				model, err = HandleSocketListenDatagramInput(model, model.Input.Value())
+			case StateSocketAccept:
+				model, err = HandleSocketAcceptInput(model, model.Input.Value())
+			case StateSocketUser:
+				model, err = HandleSocketUserInput(model, model.Input.Value())
+			case StateSocketMode:
+				model, err = HandleSocketModeInput(model, model.Input.Value())
+			case StateSocketBindIPv6Only:
+				model, err = HandleSocketBindIPv6OnlyInput(model, model.Input.Value())
+			case StatePathExists:
+				model, err = HandlePathExistsInput(model, model.Input.Value())
+			case StatePathChanged:
+				model, err = HandlePathChangedInput(model, model.Input.Value())
+			case StatePathModified:
+				model, err = HandlePathModifiedInput(model, model.Input.Value())
+			case StatePathDirectoryNotEmpty:
+				model, err = HandlePathDirectoryNotEmptyInput(model, model.Input.Value())
+			case StatePathMakeDirectory:
+				model, err = HandlePathMakeDirectoryInput(model, model.Input.Value())
+			case StateInstances:
+				model, err = HandleInstancesInput(model, model.Input.Value())
 			case StateUnitLocation:
 				model, err = HandleUnitLocationInput(model, model.Input.Value())
 			case StateOverwrite:
 				model, err = HandleOverwriteInput(model, model.Input.Value())
 			case StateOptionsSelect:
-				model, err = HandleOptionsSelect(model)
+				model, err = HandleOptionsSelect(ctx, model)
 				if err == nil {
 					return model, tea.ClearScreen, nil
 				}
 			case StatePreviewUnit:
-				model, err = HandlePreviewConfirmation(model)
+				model, err = HandlePreviewConfirmation(ctx, model)
 				if err == nil && model.Quitting {
 					// Успешно завершено, выходим
 					return model, tea.Quit, nil
@@ -463,6 +1959,11 @@ func UpdateInstall(msg tea.Msg, model InstallModel) (InstallModel, tea.Cmd, erro
 	var cmd tea.Cmd
 	model.Input, cmd = model.Input.Update(msg)
 
+	// Автодополнение дописывает имя unit к уже введенным
+	if model.Input.ShowSuggestions {
+		model.Input.SetSuggestions(unitSuggestions(model.Input.Value(), model.UnitNames))
+	}
+
 	return model, cmd, nil
 }
 
@@ -488,14 +1989,33 @@ func ViewInstall(model InstallModel) string {
 		s.WriteString(model.Viewport.View() + "\n\n")
 
 		// Показываем выбранные опции
-		s.WriteString(RenderSelectedOptions(model.Actions) + "\n")
+		s.WriteString(RenderSelectedOptions(model.Actions, activationTarget(model.Config)) + "\n")
 
 		s.WriteString("Используйте стрелки ↑/↓ для прокрутки, Enter для сохранения\n")
+	} else if model.State == StateEnvironment {
+		// В редакторе окружения показываем заданные переменные
+		s.WriteString(RenderEnvironment(model.Config.Environment, model.Config.Secrets) + "\n")
+		s.WriteString(model.Input.View() + "\n\n")
+	} else if model.State >= StateExecStartPre && model.State <= StateExecStopPost {
+		// В редакторе команд показываем уже добавленные
+		_, commands := execHookField(&model.Config, model.State)
+		s.WriteString(RenderCommands(*commands) + "\n")
+		s.WriteString(model.Input.View() + "\n\n")
 	} else if model.State != StateError {
 		// В других режимах показываем поле ввода
 		s.WriteString(model.Input.View() + "\n\n")
 	}
 
+	// Подсказка для режима редактирования
+	if model.EditMode && model.State > StateServiceName && model.State < StateUnitLocation {
+		s.WriteString(FormatInfo("Enter - оставить текущее значение, «-» - очистить") + "\n")
+	}
+
+	// Предупреждение к предыдущему вводу
+	if model.WarningMsg != "" {
+		s.WriteString(FormatWarning(model.WarningMsg) + "\n")
+	}
+
 	// Отображаем сообщение об ошибке, если оно есть
 	if model.ErrorMsg != "" {
 		s.WriteString("\n" + FormatError(model.ErrorMsg) + "\n\n")
@@ -0,0 +1,687 @@
+package sdmanager
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Ввести значение в текущий шаг мастера и нажать Enter
+func submitInstall(t *testing.T, model InstallModel, value string) InstallModel {
+	t.Helper()
+
+	model.Input.SetValue(value)
+	model, _, err := UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+	if err != nil {
+		t.Fatalf("UpdateInstall: %v", err)
+	}
+	if model.ErrorMsg != "" {
+		t.Fatalf("state %d: unexpected error: %s", model.State, model.ErrorMsg)
+	}
+
+	return model
+}
+
+func TestUpdateInstallWizard(t *testing.T) {
+	unitDir := t.TempDir()
+	workDir := t.TempDir()
+	backend := NewFakeBackend()
+	backend.UnitDir = unitDir
+
+	model := NewInstallModel(AppOptions{backend: backend})
+
+	steps := []struct {
+		state int
+		value string
+	}{
+		{StateServiceName, "api"},
+		{StateUserName, "www-data"},
+		{StateWorkingDirectory, filepath.Join(workDir, "app")},
+		{StateExecStart, "/usr/bin/api --port 8080"},
+		{StateExecHooks, ""},
+		{StateServicePolicy, ""},
+		{StateEnvironment, ""},
+		{StateEnvironmentFiles, ""},
+		{StateDependencies, ""},
+		{StateStandardOutput, "journal"},
+		{StateStandardError, ""},
+		{StateSyslogIdentifier, "api"},
+		{StateMemoryHigh, "256"},
+		{StateMemoryMax, "512"},
+		{StateCPUQuota, "50"},
+		{StateAllowedCPUs, "0"},
+		{StateResources, "y"},
+		{StateMemorySwapMax, "0"},
+		{StateCPUWeight, ""},
+		{StateAllowedMemoryNodes, ""},
+		{StateTasksMax, "512"},
+		{StateIOWeight, ""},
+		{StateIODeviceReadBandwidthMax, ""},
+		{StateIODeviceWriteBandwidthMax, "/dev/sda 10M"},
+		{StateLimitNOFILE, "1024:65536"},
+		{StateLimitNPROC, ""},
+		{StateLimitCORE, ""},
+		{StateNice, "5"},
+		{StateHardening, "basic"},
+		{StateUnitLocation, unitDir},
+	}
+
+	for _, step := range steps {
+		if model.State != step.state {
+			t.Fatalf("State = %d, want %d", model.State, step.state)
+		}
+		model = submitInstall(t, model, step.value)
+	}
+
+	if model.State != StateOptionsSelect {
+		t.Fatalf("State = %d, want options select", model.State)
+	}
+
+	// Отключаем запуск сервиса
+	model, _, _ = UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeyDown}, model)
+	model, _, _ = UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeyDown}, model)
+	model, _, _ = UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeySpace}, model)
+
+	model = submitInstall(t, model, "")
+	if model.State != StatePreviewUnit {
+		t.Fatalf("State = %d, want preview", model.State)
+	}
+	if !strings.Contains(model.PreviewContent, "MemoryMax=512M") {
+		t.Errorf("preview does not contain MemoryMax:\n%s", model.PreviewContent)
+	}
+
+	model, cmd, err := UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+	if err != nil || model.ErrorMsg != "" {
+		t.Fatalf("install failed: %v %s", err, model.ErrorMsg)
+	}
+	if !model.Quitting || cmd == nil {
+		t.Error("wizard should quit after install")
+	}
+
+	content, err := os.ReadFile(filepath.Join(unitDir, "api.service"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"User=www-data", "ExecStart=/usr/bin/api --port 8080", "CPUQuota=50%", "AllowedCPUs=0", "MemorySwapMax=0M", "TasksMax=512", "IODeviceWriteBandwidthMax=/dev/sda 10M", "LimitNOFILE=1024:65536", "Nice=5", "ProtectSystem=full", "ReadWritePaths=" + filepath.Join(workDir, "app")} {
+		if !strings.Contains(string(content), line) {
+			t.Errorf("unit file does not contain %q", line)
+		}
+	}
+
+	unit, ok := backend.Unit("api")
+	if !ok {
+		t.Fatal("unit was not loaded after daemon-reload")
+	}
+	if unit.UnitFileState != "enabled" || unit.ActiveState != "inactive" {
+		t.Errorf("unit state = %s/%s, want enabled/inactive", unit.UnitFileState, unit.ActiveState)
+	}
+}
+
+func TestUpdateInstallValidation(t *testing.T) {
+	model := NewInstallModel(AppOptions{backend: NewFakeBackend()})
+	model = submitInstall(t, model, "api")
+	for range 11 {
+		model = submitInstall(t, model, "")
+	}
+
+	model.Input.SetValue("abc")
+	model, _, _ = UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+	if model.State != StateMemoryHigh || model.ErrorMsg == "" {
+		t.Fatalf("non-numeric MemoryHigh accepted: state %d, error %q", model.State, model.ErrorMsg)
+	}
+
+	// Enter сбрасывает ошибку, после чего можно повторить ввод
+	model, _, _ = UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+	model = submitInstall(t, model, "512")
+
+	model.Input.SetValue("256")
+	model, _, _ = UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+	if !strings.Contains(model.ErrorMsg, "MemoryHigh должен быть меньше MemoryMax") {
+		t.Errorf("ErrorMsg = %q", model.ErrorMsg)
+	}
+}
+
+func TestInstallService(t *testing.T) {
+	unitDir := t.TempDir()
+	backend := NewFakeBackend()
+	backend.UnitDir = unitDir
+
+	config := ServiceConfig{
+		ServiceName:      "worker",
+		WorkingDirectory: "/srv/worker",
+		ExecStart:        "/usr/bin/worker",
+		UnitFilePath:     unitDir,
+	}
+	actions := UserActions{ReloadDaemon: true, EnableService: true, StartService: true}
+
+	result, err := InstallService(context.Background(), backend, config, actions)
+	if err != nil {
+		t.Fatalf("InstallService: %v", err)
+	}
+	if !strings.Contains(result, "Установка успешно завершена") {
+		t.Errorf("result = %q", result)
+	}
+
+	want := []string{"daemon-reload", "enable worker", "start worker"}
+	if calls := backend.Calls(); !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+
+	unit, _ := backend.Unit("worker")
+	if unit.ActiveState != "active" || unit.UnitFileState != "enabled" {
+		t.Errorf("unit state = %s/%s", unit.ActiveState, unit.UnitFileState)
+	}
+
+	// Без разрешения на перезапись существующий файл не трогаем
+	if _, err := InstallService(context.Background(), backend, config, actions); err == nil {
+		t.Error("expected error for existing unit file without overwrite")
+	}
+
+	actions.Overwrite = true
+	if _, err := InstallService(context.Background(), backend, config, actions); err != nil {
+		t.Errorf("overwrite: %v", err)
+	}
+}
+
+func TestInstallServiceBackendError(t *testing.T) {
+	unitDir := t.TempDir()
+	backend := NewFakeBackend()
+	backend.UnitDir = unitDir
+	backend.FailOn("start", "worker", errors.New("job failed"))
+
+	config := ServiceConfig{ServiceName: "worker", ExecStart: "/usr/bin/worker", UnitFilePath: unitDir}
+	actions := UserActions{ReloadDaemon: true, StartService: true}
+
+	result, err := InstallService(context.Background(), backend, config, actions)
+	if err == nil || !strings.Contains(err.Error(), "job failed") {
+		t.Fatalf("err = %v", err)
+	}
+	if !strings.Contains(result, "Systemd daemon перезагружен") {
+		t.Errorf("partial result lost: %q", result)
+	}
+}
+
+func TestUpdateInstallTimerWizard(t *testing.T) {
+	unitDir := t.TempDir()
+	backend := NewFakeBackend()
+	backend.UnitDir = unitDir
+
+	model := NewTimerInstallModel(AppOptions{backend: backend})
+	for _, value := range []string{"backup", "", "", "/usr/bin/backup", "", "", "", "", "", "", "", "", "", "", "", "", "", ""} {
+		model = submitInstall(t, model, value)
+	}
+	if model.State != StateTimerOnCalendar {
+		t.Fatalf("State = %d, want OnCalendar", model.State)
+	}
+
+	// Некорректное выражение не принимается
+	model.Input.SetValue("Mon..Fri 25:00")
+	model, _, _ = UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+	if model.State != StateTimerOnCalendar || model.ErrorMsg == "" {
+		t.Fatalf("invalid OnCalendar accepted: state %d", model.State)
+	}
+	model, _, _ = UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+
+	steps := []struct {
+		state int
+		value string
+	}{
+		{StateTimerOnCalendar, "Mon..Fri 03:00; Sat 05:00"},
+		{StateTimerOnBootSec, ""},
+		{StateTimerOnUnitActiveSec, ""},
+		{StateTimerRandomizedDelay, "10min"},
+		{StateTimerAccuracy, ""},
+		{StateTimerPersistent, "y"},
+		{StateUnitLocation, unitDir},
+		{StateOptionsSelect, ""},
+	}
+	for _, step := range steps {
+		if model.State != step.state {
+			t.Fatalf("State = %d, want %d", model.State, step.state)
+		}
+		model = submitInstall(t, model, step.value)
+	}
+
+	if model.State != StatePreviewUnit {
+		t.Fatalf("State = %d, want preview", model.State)
+	}
+	for _, line := range []string{"# backup.timer", "Type=oneshot", "OnCalendar=Sat 05:00", "Persistent=true"} {
+		if !strings.Contains(model.PreviewContent, line) {
+			t.Errorf("preview does not contain %q:\n%s", line, model.PreviewContent)
+		}
+	}
+	if !strings.Contains(model.Viewport.View(), "Ближайшие срабатывания Mon..Fri 03:00") {
+		t.Errorf("preview does not show next elapses:\n%s", model.Viewport.View())
+	}
+
+	model = submitInstall(t, model, "")
+	if !model.Quitting {
+		t.Fatal("wizard should quit after install")
+	}
+
+	// Активируется и запускается таймер, а не сам сервис
+	want := []string{"daemon-reload", "enable backup.timer", "start backup.timer"}
+	if calls := backend.Calls(); !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	service, _ := os.ReadFile(filepath.Join(unitDir, "backup.service"))
+	if strings.Contains(string(service), "Restart=") || strings.Contains(string(service), "[Install]") {
+		t.Errorf("oneshot service should not restart or be installed:\n%s", service)
+	}
+
+	// Удаление сервиса удаляет и таймер
+	if _, err := UninstallService(context.Background(), backend, unitDir, "backup"); err != nil {
+		t.Fatalf("UninstallService: %v", err)
+	}
+	if FileExists(filepath.Join(unitDir, "backup.timer")) {
+		t.Error("timer file was not removed")
+	}
+}
+
+func TestUpdateInstallTimerTriggers(t *testing.T) {
+	model := NewTimerInstallModel(AppOptions{backend: NewFakeBackend()})
+	for range 18 {
+		model = submitInstall(t, model, "")
+	}
+	model = submitInstall(t, model, "")
+	model = submitInstall(t, model, "")
+
+	// Без единого триггера мастер возвращается к OnCalendar
+	model.Input.SetValue("")
+	model, _, _ = UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+	if model.State != StateTimerOnCalendar || model.ErrorMsg == "" {
+		t.Fatalf("timer without triggers accepted: state %d", model.State)
+	}
+
+	model, _, _ = UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+	model = submitInstall(t, model, "")
+	model = submitInstall(t, model, "5min")
+	model = submitInstall(t, model, "1h")
+	model = submitInstall(t, model, "")
+	model = submitInstall(t, model, "")
+
+	// Persistent без OnCalendar не спрашивается
+	if model.State != StateUnitLocation {
+		t.Fatalf("State = %d, want unit location", model.State)
+	}
+	if model.Config.Timer.OnBootSec != "5min" || model.Config.Timer.OnUnitActiveSec != "1h" {
+		t.Errorf("timer = %+v", *model.Config.Timer)
+	}
+}
+
+func TestUpdateInstallSocketWizard(t *testing.T) {
+	unitDir := t.TempDir()
+	backend := NewFakeBackend()
+	backend.UnitDir = unitDir
+
+	model := NewSocketInstallModel(AppOptions{backend: backend})
+	for _, value := range []string{"echo", "", "", "/usr/bin/echo-server", "", "", "", "", "", "", "", "", "", "", "", "", "", ""} {
+		model = submitInstall(t, model, value)
+	}
+
+	// Порт вне диапазона не принимается
+	model.Input.SetValue("8080 99999")
+	model, _, _ = UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+	if model.State != StateSocketListenStream || model.ErrorMsg == "" {
+		t.Fatalf("invalid ListenStream accepted: state %d", model.State)
+	}
+	model, _, _ = UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+
+	steps := []struct {
+		state int
+		value string
+	}{
+		{StateSocketListenStream, "8080 /run/echo.sock"},
+		{StateSocketListenDatagram, ""},
+		{StateSocketAccept, "n"},
+		{StateSocketUser, "www-data"},
+		{StateSocketMode, "0660"},
+		{StateSocketBindIPv6Only, ""},
+		{StateUnitLocation, unitDir},
+		{StateOptionsSelect, ""},
+	}
+	for _, step := range steps {
+		if model.State != step.state {
+			t.Fatalf("State = %d, want %d", model.State, step.state)
+		}
+		model = submitInstall(t, model, step.value)
+	}
+
+	if view := ViewInstall(model); !strings.Contains(view, "Активировать (enable) сокет") {
+		t.Errorf("preview does not show socket actions:\n%s", view)
+	}
+
+	model = submitInstall(t, model, "")
+	want := []string{"daemon-reload", "enable echo.socket", "start echo.socket"}
+	if calls := backend.Calls(); !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(unitDir, "echo.socket"))
+	for _, line := range []string{"ListenStream=8080", "ListenStream=/run/echo.sock", "SocketUser=www-data"} {
+		if !strings.Contains(string(content), line) {
+			t.Errorf("socket does not contain %q:\n%s", line, content)
+		}
+	}
+}
+
+func TestUpdateInstallTemplateWizard(t *testing.T) {
+	unitDir := t.TempDir()
+	backend := NewFakeBackend()
+	backend.UnitDir = unitDir
+
+	model := NewInstallModel(AppOptions{backend: backend})
+	model = submitInstall(t, model, "worker@")
+	if model.Config.ServiceName != "worker" || model.Config.Instances != 1 {
+		t.Fatalf("config = %+v", model.Config)
+	}
+
+	for _, value := range []string{"", "", "/usr/bin/worker --id %i", "", "", "", "", "", "", "", "", "", "", "", "", "", ""} {
+		model = submitInstall(t, model, value)
+	}
+
+	// Шаблону нужен хотя бы один экземпляр
+	if model.State != StateInstances {
+		t.Fatalf("State = %d, want %d", model.State, StateInstances)
+	}
+	model.Input.SetValue("0")
+	model, _, _ = UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+	if model.State != StateInstances || model.ErrorMsg == "" {
+		t.Fatalf("zero instances accepted: state %d", model.State)
+	}
+	model, _, _ = UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+
+	model = submitInstall(t, model, "2")
+	model = submitInstall(t, model, unitDir)
+	model = submitInstall(t, model, "")
+	if view := ViewInstall(model); !strings.Contains(view, "Запустить (start) экземпляры") {
+		t.Errorf("preview does not show instance actions:\n%s", view)
+	}
+
+	model = submitInstall(t, model, "")
+	want := []string{"daemon-reload", "enable worker@1", "start worker@1", "enable worker@2", "start worker@2"}
+	if calls := backend.Calls(); !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	if !FileExists(filepath.Join(unitDir, "worker@.service")) {
+		t.Error("template unit file was not created")
+	}
+}
+
+func TestUpdateInstallPathWizard(t *testing.T) {
+	unitDir := t.TempDir()
+	backend := NewFakeBackend()
+	backend.UnitDir = unitDir
+
+	model := NewPathInstallModel(AppOptions{backend: backend})
+	for _, value := range []string{"ingest", "", "", "/usr/bin/ingest", "", "", "", "", "", "", "", "", "", "", "", "", "", ""} {
+		model = submitInstall(t, model, value)
+	}
+
+	// Относительный путь не принимается
+	model.Input.SetValue("spool/ingest")
+	model, _, _ = UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+	if model.State != StatePathExists || model.ErrorMsg == "" {
+		t.Fatalf("relative path accepted: state %d", model.State)
+	}
+	model, _, _ = UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+
+	// Без единого пути мастер возвращается к первому вопросу
+	for _, state := range []int{StatePathExists, StatePathChanged, StatePathModified} {
+		if model.State != state {
+			t.Fatalf("State = %d, want %d", model.State, state)
+		}
+		model = submitInstall(t, model, "")
+	}
+	model.Input.SetValue("")
+	model, _, _ = UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+	if model.State != StatePathExists || model.ErrorMsg == "" {
+		t.Fatalf("path unit without paths accepted: state %d", model.State)
+	}
+	model, _, _ = UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+
+	steps := []struct {
+		state int
+		value string
+	}{
+		{StatePathExists, ""},
+		{StatePathChanged, ""},
+		{StatePathModified, ""},
+		{StatePathDirectoryNotEmpty, "/var/spool/ingest"},
+		{StatePathMakeDirectory, "y"},
+		{StateUnitLocation, unitDir},
+		{StateOptionsSelect, ""},
+	}
+	for _, step := range steps {
+		if model.State != step.state {
+			t.Fatalf("State = %d, want %d", model.State, step.state)
+		}
+		model = submitInstall(t, model, step.value)
+	}
+
+	model = submitInstall(t, model, "")
+	want := []string{"daemon-reload", "enable ingest.path", "start ingest.path"}
+	if calls := backend.Calls(); !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(unitDir, "ingest.path"))
+	for _, line := range []string{"DirectoryNotEmpty=/var/spool/ingest", "MakeDirectory=yes"} {
+		if !strings.Contains(string(content), line) {
+			t.Errorf("path unit does not contain %q:\n%s", line, content)
+		}
+	}
+}
+
+func TestUpdateInstallServicePolicy(t *testing.T) {
+	model := NewInstallModel(AppOptions{backend: NewFakeBackend()})
+	for _, value := range []string{"job", "", "", "/usr/bin/job", "", "y", "oneshot"} {
+		model = submitInstall(t, model, value)
+	}
+
+	// Oneshot нельзя перезапускать всегда
+	model.Input.SetValue("always")
+	model, _, _ = UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+	if model.State != StateRestart || model.ErrorMsg == "" {
+		t.Fatalf("oneshot with Restart=always accepted: state %d", model.State)
+	}
+	model, _, _ = UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+
+	// Без перезапуска RestartSec пропускается
+	steps := []struct {
+		state int
+		value string
+	}{
+		{StateRestart, "no"},
+		{StateStartLimitInterval, ""},
+		{StateStartLimitBurst, ""},
+		{StateTimeoutStartSec, "infinity"},
+		{StateTimeoutStopSec, "30s"},
+		{StateKillMode, "mixed"},
+		{StateKillSignal, "SIGINT"},
+		{StateRemainAfterExit, "y"},
+		{StateEnvironment, ""},
+		{StateEnvironmentFiles, ""},
+		{StateDependencies, ""},
+	}
+	for _, step := range steps {
+		if model.State != step.state {
+			t.Fatalf("State = %d, want %d", model.State, step.state)
+		}
+		model = submitInstall(t, model, step.value)
+	}
+	if model.State != StateStandardOutput {
+		t.Fatalf("State = %d, want StandardOutput", model.State)
+	}
+
+	content, err := GenerateUnitPreview(model.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "ExecStart=/usr/bin/job\nType=oneshot\nRemainAfterExit=yes\nRestart=no\nTimeoutStartSec=infinity\nTimeoutStopSec=30s\nKillMode=mixed\nKillSignal=SIGINT\n"
+	if !strings.Contains(content, want) {
+		t.Errorf("unit:\n%s", content)
+	}
+}
+
+func TestUpdateInstallEnvironment(t *testing.T) {
+	model := NewInstallModel(AppOptions{backend: NewFakeBackend()})
+	for _, value := range []string{"api", "", "", "/usr/bin/api", "", ""} {
+		model = submitInstall(t, model, value)
+	}
+	if model.State != StateEnvironment {
+		t.Fatalf("State = %d, want Environment", model.State)
+	}
+
+	for _, value := range []string{"PORT=8080", "DEBUG=1", "!TOKEN=s3cret", "-DEBUG"} {
+		model = submitInstall(t, model, value)
+	}
+	model.Input.SetValue("1BAD=x")
+	model, _, _ = UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+	if model.ErrorMsg == "" {
+		t.Error("invalid name accepted")
+	}
+	model.ErrorMsg = ""
+
+	if model.Config.Environment["PORT"] != "8080" || len(model.Config.Environment) != 1 || model.Config.Secrets["TOKEN"] != "s3cret" {
+		t.Fatalf("env = %v, secrets = %v", model.Config.Environment, model.Config.Secrets)
+	}
+	// Значение секрета не показывается на экране
+	if view := ViewInstall(model); strings.Contains(view, "s3cret") || !strings.Contains(view, "TOKEN") {
+		t.Errorf("view:\n%s", view)
+	}
+
+	model = submitInstall(t, model, "")
+	model = submitInstall(t, model, "-/etc/default/api")
+	model = submitInstall(t, model, "")
+	if model.State != StateStandardOutput {
+		t.Fatalf("State = %d, want StandardOutput", model.State)
+	}
+
+	content, err := GenerateUnitPreview(model.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(content, "Environment=PORT=8080\nEnvironmentFile=-/etc/default/api\nEnvironmentFile=/etc/sdmanager/api.env\n") ||
+		strings.Contains(content, "s3cret") {
+		t.Errorf("unit:\n%s", content)
+	}
+}
+
+func TestUpdateInstallExecHooks(t *testing.T) {
+	model := NewInstallModel(AppOptions{backend: NewFakeBackend()})
+	for _, value := range []string{"api", "", "", "/usr/bin/api", "y"} {
+		model = submitInstall(t, model, value)
+	}
+	if model.State != StateExecStartPre {
+		t.Fatalf("State = %d, want ExecStartPre", model.State)
+	}
+
+	// Несуществующий исполняемый файл не принимается
+	model.Input.SetValue("/nonexistent/migrate")
+	model, _, _ = UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+	if model.ErrorMsg == "" {
+		t.Fatal("missing executable accepted")
+	}
+	model, _, _ = UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+
+	steps := []struct {
+		state int
+		value string
+	}{
+		{StateExecStartPre, "-sh -c 'mkdir -p /run/api'"},
+		{StateExecStartPre, "sh -c true"},
+		{StateExecStartPre, "-"},
+		{StateExecStartPre, "sh -c true"},
+		{StateExecStartPre, ""},
+		{StateExecStartPost, ""},
+		{StateExecReload, "kill -HUP $MAINPID"},
+		{StateExecReload, ""},
+		{StateExecStop, ""},
+		{StateExecStopPost, ""},
+	}
+	for _, step := range steps {
+		if model.State != step.state {
+			t.Fatalf("State = %d, want %d", model.State, step.state)
+		}
+		model = submitInstall(t, model, step.value)
+	}
+	if model.State != StateServicePolicy {
+		t.Fatalf("State = %d, want ServicePolicy", model.State)
+	}
+
+	content, err := GenerateUnitPreview(model.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(content, "ExecStartPre=sh -c true\nExecStart=/usr/bin/api\nExecReload=kill -HUP $MAINPID\n") {
+		t.Errorf("unit:\n%s", content)
+	}
+}
+
+func TestUpdateInstallDependencies(t *testing.T) {
+	backend := NewFakeBackend(UnitInfo{Name: "postgresql"}, UnitInfo{Name: "data.mount"}, UnitInfo{Name: "network-online.target"})
+	model := NewInstallModel(AppOptions{backend: backend})
+	for _, value := range []string{"api", "", "", "/usr/bin/api", "", "", "", "", "y"} {
+		model = submitInstall(t, model, value)
+	}
+	if model.State != StateAfter || model.Input.Placeholder != "network.target" {
+		t.Fatalf("State = %d, placeholder %q", model.State, model.Input.Placeholder)
+	}
+
+	// Имя unit дополняется после уже введенных
+	model.Input.SetValue("postgresql.service da")
+	model, _, _ = UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("t")}, model)
+	if suggestion := model.Input.CurrentSuggestion(); suggestion != "postgresql.service data.mount" {
+		t.Errorf("suggestion = %q", suggestion)
+	}
+
+	model = submitInstall(t, model, "postgresql.service data.mount redis.service")
+	if model.State != StateBefore || !strings.Contains(model.WarningMsg, "redis.service") {
+		t.Fatalf("State = %d, warning %q", model.State, model.WarningMsg)
+	}
+
+	steps := []struct {
+		state int
+		value string
+	}{
+		{StateBefore, "postgresql.service"},
+		{StateBefore, ""},
+		{StateWants, ""},
+		{StateRequires, "postgresql.service data.mount"},
+		{StateBindsTo, ""},
+		{StatePartOf, ""},
+		{StateConflicts, ""},
+		{StateWantedBy, ""},
+		{StateRequiredBy, ""},
+	}
+	for _, step := range steps {
+		if model.State != step.state {
+			t.Fatalf("State = %d, want %d", model.State, step.state)
+		}
+		model.Input.SetValue(step.value)
+		model, _, _ = UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+		// Цикл After/Before отклоняется, Enter сбрасывает ошибку
+		if model.ErrorMsg != "" {
+			model, _, _ = UpdateInstall(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+		}
+	}
+	if model.State != StateStandardOutput || model.WarningMsg != "" {
+		t.Fatalf("State = %d, warning %q", model.State, model.WarningMsg)
+	}
+
+	content, err := GenerateUnitPreview(model.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(content, "After=postgresql.service data.mount redis.service\nRequires=postgresql.service data.mount\n") ||
+		!strings.Contains(content, "WantedBy=multi-user.target") || strings.Contains(content, "Before=") {
+		t.Errorf("unit:\n%s", content)
+	}
+}
@@ -0,0 +1,176 @@
+package sdmanager
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Состояния экрана экземпляров шаблона
+const (
+	ScaleStateServiceName = iota
+	ScaleStateView
+)
+
+// Создать модель экрана экземпляров шаблона
+func NewScaleModel(appOptions AppOptions) ScaleModel {
+	ti := textinput.New()
+	ti.Placeholder = "worker@"
+	ti.Focus()
+	ti.CharLimit = 255
+	ti.Width = 80
+
+	return ScaleModel{
+		State:   ScaleStateServiceName,
+		Input:   ti,
+		backend: appOptions.backend,
+	}
+}
+
+// Шаблон, к которому относится сервис: "worker@3" и "worker@" -> "worker"
+func TemplateOf(serviceName string) (string, bool) {
+	template, _, found := strings.Cut(serviceName, "@")
+	return template, found && template != ""
+}
+
+// Загрузить экземпляры шаблона
+func LoadInstances(ctx context.Context, model ScaleModel, template string) ScaleModel {
+	instances, err := TemplateInstances(ctx, model.backend, template)
+	if err != nil {
+		model.Error = err.Error()
+		return model
+	}
+
+	model.Template = template
+	model.Instances = instances
+	model.State = ScaleStateView
+	model.Error = ""
+	return model
+}
+
+// Количество экземпляров с числовыми номерами, которыми управляет
+// масштабирование
+func numericInstances(model ScaleModel) int {
+	count := 0
+	for _, unit := range model.Instances {
+		instance, _ := templateInstance(unit.Name, model.Template)
+		if _, err := strconv.Atoi(instance); err == nil {
+			count++
+		}
+	}
+	return count
+}
+
+// Изменить количество экземпляров и обновить список
+func scaleInstances(ctx context.Context, model ScaleModel, count int) ScaleModel {
+	result, err := ScaleTemplate(ctx, model.backend, model.Template, count)
+	model.Message = result
+	if err != nil {
+		model.Error = err.Error()
+		return model
+	}
+
+	model = LoadInstances(ctx, model, model.Template)
+	return model
+}
+
+// Обработка событий экрана экземпляров шаблона
+func UpdateScale(ctx context.Context, msg tea.Msg, model ScaleModel) (ScaleModel, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+
+	if model.State == ScaleStateView {
+		if !ok {
+			return model, nil
+		}
+
+		switch keyMsg.String() {
+		case "+", "=", "up", "k":
+			model.Error = ""
+			return scaleInstances(ctx, model, numericInstances(model)+1), nil
+		case "-", "down", "j":
+			model.Error = ""
+			if count := numericInstances(model); count > 0 {
+				return scaleInstances(ctx, model, count-1), nil
+			}
+			return model, nil
+		case "r":
+			return LoadInstances(ctx, model, model.Template), nil
+		case "q", "esc", "ctrl+c":
+			model.Quitting = true
+		}
+		return model, nil
+	}
+
+	if ok {
+		switch keyMsg.Type {
+		case tea.KeyEnter:
+			// Если есть предыдущая ошибка, просто очищаем её
+			if model.Error != "" {
+				model.Error = ""
+				return model, nil
+			}
+
+			value := model.Input.Value()
+			if value == "" {
+				value = model.Input.Placeholder
+			}
+			template, _ := ParseTemplateName(value)
+			if err := IsValidServiceName(template); err != nil {
+				model.Error = err.Error()
+				return model, nil
+			}
+
+			return LoadInstances(ctx, model, template), nil
+
+		case tea.KeyEsc, tea.KeyCtrlC:
+			model.Quitting = true
+			return model, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	model.Input, cmd = model.Input.Update(msg)
+	return model, cmd
+}
+
+// Отрисовка экрана экземпляров шаблона
+func ViewScale(model ScaleModel) string {
+	var s strings.Builder
+
+	if model.State == ScaleStateServiceName {
+		s.WriteString("Введите имя шаблона сервиса (например, worker или worker@):\n\n")
+		s.WriteString(model.Input.View() + "\n\n")
+		if model.Error != "" {
+			s.WriteString(FormatError(model.Error) + "\n\n")
+		}
+		s.WriteString("Нажмите Enter для подтверждения, Esc для возврата в меню\n")
+		return s.String()
+	}
+
+	var panel strings.Builder
+	panel.WriteString(TitleStyle.Render(model.Template+"@.service") + "\n\n")
+	if len(model.Instances) == 0 {
+		panel.WriteString("Нет запущенных экземпляров\n")
+	}
+	for _, unit := range model.Instances {
+		state := StateStyle(unit.ActiveState).Render(unit.ActiveState + "/" + unit.SubState)
+		panel.WriteString("  " + unit.Name + "  " + state + "\n")
+	}
+	panel.WriteString("\nЭкземпляров: " + strconv.Itoa(len(model.Instances)))
+
+	s.WriteString(PanelStyle.Render(panel.String()) + "\n\n")
+
+	if model.Message != "" {
+		s.WriteString(FormatInfo(model.Message) + "\n\n")
+	}
+	if model.Error != "" {
+		s.WriteString(FormatError(model.Error) + "\n\n")
+	}
+
+	s.WriteString("+ - добавить экземпляр, - - остановить последний, r - обновить, Esc - назад\n")
+
+	return s.String()
+}
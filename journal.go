@@ -0,0 +1,493 @@
+package sdmanager
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Уровни приоритета журнала (syslog)
+const (
+	PriorityEmerg = iota
+	PriorityAlert
+	PriorityCrit
+	PriorityErr
+	PriorityWarning
+	PriorityNotice
+	PriorityInfo
+	PriorityDebug
+)
+
+// Названия уровней приоритета в порядке их значений
+var PriorityNames = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// Стили записей журнала по приоритету
+var (
+	PriorityErrStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	PriorityWarningStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("220"))
+	PriorityNoticeStyle  = lipgloss.NewStyle().Bold(true)
+	PriorityDebugStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	PriorityInfoStyle    = lipgloss.NewStyle()
+)
+
+// Формат времени записи журнала, как в journalctl -o short
+const journalTimeLayout = "Jan 02 15:04:05"
+
+// Запись журнала systemd
+type JournalEntry struct {
+	Timestamp        time.Time `json:"timestamp"`
+	Priority         int       `json:"priority"`
+	PID              int       `json:"pid,omitempty"`
+	SyslogIdentifier string    `json:"syslog_identifier,omitempty"`
+	Unit             string    `json:"unit,omitempty"`
+	Hostname         string    `json:"hostname,omitempty"`
+	BootID           string    `json:"boot_id,omitempty"`
+	CodeFile         string    `json:"code_file,omitempty"`
+	CodeLine         int       `json:"code_line,omitempty"`
+	CodeFunc         string    `json:"code_func,omitempty"`
+	Message          string    `json:"message"`
+}
+
+// Прочитать записи journalctl --output=json и передать их fn по мере
+// чтения. Строки, которые не являются JSON-объектом (подсказки и служебные
+// сообщения journalctl), пропускаются. Чтение прекращается на первой ошибке fn.
+func ScanJournal(r io.Reader, fn func(JournalEntry) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+
+		entry, err := ParseJournalEntry(line)
+		if err != nil {
+			return err
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("ошибка чтения вывода: %w", err)
+	}
+	return nil
+}
+
+// Разобрать запись journalctl --output=json. Поля, которых нет в записи,
+// остаются пустыми. Запись без PRIORITY считается информационной.
+func ParseJournalEntry(line []byte) (JournalEntry, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return JournalEntry{}, fmt.Errorf("ошибка парсинга JSON: %w", err)
+	}
+
+	str := func(name string) string {
+		return journalFieldValue(fields[name])
+	}
+	num := func(name string) int {
+		n, _ := strconv.Atoi(str(name))
+		return n
+	}
+
+	entry := JournalEntry{
+		Priority:         PriorityInfo,
+		PID:              num("_PID"),
+		SyslogIdentifier: str("SYSLOG_IDENTIFIER"),
+		Unit:             str("_SYSTEMD_UNIT"),
+		Hostname:         str("_HOSTNAME"),
+		BootID:           str("_BOOT_ID"),
+		CodeFile:         str("CODE_FILE"),
+		CodeLine:         num("CODE_LINE"),
+		CodeFunc:         str("CODE_FUNC"),
+		Message:          str("MESSAGE"),
+	}
+
+	if priority, err := strconv.Atoi(str("PRIORITY")); err == nil && priority >= PriorityEmerg && priority <= PriorityDebug {
+		entry.Priority = priority
+	}
+
+	if usec, err := strconv.ParseInt(str("__REALTIME_TIMESTAMP"), 10, 64); err == nil {
+		entry.Timestamp = time.UnixMicro(usec)
+	}
+
+	return entry, nil
+}
+
+// Значение поля журнала. Обычно это строка, но значения с управляющими
+// символами или не в UTF-8 journalctl передает массивом байтов, а поле,
+// встречающееся в записи несколько раз, - массивом значений.
+func journalFieldValue(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+
+	var values []json.RawMessage
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return ""
+	}
+
+	var data []byte
+	if err := json.Unmarshal(raw, &data); err == nil {
+		return string(data)
+	}
+
+	parts := make([]string, 0, len(values))
+	for _, value := range values {
+		parts = append(parts, journalFieldValue(value))
+	}
+	return strings.Join(parts, "\n")
+}
+
+// Имя сервиса, к которому относится запись
+func (e JournalEntry) ServiceName() string {
+	if e.Unit != "" {
+		return strings.TrimSuffix(e.Unit, ".service")
+	}
+	return e.SyslogIdentifier
+}
+
+// Запись в формате journalctl -o short: время, источник[pid]: сообщение
+func (e JournalEntry) String() string {
+	return e.format(journalTimeLayout)
+}
+
+// Запись в формате journalctl -o short с заданным форматом времени
+func (e JournalEntry) format(timeLayout string) string {
+	var sb strings.Builder
+
+	if !e.Timestamp.IsZero() {
+		sb.WriteString(e.Timestamp.Format(timeLayout) + " ")
+	}
+
+	source := e.SyslogIdentifier
+	if source == "" {
+		source = strings.TrimSuffix(e.Unit, ".service")
+	}
+	if source != "" {
+		sb.WriteString(source)
+		if e.PID > 0 {
+			sb.WriteString("[" + strconv.Itoa(e.PID) + "]")
+		}
+		sb.WriteString(": ")
+	}
+
+	sb.WriteString(e.Message)
+	return sb.String()
+}
+
+// Стиль записи журнала по ее приоритету
+func PriorityStyle(priority int) lipgloss.Style {
+	switch {
+	case priority <= PriorityErr:
+		return PriorityErrStyle
+	case priority == PriorityWarning:
+		return PriorityWarningStyle
+	case priority == PriorityNotice:
+		return PriorityNoticeStyle
+	case priority == PriorityDebug:
+		return PriorityDebugStyle
+	default:
+		return PriorityInfoStyle
+	}
+}
+
+// Отрисовать запись журнала с цветом по приоритету
+func RenderJournalEntry(e JournalEntry) string {
+	return PriorityStyle(e.Priority).Render(e.String())
+}
+
+// Разобрать приоритет: название (err, warning, ...) или число 0-7
+func ParsePriority(value string) (int, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	if n, err := strconv.Atoi(value); err == nil && n >= PriorityEmerg && n <= PriorityDebug {
+		return n, nil
+	}
+
+	// Допускаем распространенные синонимы
+	switch value {
+	case "error":
+		value = "err"
+	case "warn":
+		value = "warning"
+	case "critical":
+		value = "crit"
+	case "emergency", "panic":
+		value = "emerg"
+	}
+
+	for i, name := range PriorityNames {
+		if name == value {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("неизвестный приоритет %q: ожидается %s или 0-7", value, strings.Join(PriorityNames, ", "))
+}
+
+// Параметры выборки записей журнала
+type LogQuery struct {
+	// Начало и конец интервала в формате journalctl --since/--until
+	// ("2024-01-15 10:00:00", "today", "-1h", "1 hour ago")
+	Since string
+	Until string
+	// Максимальный уровень приоритета (название или число), пусто - все записи
+	Priority string
+	// Загрузка: пусто - все, "0" - текущая, "-1" - предыдущая или ID загрузки
+	Boot string
+	// Количество последних записей, 0 - значение по умолчанию
+	Lines int
+	// Регулярное выражение для отбора по тексту сообщения
+	Grep string
+}
+
+// Проверить параметры выборки
+func (q LogQuery) Validate() error {
+	if q.Priority != "" {
+		if _, err := ParsePriority(q.Priority); err != nil {
+			return err
+		}
+	}
+
+	if q.Grep != "" {
+		if _, err := regexp.Compile(q.Grep); err != nil {
+			return fmt.Errorf("некорректное регулярное выражение: %w", err)
+		}
+	}
+
+	if q.Lines < 0 {
+		return errors.New("количество строк не может быть отрицательным")
+	}
+
+	if strings.ContainsAny(q.Boot, " \t") {
+		return fmt.Errorf("некорректная загрузка %q", q.Boot)
+	}
+
+	return nil
+}
+
+// Аргументы journalctl для выборки записей сервисов. Записи нескольких
+// сервисов journalctl сам выдает вперемешку в порядке времени. Отбор по Grep
+// выполняется при чтении, так как journalctl не всегда собран с PCRE.
+func (q LogQuery) JournalctlArgs(serviceNames []string, follow bool) []string {
+	var args []string
+	for _, name := range serviceNames {
+		args = append(args, "-u", name)
+	}
+	args = append(args, "--output=json", "--no-pager")
+
+	// С отбором по тексту количество ограничивается уже после отбора
+	if q.Lines > 0 && (q.Grep == "" || follow) {
+		args = append(args, "-n", strconv.Itoa(q.Lines))
+	}
+
+	if q.Since != "" {
+		args = append(args, "--since", q.Since)
+	}
+	if q.Until != "" {
+		args = append(args, "--until", q.Until)
+	}
+	if q.Priority != "" {
+		priority, _ := ParsePriority(q.Priority)
+		args = append(args, "-p", strconv.Itoa(priority))
+	}
+	if q.Boot != "" {
+		args = append(args, "-b", q.Boot)
+	}
+
+	// Записи после until не появятся, следить за журналом бессмысленно
+	if follow && q.Until == "" {
+		args = append(args, "-f")
+	}
+
+	return args
+}
+
+// Функция отбора записей по параметрам, которые можно проверить без
+// journalctl: приоритет, текст, ID загрузки и абсолютные границы времени
+func (q LogQuery) Matcher() (func(JournalEntry) bool, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	priority := PriorityDebug
+	if q.Priority != "" {
+		priority, _ = ParsePriority(q.Priority)
+	}
+
+	var grep *regexp.Regexp
+	if q.Grep != "" {
+		grep = regexp.MustCompile(q.Grep)
+	}
+
+	since, _ := parseJournalTime(q.Since)
+	until, _ := parseJournalTime(q.Until)
+
+	// Относительные загрузки (0, -1) проверяет только journalctl
+	bootID := ""
+	if len(q.Boot) == 32 {
+		bootID = q.Boot
+	}
+
+	return func(e JournalEntry) bool {
+		if e.Priority > priority {
+			return false
+		}
+		if grep != nil && !grep.MatchString(e.Message) {
+			return false
+		}
+		if bootID != "" && e.BootID != bootID {
+			return false
+		}
+		if !since.IsZero() && e.Timestamp.Before(since) {
+			return false
+		}
+		if !until.IsZero() && e.Timestamp.After(until) {
+			return false
+		}
+		return true
+	}, nil
+}
+
+// Разобрать абсолютное время в формате journalctl
+func parseJournalTime(value string) (time.Time, bool) {
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Разобрать параметры выборки из строки вида
+// `priority=warning since="1 hour ago" grep=timeout boot=0 lines=100`.
+// Допускаются короткие имена p, S, U, b, n, g.
+func ParseLogQuery(spec string) (LogQuery, error) {
+	var q LogQuery
+
+	tokens, err := splitQuoted(spec)
+	if err != nil {
+		return LogQuery{}, err
+	}
+
+	for _, token := range tokens {
+		key, value, ok := strings.Cut(token, "=")
+		if !ok {
+			// Слово без ключа считаем регулярным выражением
+			key, value = "grep", token
+		}
+
+		switch key {
+		case "since", "S":
+			q.Since = value
+		case "until", "U":
+			q.Until = value
+		case "priority", "p":
+			q.Priority = value
+		case "boot", "b":
+			q.Boot = value
+		case "lines", "n":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return LogQuery{}, fmt.Errorf("lines: некорректное число %q", value)
+			}
+			q.Lines = n
+		case "grep", "g":
+			q.Grep = value
+		default:
+			return LogQuery{}, fmt.Errorf("неизвестный параметр %q", key)
+		}
+	}
+
+	return q, q.Validate()
+}
+
+// Строковое представление параметров выборки в формате ParseLogQuery
+func (q LogQuery) String() string {
+	var parts []string
+	add := func(key, value string) {
+		if value == "" {
+			return
+		}
+		if strings.ContainsAny(value, " \t\"") {
+			value = strconv.Quote(value)
+		}
+		parts = append(parts, key+"="+value)
+	}
+
+	add("priority", q.Priority)
+	add("since", q.Since)
+	add("until", q.Until)
+	add("boot", q.Boot)
+	if q.Lines > 0 {
+		add("lines", strconv.Itoa(q.Lines))
+	}
+	add("grep", q.Grep)
+
+	return strings.Join(parts, " ")
+}
+
+// Разбить строку на слова с учетом кавычек: key="a b" -> key=a b
+func splitQuoted(s string) ([]string, error) {
+	var (
+		tokens  []string
+		current strings.Builder
+		quote   rune
+		started bool
+		escaped bool
+	)
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote == '"':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			started = true
+		case r == ' ' || r == '\t':
+			if started {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				started = false
+			}
+		default:
+			current.WriteRune(r)
+			started = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, errors.New("незакрытая кавычка")
+	}
+	if started {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens, nil
+}
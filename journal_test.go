@@ -0,0 +1,185 @@
+package sdmanager
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readJournalFixture(t *testing.T) []JournalEntry {
+	t.Helper()
+
+	file, err := os.Open("testdata/journal.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		entry, err := ParseJournalEntry(scanner.Bytes())
+		if err != nil {
+			t.Fatalf("ParseJournalEntry: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+func TestParseJournalEntry(t *testing.T) {
+	entries := readJournalFixture(t)
+	if len(entries) != 4 {
+		t.Fatalf("entries = %d", len(entries))
+	}
+
+	first := entries[0]
+	if !first.Timestamp.Equal(time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)) {
+		t.Errorf("Timestamp = %s", first.Timestamp)
+	}
+	if first.Priority != PriorityInfo || first.PID != 1234 || first.SyslogIdentifier != "api" || first.Hostname != "web-1" {
+		t.Errorf("first = %+v", first)
+	}
+
+	errEntry := entries[2]
+	if errEntry.Priority != PriorityErr || errEntry.CodeFile != "server.go" || errEntry.CodeLine != 42 || errEntry.CodeFunc != "main.serve" {
+		t.Errorf("err entry = %+v", errEntry)
+	}
+
+	// Без PRIORITY запись информационная, источник берется из имени unit.
+	// Сообщение с управляющими символами journalctl передает массивом байтов.
+	last := entries[3]
+	if last.Priority != PriorityInfo || last.PID != 0 || last.Message != "\x1b[31mpanic\x1b[0m" {
+		t.Errorf("last = %+v", last)
+	}
+	want := last.Timestamp.Format(journalTimeLayout) + " api: " + last.Message
+	if last.String() != want {
+		t.Errorf("String() = %q, want %q", last.String(), want)
+	}
+
+	if _, err := ParseJournalEntry([]byte("not json")); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
+
+func TestLogQueryMatcher(t *testing.T) {
+	entries := readJournalFixture(t)
+
+	tests := []struct {
+		query LogQuery
+		want  []string
+	}{
+		{LogQuery{}, []string{"listening on :8080", "slow request: 2.5s", "database timeout", "\x1b[31mpanic\x1b[0m"}},
+		{LogQuery{Priority: "warning"}, []string{"slow request: 2.5s", "database timeout"}},
+		{LogQuery{Priority: "3"}, []string{"database timeout"}},
+		{LogQuery{Grep: `request|timeout`}, []string{"slow request: 2.5s", "database timeout"}},
+		{LogQuery{Boot: "00000000000000000000000000000000"}, nil},
+		{LogQuery{Since: entries[1].Timestamp.Format("2006-01-02 15:04:05"), Until: entries[2].Timestamp.Format("2006-01-02 15:04:05")}, []string{"slow request: 2.5s", "database timeout"}},
+	}
+
+	for _, tt := range tests {
+		match, err := tt.query.Matcher()
+		if err != nil {
+			t.Fatalf("Matcher(%+v): %v", tt.query, err)
+		}
+
+		var got []string
+		for _, entry := range entries {
+			if match(entry) {
+				got = append(got, entry.Message)
+			}
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("query %+v: got %q, want %q", tt.query, got, tt.want)
+		}
+	}
+
+	for _, query := range []LogQuery{{Priority: "verbose"}, {Grep: "("}, {Lines: -1}} {
+		if _, err := query.Matcher(); err == nil {
+			t.Errorf("Matcher(%+v) succeeded", query)
+		}
+	}
+}
+
+func TestParseLogQuery(t *testing.T) {
+	query, err := ParseLogQuery(`p=warn since="1 hour ago" boot=-1 n=20 timeout`)
+	if err != nil {
+		t.Fatalf("ParseLogQuery: %v", err)
+	}
+
+	want := LogQuery{Priority: "warn", Since: "1 hour ago", Boot: "-1", Lines: 20, Grep: "timeout"}
+	if query != want {
+		t.Errorf("query = %+v, want %+v", query, want)
+	}
+
+	// String() возвращает строку, которую снова можно разобрать
+	again, err := ParseLogQuery(query.String())
+	if err != nil || again != query {
+		t.Errorf("round trip: %+v, %v", again, err)
+	}
+
+	args := query.JournalctlArgs([]string{"api", "worker"}, true)
+	wantArgs := []string{"-u", "api", "-u", "worker", "--output=json", "--no-pager", "-n", "20", "--since", "1 hour ago", "-p", "4", "-b", "-1", "-f"}
+	if !slices.Equal(args, wantArgs) {
+		t.Errorf("args = %q, want %q", args, wantArgs)
+	}
+
+	for _, spec := range []string{`since="unterminated`, "color=red", "lines=many"} {
+		if _, err := ParseLogQuery(spec); err == nil {
+			t.Errorf("ParseLogQuery(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestScanJournal(t *testing.T) {
+	fixture, err := os.ReadFile("testdata/journal.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Подсказка journalctl для пользователя без доступа к системному журналу
+	hint := "Hint: You are currently not seeing messages from other users and the system.\n" +
+		"      Users in groups 'adm', 'systemd-journal', 'wheel' can see all messages.\n"
+
+	var messages []string
+	err = ScanJournal(strings.NewReader(hint+string(fixture)), func(entry JournalEntry) error {
+		messages = append(messages, entry.Message)
+		return nil
+	})
+	if err != nil || len(messages) != 4 {
+		t.Errorf("ScanJournal = %v, %v", messages, err)
+	}
+
+	if err := ScanJournal(strings.NewReader("{broken\n"), func(JournalEntry) error { return nil }); err == nil {
+		t.Error("expected error for invalid JSON object")
+	}
+}
+
+func TestExecBackendLogsStderr(t *testing.T) {
+	fixture, err := filepath.Abs("testdata/journal.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// journalctl, который пишет подсказку в stderr, а записи в stdout
+	dir := t.TempDir()
+	script := "#!/bin/sh\necho 'Hint: You are currently not seeing messages from other users and the system.' >&2\ncat " + fixture + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "journalctl"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	entries, err := NewExecBackend().Logs(context.Background(), []string{"api"}, LogQuery{Lines: 2})
+	if err != nil {
+		t.Fatalf("Logs: %v", err)
+	}
+	if messages := logMessages(entries); !slices.Equal(messages, []string{"database timeout", "\x1b[31mpanic\x1b[0m"}) {
+		t.Errorf("messages = %q", messages)
+	}
+}
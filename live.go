@@ -0,0 +1,515 @@
+package sdmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Состояния экрана изменения ограничений на лету
+const (
+	TuneStateServiceName = iota
+	TuneStateView
+	TuneStateEdit
+)
+
+// Корень иерархии cgroup v2
+var CgroupRoot = "/sys/fs/cgroup"
+
+// Каталоги drop-in, в которые systemctl set-property записывает изменения:
+// постоянные и действующие до перезагрузки (--runtime). Они применяются
+// раньше каталогов unit-файлов.
+var ControlDirs = []string{"/etc/systemd/system.control", "/run/systemd/system.control"}
+
+// Ограничение, которое можно изменить у запущенного сервиса
+type liveProperty struct {
+	Key string
+	// Свойство в systemctl show
+	Property string
+	// Файл cgroup с действующим значением
+	CgroupFile string
+}
+
+// Ограничения, которые меняет systemctl set-property без перезапуска
+var liveProperties = []liveProperty{
+	{Key: "MemoryMax", Property: "MemoryMax", CgroupFile: "memory.max"},
+	{Key: "MemoryHigh", Property: "MemoryHigh", CgroupFile: "memory.high"},
+	{Key: "CPUQuota", Property: "CPUQuotaPerSecUSec", CgroupFile: "cpu.max"},
+	{Key: "AllowedCPUs", Property: "AllowedCPUs", CgroupFile: "cpuset.cpus"},
+	{Key: "TasksMax", Property: "TasksMax", CgroupFile: "pids.max"},
+	{Key: "IOWeight", Property: "IOWeight", CgroupFile: "io.weight"},
+}
+
+// Ограничение запущенного сервиса: значение в systemd, действующее значение
+// в cgroup и новое значение
+type LiveResource struct {
+	Key string
+	// Значение из свойств сервиса
+	Unit string
+	// Значение из файла cgroup; «-», если файл недоступен
+	Cgroup string
+	// Новое значение: пустое - не менять, «-» - вернуть значение по умолчанию
+	New string
+}
+
+// Прочитать текущие ограничения сервиса из его свойств и файлов cgroup
+func LoadLiveResources(ctx context.Context, b Backend, serviceName string) ([]LiveResource, error) {
+	names := []string{"LoadState", "ControlGroup"}
+	for _, property := range liveProperties {
+		names = append(names, property.Property)
+	}
+
+	props, err := b.Properties(ctx, serviceName, names...)
+	if err != nil {
+		return nil, err
+	}
+	if props["LoadState"] == "not-found" {
+		return nil, fmt.Errorf("%s: %w", serviceName, ErrUnitNotFound)
+	}
+
+	resources := make([]LiveResource, 0, len(liveProperties))
+	for _, property := range liveProperties {
+		resources = append(resources, LiveResource{
+			Key:    property.Key,
+			Unit:   formatLiveProperty(property.Key, props[property.Property]),
+			Cgroup: readCgroupValue(props["ControlGroup"], property),
+		})
+	}
+	return resources, nil
+}
+
+// Значение свойства systemctl show в записи unit-файла
+func formatLiveProperty(key, value string) string {
+	switch value {
+	case "", "[not set]":
+		return "-"
+	case "18446744073709551615":
+		// По D-Bus незаданный вес передается максимальным значением
+		if key == "IOWeight" {
+			return "-"
+		}
+		return "infinity"
+	case "infinity":
+		return "infinity"
+	}
+
+	switch key {
+	case "MemoryMax", "MemoryHigh":
+		if bytes, err := strconv.ParseUint(value, 10, 64); err == nil {
+			return formatBytes(bytes)
+		}
+	case "CPUQuota":
+		// CPUQuotaPerSecUSec: время CPU в секунду, "500ms" или "1.500000s";
+		// без единицы - микросекунды
+		quota, err := ParseTimespan(value)
+		if usec, errUsec := strconv.ParseUint(value, 10, 64); errUsec == nil {
+			quota, err = time.Duration(usec)*time.Microsecond, nil
+		}
+		if err == nil {
+			return formatPercent(float64(quota) * 100 / float64(time.Second))
+		}
+	}
+	return value
+}
+
+// Действующее значение ограничения из файла cgroup сервиса
+func readCgroupValue(controlGroup string, property liveProperty) string {
+	if controlGroup == "" {
+		return "-"
+	}
+	data, err := os.ReadFile(filepath.Join(CgroupRoot, controlGroup, property.CgroupFile))
+	if err != nil {
+		return "-"
+	}
+	return formatCgroupValue(property.Key, string(data))
+}
+
+// Значение файла cgroup в записи unit-файла: "max" - infinity, cpu.max
+// "50000 100000" - 50%, io.weight "default 100" - 100
+func formatCgroupValue(key, value string) string {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return "-"
+	}
+	if fields[0] == "max" {
+		return "infinity"
+	}
+
+	switch key {
+	case "MemoryMax", "MemoryHigh":
+		if bytes, err := strconv.ParseUint(fields[0], 10, 64); err == nil {
+			return formatBytes(bytes)
+		}
+	case "CPUQuota":
+		quota, errQuota := strconv.ParseInt(fields[0], 10, 64)
+		if len(fields) == 2 {
+			if period, err := strconv.ParseInt(fields[1], 10, 64); errQuota == nil && err == nil && period > 0 {
+				return formatPercent(float64(quota) * 100 / float64(period))
+			}
+		}
+	case "IOWeight":
+		return fields[len(fields)-1]
+	}
+	return fields[0]
+}
+
+// Конфигурация из новых значений ограничений для проверки
+func liveConfig(resources []LiveResource) (ServiceConfig, error) {
+	var config ServiceConfig
+	for _, resource := range resources {
+		value := strings.TrimSpace(resource.New)
+		if value == "" || value == "-" {
+			continue
+		}
+
+		switch resource.Key {
+		case "MemoryMax":
+			config.MemoryMax = MemorySize(value)
+		case "MemoryHigh":
+			config.MemoryHigh = MemorySize(value)
+		case "CPUQuota":
+			quota, err := ParseCPUQuota(value)
+			if err != nil || quota <= 0 {
+				return config, errors.New("CPUQuota: ожидается процент больше 0, например 50%")
+			}
+			config.CPUQuota = quota
+		case "AllowedCPUs":
+			config.AllowedCPUs = value
+		case "TasksMax":
+			config.TasksMax = value
+		case "IOWeight":
+			weight, err := strconv.Atoi(value)
+			if err != nil || weight <= 0 {
+				return config, errors.New("IOWeight: допустимы значения от 1 до 10000")
+			}
+			config.IOWeight = weight
+		}
+	}
+	return config, nil
+}
+
+// Проверить новые значения ограничений с учетом ресурсов машины
+func ValidateLiveResources(resources []LiveResource, host HostResources) error {
+	config, err := liveConfig(resources)
+	if err != nil {
+		return err
+	}
+	return ValidateResources(config, host)
+}
+
+// Присваивание для systemctl set-property: «-» сбрасывает ограничение,
+// число мегабайт и процентов дополняется единицей
+func liveAssignment(resource LiveResource) string {
+	value := strings.TrimSpace(resource.New)
+	switch {
+	case value == "-":
+		value = ""
+	case resource.Key == "MemoryMax" || resource.Key == "MemoryHigh":
+		value = MemorySize(value).UnitValue()
+	case resource.Key == "CPUQuota" && !strings.HasSuffix(value, "%"):
+		value += "%"
+	}
+	return resource.Key + "=" + value
+}
+
+// Изменить ограничения запущенного сервиса без перезапуска. При runtime
+// изменения действуют до перезагрузки, иначе systemd сохраняет их в drop-in
+// каталога /etc/systemd/system.control, который можно отменить
+// RevertLiveResources.
+func ApplyLiveResources(ctx context.Context, b Backend, serviceName string, resources []LiveResource, runtime bool) (string, error) {
+	if err := ValidateLiveResources(resources, LocalHostResources()); err != nil {
+		return "", err
+	}
+
+	var assignments []string
+	for _, resource := range resources {
+		if strings.TrimSpace(resource.New) != "" {
+			assignments = append(assignments, liveAssignment(resource))
+		}
+	}
+	if len(assignments) == 0 {
+		return "", errors.New("новые значения не заданы")
+	}
+
+	if err := b.SetProperty(ctx, serviceName, runtime, assignments...); err != nil {
+		return "", err
+	}
+
+	result := "Ограничения изменены: " + strings.Join(assignments, ", ")
+	if runtime {
+		return result + " (до перезагрузки)", nil
+	}
+	return result + "\nDrop-in: " + DropInDir(ControlDirs[0], serviceName), nil
+}
+
+// Drop-in файлы, которые systemctl set-property создал для ограничений
+// сервиса: 50-MemoryMax.conf и т.п.
+func LiveDropIns(serviceName string) ([]DropIn, error) {
+	var dropIns []DropIn
+	for _, dir := range ControlDirs {
+		for _, property := range liveProperties {
+			path := filepath.Join(DropInDir(dir, serviceName), "50-"+property.Key+".conf")
+			content, err := os.ReadFile(path)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return nil, fmt.Errorf("ошибка чтения drop-in файла: %w", err)
+			}
+			dropIns = append(dropIns, DropIn{Name: filepath.Base(path), Path: path, Content: string(content)})
+		}
+	}
+	return dropIns, nil
+}
+
+// Отменить изменения ограничений: удалить drop-in файлы set-property и
+// выполнить daemon-reload, после чего снова действуют значения unit-файла
+func RevertLiveResources(ctx context.Context, b Backend, serviceName string) (string, error) {
+	dropIns, err := LiveDropIns(serviceName)
+	if err != nil {
+		return "", err
+	}
+	if len(dropIns) == 0 {
+		return "", errors.New("ограничения не изменялись через set-property")
+	}
+
+	var messages []string
+	for _, dropIn := range dropIns {
+		if err := RemoveDropIn(dropIn.Path); err != nil {
+			return strings.Join(messages, "\n"), err
+		}
+		messages = append(messages, "Drop-in удален: "+dropIn.Path)
+	}
+
+	return applyServiceChanges(ctx, b, serviceName, UserActions{ReloadDaemon: true}, messages...)
+}
+
+// Создать модель экрана изменения ограничений на лету
+func NewTuneModel(appOptions AppOptions) TuneModel {
+	ti := textinput.New()
+	ti.Placeholder = "myservice"
+	ti.Focus()
+	ti.CharLimit = 255
+	ti.Width = 80
+
+	return TuneModel{
+		State:   TuneStateServiceName,
+		Input:   ti,
+		backend: appOptions.backend,
+	}
+}
+
+// Загрузить текущие ограничения сервиса
+func LoadTune(ctx context.Context, model TuneModel, serviceName string) TuneModel {
+	resources, err := LoadLiveResources(ctx, model.backend, serviceName)
+	if err != nil {
+		model.Error = err.Error()
+		return model
+	}
+
+	dropIns, err := LiveDropIns(serviceName)
+	if err != nil {
+		model.Error = err.Error()
+		return model
+	}
+
+	model.ServiceName = serviceName
+	model.Resources = resources
+	model.DropIns = dropIns
+	model.Current = min(model.Current, len(resources)-1)
+	model.State = TuneStateView
+	model.Error = ""
+	return model
+}
+
+// Обработка событий экрана изменения ограничений на лету
+func UpdateTune(ctx context.Context, msg tea.Msg, model TuneModel) (TuneModel, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+
+	switch model.State {
+	case TuneStateView:
+		if !ok {
+			return model, nil
+		}
+
+		if model.ConfirmRevert {
+			model.ConfirmRevert = false
+			if keyMsg.String() != "y" && keyMsg.String() != "Y" {
+				return model, nil
+			}
+			result, err := RevertLiveResources(ctx, model.backend, model.ServiceName)
+			if err != nil {
+				model.Error = err.Error()
+				return model, nil
+			}
+			model = LoadTune(ctx, model, model.ServiceName)
+			model.Message = result
+			return model, nil
+		}
+
+		model.Error = ""
+		switch keyMsg.String() {
+		case "up", "k":
+			model.Current = (model.Current - 1 + len(model.Resources)) % len(model.Resources)
+		case "down", "j":
+			model.Current = (model.Current + 1) % len(model.Resources)
+		case "enter", "e":
+			resource := model.Resources[model.Current]
+			model.State = TuneStateEdit
+			model.Input.SetValue(resource.New)
+			model.Input.Placeholder = resource.Unit
+		case "t":
+			model.Runtime = !model.Runtime
+		case "w":
+			result, err := ApplyLiveResources(ctx, model.backend, model.ServiceName, model.Resources, model.Runtime)
+			if err != nil {
+				model.Error = err.Error()
+				return model, nil
+			}
+			model = LoadTune(ctx, model, model.ServiceName)
+			model.Message = result
+		case "u":
+			if len(model.DropIns) == 0 {
+				model.Error = "ограничения не изменялись через set-property"
+				return model, nil
+			}
+			model.ConfirmRevert = true
+		case "r":
+			model.Message = ""
+			return LoadTune(ctx, model, model.ServiceName), nil
+		case "q", "esc", "ctrl+c":
+			model.Quitting = true
+		}
+		return model, nil
+
+	case TuneStateEdit:
+		if ok {
+			switch keyMsg.Type {
+			case tea.KeyEnter:
+				resource := model.Resources[model.Current]
+				resource.New = strings.TrimSpace(model.Input.Value())
+				if err := ValidateLiveResources([]LiveResource{resource}, LocalHostResources()); err != nil {
+					model.Error = err.Error()
+					return model, nil
+				}
+				model.Resources[model.Current] = resource
+				model.State = TuneStateView
+				model.Error = ""
+				return model, nil
+			case tea.KeyEsc:
+				model.State = TuneStateView
+				model.Error = ""
+				return model, nil
+			}
+		}
+
+		var cmd tea.Cmd
+		model.Input, cmd = model.Input.Update(msg)
+		return model, cmd
+	}
+
+	if ok {
+		switch keyMsg.Type {
+		case tea.KeyEnter:
+			// Если есть предыдущая ошибка, просто очищаем её
+			if model.Error != "" {
+				model.Error = ""
+				return model, nil
+			}
+
+			serviceName := model.Input.Value()
+			if err := IsValidServiceName(serviceName); err != nil {
+				model.Error = err.Error()
+				return model, nil
+			}
+
+			model.Input.SetValue("")
+			return LoadTune(ctx, model, strings.TrimSuffix(serviceName, ".service")), nil
+
+		case tea.KeyEsc, tea.KeyCtrlC:
+			model.Quitting = true
+			return model, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	model.Input, cmd = model.Input.Update(msg)
+	return model, cmd
+}
+
+// Таблица ограничений: значение в systemd, в cgroup и новое значение
+func RenderLiveResources(resources []LiveResource, current int) string {
+	var sb strings.Builder
+	sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("245")).Render(fmt.Sprintf("    %-14s %-12s %-12s %s", "Ограничение", "systemd", "cgroup", "Новое")) + "\n")
+	for i, resource := range resources {
+		line := fmt.Sprintf("%-14s %-12s %-12s %s", resource.Key, resource.Unit, resource.Cgroup, resource.New)
+		if i == current {
+			sb.WriteString(SelectedItemStyle.Render("> "+line) + "\n")
+		} else {
+			sb.WriteString(ItemStyle.Render(line) + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// Отрисовка экрана изменения ограничений на лету
+func ViewTune(model TuneModel) string {
+	var s strings.Builder
+
+	if model.State == TuneStateServiceName {
+		s.WriteString("Введите имя запущенного сервиса для изменения ограничений:\n\n")
+		s.WriteString(model.Input.View() + "\n\n")
+		if model.Error != "" {
+			s.WriteString(FormatError(model.Error) + "\n\n")
+		}
+		s.WriteString("Нажмите Enter для подтверждения, Esc для возврата в меню\n")
+		return s.String()
+	}
+
+	mode := "постоянно (drop-in в " + ControlDirs[0] + ")"
+	if model.Runtime {
+		mode = "до перезагрузки (--runtime)"
+	}
+	s.WriteString(TitleStyle.Render(model.ServiceName) + "  Изменения: " + mode + "\n\n")
+	s.WriteString(RenderLiveResources(model.Resources, model.Current) + "\n")
+
+	for _, dropIn := range model.DropIns {
+		s.WriteString("Drop-in set-property: " + dropIn.Path + "\n")
+	}
+	if len(model.DropIns) > 0 {
+		s.WriteString("\n")
+	}
+
+	if model.State == TuneStateEdit {
+		resource := model.Resources[model.Current]
+		s.WriteString(fmt.Sprintf("Новое значение %s («-» - по умолчанию, пусто - не менять):\n", resource.Key))
+		s.WriteString(model.Input.View() + "\n\n")
+	}
+
+	if model.Message != "" {
+		s.WriteString(FormatInfo(model.Message) + "\n")
+	}
+	if model.Error != "" {
+		s.WriteString(FormatError(model.Error) + "\n")
+	}
+
+	switch {
+	case model.ConfirmRevert:
+		s.WriteString("Удалить drop-in файлы set-property и вернуть значения unit-файла? (y/n)\n")
+	case model.State == TuneStateEdit:
+		s.WriteString("Enter - сохранить, Esc - отмена\n")
+	default:
+		s.WriteString("↑/↓ - выбор, Enter - новое значение, t - постоянно/до перезагрузки, w - применить, u - отменить изменения, r - обновить, Esc - назад\n")
+	}
+
+	return s.String()
+}
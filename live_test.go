@@ -0,0 +1,182 @@
+package sdmanager
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/godbus/dbus/v5"
+)
+
+// Backend с запущенным сервисом api, cgroup из testdata и временными
+// каталогами set-property
+func newLiveBackend(t *testing.T) *FakeBackend {
+	cgroupRoot, controlDirs := CgroupRoot, ControlDirs
+	t.Cleanup(func() { CgroupRoot, ControlDirs = cgroupRoot, controlDirs })
+	CgroupRoot = "testdata/cgroup"
+	ControlDirs = []string{filepath.Join(t.TempDir(), "etc"), filepath.Join(t.TempDir(), "run")}
+
+	backend := NewFakeBackend(UnitInfo{Name: "api", ActiveState: "active", SubState: "running"})
+	backend.SetProperties("api", map[string]string{
+		"ControlGroup":       "/system.slice/api.service",
+		"MemoryMax":          "536870912",
+		"MemoryHigh":         "infinity",
+		"CPUQuotaPerSecUSec": "500ms",
+		"AllowedCPUs":        "",
+		"TasksMax":           "4915",
+		"IOWeight":           "[not set]",
+	})
+	return backend
+}
+
+func TestLiveResources(t *testing.T) {
+	backend := newLiveBackend(t)
+	ctx := context.Background()
+
+	resources, err := LoadLiveResources(ctx, backend, "api")
+	if err != nil {
+		t.Fatalf("LoadLiveResources: %v", err)
+	}
+	want := []LiveResource{
+		{Key: "MemoryMax", Unit: "512.0M", Cgroup: "512.0M"},
+		{Key: "MemoryHigh", Unit: "infinity", Cgroup: "infinity"},
+		{Key: "CPUQuota", Unit: "50%", Cgroup: "50%"},
+		{Key: "AllowedCPUs", Unit: "-", Cgroup: "-"},
+		{Key: "TasksMax", Unit: "4915", Cgroup: "4915"},
+		{Key: "IOWeight", Unit: "-", Cgroup: "100"},
+	}
+	if !slices.Equal(resources, want) {
+		t.Errorf("resources = %+v", resources)
+	}
+
+	resources[0].New = "256"
+	resources[2].New = "25"
+	resources[5].New = "-"
+	if _, err := ApplyLiveResources(ctx, backend, "api", resources, false); err != nil {
+		t.Fatalf("ApplyLiveResources: %v", err)
+	}
+	props, _ := backend.Properties(ctx, "api", "MemoryMax", "CPUQuota", "IOWeight")
+	if props["MemoryMax"] != "256M" || props["CPUQuota"] != "25%" || props["IOWeight"] != "" {
+		t.Errorf("properties = %v", props)
+	}
+	if calls := backend.Calls(); !slices.Contains(calls, "set-property api") {
+		t.Errorf("calls = %v", calls)
+	}
+
+	host := HostResources{Memory: 1 << 30, CPUs: 2}
+	for _, resource := range []LiveResource{
+		{Key: "MemoryMax", New: "2G"},
+		{Key: "AllowedCPUs", New: "0-3"},
+		{Key: "CPUQuota", New: "abc"},
+	} {
+		if err := ValidateLiveResources([]LiveResource{resource}, host); err == nil {
+			t.Errorf("%s=%s accepted", resource.Key, resource.New)
+		}
+	}
+
+	// Постоянные изменения systemd сохраняет в drop-in, который видно в
+	// списке drop-in и можно удалить
+	path := filepath.Join(DropInDir(ControlDirs[0], "api"), "50-MemoryMax.conf")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("[Service]\nMemoryMax=268435456\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dropIns, err := ListDropIns("api")
+	if err != nil || len(dropIns) != 1 || dropIns[0].Path != path {
+		t.Fatalf("ListDropIns = %+v, %v", dropIns, err)
+	}
+
+	result, err := RevertLiveResources(ctx, backend, "api")
+	if err != nil {
+		t.Fatalf("RevertLiveResources: %v", err)
+	}
+	if FileExists(path) || !strings.Contains(result, "Systemd daemon перезагружен") {
+		t.Errorf("drop-in was not reverted: %s", result)
+	}
+	if _, err := RevertLiveResources(ctx, backend, "api"); err == nil {
+		t.Error("revert without set-property drop-ins succeeded")
+	}
+}
+
+func TestFormatLiveProperty(t *testing.T) {
+	// Значения в виде systemctl show и в виде свойств D-Bus
+	dbusValue := func(name string, value any) string {
+		s, ok := formatDBusProperty(name, dbus.MakeVariant(value))
+		if !ok {
+			t.Errorf("formatDBusProperty(%s, %v) dropped the value", name, value)
+		}
+		return s
+	}
+
+	tests := []struct {
+		key, value, want string
+	}{
+		{"CPUQuota", "500ms", "50%"},
+		{"CPUQuota", "1.500000s", "150%"},
+		{"CPUQuota", "2s", "200%"},
+		{"CPUQuota", "500000", "50%"},
+		{"CPUQuota", "125ms", "12.5%"},
+		{"CPUQuota", dbusValue("CPUQuotaPerSecUSec", uint64(250000)), "25%"},
+		{"CPUQuota", dbusValue("CPUQuotaPerSecUSec", uint64(1500000)), "150%"},
+		{"CPUQuota", dbusValue("CPUQuotaPerSecUSec", uint64(math.MaxUint64)), "infinity"},
+		{"AllowedCPUs", "0-1 3", "0-1 3"},
+		{"AllowedCPUs", dbusValue("AllowedCPUs", []byte{0x0b}), "0-1 3"},
+		{"AllowedCPUs", dbusValue("AllowedCPUs", []byte{0x00, 0xf0, 0x01}), "12-16"},
+		{"AllowedCPUs", dbusValue("AllowedCPUs", []byte{}), "-"},
+		{"MemoryMax", dbusValue("MemoryMax", uint64(536870912)), "512.0M"},
+		{"MemoryHigh", dbusValue("MemoryHigh", uint64(math.MaxUint64)), "infinity"},
+		{"IOWeight", dbusValue("IOWeight", uint64(math.MaxUint64)), "-"},
+		{"IOWeight", dbusValue("IOWeight", uint64(200)), "200"},
+	}
+	for _, tt := range tests {
+		if got := formatLiveProperty(tt.key, tt.value); got != tt.want {
+			t.Errorf("formatLiveProperty(%s, %q) = %q, want %q", tt.key, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestTuneScreen(t *testing.T) {
+	backend := newLiveBackend(t)
+	ctx := context.Background()
+
+	model := NewTuneModel(AppOptions{backend: backend})
+	model.Input.SetValue("api")
+	model, _ = UpdateTune(ctx, tea.KeyMsg{Type: tea.KeyEnter}, model)
+	if model.State != TuneStateView || model.Error != "" {
+		t.Fatalf("state = %d, error = %q", model.State, model.Error)
+	}
+
+	// Новое значение MemoryHigh, изменение только до перезагрузки
+	model, _ = UpdateTune(ctx, tea.KeyMsg{Type: tea.KeyDown}, model)
+	model, _ = UpdateTune(ctx, tea.KeyMsg{Type: tea.KeyEnter}, model)
+	model.Input.SetValue("128M")
+	model, _ = UpdateTune(ctx, tea.KeyMsg{Type: tea.KeyEnter}, model)
+	if model.State != TuneStateView || model.Resources[1].New != "128M" {
+		t.Fatalf("state = %d, resources = %+v, error = %q", model.State, model.Resources, model.Error)
+	}
+	if view := ViewTune(model); !strings.Contains(view, "128M") || !strings.Contains(view, "512.0M") {
+		t.Errorf("view does not show current and new values:\n%s", view)
+	}
+
+	model, _ = UpdateTune(ctx, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("t")}, model)
+	model, _ = UpdateTune(ctx, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("w")}, model)
+	if model.Error != "" || !strings.Contains(model.Message, "MemoryHigh=128M") {
+		t.Fatalf("message = %q, error = %q", model.Message, model.Error)
+	}
+	if calls := backend.Calls(); !slices.Contains(calls, "set-property-runtime api") {
+		t.Errorf("calls = %v", calls)
+	}
+
+	// Без drop-in отменять нечего
+	model, _ = UpdateTune(ctx, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("u")}, model)
+	if model.ConfirmRevert || model.Error == "" {
+		t.Errorf("revert without drop-ins: confirm = %v, error = %q", model.ConfirmRevert, model.Error)
+	}
+}
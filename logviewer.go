@@ -0,0 +1,626 @@
+package sdmanager
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Параметры просмотра журнала
+const (
+	// Сколько последних записей загружать при открытии
+	LogInitialLines = 200
+	// Максимальное количество строк в буфере просмотра
+	LogBufferLimit = 5000
+	// Максимальное количество строк, обрабатываемых за одно сообщение
+	logBatchSize = 256
+)
+
+// Состояния просмотра журнала
+const (
+	LogStateServiceName = iota
+	LogStateView
+	LogStateSearch
+	LogStateFilter
+	LogStateExport
+)
+
+// Стили просмотра журнала
+var (
+	LogMatchStyle  = lipgloss.NewStyle().Background(lipgloss.Color("220")).Foreground(lipgloss.Color("0"))
+	LogStatusStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	LogPausedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Bold(true)
+)
+
+// Цвета префиксов сервисов в общем журнале
+var ServiceColors = []lipgloss.Color{"39", "208", "141", "42", "205", "45", "178", "99", "118", "169", "33", "209"}
+
+// Стиль префикса сервиса. Цвет выбирается по хешу имени, поэтому у сервиса
+// он один и тот же при каждом открытии журнала.
+func ServiceStyle(serviceName string) lipgloss.Style {
+	h := fnv.New32a()
+	h.Write([]byte(serviceName))
+	return lipgloss.NewStyle().Foreground(ServiceColors[h.Sum32()%uint32(len(ServiceColors))])
+}
+
+// Префикс записи общего журнала: имя сервиса его цветом, выровненное по ширине
+func ServicePrefix(serviceName string, width int) string {
+	return ServiceStyle(serviceName).Render(fmt.Sprintf("%-*s", width, serviceName)) + " "
+}
+
+// Ширина префикса общего журнала; для одного сервиса префикс не нужен
+func ServicePrefixWidth(serviceNames []string) int {
+	if len(serviceNames) < 2 {
+		return 0
+	}
+
+	width := 0
+	for _, name := range serviceNames {
+		width = max(width, len(name))
+	}
+	return width
+}
+
+// Новые записи журнала
+type logLinesMsg struct {
+	id      int64
+	entries []JournalEntry
+}
+
+// Чтение журнала завершено
+type logStreamEndMsg struct {
+	id int64
+}
+
+// Прогресс экспорта журнала
+type logExportProgressMsg struct {
+	done  int
+	total int
+}
+
+// Экспорт журнала завершен
+type logExportDoneMsg struct {
+	path  string
+	count int
+	err   error
+}
+
+// Создать модель просмотра журнала
+func NewLogViewerModel(appOptions AppOptions) LogViewerModel {
+	ti := textinput.New()
+	ti.Placeholder = "api worker scheduler"
+	ti.Focus()
+	ti.CharLimit = 1024
+	ti.Width = 80
+
+	search := textinput.New()
+	search.Prompt = "/"
+	search.CharLimit = 255
+
+	filter := textinput.New()
+	filter.Prompt = "Фильтр: "
+	filter.Placeholder = `priority=warning since="1 hour ago" boot=0 grep=timeout`
+	filter.CharLimit = 512
+	filter.Width = 80
+
+	export := textinput.New()
+	export.Prompt = "Файл: "
+	export.CharLimit = 4096
+	export.Width = 80
+
+	return LogViewerModel{
+		State:       LogStateServiceName,
+		Input:       ti,
+		Search:      search,
+		FilterInput: filter,
+		ExportInput: export,
+		Viewport:    viewport.New(100, 20),
+		backend:     appOptions.backend,
+	}
+}
+
+// Начать чтение журнала одного или нескольких сервисов с текущим фильтром.
+// Записи нескольких сервисов идут одним потоком в порядке времени. Предыдущее
+// чтение, если было, останавливается.
+func FollowServiceLogs(ctx context.Context, model LogViewerModel, serviceNames ...string) (LogViewerModel, tea.Cmd) {
+	model = StopFollowing(model)
+
+	query := model.Filter
+	if query.Lines == 0 {
+		query.Lines = LogInitialLines
+	}
+
+	followCtx, cancel := context.WithCancel(ctx)
+	entries, err := model.backend.FollowLogs(followCtx, serviceNames, query)
+	if err != nil {
+		cancel()
+		model.Error = err.Error()
+		return model, nil
+	}
+
+	model.Services = serviceNames
+	model.State = LogStateView
+	model.Entries = nil
+	model.Pending = nil
+	model.Paused = false
+	model.Following = true
+	model.Error = ""
+	model.cancel = cancel
+	model.stream = entries
+	model.streamID = time.Now().UnixNano()
+	model = refreshLogViewport(model)
+
+	return model, waitForLogLines(model.streamID, entries)
+}
+
+// Остановить чтение журнала
+func StopFollowing(model LogViewerModel) LogViewerModel {
+	if model.cancel != nil {
+		model.cancel()
+	}
+	model.cancel = nil
+	model.stream = nil
+	model.Following = false
+	return model
+}
+
+// Начать экспорт журнала с текущим фильтром в фоне. Количество записей
+// ограничивается только явно заданным в фильтре lines.
+func StartLogExport(ctx context.Context, model LogViewerModel, path string) (LogViewerModel, tea.Cmd) {
+	exportCtx, cancel := context.WithCancel(ctx)
+	events := make(chan tea.Msg, 1)
+
+	go func() {
+		defer close(events)
+
+		count, err := ExportLogs(exportCtx, model.backend, model.Services, model.Filter, path, ExportOptionsFromPath(path), func(done, total int) {
+			// Промежуточный прогресс можно пропустить, если экран не успевает
+			select {
+			case events <- logExportProgressMsg{done: done, total: total}:
+			default:
+			}
+		})
+		events <- logExportDoneMsg{path: path, count: count, err: err}
+	}()
+
+	model.Exporting = true
+	model.ExportDone = 0
+	model.ExportTotal = 0
+	model.Message = ""
+	model.Error = ""
+	model.exportCancel = cancel
+	model.exportEvents = events
+	return model, waitForExport(events)
+}
+
+// Отменить экспорт журнала
+func CancelLogExport(model LogViewerModel) LogViewerModel {
+	if model.exportCancel != nil {
+		model.exportCancel()
+	}
+	model.exportCancel = nil
+	model.exportEvents = nil
+	model.Exporting = false
+	return model
+}
+
+// Дождаться следующего события экспорта
+func waitForExport(events <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-events
+	}
+}
+
+// Дождаться следующей записи журнала и забрать все уже доступные записи,
+// чтобы не перерисовывать экран на каждую запись
+func waitForLogLines(id int64, stream <-chan JournalEntry) tea.Cmd {
+	return func() tea.Msg {
+		entry, ok := <-stream
+		if !ok {
+			return logStreamEndMsg{id: id}
+		}
+
+		entries := []JournalEntry{entry}
+		for len(entries) < logBatchSize {
+			select {
+			case entry, ok := <-stream:
+				if !ok {
+					return logLinesMsg{id: id, entries: entries}
+				}
+				entries = append(entries, entry)
+			default:
+				return logLinesMsg{id: id, entries: entries}
+			}
+		}
+		return logLinesMsg{id: id, entries: entries}
+	}
+}
+
+// Добавить записи в буфер с учетом ограничения его размера
+func appendLogLines(buffer, entries []JournalEntry) []JournalEntry {
+	buffer = append(buffer, entries...)
+	if len(buffer) > LogBufferLimit {
+		buffer = append([]JournalEntry(nil), buffer[len(buffer)-LogBufferLimit:]...)
+	}
+	return buffer
+}
+
+// Регулярное выражение для поиска без учета регистра
+func logSearchPattern(query string) *regexp.Regexp {
+	if query == "" {
+		return nil
+	}
+	return regexp.MustCompile("(?i)" + regexp.QuoteMeta(query))
+}
+
+// Отрисовать запись с цветом по приоритету и подсветкой совпадений. При
+// prefixWidth > 0 строка начинается с имени сервиса его цветом.
+// Стили применяются к отдельным фрагментам, чтобы не вкладывать их друг в друга.
+func renderLogLine(entry JournalEntry, pattern *regexp.Regexp, prefixWidth int) string {
+	var sb strings.Builder
+	if prefixWidth > 0 {
+		sb.WriteString(ServicePrefix(entry.ServiceName(), prefixWidth))
+	}
+
+	style := PriorityStyle(entry.Priority)
+	line := entry.String()
+	if pattern == nil {
+		sb.WriteString(style.Render(line))
+		return sb.String()
+	}
+
+	last := 0
+	for _, match := range pattern.FindAllStringIndex(line, -1) {
+		if match[0] > last {
+			sb.WriteString(style.Render(line[last:match[0]]))
+		}
+		sb.WriteString(LogMatchStyle.Render(line[match[0]:match[1]]))
+		last = match[1]
+	}
+	if last < len(line) {
+		sb.WriteString(style.Render(line[last:]))
+	}
+
+	return sb.String()
+}
+
+// Обновить содержимое viewport. Если пользователь был в конце журнала,
+// остаемся в конце; иначе сохраняем позицию прокрутки.
+func refreshLogViewport(model LogViewerModel) LogViewerModel {
+	atBottom := model.Viewport.AtBottom()
+	pattern := logSearchPattern(model.SearchQuery)
+
+	prefixWidth := ServicePrefixWidth(model.Services)
+
+	rendered := make([]string, len(model.Entries))
+	for i, entry := range model.Entries {
+		rendered[i] = renderLogLine(entry, pattern, prefixWidth)
+	}
+	model.Viewport.SetContent(strings.Join(rendered, "\n"))
+
+	if atBottom {
+		model.Viewport.GotoBottom()
+	}
+	return model
+}
+
+// Перейти к следующему (step = 1) или предыдущему (step = -1) совпадению
+func jumpToMatch(model LogViewerModel, step int) LogViewerModel {
+	pattern := logSearchPattern(model.SearchQuery)
+	if pattern == nil || len(model.Entries) == 0 {
+		return model
+	}
+
+	n := len(model.Entries)
+	start := model.Viewport.YOffset
+	for i := 1; i <= n; i++ {
+		idx := ((start+step*i)%n + n) % n
+		if pattern.MatchString(model.Entries[idx].String()) {
+			model.Viewport.SetYOffset(idx)
+			model.Message = ""
+			return model
+		}
+	}
+
+	model.Message = fmt.Sprintf("Совпадений для %q нет", model.SearchQuery)
+	return model
+}
+
+// Обработка событий просмотра журнала
+func UpdateLogViewer(ctx context.Context, msg tea.Msg, model LogViewerModel) (LogViewerModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		model.Viewport.Width = msg.Width
+		model.Viewport.Height = max(msg.Height-6, 3)
+		return refreshLogViewport(model), nil
+
+	case logLinesMsg:
+		// Сообщения от предыдущего чтения журнала игнорируем
+		if msg.id != model.streamID || model.stream == nil {
+			return model, nil
+		}
+
+		if model.Paused {
+			model.Pending = appendLogLines(model.Pending, msg.entries)
+		} else {
+			model.Entries = appendLogLines(model.Entries, msg.entries)
+			model = refreshLogViewport(model)
+		}
+		return model, waitForLogLines(model.streamID, model.stream)
+
+	case logStreamEndMsg:
+		if msg.id == model.streamID {
+			model = StopFollowing(model)
+			model.Message = "Чтение журнала завершено"
+		}
+		return model, nil
+
+	case logExportProgressMsg:
+		if !model.Exporting {
+			return model, nil
+		}
+		model.ExportDone = msg.done
+		model.ExportTotal = msg.total
+		return model, waitForExport(model.exportEvents)
+
+	case logExportDoneMsg:
+		if !model.Exporting {
+			return model, nil
+		}
+		model = CancelLogExport(model)
+		if msg.err != nil {
+			model.Error = "экспорт не выполнен: " + msg.err.Error()
+			return model, nil
+		}
+		model.Message = fmt.Sprintf("Экспортировано записей: %d, файл %s", msg.count, msg.path)
+		return model, nil
+
+	case tea.KeyMsg:
+		switch model.State {
+		case LogStateServiceName:
+			return updateLogServiceName(ctx, msg, model)
+		case LogStateSearch:
+			return updateLogSearch(msg, model)
+		case LogStateFilter:
+			return updateLogFilter(ctx, msg, model)
+		case LogStateExport:
+			return updateLogExport(ctx, msg, model)
+		}
+
+		switch msg.String() {
+		case "esc", "q", "ctrl+c":
+			// Первое нажатие во время экспорта отменяет только экспорт
+			if model.Exporting {
+				model = CancelLogExport(model)
+				model.Message = "Экспорт отменен"
+				return model, nil
+			}
+			model = StopFollowing(model)
+			model.Quitting = true
+			return model, nil
+
+		case "p", " ":
+			model.Paused = !model.Paused
+			if !model.Paused {
+				model.Entries = appendLogLines(model.Entries, model.Pending)
+				model.Pending = nil
+				model.Viewport.GotoBottom()
+				model = refreshLogViewport(model)
+			}
+			return model, nil
+
+		case "/":
+			model.State = LogStateSearch
+			model.Search.SetValue(model.SearchQuery)
+			model.Search.Focus()
+			return model, textinput.Blink
+
+		case "f":
+			model.State = LogStateFilter
+			model.FilterInput.SetValue(model.Filter.String())
+			model.FilterInput.Focus()
+			return model, textinput.Blink
+
+		case "e":
+			if model.Exporting {
+				return model, nil
+			}
+			model.State = LogStateExport
+			model.ExportInput.SetValue(DefaultExportFileName(strings.Join(model.Services, "+"), ExportOptions{Format: ExportText}, time.Now()))
+			model.ExportInput.CursorEnd()
+			model.ExportInput.Focus()
+			model.Error = ""
+			return model, textinput.Blink
+
+		case "n":
+			return jumpToMatch(model, 1), nil
+
+		case "N":
+			return jumpToMatch(model, -1), nil
+
+		case "G", "end":
+			model.Viewport.GotoBottom()
+			return model, nil
+
+		case "g", "home":
+			model.Viewport.GotoTop()
+			return model, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	model.Viewport, cmd = model.Viewport.Update(msg)
+	return model, cmd
+}
+
+// Обработка ввода имени сервиса
+func updateLogServiceName(ctx context.Context, msg tea.KeyMsg, model LogViewerModel) (LogViewerModel, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		// Если есть предыдущая ошибка, просто очищаем её
+		if model.Error != "" {
+			model.Error = ""
+			return model, nil
+		}
+
+		serviceNames, err := ParseServiceNames(model.Input.Value())
+		if err != nil {
+			model.Error = err.Error()
+			return model, nil
+		}
+		return FollowServiceLogs(ctx, model, serviceNames...)
+
+	case tea.KeyEsc, tea.KeyCtrlC:
+		model.Quitting = true
+		return model, nil
+	}
+
+	var cmd tea.Cmd
+	model.Input, cmd = model.Input.Update(msg)
+	return model, cmd
+}
+
+// Обработка ввода поискового запроса
+func updateLogSearch(msg tea.KeyMsg, model LogViewerModel) (LogViewerModel, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		model.SearchQuery = model.Search.Value()
+		model.Search.Blur()
+		model.State = LogStateView
+		model = refreshLogViewport(model)
+		return jumpToMatch(model, 1), nil
+
+	case tea.KeyEsc, tea.KeyCtrlC:
+		model.Search.Blur()
+		model.State = LogStateView
+		return model, nil
+	}
+
+	var cmd tea.Cmd
+	model.Search, cmd = model.Search.Update(msg)
+	return model, cmd
+}
+
+// Обработка ввода фильтра журнала. Новый фильтр перезапускает чтение журнала.
+func updateLogFilter(ctx context.Context, msg tea.KeyMsg, model LogViewerModel) (LogViewerModel, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		query, err := ParseLogQuery(model.FilterInput.Value())
+		if err != nil {
+			model.Error = err.Error()
+			return model, nil
+		}
+
+		model.FilterInput.Blur()
+		model.Filter = query
+		model.Message = ""
+		return FollowServiceLogs(ctx, model, model.Services...)
+
+	case tea.KeyEsc, tea.KeyCtrlC:
+		model.FilterInput.Blur()
+		model.State = LogStateView
+		model.Error = ""
+		return model, nil
+	}
+
+	var cmd tea.Cmd
+	model.FilterInput, cmd = model.FilterInput.Update(msg)
+	model.Error = ""
+	return model, cmd
+}
+
+// Обработка ввода имени файла экспорта. Формат определяется по расширению.
+func updateLogExport(ctx context.Context, msg tea.KeyMsg, model LogViewerModel) (LogViewerModel, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		path := strings.TrimSpace(model.ExportInput.Value())
+		if path == "" {
+			model.Error = "имя файла не может быть пустым"
+			return model, nil
+		}
+
+		model.ExportInput.Blur()
+		model.State = LogStateView
+		return StartLogExport(ctx, model, path)
+
+	case tea.KeyEsc, tea.KeyCtrlC:
+		model.ExportInput.Blur()
+		model.State = LogStateView
+		model.Error = ""
+		return model, nil
+	}
+
+	var cmd tea.Cmd
+	model.ExportInput, cmd = model.ExportInput.Update(msg)
+	model.Error = ""
+	return model, cmd
+}
+
+// Отрисовка просмотра журнала
+func ViewLogViewer(model LogViewerModel) string {
+	var s strings.Builder
+
+	if model.State == LogStateServiceName {
+		s.WriteString("Введите имя сервиса для просмотра логов (несколько имен через пробел - общий журнал):\n\n")
+		s.WriteString(model.Input.View() + "\n\n")
+		if model.Error != "" {
+			s.WriteString(FormatError(model.Error) + "\n\n")
+		}
+		s.WriteString("Нажмите Enter для подтверждения, Esc для возврата в меню\n")
+		return s.String()
+	}
+
+	status := "следим за журналом"
+	switch {
+	case model.Paused:
+		status = LogPausedStyle.Render(fmt.Sprintf("пауза, новых строк: %d", len(model.Pending)))
+	case !model.Following:
+		status = "журнал не обновляется"
+	}
+
+	header := TitleStyle.Render("Логи "+strings.Join(model.Services, ", ")) + "  " + LogStatusStyle.Render(fmt.Sprintf("%d строк, ", len(model.Entries))) + status
+	if filter := model.Filter.String(); filter != "" {
+		header += LogStatusStyle.Render(", фильтр: " + filter)
+	}
+	s.WriteString(header + "\n\n")
+	s.WriteString(model.Viewport.View() + "\n\n")
+
+	switch {
+	case model.State == LogStateSearch:
+		s.WriteString(model.Search.View() + "\n")
+	case model.State == LogStateFilter:
+		s.WriteString(model.FilterInput.View() + "\n")
+		if model.Error != "" {
+			s.WriteString(FormatError(model.Error) + "\n")
+		}
+	case model.State == LogStateExport:
+		s.WriteString(model.ExportInput.View() + "\n")
+		if model.Error != "" {
+			s.WriteString(FormatError(model.Error) + "\n")
+		}
+		s.WriteString(LogStatusStyle.Render("Формат по расширению: .log, .jsonl, .csv, добавьте .gz для сжатия. Enter - экспорт, Esc - отмена") + "\n")
+	case model.Exporting && model.ExportTotal == 0 && model.ExportDone > 0:
+		// Без ограничения количества записей журнал пишется по мере чтения
+		s.WriteString(FormatInfo(fmt.Sprintf("Экспорт: записано %d записей... Esc - отменить", model.ExportDone)) + "\n")
+	case model.Exporting && model.ExportTotal == 0:
+		s.WriteString(FormatInfo("Экспорт: чтение журнала... Esc - отменить") + "\n")
+	case model.Exporting:
+		s.WriteString(FormatInfo("Экспорт: "+RenderProgress(model.ExportDone, model.ExportTotal, 30)+" Esc - отменить") + "\n")
+	case model.Error != "":
+		s.WriteString(FormatError(model.Error) + "\n")
+	case model.Message != "":
+		s.WriteString(FormatInfo(model.Message) + "\n")
+	default:
+		s.WriteString(LogStatusStyle.Render("p/пробел - пауза, / - поиск, n/N - следующее/предыдущее совпадение, f - фильтр, e - экспорт, g/G - начало/конец, Esc - назад") + "\n")
+	}
+
+	return s.String()
+}
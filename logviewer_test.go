@@ -0,0 +1,152 @@
+package sdmanager
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Выполнить команду ожидания строк журнала и передать результат модели
+func receiveLogLines(t *testing.T, model LogViewerModel, cmd tea.Cmd) (LogViewerModel, tea.Cmd) {
+	t.Helper()
+
+	if cmd == nil {
+		t.Fatal("expected command waiting for log lines")
+	}
+	return UpdateLogViewer(context.Background(), cmd(), model)
+}
+
+func TestLogViewerFollow(t *testing.T) {
+	backend := NewFakeBackend(UnitInfo{Name: "api"})
+	backend.SetLogs("api", "first", "second")
+
+	model := NewLogViewerModel(AppOptions{backend: backend})
+	model, _ = UpdateLogViewer(context.Background(), tea.WindowSizeMsg{Width: 80, Height: 20}, model)
+	model.Input.SetValue("api")
+	model, cmd := UpdateLogViewer(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+	if model.State != LogStateView || model.Error != "" {
+		t.Fatalf("state = %d, error = %q", model.State, model.Error)
+	}
+
+	model, cmd = receiveLogLines(t, model, cmd)
+	if !slices.Equal(logMessages(model.Entries), []string{"first", "second"}) {
+		t.Errorf("entries = %v", model.Entries)
+	}
+
+	// Во время паузы новые строки копятся отдельно
+	model, _ = UpdateLogViewer(context.Background(), tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")}, model)
+	backend.PushLog("api", JournalEntry{Priority: PriorityWarning, Message: "third"})
+	model, cmd = receiveLogLines(t, model, cmd)
+	if len(model.Entries) != 2 || !slices.Equal(logMessages(model.Pending), []string{"third"}) {
+		t.Errorf("paused: entries = %v, pending = %v", model.Entries, model.Pending)
+	}
+
+	model, _ = UpdateLogViewer(context.Background(), tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")}, model)
+	if len(model.Entries) != 3 || model.Pending != nil || model.Paused {
+		t.Errorf("resumed: entries = %v, pending = %v", model.Entries, model.Pending)
+	}
+
+	// Поиск подсвечивает совпадения без учета регистра
+	model, _ = UpdateLogViewer(context.Background(), tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")}, model)
+	model.Search.SetValue("SEC")
+	model, _ = UpdateLogViewer(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+	if model.SearchQuery != "SEC" || model.State != LogStateView || model.Message != "" {
+		t.Errorf("search: query = %q, state = %d, message = %q", model.SearchQuery, model.State, model.Message)
+	}
+	entry := JournalEntry{Priority: PriorityInfo, Message: "second"}
+	if got := renderLogLine(entry, logSearchPattern(model.SearchQuery), 0); got != LogMatchStyle.Render("sec")+PriorityInfoStyle.Render("ond") {
+		t.Errorf("highlight = %q", got)
+	}
+
+	// Фильтр перезапускает чтение журнала с новыми параметрами
+	model, _ = UpdateLogViewer(context.Background(), tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")}, model)
+	model.FilterInput.SetValue("priority=warning")
+	oldCmd := cmd
+	model, cmd = UpdateLogViewer(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+	if model.State != LogStateView || model.Filter.Priority != "warning" {
+		t.Fatalf("filter: state = %d, filter = %+v, error = %q", model.State, model.Filter, model.Error)
+	}
+	if _, ok := oldCmd().(logStreamEndMsg); !ok {
+		t.Error("previous stream should be closed after filter change")
+	}
+	model, cmd = receiveLogLines(t, model, cmd)
+	if !slices.Equal(logMessages(model.Entries), []string{"third"}) {
+		t.Errorf("filtered entries = %v", model.Entries)
+	}
+
+	model, _ = UpdateLogViewer(context.Background(), tea.KeyMsg{Type: tea.KeyEsc}, model)
+	if !model.Quitting || model.Following {
+		t.Error("Esc should stop following and quit the viewer")
+	}
+
+	// После отмены чтения канал закрывается
+	if msg := cmd(); msg != (logStreamEndMsg{id: model.streamID}) {
+		t.Errorf("msg after stop = %#v", msg)
+	}
+}
+
+func TestAppendLogLinesLimit(t *testing.T) {
+	var buffer []JournalEntry
+	for i := range LogBufferLimit + 10 {
+		buffer = appendLogLines(buffer, []JournalEntry{{Message: fmt.Sprint(i)}})
+	}
+
+	if len(buffer) != LogBufferLimit || buffer[0].Message != "10" {
+		t.Errorf("len = %d, first = %s", len(buffer), buffer[0].Message)
+	}
+}
+
+// Тексты сообщений записей журнала
+func logMessages(entries []JournalEntry) []string {
+	messages := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		messages = append(messages, entry.Message)
+	}
+	return messages
+}
+
+func TestLogViewerMerged(t *testing.T) {
+	backend := NewFakeBackend(UnitInfo{Name: "api"}, UnitInfo{Name: "worker"})
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	entry := func(unit string, sec int, message string) JournalEntry {
+		return JournalEntry{Timestamp: start.Add(time.Duration(sec) * time.Second), Unit: unit + ".service", Message: message}
+	}
+	backend.SetLogEntries("api", entry("api", 1, "api started"), entry("api", 3, "api ready"))
+	backend.SetLogEntries("worker", entry("worker", 2, "worker started"))
+
+	model := NewLogViewerModel(AppOptions{backend: backend})
+	model.Input.SetValue("api, worker api")
+	model, cmd := UpdateLogViewer(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+	if !slices.Equal(model.Services, []string{"api", "worker"}) {
+		t.Fatalf("services = %v, error = %q", model.Services, model.Error)
+	}
+
+	// Записи разных сервисов идут одним потоком в порядке времени
+	model, cmd = receiveLogLines(t, model, cmd)
+	if want := []string{"api started", "worker started", "api ready"}; !slices.Equal(logMessages(model.Entries), want) {
+		t.Errorf("entries = %v, want %v", logMessages(model.Entries), want)
+	}
+
+	backend.PushLog("worker", entry("worker", 4, "job done"))
+	model, _ = receiveLogLines(t, model, cmd)
+	if last := model.Entries[len(model.Entries)-1]; last.Message != "job done" {
+		t.Errorf("last = %+v", last)
+	}
+
+	// Префикс сервиса выровнен по самому длинному имени и окрашен его цветом
+	width := ServicePrefixWidth(model.Services)
+	line := renderLogLine(model.Entries[0], nil, width)
+	if want := ServiceStyle("api").Render("api   ") + " "; !strings.HasPrefix(line, want) {
+		t.Errorf("line = %q, want prefix %q", line, want)
+	}
+	if ServicePrefixWidth([]string{"api"}) != 0 {
+		t.Error("single service should not have prefix")
+	}
+
+	StopFollowing(model)
+}
@@ -0,0 +1,387 @@
+package sdmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Манифест: декларативное описание набора сервисов
+type Manifest struct {
+	Actions  UserActions     `json:"actions" yaml:"actions"`
+	Services []ServiceConfig `json:"services" yaml:"services"`
+}
+
+// Действия по умолчанию, если в манифесте нет секции actions
+var DefaultManifestActions = UserActions{
+	Overwrite:      true,
+	ReloadDaemon:   true,
+	EnableService:  true,
+	StartService:   true,
+	RestartService: true,
+}
+
+// Прочитать манифест из файла. Формат определяется по расширению:
+// .json - JSON, иначе YAML. Путь "-" означает стандартный ввод.
+func LoadManifest(path string) (Manifest, error) {
+	if path == "-" {
+		return ParseManifest(os.Stdin, false)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("ошибка при открытии манифеста: %w", err)
+	}
+	defer file.Close()
+
+	manifest, err := ParseManifest(file, strings.EqualFold(filepath.Ext(path), ".json"))
+	if err != nil {
+		return Manifest{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return manifest, nil
+}
+
+// Разобрать манифест. Вместо списка services допускается описание
+// одного сервиса на верхнем уровне.
+func ParseManifest(r io.Reader, isJSON bool) (Manifest, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("ошибка при чтении манифеста: %w", err)
+	}
+
+	// Поля верхнего уровня определяют, список это или один сервис
+	var keys map[string]any
+	if err := decodeManifest(data, isJSON, &keys); err != nil {
+		return Manifest{}, err
+	}
+
+	manifest := Manifest{Actions: DefaultManifestActions}
+	if _, ok := keys["services"]; ok {
+		if err := decodeManifest(data, isJSON, &manifest); err != nil {
+			return Manifest{}, err
+		}
+	} else {
+		// Неуказанные в actions действия остаются действиями по умолчанию,
+		// как и в форме со списком services
+		var single struct {
+			ServiceConfig `yaml:",inline"`
+			Actions       UserActions `json:"actions" yaml:"actions"`
+		}
+		single.Actions = DefaultManifestActions
+		if err := decodeManifest(data, isJSON, &single); err != nil {
+			return Manifest{}, err
+		}
+		manifest.Actions = single.Actions
+		manifest.Services = []ServiceConfig{single.ServiceConfig}
+	}
+
+	if len(manifest.Services) == 0 {
+		return Manifest{}, errors.New("манифест не содержит сервисов")
+	}
+
+	seen := make(map[string]bool)
+	for i := range manifest.Services {
+		config := &manifest.Services[i]
+		*config = NormalizeTemplateConfig(*config)
+		if config.UnitFilePath == "" {
+			config.UnitFilePath = DefaultUnitDir
+		}
+
+		if err := ValidateServiceConfig(*config); err != nil {
+			return Manifest{}, fmt.Errorf("сервис #%d (%s): %w", i+1, config.ServiceName, err)
+		}
+
+		path := filepath.Join(config.UnitFilePath, config.ServiceName+".service")
+		if seen[path] {
+			return Manifest{}, fmt.Errorf("сервис %s описан несколько раз", config.ServiceName)
+		}
+		seen[path] = true
+	}
+
+	return manifest, nil
+}
+
+// Декодировать манифест со строгой проверкой полей
+func decodeManifest(data []byte, isJSON bool, v any) error {
+	if isJSON {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(v); err != nil {
+			return fmt.Errorf("ошибка разбора JSON: %w", err)
+		}
+		return nil
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("ошибка разбора YAML: %w", err)
+	}
+	return nil
+}
+
+// Изменение сервиса в плане
+type PlanAction int
+
+const (
+	PlanUnchanged PlanAction = iota
+	PlanCreate
+	PlanUpdate
+	PlanConflict
+)
+
+func (a PlanAction) String() string {
+	switch a {
+	case PlanCreate:
+		return "создание"
+	case PlanUpdate:
+		return "изменение"
+	case PlanConflict:
+		return "конфликт"
+	default:
+		return "без изменений"
+	}
+}
+
+// Элемент плана для одного unit-файла сервиса
+type PlanItem struct {
+	Config ServiceConfig
+	// Имя unit для systemctl и имена, которые активируются при установке
+	// (см. GeneratedUnit.ActivateNames)
+	Name     string
+	Activate []string
+	Path     string
+	Action   PlanAction
+	Current  string
+	Desired  string
+}
+
+// План применения манифеста
+type Plan struct {
+	Items   []PlanItem
+	Actions UserActions
+}
+
+// Построить план: сравнить желаемое содержимое unit-файлов с установленным
+func BuildPlan(manifest Manifest) (Plan, error) {
+	plan := Plan{Actions: manifest.Actions}
+
+	for _, config := range manifest.Services {
+		units, err := GenerateUnits(config)
+		if err != nil {
+			return Plan{}, fmt.Errorf("%s: %w", config.ServiceName, err)
+		}
+
+		for _, unit := range units {
+			item := PlanItem{
+				Config:   config,
+				Name:     unit.Name,
+				Activate: unit.ActivateNames(),
+				Path:     unit.Path,
+				Desired:  unit.Content,
+			}
+
+			current, err := os.ReadFile(item.Path)
+			switch {
+			case errors.Is(err, os.ErrNotExist):
+				item.Action = PlanCreate
+			case err != nil:
+				return Plan{}, fmt.Errorf("ошибка при чтении unit-файла: %w", err)
+			default:
+				item.Current = string(current)
+				if HasChanges(DiffLines(item.Current, item.Desired)) {
+					item.Action = PlanUpdate
+					if !manifest.Actions.Overwrite {
+						item.Action = PlanConflict
+					}
+				}
+			}
+
+			plan.Items = append(plan.Items, item)
+		}
+	}
+
+	return plan, nil
+}
+
+// Есть ли в плане шаблоны: количество их экземпляров проверяется при
+// применении, даже если unit-файл не изменился
+func (p Plan) hasTemplates() bool {
+	for _, item := range p.Items {
+		if item.Config.Instances > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Есть ли в плане изменения
+func (p Plan) HasChanges() bool {
+	for _, item := range p.Items {
+		if item.Action == PlanCreate || item.Action == PlanUpdate {
+			return true
+		}
+	}
+	return false
+}
+
+// Ошибка, если в плане есть конфликты
+func (p Plan) Conflicts() error {
+	var names []string
+	for _, item := range p.Items {
+		if item.Action == PlanConflict {
+			names = append(names, item.Path)
+		}
+	}
+
+	if len(names) > 0 {
+		return fmt.Errorf("файлы отличаются от манифеста, а перезапись запрещена (overwrite: false): %s", strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// Текстовое описание плана с diff изменяемых файлов
+func (p Plan) Render() string {
+	var sb strings.Builder
+
+	for _, item := range p.Items {
+		fmt.Fprintf(&sb, "%s: %s (%s)\n", item.Name, item.Action, item.Path)
+		if item.Action != PlanUnchanged {
+			sb.WriteString(RenderDiff(item.Current, item.Desired))
+			sb.WriteString("\n")
+		}
+	}
+
+	if !p.HasChanges() {
+		sb.WriteString("Изменений нет\n")
+	}
+
+	return sb.String()
+}
+
+// Применить план: записать измененные файлы, один раз выполнить daemon-reload,
+// затем активировать и запустить новые сервисы и перезапустить измененные.
+// Неизмененные сервисы не затрагиваются. Для сервиса с таймером
+// активируется и перезапускается только таймер.
+func ApplyPlan(ctx context.Context, b Backend, plan Plan) (string, error) {
+	if err := plan.Conflicts(); err != nil {
+		return "", err
+	}
+
+	if !plan.HasChanges() && !plan.hasTemplates() {
+		return "Изменений нет", nil
+	}
+
+	var resultMessages []string
+
+	// 1. Записываем измененные файлы
+	for _, item := range plan.Items {
+		switch item.Action {
+		case PlanCreate:
+			if err := WriteUnitFile(item.Path, item.Desired); err != nil {
+				return strings.Join(resultMessages, "\n"), err
+			}
+			resultMessages = append(resultMessages, "Systemd unit файл создан: "+item.Path)
+		case PlanUpdate:
+			if err := WriteUnitFile(item.Path, item.Desired); err != nil {
+				return strings.Join(resultMessages, "\n"), err
+			}
+			resultMessages = append(resultMessages, "Systemd unit файл обновлен: "+item.Path)
+		}
+	}
+
+	// 2. Один daemon-reload на все изменения
+	if plan.Actions.ReloadDaemon && plan.HasChanges() {
+		if err := b.DaemonReload(ctx); err != nil {
+			return strings.Join(resultMessages, "\n"), err
+		}
+		resultMessages = append(resultMessages, "Systemd daemon перезагружен")
+	}
+
+	// 3. Новые сервисы активируем и запускаем, измененные перезапускаем.
+	// Сервисы, запускаемые таймером, не трогаем: их запускает таймер.
+	for _, item := range plan.Items {
+		if item.Config.Instances > 0 {
+			messages, err := applyTemplateItem(ctx, b, plan.Actions, item)
+			resultMessages = append(resultMessages, messages...)
+			if err != nil {
+				return strings.Join(resultMessages, "\n"), err
+			}
+			continue
+		}
+
+		for _, name := range item.Activate {
+			switch item.Action {
+			case PlanCreate:
+				if plan.Actions.EnableService {
+					if err := b.Enable(ctx, name); err != nil {
+						return strings.Join(resultMessages, "\n"), err
+					}
+					resultMessages = append(resultMessages, fmt.Sprintf("Сервис %s активирован (enabled)", name))
+				}
+				if plan.Actions.StartService {
+					output, err := StartService(ctx, b, name)
+					if err != nil {
+						return strings.Join(resultMessages, "\n"), err
+					}
+					resultMessages = append(resultMessages, output)
+				}
+
+			case PlanUpdate:
+				if plan.Actions.RestartService {
+					output, err := RestartService(ctx, b, name)
+					if err != nil {
+						return strings.Join(resultMessages, "\n"), err
+					}
+					resultMessages = append(resultMessages, output)
+				}
+			}
+		}
+	}
+
+	if len(resultMessages) == 0 {
+		return "Изменений нет", nil
+	}
+
+	resultMessages = append(resultMessages, "Манифест успешно применен")
+	return strings.Join(resultMessages, "\n"), nil
+}
+
+// Применить шаблон: после изменения unit-файла перезапустить работающие
+// экземпляры, затем привести их количество к заданному в манифесте
+func applyTemplateItem(ctx context.Context, b Backend, actions UserActions, item PlanItem) ([]string, error) {
+	var resultMessages []string
+
+	if item.Action == PlanUpdate && actions.RestartService {
+		instances, err := TemplateInstances(ctx, b, item.Config.ServiceName)
+		if err != nil {
+			return nil, err
+		}
+		for _, unit := range instances {
+			output, err := RestartService(ctx, b, unit.Name)
+			if err != nil {
+				return resultMessages, err
+			}
+			resultMessages = append(resultMessages, output)
+		}
+	}
+
+	if actions.StartService {
+		messages, err := scaleTemplate(ctx, b, item.Config.ServiceName, item.Config.Instances)
+		resultMessages = append(resultMessages, messages...)
+		if err != nil {
+			return resultMessages, err
+		}
+	}
+
+	return resultMessages, nil
+}
@@ -0,0 +1,240 @@
+package sdmanager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseManifest(t *testing.T) {
+	yamlManifest := `
+actions:
+  start: false
+services:
+  - name: api
+    exec_start: /usr/bin/api
+    memory_max: 512
+  - name: worker
+    exec_start: /usr/bin/worker
+    unit_dir: /tmp
+`
+	manifest, err := ParseManifest(strings.NewReader(yamlManifest), false)
+	if err != nil {
+		t.Fatalf("ParseManifest: %v", err)
+	}
+	if len(manifest.Services) != 2 || manifest.Services[0].MemoryMax != "512" {
+		t.Errorf("services = %+v", manifest.Services)
+	}
+	if manifest.Services[0].UnitFilePath != DefaultUnitDir || manifest.Services[1].UnitFilePath != "/tmp" {
+		t.Errorf("unit dirs = %q, %q", manifest.Services[0].UnitFilePath, manifest.Services[1].UnitFilePath)
+	}
+	// Не указанные действия берутся по умолчанию
+	if manifest.Actions.StartService || !manifest.Actions.EnableService {
+		t.Errorf("actions = %+v", manifest.Actions)
+	}
+
+	single, err := ParseManifest(strings.NewReader(`{"name": "api", "exec_start": "/usr/bin/api", "cpu_quota": 50, "memory_max": 512}`), true)
+	if err != nil {
+		t.Fatalf("ParseManifest(json): %v", err)
+	}
+	if len(single.Services) != 1 || single.Services[0].CPUQuota != 50 || single.Services[0].MemoryMax != "512" || single.Actions != DefaultManifestActions {
+		t.Errorf("single = %+v", single)
+	}
+
+	// Частичная секция actions дополняет действия по умолчанию в обеих формах
+	partial := DefaultManifestActions
+	partial.RestartService = false
+	for _, tt := range []struct {
+		data   string
+		isJSON bool
+	}{
+		{"actions:\n  restart: false\nservices:\n  - name: api\n    exec_start: /usr/bin/api\n", false},
+		{"name: api\nexec_start: /usr/bin/api\nactions: {restart: false}\n", false},
+		{`{"actions": {"restart": false}, "services": [{"name": "api", "exec_start": "/usr/bin/api"}]}`, true},
+		{`{"name": "api", "exec_start": "/usr/bin/api", "actions": {"restart": false}}`, true},
+	} {
+		manifest, err := ParseManifest(strings.NewReader(tt.data), tt.isJSON)
+		if err != nil {
+			t.Errorf("ParseManifest(%q): %v", tt.data, err)
+			continue
+		}
+		if manifest.Actions != partial {
+			t.Errorf("%q: actions = %+v, want %+v", tt.data, manifest.Actions, partial)
+		}
+	}
+
+	invalid := []string{
+		"services: []",
+		"name: api\nexec_start: /bin/true\nmemory: 1",
+		"services:\n  - name: api\n    exec_start: /bin/true\n  - name: api\n    exec_start: /bin/false",
+		"name: api",
+	}
+	for _, data := range invalid {
+		if _, err := ParseManifest(strings.NewReader(data), false); err == nil {
+			t.Errorf("ParseManifest(%q) succeeded", data)
+		}
+	}
+}
+
+func TestApplyPlan(t *testing.T) {
+	unitDir := t.TempDir()
+	backend := NewFakeBackend()
+	backend.UnitDir = unitDir
+
+	manifest := Manifest{
+		Actions: DefaultManifestActions,
+		Services: []ServiceConfig{
+			{ServiceName: "api", ExecStart: "/usr/bin/api", UnitFilePath: unitDir},
+			{ServiceName: "worker", ExecStart: "/usr/bin/worker", UnitFilePath: unitDir},
+		},
+	}
+
+	plan, err := BuildPlan(manifest)
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	for _, item := range plan.Items {
+		if item.Action != PlanCreate {
+			t.Errorf("%s: action = %s, want create", item.Config.ServiceName, item.Action)
+		}
+	}
+
+	if _, err := ApplyPlan(context.Background(), backend, plan); err != nil {
+		t.Fatalf("ApplyPlan: %v", err)
+	}
+	want := []string{"daemon-reload", "enable api", "start api", "enable worker", "start worker"}
+	if calls := backend.Calls(); !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+
+	// Повторное применение ничего не меняет
+	plan, _ = BuildPlan(manifest)
+	if plan.HasChanges() {
+		t.Errorf("plan after apply has changes:\n%s", plan.Render())
+	}
+
+	// Изменение одного сервиса перезапускает только его
+	manifest.Services[1].MemoryMax = "256"
+	plan, _ = BuildPlan(manifest)
+	if plan.Items[0].Action != PlanUnchanged || plan.Items[1].Action != PlanUpdate {
+		t.Fatalf("actions = %s, %s", plan.Items[0].Action, plan.Items[1].Action)
+	}
+	if render := plan.Render(); !strings.Contains(render, "+ MemoryMax=256M") {
+		t.Errorf("render does not contain diff:\n%s", render)
+	}
+
+	calls := len(backend.Calls())
+	if _, err := ApplyPlan(context.Background(), backend, plan); err != nil {
+		t.Fatalf("ApplyPlan: %v", err)
+	}
+	want = []string{"daemon-reload", "restart worker"}
+	if got := backend.Calls()[calls:]; !slices.Equal(got, want) {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+
+	// Без overwrite отличающийся файл - конфликт, ничего не записывается
+	manifest.Actions.Overwrite = false
+	manifest.Services[0].UserName = "www-data"
+	plan, _ = BuildPlan(manifest)
+	if _, err := ApplyPlan(context.Background(), backend, plan); err == nil {
+		t.Error("expected conflict error")
+	}
+	content, _ := os.ReadFile(filepath.Join(unitDir, "api.service"))
+	if strings.Contains(string(content), "User=") {
+		t.Errorf("unit file was overwritten:\n%s", content)
+	}
+}
+
+func TestApplyPlanTimer(t *testing.T) {
+	unitDir := t.TempDir()
+	backend := NewFakeBackend()
+	backend.UnitDir = unitDir
+
+	data := `
+name: backup
+exec_start: /usr/bin/backup
+unit_dir: ` + unitDir + `
+timer:
+  on_calendar: ["daily"]
+  persistent: true
+`
+	manifest, err := ParseManifest(strings.NewReader(data), false)
+	if err != nil {
+		t.Fatalf("ParseManifest: %v", err)
+	}
+
+	plan, err := BuildPlan(manifest)
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	if len(plan.Items) != 2 || plan.Items[1].Name != "backup.timer" {
+		t.Fatalf("items = %+v", plan.Items)
+	}
+	if _, err := ApplyPlan(context.Background(), backend, plan); err != nil {
+		t.Fatalf("ApplyPlan: %v", err)
+	}
+
+	// Сервис с таймером не активируется и не перезапускается сам
+	manifest.Services[0].Timer.OnCalendar = []string{"weekly"}
+	manifest.Services[0].ExecStart = "/usr/bin/backup --full"
+	plan, _ = BuildPlan(manifest)
+	if _, err := ApplyPlan(context.Background(), backend, plan); err != nil {
+		t.Fatalf("ApplyPlan: %v", err)
+	}
+	want := []string{"daemon-reload", "enable backup.timer", "start backup.timer", "daemon-reload", "restart backup.timer"}
+	if calls := backend.Calls(); !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+
+	if _, err := ParseManifest(strings.NewReader("name: backup\nexec_start: /bin/true\ntimer: {persistent: true}"), false); err == nil {
+		t.Error("expected error for timer without triggers")
+	}
+}
+
+func TestApplyPlanTemplate(t *testing.T) {
+	unitDir := t.TempDir()
+	backend := NewFakeBackend()
+	backend.UnitDir = unitDir
+
+	data := `
+name: worker@
+exec_start: /usr/bin/worker --id %i
+unit_dir: ` + unitDir + `
+instances: 3
+`
+	manifest, err := ParseManifest(strings.NewReader(data), false)
+	if err != nil {
+		t.Fatalf("ParseManifest: %v", err)
+	}
+	if _, err := ApplyPlan(context.Background(), backend, mustBuildPlan(t, manifest)); err != nil {
+		t.Fatalf("ApplyPlan: %v", err)
+	}
+
+	// Файл не изменился, но количество экземпляров приводится к манифесту
+	manifest.Services[0].Instances = 1
+	message, err := ApplyPlan(context.Background(), backend, mustBuildPlan(t, manifest))
+	if err != nil {
+		t.Fatalf("ApplyPlan: %v", err)
+	}
+	if !strings.Contains(message, "worker@3 остановлен") {
+		t.Errorf("message = %q", message)
+	}
+
+	instances, _ := TemplateInstances(context.Background(), backend, "worker")
+	if len(instances) != 1 || instances[0].Name != "worker@1" {
+		t.Errorf("instances = %+v", instances)
+	}
+}
+
+func mustBuildPlan(t *testing.T, manifest Manifest) Plan {
+	t.Helper()
+
+	plan, err := BuildPlan(manifest)
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	return plan
+}
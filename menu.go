@@ -8,11 +8,22 @@ import (
 // Создать пункты главного меню
 func GetMenuItems() []list.Item {
 	items := []list.Item{
+		MenuItem{Title: string(ActionBrowseServices), Action: ActionBrowseServices},
 		MenuItem{Title: string(ActionStartService), Action: ActionStartService},
 		MenuItem{Title: string(ActionStopService), Action: ActionStopService},
 		MenuItem{Title: string(ActionRestartService), Action: ActionRestartService},
+		MenuItem{Title: string(ActionReloadService), Action: ActionReloadService},
 		MenuItem{Title: string(ActionViewLogs), Action: ActionViewLogs},
+		MenuItem{Title: string(ActionServiceStatus), Action: ActionServiceStatus},
+		MenuItem{Title: string(ActionSecurityAudit), Action: ActionSecurityAudit},
+		MenuItem{Title: string(ActionTuneLive), Action: ActionTuneLive},
 		MenuItem{Title: string(ActionInstallService), Action: ActionInstallService},
+		MenuItem{Title: string(ActionInstallTimer), Action: ActionInstallTimer},
+		MenuItem{Title: string(ActionInstallSocket), Action: ActionInstallSocket},
+		MenuItem{Title: string(ActionInstallPath), Action: ActionInstallPath},
+		MenuItem{Title: string(ActionEditService), Action: ActionEditService},
+		MenuItem{Title: string(ActionManageDropIns), Action: ActionManageDropIns},
+		MenuItem{Title: string(ActionScaleTemplate), Action: ActionScaleTemplate},
 		MenuItem{Title: string(ActionExit), Action: ActionExit},
 	}
 	return items
@@ -1,9 +1,12 @@
 package sdmanager
 
 import (
+	"context"
+
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
 )
 
 // Режимы приложения
@@ -11,6 +14,13 @@ const (
 	ModeMainMenu = iota
 	ModeInstallService
 	ModeServiceInput
+	ModeDropIns
+	ModeBrowser
+	ModeStatus
+	ModeLogs
+	ModeScale
+	ModeSecurity
+	ModeTune
 	ModeExit  // Режим выхода из приложения
 	ModeError // Режим отображения ошибки
 )
@@ -20,18 +30,36 @@ const (
 	ActionStart   = "start"
 	ActionStop    = "stop"
 	ActionRestart = "restart"
+	ActionReload  = "reload"
 	ActionViewLog = "log"
+	ActionEdit    = "edit"
+	ActionDisable = "disable"
+	ActionStatus  = "status"
+	ActionScale   = "scale"
+	ActionAudit   = "audit"
+	ActionTune    = "tune"
 )
 
 // Пункты меню
 type MenuAction string
 
 const (
+	ActionBrowseServices MenuAction = "Список сервисов"
 	ActionStartService   MenuAction = "Запустить сервис"
 	ActionStopService    MenuAction = "Остановить сервис"
 	ActionRestartService MenuAction = "Перезапустить сервис"
+	ActionReloadService  MenuAction = "Перечитать конфигурацию сервиса (reload)"
 	ActionViewLogs       MenuAction = "Просмотр логов"
+	ActionServiceStatus  MenuAction = "Статус сервиса"
 	ActionInstallService MenuAction = "Установить сервис"
+	ActionInstallTimer   MenuAction = "Установить сервис с таймером"
+	ActionInstallSocket  MenuAction = "Установить сервис с сокетом"
+	ActionInstallPath    MenuAction = "Установить сервис с отслеживанием файлов"
+	ActionEditService    MenuAction = "Редактировать сервис"
+	ActionManageDropIns  MenuAction = "Drop-in файлы сервиса"
+	ActionScaleTemplate  MenuAction = "Экземпляры шаблона"
+	ActionSecurityAudit  MenuAction = "Аудит безопасности сервиса"
+	ActionTuneLive       MenuAction = "Изменить ограничения на лету"
 	ActionExit           MenuAction = "Выход"
 )
 
@@ -41,6 +69,35 @@ const (
 	StateUserName
 	StateWorkingDirectory
 	StateExecStart
+	StateExecHooks
+	StateExecStartPre
+	StateExecStartPost
+	StateExecReload
+	StateExecStop
+	StateExecStopPost
+	StateServicePolicy
+	StateServiceType
+	StateRestart
+	StateRestartSec
+	StateStartLimitInterval
+	StateStartLimitBurst
+	StateTimeoutStartSec
+	StateTimeoutStopSec
+	StateKillMode
+	StateKillSignal
+	StateRemainAfterExit
+	StateEnvironment
+	StateEnvironmentFiles
+	StateDependencies
+	StateAfter
+	StateBefore
+	StateWants
+	StateRequires
+	StateBindsTo
+	StatePartOf
+	StateConflicts
+	StateWantedBy
+	StateRequiredBy
 	StateStandardOutput
 	StateStandardError
 	StateSyslogIdentifier
@@ -48,6 +105,37 @@ const (
 	StateMemoryMax
 	StateCPUQuota
 	StateAllowedCPUs
+	StateResources
+	StateMemorySwapMax
+	StateCPUWeight
+	StateAllowedMemoryNodes
+	StateTasksMax
+	StateIOWeight
+	StateIODeviceReadBandwidthMax
+	StateIODeviceWriteBandwidthMax
+	StateLimitNOFILE
+	StateLimitNPROC
+	StateLimitCORE
+	StateNice
+	StateHardening
+	StateTimerOnCalendar
+	StateTimerOnBootSec
+	StateTimerOnUnitActiveSec
+	StateTimerRandomizedDelay
+	StateTimerAccuracy
+	StateTimerPersistent
+	StateSocketListenStream
+	StateSocketListenDatagram
+	StateSocketAccept
+	StateSocketUser
+	StateSocketMode
+	StateSocketBindIPv6Only
+	StatePathExists
+	StatePathChanged
+	StatePathModified
+	StatePathDirectoryNotEmpty
+	StatePathMakeDirectory
+	StateInstances
 	StateUnitLocation
 	StateOverwrite
 	StateOptionsSelect
@@ -72,28 +160,115 @@ type Option struct {
 
 // Данные для создания и настройки systemd unit
 type ServiceConfig struct {
-	ServiceName      string
-	UserName         string
-	WorkingDirectory string
-	ExecStart        string
-	StandardOutput   string
-	StandardError    string
-	SyslogIdentifier string
-	MemoryHigh       int
-	MemoryMax        int
-	CPUQuota         int
-	AllowedCPUs      string
-	UnitFilePath     string
+	ServiceName      string `json:"name" yaml:"name"`
+	UserName         string `json:"user,omitempty" yaml:"user,omitempty"`
+	WorkingDirectory string `json:"working_directory,omitempty" yaml:"working_directory,omitempty"`
+	ExecStart        string `json:"exec_start" yaml:"exec_start"`
+	// Следующие команды ExecStart= сервиса oneshot: systemd выполняет их по
+	// очереди после ExecStart
+	ExecStartNext []string `json:"exec_start_next,omitempty" yaml:"exec_start_next,omitempty"`
+	// Команды жизненного цикла; каждой может быть несколько, префиксы
+	// "-", "+", "!" как в systemd
+	ExecStartPre  []string `json:"exec_start_pre,omitempty" yaml:"exec_start_pre,omitempty"`
+	ExecStartPost []string `json:"exec_start_post,omitempty" yaml:"exec_start_post,omitempty"`
+	ExecReload    []string `json:"exec_reload,omitempty" yaml:"exec_reload,omitempty"`
+	ExecStop      []string `json:"exec_stop,omitempty" yaml:"exec_stop,omitempty"`
+	ExecStopPost  []string `json:"exec_stop_post,omitempty" yaml:"exec_stop_post,omitempty"`
+	// Переменные окружения (Environment=) и файлы с ними (EnvironmentFile=,
+	// префикс "-" - файл может отсутствовать)
+	Environment      map[string]string `json:"environment,omitempty" yaml:"environment,omitempty"`
+	EnvironmentFiles []string          `json:"environment_files,omitempty" yaml:"environment_files,omitempty"`
+	// Секреты записываются в файл с правами 0600, а не в unit-файл. В
+	// манифест они не попадают: там указывается путь в environment_files.
+	Secrets map[string]string `json:"-" yaml:"-"`
+	// Тип сервиса и политика перезапуска. Пустое значение - поведение по
+	// умолчанию: Restart=always с RestartSec=10, oneshot для таймера и
+	// path-unit
+	Type                  string `json:"type,omitempty" yaml:"type,omitempty"`
+	Restart               string `json:"restart,omitempty" yaml:"restart,omitempty"`
+	RestartSec            string `json:"restart_sec,omitempty" yaml:"restart_sec,omitempty"`
+	StartLimitIntervalSec string `json:"start_limit_interval_sec,omitempty" yaml:"start_limit_interval_sec,omitempty"`
+	StartLimitBurst       int    `json:"start_limit_burst,omitempty" yaml:"start_limit_burst,omitempty"`
+	TimeoutStartSec       string `json:"timeout_start_sec,omitempty" yaml:"timeout_start_sec,omitempty"`
+	TimeoutStopSec        string `json:"timeout_stop_sec,omitempty" yaml:"timeout_stop_sec,omitempty"`
+	KillMode              string `json:"kill_mode,omitempty" yaml:"kill_mode,omitempty"`
+	KillSignal            string `json:"kill_signal,omitempty" yaml:"kill_signal,omitempty"`
+	RemainAfterExit       bool   `json:"remain_after_exit,omitempty" yaml:"remain_after_exit,omitempty"`
+	// Зависимости и порядок запуска. Пустой After - после network.target,
+	// пустые WantedBy и RequiredBy - включение в multi-user.target
+	After            []string `json:"after,omitempty" yaml:"after,omitempty"`
+	Before           []string `json:"before,omitempty" yaml:"before,omitempty"`
+	Wants            []string `json:"wants,omitempty" yaml:"wants,omitempty"`
+	Requires         []string `json:"requires,omitempty" yaml:"requires,omitempty"`
+	BindsTo          []string `json:"binds_to,omitempty" yaml:"binds_to,omitempty"`
+	PartOf           []string `json:"part_of,omitempty" yaml:"part_of,omitempty"`
+	Conflicts        []string `json:"conflicts,omitempty" yaml:"conflicts,omitempty"`
+	WantedBy         []string `json:"wanted_by,omitempty" yaml:"wanted_by,omitempty"`
+	RequiredBy       []string `json:"required_by,omitempty" yaml:"required_by,omitempty"`
+	StandardOutput   string   `json:"standard_output,omitempty" yaml:"standard_output,omitempty"`
+	StandardError    string   `json:"standard_error,omitempty" yaml:"standard_error,omitempty"`
+	SyslogIdentifier string   `json:"syslog_identifier,omitempty" yaml:"syslog_identifier,omitempty"`
+	// Ограничения ресурсов. Размеры памяти задаются с единицей (512M, 1.5G),
+	// в процентах от памяти машины или числом мегабайт
+	MemoryHigh    MemorySize `json:"memory_high,omitempty" yaml:"memory_high,omitempty"`
+	MemoryMax     MemorySize `json:"memory_max,omitempty" yaml:"memory_max,omitempty"`
+	MemorySwapMax MemorySize `json:"memory_swap_max,omitempty" yaml:"memory_swap_max,omitempty"`
+	// Доля времени одного ядра в процентах, допускается дробная: 12.5
+	CPUQuota    float64 `json:"cpu_quota,omitempty" yaml:"cpu_quota,omitempty"`
+	CPUWeight   int     `json:"cpu_weight,omitempty" yaml:"cpu_weight,omitempty"`
+	AllowedCPUs string  `json:"allowed_cpus,omitempty" yaml:"allowed_cpus,omitempty"`
+	// Узлы NUMA, память которых может использовать сервис: 0-1
+	AllowedMemoryNodes string `json:"allowed_memory_nodes,omitempty" yaml:"allowed_memory_nodes,omitempty"`
+	// Число задач, процент от системного ограничения или infinity
+	TasksMax string `json:"tasks_max,omitempty" yaml:"tasks_max,omitempty"`
+	IOWeight int    `json:"io_weight,omitempty" yaml:"io_weight,omitempty"`
+	// Ограничения скорости ввода-вывода вида «/dev/sda 10M»
+	IODeviceReadBandwidthMax  []string `json:"io_device_read_bandwidth_max,omitempty" yaml:"io_device_read_bandwidth_max,omitempty"`
+	IODeviceWriteBandwidthMax []string `json:"io_device_write_bandwidth_max,omitempty" yaml:"io_device_write_bandwidth_max,omitempty"`
+	// Ограничения процесса: число, infinity или soft:hard
+	LimitNOFILE string `json:"limit_nofile,omitempty" yaml:"limit_nofile,omitempty"`
+	LimitNPROC  string `json:"limit_nproc,omitempty" yaml:"limit_nproc,omitempty"`
+	LimitCORE   string `json:"limit_core,omitempty" yaml:"limit_core,omitempty"`
+	// Приоритет от -20 до 19; nil - не задан
+	Nice *int `json:"nice,omitempty" yaml:"nice,omitempty"`
+	// Профиль защиты: none, basic или strict. Пустой - директивы защиты в
+	// unit-файле не меняются
+	Hardening    string `json:"hardening,omitempty" yaml:"hardening,omitempty"`
+	UnitFilePath string `json:"unit_dir,omitempty" yaml:"unit_dir,omitempty"`
+	// Таймер, запускающий сервис по расписанию. Сервис с таймером
+	// создается как oneshot
+	Timer *TimerConfig `json:"timer,omitempty" yaml:"timer,omitempty"`
+	// Сокет, при подключении к которому запускается сервис
+	Socket *SocketConfig `json:"socket,omitempty" yaml:"socket,omitempty"`
+	// Path-unit, запускающий сервис при появлении или изменении файлов.
+	// Сервис создается как oneshot
+	Path *PathConfig `json:"path,omitempty" yaml:"path,omitempty"`
+	// Количество экземпляров шаблона: больше 0 - сервис создается как
+	// name@.service и запускаются экземпляры name@1 .. name@N
+	Instances int `json:"instances,omitempty" yaml:"instances,omitempty"`
 }
 
 // Действия пользователя
 type UserActions struct {
-	Overwrite     bool
-	ReloadDaemon  bool
-	EnableService bool
-	StartService  bool
+	Overwrite      bool `json:"overwrite" yaml:"overwrite"`
+	ReloadDaemon   bool `json:"reload_daemon" yaml:"reload_daemon"`
+	EnableService  bool `json:"enable" yaml:"enable"`
+	StartService   bool `json:"start" yaml:"start"`
+	RestartService bool `json:"restart" yaml:"restart"`
+}
+
+// Сервис systemd с текущим состоянием
+type UnitInfo struct {
+	Name          string
+	Description   string
+	LoadState     string
+	ActiveState   string
+	SubState      string
+	UnitFileState string
 }
 
+func (u UnitInfo) FilterValue() string { return u.Name + " " + u.Description }
+
 // Модель меню
 type MenuModel struct {
 	List     list.Model
@@ -109,6 +284,106 @@ type ServiceInputModel struct {
 	Error     string
 	ResultMsg string
 	Quitting  bool
+	// Сервис не поддерживает reload: ждем подтверждения перезапуска
+	ConfirmRestart bool
+
+	backend Backend
+}
+
+// Модель экрана статуса сервиса
+type StatusModel struct {
+	State       int
+	Input       textinput.Model
+	ServiceName string
+	Status      ServiceStatus
+	Logs        []JournalEntry
+	Error       string
+	Quitting    bool
+
+	backend Backend
+}
+
+// Модель экрана аудита безопасности сервиса
+type SecurityModel struct {
+	State       int
+	Input       textinput.Model
+	Viewport    viewport.Model
+	ServiceName string
+	Report      SecurityReport
+	Message     string
+	Error       string
+	// Рекомендации записаны, ожидается подтверждение перезапуска
+	ConfirmRestart bool
+	Quitting       bool
+
+	backend Backend
+}
+
+// Модель экрана изменения ограничений запущенного сервиса
+type TuneModel struct {
+	State       int
+	Input       textinput.Model
+	ServiceName string
+	Resources   []LiveResource
+	// Выбранное ограничение
+	Current int
+	// Изменения действуют только до перезагрузки
+	Runtime bool
+	// Drop-in файлы, созданные systemctl set-property
+	DropIns []DropIn
+	Message string
+	Error   string
+	// Ожидается подтверждение отмены изменений
+	ConfirmRevert bool
+	Quitting      bool
+
+	backend Backend
+}
+
+// Модель экрана экземпляров шаблона name@.service
+type ScaleModel struct {
+	State     int
+	Input     textinput.Model
+	Template  string
+	Instances []UnitInfo
+	Message   string
+	Error     string
+	Quitting  bool
+
+	backend Backend
+}
+
+// Модель просмотра журнала сервиса
+type LogViewerModel struct {
+	State       int
+	Input       textinput.Model
+	Search      textinput.Model
+	FilterInput textinput.Model
+	ExportInput textinput.Model
+	Viewport    viewport.Model
+	// Сервисы, записи которых показываются в одном потоке
+	Services []string
+	Entries  []JournalEntry
+	// Записи, полученные во время паузы
+	Pending     []JournalEntry
+	Paused      bool
+	Following   bool
+	SearchQuery string
+	Filter      LogQuery
+	// Прогресс экспорта: записано записей из общего количества
+	Exporting   bool
+	ExportDone  int
+	ExportTotal int
+	Message     string
+	Error       string
+	Quitting    bool
+
+	cancel       context.CancelFunc
+	stream       <-chan JournalEntry
+	streamID     int64
+	exportCancel context.CancelFunc
+	exportEvents <-chan tea.Msg
+	backend      Backend
 }
 
 // Модель для установки сервиса
@@ -127,6 +402,68 @@ type InstallModel struct {
 	ResultMsg      string
 	Options        []Option
 	CurrentOption  int
+	// Предупреждение к предыдущему вводу, которое не мешает продолжить
+	WarningMsg string
+	// Имена unit-файлов для автодополнения зависимостей
+	UnitNames []string
+
+	// Режим редактирования существующего unit-файла
+	EditMode        bool
+	Unit            *UnitFile
+	OriginalContent string
+
+	// Запись изменений в drop-in вместо основного unit-файла
+	DropInMode bool
+	BaseConfig ServiceConfig
+	DropInPath string
+
+	backend Backend
+}
+
+// Состояния экрана drop-in файлов
+const (
+	DropInStateServiceName = iota
+	DropInStateList
+	DropInStateConfirmDelete
+)
+
+// Модель управления drop-in файлами сервиса
+type DropInModel struct {
+	State       int
+	Input       textinput.Model
+	Viewport    viewport.Model
+	ServiceName string
+	DropIns     []DropIn
+	Current     int
+	Message     string
+	Error       string
+	Quitting    bool
+
+	backend Backend
+}
+
+// Модель списка сервисов
+type BrowserModel struct {
+	List     list.Model
+	Units    []UnitInfo
+	Selected string
+	// Сервисы, отмеченные для общего журнала
+	Marked      map[string]bool
+	PanelOpen   bool
+	PanelAction int
+	// Сервис не поддерживает reload: ждем подтверждения перезапуска
+	ConfirmRestart bool
+	// Действие над сервисом, которое выполняется в фоне
+	Running  string
+	Message  string
+	Error    string
+	Quitting bool
+
+	// Действие, которое должно выполнить приложение (редактирование)
+	Request MenuAction
+
+	tickID  int64
+	backend Backend
 }
 
 // Основная модель приложения
@@ -135,9 +472,21 @@ type AppModel struct {
 	MenuModel         MenuModel
 	InstallModel      InstallModel
 	ServiceInputModel ServiceInputModel
+	DropInModel       DropInModel
+	BrowserModel      BrowserModel
+	StatusModel       StatusModel
+	ScaleModel        ScaleModel
+	SecurityModel     SecurityModel
+	TuneModel         TuneModel
+	LogViewerModel    LogViewerModel
 	Message           string
 	Error             string
 	FatalError        bool
 
+	// Вернуться к списку сервисов после завершения мастера или экрана статуса
+	returnToBrowser bool
+	width           int
+	height          int
+
 	options AppOptions
 }
@@ -0,0 +1,98 @@
+package sdmanager
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// Настройки path-unit (.path), который запускает сервис при появлении или
+// изменении файлов
+type PathConfig struct {
+	// Файл или каталог существует
+	PathExists []string `json:"path_exists,omitempty" yaml:"path_exists,omitempty"`
+	// Файл закрыт после записи или переименован
+	PathChanged []string `json:"path_changed,omitempty" yaml:"path_changed,omitempty"`
+	// Как PathChanged, но срабатывает и на каждую запись
+	PathModified []string `json:"path_modified,omitempty" yaml:"path_modified,omitempty"`
+	// В каталоге есть хотя бы один файл
+	DirectoryNotEmpty []string `json:"directory_not_empty,omitempty" yaml:"directory_not_empty,omitempty"`
+	// Создать отслеживаемые каталоги, если их нет
+	MakeDirectory bool `json:"make_directory,omitempty" yaml:"make_directory,omitempty"`
+}
+
+// Все отслеживаемые пути с именами директив
+func (p PathConfig) directives() [][2]string {
+	var directives [][2]string
+	for _, group := range []struct {
+		name  string
+		paths []string
+	}{
+		{"PathExists", p.PathExists},
+		{"PathChanged", p.PathChanged},
+		{"PathModified", p.PathModified},
+		{"DirectoryNotEmpty", p.DirectoryNotEmpty},
+	} {
+		for _, path := range group.paths {
+			directives = append(directives, [2]string{group.name, path})
+		}
+	}
+	return directives
+}
+
+// Проверить настройки path-unit: нужен хотя бы один путь, все пути
+// абсолютные
+func ValidatePathConfig(path PathConfig) error {
+	directives := path.directives()
+	if len(directives) == 0 {
+		return errors.New("нужен хотя бы один путь: PathExists, PathChanged, PathModified или DirectoryNotEmpty")
+	}
+
+	for _, directive := range directives {
+		if err := ValidateWatchPath(directive[1]); err != nil {
+			return fmt.Errorf("%s: %w", directive[0], err)
+		}
+	}
+
+	return nil
+}
+
+// Проверить отслеживаемый путь. В отличие от IsValidPath каталог может еще не
+// существовать: файлы появятся позже, а MakeDirectory создаст его сам.
+func ValidateWatchPath(path string) error {
+	if path == "" {
+		return errors.New("путь не может быть пустым")
+	}
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("путь должен быть абсолютным: %s", path)
+	}
+	return checkPathChars(path)
+}
+
+// Разобрать список путей, разделенных пробелами или запятыми
+func ParsePathList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' })
+}
+
+// Сформировать содержимое .path для сервиса
+func GeneratePathUnit(serviceName string, path PathConfig) string {
+	var sb strings.Builder
+
+	sb.WriteString("[Unit]\n")
+	sb.WriteString("Description=" + cases.Title(language.English).String(serviceName) + " Path\n\n")
+
+	sb.WriteString("[Path]\n")
+	for _, directive := range path.directives() {
+		sb.WriteString(directive[0] + "=" + directive[1] + "\n")
+	}
+	if path.MakeDirectory {
+		sb.WriteString("MakeDirectory=yes\n")
+	}
+	sb.WriteString("\n[Install]\nWantedBy=paths.target\n")
+
+	return sb.String()
+}
@@ -0,0 +1,71 @@
+package sdmanager
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidatePathConfig(t *testing.T) {
+	valid := []PathConfig{
+		{DirectoryNotEmpty: []string{"/var/spool/ingest"}, MakeDirectory: true},
+		{PathExists: []string{"/run/app.ready"}, PathChanged: []string{"/etc/app/config.yaml"}},
+		{PathModified: []string{"/var/lib/app/state"}},
+	}
+	for _, path := range valid {
+		if err := ValidatePathConfig(path); err != nil {
+			t.Errorf("ValidatePathConfig(%+v): %v", path, err)
+		}
+	}
+
+	invalid := []PathConfig{
+		{},
+		{MakeDirectory: true},
+		{PathExists: []string{"spool/ingest"}},
+		{PathChanged: []string{"/var/spool/*.csv"}},
+		{DirectoryNotEmpty: []string{"/var/spool/ingest;rm"}},
+	}
+	for _, path := range invalid {
+		if err := ValidatePathConfig(path); err == nil {
+			t.Errorf("ValidatePathConfig(%+v) succeeded", path)
+		}
+	}
+}
+
+func TestGeneratePathUnits(t *testing.T) {
+	config := ServiceConfig{
+		ServiceName:  "ingest",
+		ExecStart:    "/usr/bin/ingest /var/spool/ingest",
+		UnitFilePath: "/etc/systemd/system",
+		Path: &PathConfig{
+			PathChanged:       []string{"/etc/ingest.conf"},
+			DirectoryNotEmpty: []string{"/var/spool/ingest"},
+			MakeDirectory:     true,
+		},
+	}
+	if err := ValidateServiceConfig(config); err != nil {
+		t.Fatalf("ValidateServiceConfig: %v", err)
+	}
+
+	units, err := GenerateUnits(config)
+	if err != nil {
+		t.Fatalf("GenerateUnits: %v", err)
+	}
+	if len(units) != 2 || units[0].Activate || !units[1].Activate || units[1].Name != "ingest.path" {
+		t.Fatalf("units = %+v", units)
+	}
+
+	// Сервис выполняет задачу и завершается, запускает его path-unit
+	if !strings.Contains(units[0].Content, "Type=oneshot") || strings.Contains(units[0].Content, "Restart=") ||
+		strings.Contains(units[0].Content, "WantedBy=multi-user.target") {
+		t.Errorf("service:\n%s", units[0].Content)
+	}
+	want := "[Path]\nPathChanged=/etc/ingest.conf\nDirectoryNotEmpty=/var/spool/ingest\nMakeDirectory=yes\n\n[Install]\nWantedBy=paths.target\n"
+	if !strings.HasSuffix(units[1].Content, want) {
+		t.Errorf("path unit:\n%s", units[1].Content)
+	}
+
+	config.Socket = &SocketConfig{ListenStream: []string{"7777"}}
+	if err := ValidateServiceConfig(config); err == nil {
+		t.Error("expected error for service with both path unit and socket")
+	}
+}
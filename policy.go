@@ -0,0 +1,175 @@
+package sdmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Допустимые значения Type
+var ServiceTypes = []string{"simple", "exec", "forking", "oneshot", "notify", "idle"}
+
+// Допустимые значения Restart
+var RestartPolicies = []string{"no", "on-success", "on-failure", "on-abnormal", "on-watchdog", "on-abort", "always"}
+
+// Допустимые значения KillMode
+var KillModes = []string{"control-group", "mixed", "process", "none"}
+
+// Сигналы, которые можно указать в KillSignal
+var killSignals = []string{
+	"SIGHUP", "SIGINT", "SIGQUIT", "SIGKILL", "SIGUSR1", "SIGUSR2",
+	"SIGTERM", "SIGCONT", "SIGSTOP", "SIGWINCH", "SIGPWR", "SIGRTMIN",
+}
+
+// Интервал RestartSec по умолчанию: systemd перезапускает через 100мс, что
+// для упавшего сервиса обычно слишком часто
+const DefaultRestartSec = "10"
+
+// Первая версия systemd, допускающая Restart= у Type=oneshot
+const oneshotRestartVersion = 244
+
+// Итоговый Type сервиса: сервис, запускаемый таймером или path-unit, по
+// умолчанию oneshot
+func effectiveType(config ServiceConfig) string {
+	if config.Type != "" {
+		return config.Type
+	}
+	if config.Timer != nil || config.Path != nil {
+		return "oneshot"
+	}
+	return ""
+}
+
+// Итоговый Restart сервиса. По умолчанию сервис перезапускается всегда,
+// кроме oneshot и экземпляров сокета с Accept=yes, которые завершаются сами.
+func effectiveRestart(config ServiceConfig) string {
+	if config.Restart != "" {
+		return config.Restart
+	}
+	if effectiveType(config) == "oneshot" || (config.Socket != nil && config.Socket.Accept) {
+		return ""
+	}
+	return "always"
+}
+
+// Перезапуск включен: задан и не равен "no"
+func restartEnabled(restart string) bool {
+	return restart != "" && restart != "no"
+}
+
+// Итоговый RestartSec: явное значение или DefaultRestartSec при включенном
+// перезапуске
+func effectiveRestartSec(config ServiceConfig) string {
+	if config.RestartSec != "" || !restartEnabled(effectiveRestart(config)) {
+		return config.RestartSec
+	}
+	return DefaultRestartSec
+}
+
+// Проверить тип сервиса, политику перезапуска и таймауты. systemdVersion -
+// версия systemd на машине; 0 означает, что она неизвестна, и проверяются
+// правила актуальных версий.
+func ValidateServicePolicy(config ServiceConfig, systemdVersion int) error {
+	if config.Type != "" && !slices.Contains(ServiceTypes, config.Type) {
+		return fmt.Errorf("Type: допустимые значения: %s", strings.Join(ServiceTypes, ", "))
+	}
+	if config.Restart != "" && !slices.Contains(RestartPolicies, config.Restart) {
+		return fmt.Errorf("Restart: допустимые значения: %s", strings.Join(RestartPolicies, ", "))
+	}
+	if config.KillMode != "" && !slices.Contains(KillModes, config.KillMode) {
+		return fmt.Errorf("KillMode: допустимые значения: %s", strings.Join(KillModes, ", "))
+	}
+	if err := ValidateKillSignal(config.KillSignal); err != nil {
+		return fmt.Errorf("KillSignal: %w", err)
+	}
+	if config.StartLimitBurst < 0 {
+		return errors.New("StartLimitBurst не может быть отрицательным")
+	}
+	if len(config.ExecStartNext) > 0 && effectiveType(config) != "oneshot" {
+		return errors.New("несколько команд ExecStart допустимы только для Type=oneshot")
+	}
+
+	for _, field := range []struct {
+		name, value string
+		infinity    bool
+	}{
+		{"RestartSec", config.RestartSec, false},
+		{"StartLimitIntervalSec", config.StartLimitIntervalSec, true},
+		{"TimeoutStartSec", config.TimeoutStartSec, true},
+		{"TimeoutStopSec", config.TimeoutStopSec, true},
+	} {
+		if field.value == "" || (field.infinity && field.value == "infinity") {
+			continue
+		}
+		if _, err := ParseTimespan(field.value); err != nil {
+			return fmt.Errorf("%s: %w", field.name, err)
+		}
+	}
+
+	serviceType := effectiveType(config)
+	restart := effectiveRestart(config)
+	if serviceType == "oneshot" {
+		switch {
+		case restart == "always" || restart == "on-success":
+			return fmt.Errorf("Type=oneshot несовместим с Restart=%s: сервис завершается после каждого запуска", restart)
+		case restartEnabled(restart) && systemdVersion > 0 && systemdVersion < oneshotRestartVersion:
+			return fmt.Errorf("Restart=%s для Type=oneshot поддерживается с systemd %d, установлена версия %d", restart, oneshotRestartVersion, systemdVersion)
+		}
+	} else if config.RemainAfterExit {
+		return errors.New("RemainAfterExit имеет смысл только для Type=oneshot")
+	}
+
+	if !restartEnabled(restart) && config.RestartSec != "" {
+		return errors.New("RestartSec задается только вместе с перезапуском (Restart)")
+	}
+
+	return nil
+}
+
+// Проверить сигнал KillSignal: имя (SIGTERM или TERM) либо номер
+func ValidateKillSignal(signal string) error {
+	if signal == "" {
+		return nil
+	}
+	if n, err := strconv.Atoi(signal); err == nil {
+		if n < 1 || n > 64 {
+			return fmt.Errorf("номер сигнала должен быть от 1 до 64, получено %d", n)
+		}
+		return nil
+	}
+
+	name := strings.ToUpper(signal)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	if !slices.Contains(killSignals, name) {
+		return fmt.Errorf("неизвестный сигнал %q", signal)
+	}
+	return nil
+}
+
+// Версия systemd на этой машине; 0, если ее не удалось определить
+var LocalSystemdVersion = sync.OnceValue(func() int {
+	output, err := ExecuteCommand(context.Background(), "systemctl", "--version")
+	if err != nil {
+		return 0
+	}
+	return parseSystemdVersion(output)
+})
+
+// Разобрать версию из вывода systemctl --version: "systemd 252 (252.22-1)"
+func parseSystemdVersion(output string) int {
+	fields := strings.Fields(output)
+	if len(fields) < 2 || fields[0] != "systemd" {
+		return 0
+	}
+	version, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0
+	}
+	return version
+}
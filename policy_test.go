@@ -0,0 +1,57 @@
+package sdmanager
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateServicePolicy(t *testing.T) {
+	valid := []ServiceConfig{
+		{},
+		{Type: "notify", Restart: "on-failure", RestartSec: "5s", StartLimitIntervalSec: "5min", StartLimitBurst: 3},
+		{Type: "oneshot", RemainAfterExit: true},
+		{Type: "oneshot", Restart: "on-failure"},
+		{Type: "oneshot", ExecStartNext: []string{"/bin/b"}},
+		{TimeoutStartSec: "infinity", TimeoutStopSec: "30s", KillMode: "mixed", KillSignal: "SIGINT"},
+		{KillSignal: "TERM"},
+		{Timer: &TimerConfig{OnCalendar: []string{"daily"}}, Restart: "on-abnormal"},
+	}
+	for _, config := range valid {
+		if err := ValidateServicePolicy(config, 0); err != nil {
+			t.Errorf("ValidateServicePolicy(%+v): %v", config, err)
+		}
+	}
+
+	invalid := []ServiceConfig{
+		{Type: "daemon"},
+		{Restart: "sometimes"},
+		{KillMode: "all"},
+		{KillSignal: "SIGFOO"},
+		{KillSignal: "99"},
+		{RestartSec: "soon"},
+		{StartLimitBurst: -1},
+		{Type: "oneshot", Restart: "always"},
+		{Path: &PathConfig{}, Restart: "on-success"},
+		{RemainAfterExit: true},
+		{Restart: "no", RestartSec: "5"},
+		{Type: "simple", ExecStartNext: []string{"/bin/b"}},
+	}
+	for _, config := range invalid {
+		if err := ValidateServicePolicy(config, 0); err == nil {
+			t.Errorf("ValidateServicePolicy(%+v) succeeded", config)
+		}
+	}
+
+	// До systemd 244 oneshot нельзя перезапускать вообще
+	config := ServiceConfig{Type: "oneshot", Restart: "on-failure"}
+	if err := ValidateServicePolicy(config, 243); err == nil || !strings.Contains(err.Error(), "244") {
+		t.Errorf("old systemd: err = %v", err)
+	}
+	if err := ValidateServicePolicy(config, 252); err != nil {
+		t.Errorf("new systemd: %v", err)
+	}
+
+	if version := parseSystemdVersion("systemd 252 (252.22-1~deb12u1)\n+PAM +AUDIT"); version != 252 {
+		t.Errorf("parseSystemdVersion = %d", version)
+	}
+}
@@ -0,0 +1,373 @@
+package sdmanager
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Размер памяти для MemoryHigh, MemoryMax и MemorySwapMax: число с единицей
+// K, M, G или T, процент от памяти хоста или infinity. Число без единицы -
+// мегабайты, как в манифестах прежних версий.
+type MemorySize string
+
+// Размер в JSON может быть задан и числом мегабайт
+func (s *MemorySize) UnmarshalJSON(data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case nil:
+		*s = ""
+	case string:
+		*s = MemorySize(v)
+	case float64:
+		*s = MemorySize(strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		return fmt.Errorf("некорректный размер памяти: %s", data)
+	}
+	return nil
+}
+
+// Значение для unit-файла: число без единицы дополняется M
+func (s MemorySize) UnitValue() string {
+	value := strings.TrimSpace(string(s))
+	if value == "" || value == "0" {
+		return ""
+	}
+	if number, err := strconv.ParseFloat(value, 64); err == nil && !math.IsInf(number, 0) && !math.IsNaN(number) {
+		return value + "M"
+	}
+	return value
+}
+
+// Размер памяти из unit-файла. В unit-файле число без единицы - байты,
+// поэтому оно переводится в мегабайты или записывается с единицей B.
+func memorySizeFromUnit(value string) (MemorySize, error) {
+	value = strings.TrimSpace(value)
+	if _, err := unitSizeBytes(value, 0, true); err != nil {
+		return "", err
+	}
+
+	bytes, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return MemorySize(value), nil
+	}
+	if bytes == 0 {
+		// Для MemorySwapMax 0 - запрет swap, а не отсутствие ограничения
+		return "0M", nil
+	}
+	if bytes%(1024*1024) == 0 {
+		return MemorySize(strconv.FormatUint(bytes/(1024*1024), 10)), nil
+	}
+	return MemorySize(value + "B"), nil
+}
+
+// Размер в байтах из записи unit-файла: байты или единицы B/K/M/G/T,
+// infinity, а при percent - процент от total. Если total неизвестен (0),
+// процент проверяется, но возвращается 0.
+func unitSizeBytes(value string, total uint64, percent bool) (uint64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "infinity" {
+		return math.MaxUint64, nil
+	}
+
+	if number, ok := strings.CutSuffix(value, "%"); ok && percent {
+		p, err := strconv.ParseFloat(number, 64)
+		if err != nil || p < 0 || p > 100 {
+			return 0, fmt.Errorf("некорректный процент: %s", value)
+		}
+		return uint64(float64(total) * p / 100), nil
+	}
+
+	multiplier := uint64(1)
+	if unit := strings.Index("BKMGT", value[len(value)-1:]); unit >= 0 {
+		multiplier = 1 << (10 * unit)
+		value = value[:len(value)-1]
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 || math.IsInf(number, 0) || math.IsNaN(number) {
+		return 0, errors.New("некорректный размер: допустимы число с единицей K, M, G, T, процент или infinity")
+	}
+	return uint64(number * float64(multiplier)), nil
+}
+
+// Ресурсы машины, с которыми сравниваются ограничения сервиса. Нулевое
+// значение означает, что ресурс определить не удалось, и он не проверяется.
+type HostResources struct {
+	// Объем памяти в байтах (MemTotal из /proc/meminfo)
+	Memory uint64
+	// Число доступных ядер, как у nproc
+	CPUs int
+	// Число узлов NUMA
+	MemoryNodes int
+}
+
+// Ресурсы этой машины
+var LocalHostResources = sync.OnceValue(func() HostResources {
+	host := HostResources{CPUs: runtime.NumCPU()}
+
+	if file, err := os.Open("/proc/meminfo"); err == nil {
+		host.Memory, _ = parseMemTotal(file)
+		file.Close()
+	}
+
+	nodes, _ := filepath.Glob("/sys/devices/system/node/node[0-9]*")
+	host.MemoryNodes = len(nodes)
+
+	return host
+})
+
+// Разобрать MemTotal из /proc/meminfo: "MemTotal:  16318480 kB"
+func parseMemTotal(r io.Reader) (uint64, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+		kilobytes, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("некорректный MemTotal: %s", fields[1])
+		}
+		return kilobytes * 1024, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, errors.New("MemTotal не найден")
+}
+
+// Номера из списка ядер или узлов NUMA: "0-3,6 8"
+func parseIndexList(value string) ([]int, error) {
+	var indexes []int
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+		first, last, isRange := strings.Cut(item, "-")
+		start, err := strconv.Atoi(first)
+		if err != nil || start < 0 {
+			return nil, fmt.Errorf("некорректный номер %q: ожидается список вида 0-3,6", item)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(last); err != nil || end < start {
+				return nil, fmt.Errorf("некорректный диапазон %q", item)
+			}
+		}
+		for i := start; i <= end; i++ {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes, nil
+}
+
+// Проверить список номеров; count - число ядер или узлов хоста (0 - не
+// проверять)
+func validateIndexList(key, value string, count int, what string) error {
+	indexes, err := parseIndexList(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	for _, index := range indexes {
+		if count > 0 && index >= count {
+			return fmt.Errorf("%s: %s %d нет на этой машине (доступно %d: 0-%d)", key, what, index, count, count-1)
+		}
+	}
+	return nil
+}
+
+// Проверить ограничение LimitNOFILE, LimitNPROC или LimitCORE: число,
+// infinity или пара мягкого и жесткого ограничений «soft:hard». LimitCORE
+// задается размером и допускает единицы K, M, G.
+func validateLimit(key, value string) error {
+	values := strings.Split(value, ":")
+	if len(values) > 2 {
+		return fmt.Errorf("%s: ожидается число, infinity или soft:hard", key)
+	}
+
+	var limits []uint64
+	for _, v := range values {
+		limit, err := strconv.ParseUint(v, 10, 64)
+		switch {
+		case v == "infinity":
+			limit = math.MaxUint64
+		case err == nil:
+		case key == "LimitCORE":
+			if limit, err = unitSizeBytes(v, 0, false); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		default:
+			return fmt.Errorf("%s: ожидается число, infinity или soft:hard", key)
+		}
+		limits = append(limits, limit)
+	}
+
+	if len(limits) == 2 && limits[0] > limits[1] {
+		return fmt.Errorf("%s: мягкое ограничение больше жесткого", key)
+	}
+	return nil
+}
+
+// Проверить ограничение памяти и сравнить его с памятью хоста
+func validateMemorySize(key string, size MemorySize, host HostResources) (uint64, error) {
+	bytes, err := unitSizeBytes(size.UnitValue(), host.Memory, true)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+	if host.Memory > 0 && bytes != math.MaxUint64 && bytes > host.Memory && key != "MemorySwapMax" {
+		return 0, fmt.Errorf("%s: %s больше памяти машины (%s)", key, size.UnitValue(), formatBytes(host.Memory))
+	}
+	return bytes, nil
+}
+
+// Проверить ограничения памяти: MemoryHigh должен быть меньше MemoryMax,
+// и оба не больше памяти машины
+func validateMemoryLimits(config ServiceConfig, host HostResources) error {
+	memoryHigh, err := validateMemorySize("MemoryHigh", config.MemoryHigh, host)
+	if err != nil {
+		return err
+	}
+	memoryMax, err := validateMemorySize("MemoryMax", config.MemoryMax, host)
+	if err != nil {
+		return err
+	}
+	if _, err := validateMemorySize("MemorySwapMax", config.MemorySwapMax, host); err != nil {
+		return err
+	}
+
+	if config.MemoryHigh.UnitValue() != "" && config.MemoryMax.UnitValue() != "" &&
+		memoryMax > 0 && memoryMax != math.MaxUint64 && memoryHigh >= memoryMax {
+		return errors.New("MemoryHigh должен быть меньше MemoryMax")
+	}
+	return nil
+}
+
+// Разобрать CPUQuota в процентах: "50", "50%" или "12.5%"
+func ParseCPUQuota(value string) (float64, error) {
+	quota, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "%"), 64)
+	if err != nil || math.IsNaN(quota) || math.IsInf(quota, 0) {
+		return 0, errors.New("введено не число")
+	}
+	if quota < 0 {
+		return 0, errors.New("значение не может быть отрицательным")
+	}
+	return quota, nil
+}
+
+// Проверить ограничения ресурсов сервиса с учетом памяти и ядер машины
+func ValidateResources(config ServiceConfig, host HostResources) error {
+	if err := validateMemoryLimits(config, host); err != nil {
+		return err
+	}
+
+	if config.CPUQuota < 0 {
+		return errors.New("значение не может быть отрицательным")
+	}
+
+	if err := validateIndexList("AllowedCPUs", config.AllowedCPUs, host.CPUs, "ядра"); err != nil {
+		return err
+	}
+	if err := validateIndexList("AllowedMemoryNodes", config.AllowedMemoryNodes, host.MemoryNodes, "узла NUMA"); err != nil {
+		return err
+	}
+
+	if config.TasksMax != "" && config.TasksMax != "infinity" {
+		if number, ok := strings.CutSuffix(config.TasksMax, "%"); ok {
+			if p, err := strconv.ParseFloat(number, 64); err != nil || p <= 0 || p > 100 {
+				return fmt.Errorf("TasksMax: некорректный процент: %s", config.TasksMax)
+			}
+		} else if tasks, err := strconv.Atoi(config.TasksMax); err != nil || tasks <= 0 {
+			return errors.New("TasksMax: ожидается число задач, процент или infinity")
+		}
+	}
+
+	if config.CPUWeight < 0 || config.CPUWeight > 10000 {
+		return errors.New("CPUWeight: допустимы значения от 1 до 10000")
+	}
+	if config.IOWeight < 0 || config.IOWeight > 10000 {
+		return errors.New("IOWeight: допустимы значения от 1 до 10000")
+	}
+
+	for _, key := range []string{"IODeviceReadBandwidthMax", "IODeviceWriteBandwidthMax"} {
+		limits := config.IODeviceReadBandwidthMax
+		if key == "IODeviceWriteBandwidthMax" {
+			limits = config.IODeviceWriteBandwidthMax
+		}
+		for _, limit := range limits {
+			device, bandwidth, ok := strings.Cut(strings.TrimSpace(limit), " ")
+			if !ok || !filepath.IsAbs(device) {
+				return fmt.Errorf("%s: ожидается «устройство скорость», например /dev/sda 10M", key)
+			}
+			if _, err := unitSizeBytes(strings.TrimSpace(bandwidth), 0, false); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		}
+	}
+
+	for i, value := range []string{config.LimitNOFILE, config.LimitNPROC, config.LimitCORE} {
+		if value == "" {
+			continue
+		}
+		if err := validateLimit([]string{"LimitNOFILE", "LimitNPROC", "LimitCORE"}[i], value); err != nil {
+			return err
+		}
+	}
+
+	if config.Nice != nil && (*config.Nice < -20 || *config.Nice > 19) {
+		return errors.New("Nice: допустимы значения от -20 до 19")
+	}
+
+	return nil
+}
+
+// Директива ограничения ресурсов с итоговым значением
+type resourceDirective struct {
+	Key   string
+	Value string
+}
+
+// Дополнительные директивы ограничения ресурсов в порядке записи в
+// unit-файл
+func resourceDirectives(config ServiceConfig) []resourceDirective {
+	var directives []resourceDirective
+	add := func(key string, values ...string) {
+		for _, value := range values {
+			if value != "" {
+				directives = append(directives, resourceDirective{Key: key, Value: value})
+			}
+		}
+	}
+
+	add("MemorySwapMax", config.MemorySwapMax.UnitValue())
+	add("CPUWeight", formatPositive(config.CPUWeight))
+	add("AllowedMemoryNodes", config.AllowedMemoryNodes)
+	add("TasksMax", config.TasksMax)
+	add("IOWeight", formatPositive(config.IOWeight))
+	add("IODeviceReadBandwidthMax", config.IODeviceReadBandwidthMax...)
+	add("IODeviceWriteBandwidthMax", config.IODeviceWriteBandwidthMax...)
+	add("LimitNOFILE", config.LimitNOFILE)
+	add("LimitNPROC", config.LimitNPROC)
+	add("LimitCORE", config.LimitCORE)
+	add("Nice", formatNice(config.Nice))
+
+	return directives
+}
+
+// Форматирование Nice: не заданное значение не записывается
+func formatNice(nice *int) string {
+	if nice == nil {
+		return ""
+	}
+	return strconv.Itoa(*nice)
+}
@@ -0,0 +1,86 @@
+package sdmanager
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestValidateResources(t *testing.T) {
+	host := HostResources{Memory: 2 << 30, CPUs: 4, MemoryNodes: 1}
+	nice := 5
+
+	valid := ServiceConfig{
+		MemoryHigh:                "1.5G",
+		MemoryMax:                 "90%",
+		MemorySwapMax:             "0M",
+		AllowedCPUs:               "0-1,3",
+		AllowedMemoryNodes:        "0",
+		TasksMax:                  "20%",
+		CPUWeight:                 200,
+		IOWeight:                  50,
+		IODeviceReadBandwidthMax:  []string{"/dev/sda 10M"},
+		IODeviceWriteBandwidthMax: []string{"/dev/nvme0n1 1G"},
+		LimitNOFILE:               "1024:65536",
+		LimitNPROC:                "infinity",
+		LimitCORE:                 "512M",
+		Nice:                      &nice,
+	}
+	if err := ValidateResources(valid, host); err != nil {
+		t.Fatalf("ValidateResources: %v", err)
+	}
+
+	invalid := map[string]func(config *ServiceConfig){
+		"больше памяти машины":                    func(config *ServiceConfig) { config.MemoryMax = "4G" },
+		"MemoryHigh должен быть меньше MemoryMax": func(config *ServiceConfig) { config.MemoryHigh = "2000" },
+		"некорректный процент":                    func(config *ServiceConfig) { config.MemoryMax = "150%" },
+		"ядра 4 нет на этой машине":               func(config *ServiceConfig) { config.AllowedCPUs = "2-4" },
+		"узла NUMA 1 нет":                         func(config *ServiceConfig) { config.AllowedMemoryNodes = "0,1" },
+		"TasksMax":                                func(config *ServiceConfig) { config.TasksMax = "many" },
+		"IOWeight":                                func(config *ServiceConfig) { config.IOWeight = 20000 },
+		"IODeviceReadBandwidthMax":                func(config *ServiceConfig) { config.IODeviceReadBandwidthMax = []string{"sda 10M"} },
+		"мягкое ограничение больше жесткого": func(config *ServiceConfig) { config.LimitNOFILE = "65536:1024" },
+		"Nice": func(config *ServiceConfig) { config.Nice = new(int); *config.Nice = 20 },
+	}
+	for want, change := range invalid {
+		config := valid
+		change(&config)
+		if err := ValidateResources(config, host); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: error = %v", want, err)
+		}
+	}
+
+	// Неизвестные ресурсы машины не проверяются
+	if err := ValidateResources(ServiceConfig{MemoryMax: "64G", AllowedCPUs: "0-63"}, HostResources{}); err != nil {
+		t.Errorf("unknown host: %v", err)
+	}
+}
+
+func TestMemorySize(t *testing.T) {
+	for value, want := range map[MemorySize]string{"512": "512M", "1.5G": "1.5G", "50%": "50%", "0": "", "infinity": "infinity"} {
+		if got := value.UnitValue(); got != want {
+			t.Errorf("%q.UnitValue() = %q, want %q", value, got, want)
+		}
+	}
+
+	// В unit-файле число без единицы - байты
+	for value, want := range map[string]MemorySize{"536870912": "512", "1000": "1000B", "0": "0M", "2G": "2G"} {
+		if got, err := memorySizeFromUnit(value); err != nil || got != want {
+			t.Errorf("memorySizeFromUnit(%q) = %q, %v, want %q", value, got, err, want)
+		}
+	}
+
+	// Прежние манифесты задают размер числом мегабайт
+	var config ServiceConfig
+	if err := json.Unmarshal([]byte(`{"memory_high": 256, "memory_max": "1G"}`), &config); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+	if config.MemoryHigh != "256" || config.MemoryMax != "1G" {
+		t.Errorf("config = %q, %q", config.MemoryHigh, config.MemoryMax)
+	}
+
+	total, err := parseMemTotal(strings.NewReader("MemTotal:       16318480 kB\nMemFree:         1024 kB\n"))
+	if err != nil || total != 16318480*1024 {
+		t.Errorf("parseMemTotal = %d, %v", total, err)
+	}
+}
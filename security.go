@@ -0,0 +1,410 @@
+package sdmanager
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Состояния экрана аудита безопасности
+const (
+	SecurityStateServiceName = iota
+	SecurityStateView
+)
+
+// Приоритет drop-in с рекомендациями аудита: он применяется после drop-in,
+// который sdmanager создает при редактировании
+const SecurityDropInPriority = 60
+
+// Находка systemd-analyze security: настройка, из-за которой сервис
+// уязвимее, чем мог бы быть
+type SecurityFinding struct {
+	// Проверка в записи systemd-analyze: "NoNewPrivileges=",
+	// "CapabilityBoundingSet=~CAP_SYS_TIME"
+	Name string
+	// Идентификатор проверки (json_field)
+	Field       string
+	Description string
+	// Вклад в итоговую оценку
+	Exposure float64
+}
+
+// Результат аудита безопасности сервиса
+type SecurityReport struct {
+	// Итоговая оценка: "9.6 UNSAFE"
+	Exposure string
+	// Находки по убыванию вклада в оценку
+	Findings []SecurityFinding
+}
+
+// Директива, закрывающая находку. Значение с "~" - запрещающий список:
+// значения нескольких находок объединяются в одну строку.
+type securityRecommendation struct {
+	Key   string
+	Value string
+}
+
+// Рекомендации для находок, которые можно закрыть без риска сломать обычный
+// сервис. Сеть, пользователь, запись в файлы и capabilities, без которых
+// сервис может не запуститься (CAP_DAC_*, CAP_SETUID, CAP_NET_*, CAP_KILL),
+// остаются на усмотрение администратора.
+var securityRecommendations = map[string]securityRecommendation{
+	"NoNewPrivileges":         {"NoNewPrivileges", "yes"},
+	"PrivateDevices":          {"PrivateDevices", "yes"},
+	"PrivateTmp":              {"PrivateTmp", "yes"},
+	"PrivateMounts":           {"PrivateMounts", "yes"},
+	"ProtectSystem":           {"ProtectSystem", "full"},
+	"ProtectHome":             {"ProtectHome", "read-only"},
+	"ProtectClock":            {"ProtectClock", "yes"},
+	"ProtectKernelLogs":       {"ProtectKernelLogs", "yes"},
+	"ProtectKernelModules":    {"ProtectKernelModules", "yes"},
+	"ProtectKernelTunables":   {"ProtectKernelTunables", "yes"},
+	"ProtectControlGroups":    {"ProtectControlGroups", "yes"},
+	"ProtectHostname":         {"ProtectHostname", "yes"},
+	"ProtectProc":             {"ProtectProc", "invisible"},
+	"RestrictSUIDSGID":        {"RestrictSUIDSGID", "yes"},
+	"RestrictRealtime":        {"RestrictRealtime", "yes"},
+	"LockPersonality":         {"LockPersonality", "yes"},
+	"SystemCallArchitectures": {"SystemCallArchitectures", "native"},
+	"UMask":                   {"UMask", "0027"},
+
+	"RestrictNamespaces_user":   {"RestrictNamespaces", "~user"},
+	"RestrictNamespaces_pid":    {"RestrictNamespaces", "~pid"},
+	"RestrictNamespaces_net":    {"RestrictNamespaces", "~net"},
+	"RestrictNamespaces_uts":    {"RestrictNamespaces", "~uts"},
+	"RestrictNamespaces_mnt":    {"RestrictNamespaces", "~mnt"},
+	"RestrictNamespaces_ipc":    {"RestrictNamespaces", "~ipc"},
+	"RestrictNamespaces_cgroup": {"RestrictNamespaces", "~cgroup"},
+
+	"RestrictAddressFamilies_AF_PACKET": {"RestrictAddressFamilies", "~AF_PACKET"},
+
+	"CapabilityBoundingSet_CAP_SYS_TIME":        {"CapabilityBoundingSet", "~CAP_SYS_TIME"},
+	"CapabilityBoundingSet_CAP_SYS_PACCT":       {"CapabilityBoundingSet", "~CAP_SYS_PACCT"},
+	"CapabilityBoundingSet_CAP_WAKE_ALARM":      {"CapabilityBoundingSet", "~CAP_WAKE_ALARM"},
+	"CapabilityBoundingSet_CAP_LINUX_IMMUTABLE": {"CapabilityBoundingSet", "~CAP_LINUX_IMMUTABLE"},
+	"CapabilityBoundingSet_CAP_SYS_MODULE":      {"CapabilityBoundingSet", "~CAP_SYS_MODULE"},
+	"CapabilityBoundingSet_CAP_BPF":             {"CapabilityBoundingSet", "~CAP_BPF"},
+	"CapabilityBoundingSet_CAP_SYS_TTY_CONFIG":  {"CapabilityBoundingSet", "~CAP_SYS_TTY_CONFIG"},
+	"CapabilityBoundingSet_CAP_SYS_BOOT":        {"CapabilityBoundingSet", "~CAP_SYS_BOOT"},
+	"CapabilityBoundingSet_CAP_BLOCK_SUSPEND":   {"CapabilityBoundingSet", "~CAP_BLOCK_SUSPEND"},
+	"CapabilityBoundingSet_CAP_LEASE":           {"CapabilityBoundingSet", "~CAP_LEASE"},
+	"CapabilityBoundingSet_CAP_MKNOD":           {"CapabilityBoundingSet", "~CAP_MKNOD"},
+	"CapabilityBoundingSet_CAP_SYS_RAWIO":       {"CapabilityBoundingSet", "~CAP_SYS_RAWIO"},
+	"CapabilityBoundingSet_CAP_SYS_PTRACE":      {"CapabilityBoundingSet", "~CAP_SYS_PTRACE"},
+	"CapabilityBoundingSet_CAP_SYSLOG":          {"CapabilityBoundingSet", "~CAP_SYSLOG"},
+	"CapabilityBoundingSet_CAP_MAC":             {"CapabilityBoundingSet", "~CAP_MAC_ADMIN CAP_MAC_OVERRIDE"},
+	"CapabilityBoundingSet_CAP_AUDIT":           {"CapabilityBoundingSet", "~CAP_AUDIT_CONTROL CAP_AUDIT_READ CAP_AUDIT_WRITE"},
+
+	"SystemCallFilter_clock":         {"SystemCallFilter", "~@clock"},
+	"SystemCallFilter_cpu_emulation": {"SystemCallFilter", "~@cpu-emulation"},
+	"SystemCallFilter_debug":         {"SystemCallFilter", "~@debug"},
+	"SystemCallFilter_module":        {"SystemCallFilter", "~@module"},
+	"SystemCallFilter_mount":         {"SystemCallFilter", "~@mount"},
+	"SystemCallFilter_obsolete":      {"SystemCallFilter", "~@obsolete"},
+	"SystemCallFilter_raw_io":        {"SystemCallFilter", "~@raw-io"},
+	"SystemCallFilter_reboot":        {"SystemCallFilter", "~@reboot"},
+	"SystemCallFilter_swap":          {"SystemCallFilter", "~@swap"},
+}
+
+// Рекомендованная директива для находки: "NoNewPrivileges=yes". Пустая
+// строка - находку нужно разобрать вручную.
+func (f SecurityFinding) Recommendation() string {
+	rec, ok := securityRecommendations[f.Field]
+	if !ok {
+		return ""
+	}
+	return rec.Key + "=" + rec.Value
+}
+
+// Разобрать вывод systemd-analyze security --json=short. Остаются только
+// проверки, которые не пройдены и влияют на оценку.
+func ParseSecurityFindings(output string) ([]SecurityFinding, error) {
+	// Предупреждения systemd-analyze выводятся перед JSON
+	if start := strings.Index(output, "["); start > 0 {
+		output = output[start:]
+	}
+
+	var entries []struct {
+		Set         *bool   `json:"set"`
+		Name        string  `json:"name"`
+		Field       string  `json:"json_field"`
+		Description string  `json:"description"`
+		Exposure    *string `json:"exposure"`
+	}
+	if err := json.Unmarshal([]byte(output), &entries); err != nil {
+		return nil, fmt.Errorf("не удалось разобрать вывод systemd-analyze security: %w", err)
+	}
+
+	var findings []SecurityFinding
+	for _, entry := range entries {
+		if entry.Set == nil || *entry.Set || entry.Exposure == nil {
+			continue
+		}
+		exposure, err := strconv.ParseFloat(*entry.Exposure, 64)
+		if err != nil || exposure <= 0 {
+			continue
+		}
+		findings = append(findings, SecurityFinding{
+			Name:        entry.Name,
+			Field:       entry.Field,
+			Description: entry.Description,
+			Exposure:    exposure,
+		})
+	}
+
+	slices.SortStableFunc(findings, func(a, b SecurityFinding) int {
+		return cmp.Compare(b.Exposure, a.Exposure)
+	})
+	return findings, nil
+}
+
+// Итоговая оценка из текстового вывода systemd-analyze security
+func parseExposure(output string) (string, error) {
+	match := exposurePattern.FindStringSubmatch(output)
+	if match == nil {
+		return "", errors.New("не удалось разобрать вывод systemd-analyze security")
+	}
+	return match[1] + " " + match[2], nil
+}
+
+// Сформировать drop-in с рекомендациями для находок. Запрещающие списки
+// одной директивы объединяются в одну строку.
+func SecurityDropIn(findings []SecurityFinding) (string, error) {
+	var keys []string
+	values := make(map[string][]string)
+	for _, finding := range findings {
+		rec, ok := securityRecommendations[finding.Field]
+		if !ok {
+			continue
+		}
+		if _, seen := values[rec.Key]; !seen {
+			keys = append(keys, rec.Key)
+		}
+		if !slices.Contains(values[rec.Key], rec.Value) {
+			values[rec.Key] = append(values[rec.Key], rec.Value)
+		}
+	}
+
+	if len(keys) == 0 {
+		return "", errors.New("нет рекомендаций, которые можно применить автоматически")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# Создано sdmanager по результатам systemd-analyze security\n[Service]\n")
+	for _, key := range keys {
+		list := values[key]
+		if strings.HasPrefix(list[0], "~") {
+			for i := range list {
+				list[i] = strings.TrimPrefix(list[i], "~")
+			}
+			sb.WriteString(key + "=~" + strings.Join(list, " ") + "\n")
+			continue
+		}
+		sb.WriteString(key + "=" + list[0] + "\n")
+	}
+	return sb.String(), nil
+}
+
+// Записать рекомендации аудита в drop-in сервиса и выполнить daemon-reload.
+// Ограничения вступают в силу после перезапуска сервиса.
+func ApplySecurityRecommendations(ctx context.Context, b Backend, serviceName string, findings []SecurityFinding) (string, error) {
+	content, err := SecurityDropIn(findings)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := WriteDropIn(DefaultUnitDir, serviceName, SecurityDropInPriority, content)
+	if err != nil {
+		return "", err
+	}
+
+	return applyServiceChanges(ctx, b, serviceName, UserActions{ReloadDaemon: true}, "Drop-in файл записан: "+path)
+}
+
+// Создать модель экрана аудита безопасности
+func NewSecurityModel(appOptions AppOptions) SecurityModel {
+	ti := textinput.New()
+	ti.Placeholder = "myservice"
+	ti.Focus()
+	ti.CharLimit = 255
+	ti.Width = 80
+
+	return SecurityModel{
+		State:    SecurityStateServiceName,
+		Input:    ti,
+		Viewport: viewport.New(100, 20),
+		backend:  appOptions.backend,
+	}
+}
+
+// Выполнить аудит безопасности сервиса
+func LoadSecurityReport(ctx context.Context, model SecurityModel, serviceName string) SecurityModel {
+	report, err := model.backend.SecurityReport(ctx, serviceName)
+	if err != nil {
+		model.Error = err.Error()
+		return model
+	}
+
+	model.ServiceName = serviceName
+	model.Report = report
+	model.State = SecurityStateView
+	model.Error = ""
+	model.Viewport.SetContent(RenderSecurityFindings(report.Findings))
+	model.Viewport.GotoTop()
+	return model
+}
+
+// Таблица находок: вклад в оценку, проверка, рекомендация и описание
+func RenderSecurityFindings(findings []SecurityFinding) string {
+	if len(findings) == 0 {
+		return "Замечаний нет\n"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("245")).Render(fmt.Sprintf("%-6s %-36s %-36s %s", "Вклад", "Проверка", "Рекомендация", "Описание")) + "\n")
+	for _, finding := range findings {
+		recommendation := finding.Recommendation()
+		if recommendation == "" {
+			recommendation = "вручную"
+		}
+		sb.WriteString(fmt.Sprintf("%-6.1f %-36s %-36s %s\n",
+			finding.Exposure, truncate(finding.Name, 36), truncate(recommendation, 36), finding.Description))
+	}
+	return sb.String()
+}
+
+// Обработка событий экрана аудита безопасности
+func UpdateSecurity(ctx context.Context, msg tea.Msg, model SecurityModel) (SecurityModel, tea.Cmd) {
+	if size, ok := msg.(tea.WindowSizeMsg); ok {
+		model.Viewport.Width = size.Width
+		model.Viewport.Height = max(size.Height-8, 3)
+		return model, nil
+	}
+
+	keyMsg, ok := msg.(tea.KeyMsg)
+
+	if model.State == SecurityStateView {
+		if !ok {
+			return model, nil
+		}
+
+		if model.ConfirmRestart {
+			return confirmSecurityRestart(ctx, keyMsg, model), nil
+		}
+
+		switch keyMsg.String() {
+		case "w":
+			result, err := ApplySecurityRecommendations(ctx, model.backend, model.ServiceName, model.Report.Findings)
+			if err != nil {
+				model.Error = err.Error()
+				return model, nil
+			}
+			model = LoadSecurityReport(ctx, model, model.ServiceName)
+			model.Message = strings.TrimSpace(result)
+			model.ConfirmRestart = true
+			return model, nil
+		case "r":
+			model.Message = ""
+			return LoadSecurityReport(ctx, model, model.ServiceName), nil
+		case "q", "esc", "ctrl+c":
+			model.Quitting = true
+			return model, nil
+		}
+
+		var cmd tea.Cmd
+		model.Viewport, cmd = model.Viewport.Update(msg)
+		return model, cmd
+	}
+
+	if ok {
+		switch keyMsg.Type {
+		case tea.KeyEnter:
+			// Если есть предыдущая ошибка, просто очищаем её
+			if model.Error != "" {
+				model.Error = ""
+				return model, nil
+			}
+
+			serviceName := model.Input.Value()
+			if err := IsValidServiceName(serviceName); err != nil {
+				model.Error = err.Error()
+				return model, nil
+			}
+
+			return LoadSecurityReport(ctx, model, serviceName), nil
+
+		case tea.KeyEsc, tea.KeyCtrlC:
+			model.Quitting = true
+			return model, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	model.Input, cmd = model.Input.Update(msg)
+	return model, cmd
+}
+
+// Подтверждение перезапуска после записи рекомендаций
+func confirmSecurityRestart(ctx context.Context, msg tea.KeyMsg, model SecurityModel) SecurityModel {
+	switch msg.String() {
+	case "y", "Y":
+		model.ConfirmRestart = false
+		result, err := RestartService(ctx, model.backend, model.ServiceName)
+		if err != nil {
+			model.Error = err.Error()
+			return model
+		}
+		model = LoadSecurityReport(ctx, model, model.ServiceName)
+		model.Message = result
+
+	case "n", "N", "esc", "q":
+		model.ConfirmRestart = false
+		model.Message = "Ограничения вступят в силу после перезапуска сервиса"
+	}
+
+	return model
+}
+
+// Отрисовка экрана аудита безопасности
+func ViewSecurity(model SecurityModel) string {
+	var s strings.Builder
+
+	if model.State == SecurityStateServiceName {
+		s.WriteString("Введите имя сервиса для аудита безопасности:\n\n")
+		s.WriteString(model.Input.View() + "\n\n")
+		if model.Error != "" {
+			s.WriteString(FormatError(model.Error) + "\n\n")
+		}
+		s.WriteString("Нажмите Enter для подтверждения, Esc для возврата в меню\n")
+		return s.String()
+	}
+
+	s.WriteString(TitleStyle.Render(model.ServiceName) + "  ")
+	s.WriteString("Оценка systemd-analyze security (0 - лучше, 10 - хуже): " + model.Report.Exposure + "\n\n")
+	s.WriteString(model.Viewport.View() + "\n\n")
+
+	if model.Message != "" {
+		s.WriteString(FormatInfo(model.Message) + "\n")
+	}
+	if model.Error != "" {
+		s.WriteString(FormatError(model.Error) + "\n")
+	}
+
+	if model.ConfirmRestart {
+		s.WriteString("Перезапустить сервис, чтобы применить ограничения? (y/n)\n")
+	} else {
+		s.WriteString("w - записать рекомендации в drop-in, r - обновить, ↑/↓ - прокрутка, Esc - назад\n")
+	}
+
+	return s.String()
+}
@@ -0,0 +1,84 @@
+package sdmanager
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestParseSecurityFindings(t *testing.T) {
+	findings, err := ParseSecurityFindings("Warning: unit is not loaded\n" + readFixture(t, "security.json"))
+	if err != nil {
+		t.Fatalf("ParseSecurityFindings: %v", err)
+	}
+
+	// Пройденные и неприменимые проверки (AmbientCapabilities, RemoveIPC)
+	// в отчет не попадают
+	for _, finding := range findings {
+		if finding.Field == "AmbientCapabilities" || finding.Field == "RemoveIPC" {
+			t.Errorf("unexpected finding %+v", finding)
+		}
+	}
+	if len(findings) == 0 || findings[0].Field != "PrivateNetwork" || findings[0].Exposure != 0.5 {
+		t.Fatalf("findings are not sorted by exposure: %+v", findings[:min(len(findings), 3)])
+	}
+
+	if got := findings[0].Recommendation(); got != "" {
+		t.Errorf("PrivateNetwork recommendation = %q, want manual review", got)
+	}
+
+	dropIn, err := SecurityDropIn(findings)
+	if err != nil {
+		t.Fatalf("SecurityDropIn: %v", err)
+	}
+	for _, line := range []string{
+		"[Service]\n",
+		"NoNewPrivileges=yes\n",
+		"ProtectSystem=full\n",
+		"RestrictNamespaces=~user pid net uts mnt cgroup ipc\n",
+		"SystemCallFilter=~@clock @debug @module @mount @raw-io @reboot @swap @cpu-emulation @obsolete\n",
+	} {
+		if !strings.Contains(dropIn, line) {
+			t.Errorf("drop-in does not contain %q:\n%s", line, dropIn)
+		}
+	}
+	if strings.Count(dropIn, "CapabilityBoundingSet=") != 1 || strings.Contains(dropIn, "CAP_SYS_ADMIN") || strings.Contains(dropIn, "PrivateNetwork") {
+		t.Errorf("drop-in:\n%s", dropIn)
+	}
+
+	if _, err := SecurityDropIn([]SecurityFinding{{Field: "PrivateNetwork", Exposure: 0.5}}); err == nil {
+		t.Error("drop-in without applicable recommendations accepted")
+	}
+}
+
+func TestSecurityScreen(t *testing.T) {
+	backend := NewFakeBackend(UnitInfo{Name: "api", ActiveState: "active", SubState: "running"})
+	backend.SetSecurityReport("api", SecurityReport{
+		Exposure: "9.2 UNSAFE",
+		Findings: []SecurityFinding{
+			{Name: "PrivateNetwork=", Field: "PrivateNetwork", Description: "Service has access to the host's network", Exposure: 0.5},
+			{Name: "NoNewPrivileges=", Field: "NoNewPrivileges", Description: "Service processes may acquire new privileges", Exposure: 0.2},
+		},
+	})
+
+	model := NewSecurityModel(AppOptions{backend: backend})
+	model.Input.SetValue("api")
+	model, _ = UpdateSecurity(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+
+	if model.State != SecurityStateView || model.Error != "" {
+		t.Fatalf("state = %d, error = %q", model.State, model.Error)
+	}
+	view := ViewSecurity(model)
+	for _, text := range []string{"9.2 UNSAFE", "NoNewPrivileges=yes", "вручную"} {
+		if !strings.Contains(view, text) {
+			t.Errorf("view does not contain %q:\n%s", text, view)
+		}
+	}
+
+	model = LoadSecurityReport(context.Background(), NewSecurityModel(AppOptions{backend: backend}), "missing")
+	if model.State != SecurityStateServiceName || model.Error == "" {
+		t.Errorf("missing unit: state = %d, error = %q", model.State, model.Error)
+	}
+}
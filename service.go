@@ -1,18 +1,18 @@
 package sdmanager
 
 import (
-	"bufio"
 	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"text/template"
+	"unicode"
 
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
@@ -20,29 +20,70 @@ import (
 
 // Шаблон systemd unit
 const systemdUnitTemplate = `[Unit]
-Description={{.ServiceName}} Service
-After=network.target
+Description={{.ServiceName}} Service{{ if .Template }} %i{{ end }}
+{{- range .Dependencies }}
+{{ .Key }}={{ join .Units }}{{ end }}
+{{- if neq .StartLimitIntervalSec "" }}
+StartLimitIntervalSec={{.StartLimitIntervalSec}}{{ end }}
+{{- if gt .StartLimitBurst 0 }}
+StartLimitBurst={{.StartLimitBurst}}{{ end }}
 
 [Service]
 {{ if neq .UserName "" }}User={{.UserName}}{{ end }}
-WorkingDirectory={{.WorkingDirectory}}
+{{ if neq .WorkingDirectory "" }}WorkingDirectory={{.WorkingDirectory}}{{ end }}
+{{- range .Environment }}
+Environment={{ . }}{{ end }}
+{{- range .EnvironmentFiles }}
+EnvironmentFile={{ . }}{{ end }}
+{{- range .ExecStartPre }}
+ExecStartPre={{ . }}{{ end }}
 ExecStart={{.ExecStart}}
-Restart=always
-RestartSec=10
-OOMPolicy=restart
+{{- range .ExecStartNext }}
+ExecStart={{ . }}{{ end }}
+{{- range .ExecStartPost }}
+ExecStartPost={{ . }}{{ end }}
+{{- range .ExecReload }}
+ExecReload={{ . }}{{ end }}
+{{- range .ExecStop }}
+ExecStop={{ . }}{{ end }}
+{{- range .ExecStopPost }}
+ExecStopPost={{ . }}{{ end }}
+{{- if neq .Type "" }}
+Type={{.Type}}{{ end }}
+{{- if .RemainAfterExit }}
+RemainAfterExit=yes{{ end }}
+{{- if neq .Restart "" }}
+Restart={{.Restart}}{{ end }}
+{{- if neq .RestartSec "" }}
+RestartSec={{.RestartSec}}{{ end }}
+{{- if .OOMPolicy }}
+OOMPolicy=restart{{ end }}
+{{- if neq .TimeoutStartSec "" }}
+TimeoutStartSec={{.TimeoutStartSec}}{{ end }}
+{{- if neq .TimeoutStopSec "" }}
+TimeoutStopSec={{.TimeoutStopSec}}{{ end }}
+{{- if neq .KillMode "" }}
+KillMode={{.KillMode}}{{ end }}
+{{- if neq .KillSignal "" }}
+KillSignal={{.KillSignal}}{{ end }}
 
 {{ if neq .StandardOutput "" }}StandardOutput={{.StandardOutput}}{{ end }}
 {{ if neq .StandardError "" }}StandardError={{.StandardError}}{{ end }}
 {{ if neq .SyslogIdentifier "" }}SyslogIdentifier={{.SyslogIdentifier}}{{ end }}
 
-{{ if gt .MemoryMax 0 }}MemoryMax={{.MemoryMax}}M{{ end }}
-{{ if gt .MemoryHigh 0 }}MemoryHigh={{.MemoryHigh}}M{{ end }}
+{{ if neq .MemoryMax "" }}MemoryMax={{.MemoryMax}}{{ end }}
+{{ if neq .MemoryHigh "" }}MemoryHigh={{.MemoryHigh}}{{ end }}
 
-{{ if gt .CPUQuota 0 }}CPUQuota={{.CPUQuota}}%{{ end }}
+{{ if neq .CPUQuota "" }}CPUQuota={{.CPUQuota}}{{ end }}
 {{ if neq .AllowedCPUs "" }}AllowedCPUs={{.AllowedCPUs}}{{ end }}
-
-[Install]
-WantedBy=multi-user.target
+{{ range .Resources }}
+{{ .Key }}={{ .Value }}{{ end }}
+{{ range .Hardening }}
+{{ .Key }}={{ .Value }}{{ end }}
+
+{{ if .Install }}[Install]
+{{- range .Install }}
+{{ .Key }}={{ join .Units }}{{ end }}{{ end }}
 `
 
 // Получить текущую директорию
@@ -78,17 +119,24 @@ func GetShortPath(path string) string {
 	return parts[0] + "/.../" + parts[len(parts)-1]
 }
 
+// Проверка символов, недопустимых в пути
+func checkPathChars(path string) error {
+	for _, char := range []string{"*", "?", "<", ">", "|", ";"} {
+		if strings.Contains(path, char) {
+			return fmt.Errorf("путь содержит недопустимый символ: %s", char)
+		}
+	}
+	return nil
+}
+
 // Проверка валидности пути
 func IsValidPath(path string) error {
 	if path == "" {
 		return errors.New("путь не может быть пустым")
 	}
 
-	// Проверка символов, недопустимых в пути
-	for _, char := range []string{"*", "?", "<", ">", "|", ";"} {
-		if strings.Contains(path, char) {
-			return fmt.Errorf("путь содержит недопустимый символ: %s", char)
-		}
+	if err := checkPathChars(path); err != nil {
+		return err
 	}
 
 	// Проверка существования директории
@@ -124,6 +172,25 @@ func IsValidServiceName(name string) error {
 	return nil
 }
 
+// Разобрать список имен сервисов, разделенных пробелами или запятыми.
+// Повторы отбрасываются, порядок сохраняется.
+func ParseServiceNames(value string) ([]string, error) {
+	var names []string
+	for _, name := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		if err := IsValidServiceName(name); err != nil {
+			return nil, err
+		}
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, errors.New("имя сервиса не может быть пустым")
+	}
+	return names, nil
+}
+
 // Проверка валидности имени юзера
 func IsValidUserName(name string) error {
 	// Проверка на наличие специальных символов
@@ -155,12 +222,116 @@ func ParseIntValue(value string, defaultVal int) (int, error) {
 	return val, nil
 }
 
+// Проверка конфигурации сервиса целиком, с теми же правилами, что и в мастере
+// установки. Используется для неинтерактивной установки.
+func ValidateServiceConfig(config ServiceConfig) error {
+	if err := IsValidServiceName(config.ServiceName); err != nil {
+		return err
+	}
+
+	if err := IsValidUserName(config.UserName); err != nil {
+		return err
+	}
+
+	if config.WorkingDirectory != "" {
+		if err := IsValidPath(config.WorkingDirectory); err != nil {
+			return fmt.Errorf("WorkingDirectory: %w", err)
+		}
+	}
+
+	if strings.TrimSpace(config.ExecStart) == "" {
+		return errors.New("ExecStart не может быть пустым")
+	}
+
+	if err := ValidateExecHooks(config); err != nil {
+		return err
+	}
+
+	if err := ValidateDependencies(config); err != nil {
+		return err
+	}
+
+	if err := ValidateHardening(config.Hardening); err != nil {
+		return err
+	}
+	if err := validateHardeningPaths(config); err != nil {
+		return err
+	}
+
+	if err := ValidateResources(config, LocalHostResources()); err != nil {
+		return err
+	}
+
+	if err := IsValidPath(config.UnitFilePath); err != nil {
+		return fmt.Errorf("путь unit-файла: %w", err)
+	}
+
+	if err := ValidateServicePolicy(config, LocalSystemdVersion()); err != nil {
+		return err
+	}
+
+	if err := ValidateEnvironment(config.Environment); err != nil {
+		return fmt.Errorf("Environment: %w", err)
+	}
+	if err := ValidateEnvironment(config.Secrets); err != nil {
+		return fmt.Errorf("секреты: %w", err)
+	}
+	for _, path := range config.EnvironmentFiles {
+		if err := ValidateEnvironmentFile(path); err != nil {
+			return err
+		}
+	}
+
+	if triggerCount(config) > 1 {
+		return errors.New("сервис запускается только чем-то одним: таймером, сокетом или path-unit")
+	}
+
+	if err := ValidateInstanceCount(config.Instances); err != nil {
+		return err
+	}
+	if config.Instances > 0 && triggerCount(config) > 0 {
+		return errors.New("шаблон с экземплярами нельзя совмещать с таймером, сокетом или path-unit")
+	}
+
+	if config.Timer != nil {
+		if err := ValidateTimerConfig(context.Background(), *config.Timer); err != nil {
+			return fmt.Errorf("таймер: %w", err)
+		}
+	}
+
+	if config.Socket != nil {
+		if err := ValidateSocketConfig(*config.Socket); err != nil {
+			return fmt.Errorf("сокет: %w", err)
+		}
+	}
+
+	if config.Path != nil {
+		if err := ValidatePathConfig(*config.Path); err != nil {
+			return fmt.Errorf("path-unit: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Количество unit, которые запускают сервис вместо него самого
+func triggerCount(config ServiceConfig) int {
+	count := 0
+	for _, set := range []bool{config.Timer != nil, config.Socket != nil, config.Path != nil} {
+		if set {
+			count++
+		}
+	}
+	return count
+}
+
 // Генерация предпросмотра unit файла
 func GenerateUnitPreview(config ServiceConfig) (string, error) {
 	// Подготовка шаблона
 	tmpl, err := template.New("systemd-unit").Funcs(template.FuncMap{
-		"gt":  func(a, b int) bool { return a > b },
-		"neq": func(a, b string) bool { return a != b },
+		"gt":   func(a, b int) bool { return a > b },
+		"neq":  func(a, b string) bool { return a != b },
+		"join": func(units []string) string { return strings.Join(units, " ") },
 	}).Parse(systemdUnitTemplate)
 	if err != nil {
 		return "", fmt.Errorf("ошибка при разборе шаблона: %w", err)
@@ -178,25 +349,79 @@ func GenerateUnitPreview(config ServiceConfig) (string, error) {
 		UserName         string
 		WorkingDirectory string
 		ExecStart        string
+		ExecStartNext    []string
+		ExecStartPre     []string
+		ExecStartPost    []string
+		ExecReload       []string
+		ExecStop         []string
+		ExecStopPost     []string
+		Environment      []string
+		EnvironmentFiles []string
+		Type             string
+		Restart          string
+		RestartSec       string
+		OOMPolicy        bool
+		RemainAfterExit  bool
+		TimeoutStartSec  string
+		TimeoutStopSec   string
+		KillMode         string
+		KillSignal       string
 		StandardOutput   string
 		StandardError    string
 		SyslogIdentifier string
-		MemoryHigh       int
-		MemoryMax        int
-		CPUQuota         int
+		MemoryHigh       string
+		MemoryMax        string
+		CPUQuota         string
 		AllowedCPUs      string
+		Resources        []resourceDirective
+		Hardening        []hardeningDirective
+		Template         bool
+		// Непустые директивы зависимостей секций [Unit] и [Install]
+		Dependencies []dependency
+		Install      []dependency
+		// Ограничение частоты запусков задается в секции [Unit]
+		StartLimitIntervalSec string
+		StartLimitBurst       int
 	}{
 		ServiceName:      caser.String(config.ServiceName),
 		UserName:         config.UserName,
 		WorkingDirectory: config.WorkingDirectory,
 		ExecStart:        config.ExecStart,
+		ExecStartNext:    config.ExecStartNext,
+		ExecStartPre:     config.ExecStartPre,
+		ExecStartPost:    config.ExecStartPost,
+		ExecReload:       config.ExecReload,
+		ExecStop:         config.ExecStop,
+		ExecStopPost:     config.ExecStopPost,
+		Environment:      FormatEnvironment(config.Environment),
+		EnvironmentFiles: environmentFiles(config),
+		Type:             effectiveType(config),
+		Restart:          effectiveRestart(config),
+		RestartSec:       effectiveRestartSec(config),
+		// OOMPolicy=restart остается только в политике по умолчанию, чтобы
+		// не менять уже созданные unit-файлы
+		OOMPolicy:        config.Restart == "" && effectiveRestart(config) == "always",
+		RemainAfterExit:  config.RemainAfterExit,
+		TimeoutStartSec:  config.TimeoutStartSec,
+		TimeoutStopSec:   config.TimeoutStopSec,
+		KillMode:         config.KillMode,
+		KillSignal:       config.KillSignal,
 		StandardOutput:   config.StandardOutput,
 		StandardError:    config.StandardError,
 		SyslogIdentifier: config.SyslogIdentifier,
-		MemoryHigh:       config.MemoryHigh,
-		MemoryMax:        config.MemoryMax,
-		CPUQuota:         config.CPUQuota,
+		MemoryHigh:       config.MemoryHigh.UnitValue(),
+		MemoryMax:        config.MemoryMax.UnitValue(),
+		CPUQuota:         formatPercent(config.CPUQuota),
 		AllowedCPUs:      config.AllowedCPUs,
+		Resources:        resourceDirectives(config),
+		Hardening:        hardeningDirectives(config),
+		Template:         config.Instances > 0,
+		Dependencies:     unitDependencies(config, "Unit"),
+		// Сервис с таймером, сокетом или path-unit запускается ими, а не при
+		// загрузке
+		Install:               unitDependencies(config, "Install"),
+		StartLimitIntervalSec: config.StartLimitIntervalSec,
+		StartLimitBurst:       config.StartLimitBurst,
 	}
 
 	// Выполнение шаблона
@@ -209,23 +434,128 @@ func GenerateUnitPreview(config ServiceConfig) (string, error) {
 	return serviceUnit, nil
 }
 
-// Создание unit-файла
-func CreateUnitFile(config ServiceConfig, overwrite bool) error {
-	unitFilePath := filepath.Join(config.UnitFilePath, config.ServiceName+".service")
+// Unit-файл, создаваемый при установке сервиса
+type GeneratedUnit struct {
+	// Имя для systemctl: имя сервиса или имя с типом (api.timer)
+	Name    string
+	Path    string
+	Content string
+	// Активировать (enable/start) при установке. Сервис, который запускается
+	// таймером, сокетом или path-unit, сам не активируется.
+	Activate bool
+	// Экземпляры шаблона, которые активируются вместо него самого
+	Instances []string
+}
 
-	// Проверяем, существует ли файл и нужно ли его перезаписывать
-	if !overwrite {
-		if _, err := os.Stat(unitFilePath); err == nil {
-			return fmt.Errorf("файл %s уже существует и не будет перезаписан", unitFilePath)
-		}
+// Имена unit, которые нужно активировать при установке
+func (u GeneratedUnit) ActivateNames() []string {
+	switch {
+	case !u.Activate:
+		return nil
+	case len(u.Instances) > 0:
+		return u.Instances
+	default:
+		return []string{u.Name}
 	}
+}
 
-	// Получаем предпросмотр содержимого
+// Сформировать все unit-файлы сервиса: сам сервис и, если заданы, таймер,
+// сокет или path-unit
+func GenerateUnits(config ServiceConfig) ([]GeneratedUnit, error) {
 	content, err := GenerateUnitPreview(config)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	// Шаблон и сокет с Accept=yes запускают экземпляры name@.service
+	serviceFile := config.ServiceName + ".service"
+	if config.Instances > 0 || (config.Socket != nil && config.Socket.Accept) {
+		serviceFile = config.ServiceName + "@.service"
+	}
+
+	units := []GeneratedUnit{{
+		Name:      strings.TrimSuffix(serviceFile, ".service"),
+		Path:      filepath.Join(config.UnitFilePath, serviceFile),
+		Content:   content,
+		Activate:  triggerCount(config) == 0,
+		Instances: TemplateInstanceNames(config.ServiceName, config.Instances),
+	}}
+
+	if config.Timer != nil {
+		units = append(units, GeneratedUnit{
+			Name:     config.ServiceName + ".timer",
+			Path:     filepath.Join(config.UnitFilePath, config.ServiceName+".timer"),
+			Content:  GenerateTimerUnit(config.ServiceName, *config.Timer),
+			Activate: true,
+		})
+	}
+
+	if config.Socket != nil {
+		units = append(units, GeneratedUnit{
+			Name:     config.ServiceName + ".socket",
+			Path:     filepath.Join(config.UnitFilePath, config.ServiceName+".socket"),
+			Content:  GenerateSocketUnit(config.ServiceName, *config.Socket),
+			Activate: true,
+		})
+	}
+
+	if config.Path != nil {
+		units = append(units, GeneratedUnit{
+			Name:     config.ServiceName + ".path",
+			Path:     filepath.Join(config.UnitFilePath, config.ServiceName+".path"),
+			Content:  GeneratePathUnit(config.ServiceName, *config.Path),
+			Activate: true,
+		})
+	}
+
+	return units, nil
+}
+
+// Предпросмотр всех unit-файлов сервиса; файлы разделяются заголовками
+func GenerateUnitsPreview(units []GeneratedUnit) string {
+	if len(units) == 1 {
+		return units[0].Content
+	}
+
+	var sb strings.Builder
+	for i, unit := range units {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString("# " + filepath.Base(unit.Path) + "\n")
+		sb.WriteString(unit.Content)
+	}
+	return sb.String()
+}
+
+// Создание unit-файлов сервиса (и таймера, если он задан). Существующие
+// файлы проверяются заранее, чтобы не записать только часть из них.
+func CreateUnitFile(config ServiceConfig, overwrite bool) ([]GeneratedUnit, error) {
+	units, err := GenerateUnits(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Проверяем, существуют ли файлы и нужно ли их перезаписывать
+	if !overwrite {
+		for _, unit := range units {
+			if _, err := os.Stat(unit.Path); err == nil {
+				return nil, fmt.Errorf("файл %s уже существует и не будет перезаписан", unit.Path)
+			}
+		}
 	}
 
+	for _, unit := range units {
+		if err := WriteUnitFile(unit.Path, unit.Content); err != nil {
+			return nil, err
+		}
+	}
+
+	return units, nil
+}
+
+// Запись содержимого unit-файла на диск
+func WriteUnitFile(unitFilePath, content string) error {
 	// Создание файла
 	file, err := os.Create(unitFilePath)
 	if err != nil {
@@ -242,14 +572,14 @@ func CreateUnitFile(config ServiceConfig, overwrite bool) error {
 }
 
 // Выполнение системных команд с выводом результата
-func ExecuteCommand(name string, args ...string) (string, error) {
+func ExecuteCommand(ctx context.Context, name string, args ...string) (string, error) {
 	// Проверяем наличие исполняемого файла
 	path, err := exec.LookPath(name)
 	if err != nil {
 		return "", fmt.Errorf("команда %s не найдена. Убедитесь, что системный сервис установлен и путь корректен", name)
 	}
 
-	cmd := exec.Command(path, args...)
+	cmd := exec.CommandContext(ctx, path, args...)
 	output, err := cmd.CombinedOutput()
 	outputStr := strings.TrimSpace(string(output))
 
@@ -263,156 +593,331 @@ func ExecuteCommand(name string, args ...string) (string, error) {
 	return outputStr, nil
 }
 
-// Выполнение команды daemon-reload
-func ReloadDaemon() (string, error) {
-	return ExecuteCommand("systemctl", "daemon-reload")
-}
-
-// Выполнение команды enable
-func EnableService(serviceName string) (string, error) {
-	return ExecuteCommand("systemctl", "enable", serviceName)
-}
-
 // Выполнение команды start
-func StartService(serviceName string) (string, error) {
-	output, err := ExecuteCommand("systemctl", "start", serviceName)
-	if err != nil {
+func StartService(ctx context.Context, b Backend, serviceName string) (string, error) {
+	if err := b.Start(ctx, serviceName); err != nil {
 		return "", err
 	}
-
-	if output != "" {
-		return fmt.Sprintf("Сервис %s успешно запущен\n%s", serviceName, output), nil
-	}
 	return fmt.Sprintf("Сервис %s успешно запущен", serviceName), nil
 }
 
 // Выполнение команды stop
-func StopService(serviceName string) (string, error) {
-	output, err := ExecuteCommand("systemctl", "stop", serviceName)
-	if err != nil {
+func StopService(ctx context.Context, b Backend, serviceName string) (string, error) {
+	if err := b.Stop(ctx, serviceName); err != nil {
 		return "", err
 	}
-
-	if output != "" {
-		return fmt.Sprintf("Сервис %s успешно остановлен\n%s", serviceName, output), nil
-	}
 	return fmt.Sprintf("Сервис %s успешно остановлен", serviceName), nil
 }
 
 // Выполнение команды restart
-func RestartService(serviceName string) (string, error) {
-	output, err := ExecuteCommand("systemctl", "restart", serviceName)
-	if err != nil {
+func RestartService(ctx context.Context, b Backend, serviceName string) (string, error) {
+	if err := b.Restart(ctx, serviceName); err != nil {
 		return "", err
 	}
-
-	if output != "" {
-		return fmt.Sprintf("Сервис %s успешно перезапущен\n%s", serviceName, output), nil
-	}
 	return fmt.Sprintf("Сервис %s успешно перезапущен", serviceName), nil
 }
 
-// Выполнение просмотра логов
-func ViewServiceLogs(ctx context.Context, serviceName string) error {
-	cmd := exec.CommandContext(ctx, "journalctl", "-n", "50", "-u", serviceName, "--output=json", "--no-pager")
+// Ошибка: у сервиса нет ExecReload, перечитать конфигурацию можно только
+// перезапуском
+var ErrReloadUnsupported = errors.New("сервис не поддерживает reload (не задан ExecReload)")
 
-	stdout, err := cmd.StdoutPipe()
+// Выполнение команды reload. Если сервис ее не поддерживает, возвращается
+// ErrReloadUnsupported, и решение о перезапуске остается за вызывающим.
+func ReloadService(ctx context.Context, b Backend, serviceName string) (string, error) {
+	props, err := b.Properties(ctx, serviceName, "CanReload")
 	if err != nil {
-		return fmt.Errorf("stdout pipe: %w", err)
+		return "", err
 	}
-
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("ошибка запуска: %w", err)
+	if props["CanReload"] != "yes" {
+		return "", fmt.Errorf("%s: %w", serviceName, ErrReloadUnsupported)
 	}
 
-	done := make(chan struct{})
-	go func() {
-		scanner := bufio.NewScanner(stdout)
+	if err := b.Reload(ctx, serviceName); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Конфигурация сервиса %s перечитана (reload)", serviceName), nil
+}
 
-		for scanner.Scan() {
-			jsonLine := scanner.Text()
+// Выполнение команды disable
+func DisableService(ctx context.Context, b Backend, serviceName string) (string, error) {
+	if err := b.Disable(ctx, serviceName); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Сервис %s деактивирован (disabled)", serviceName), nil
+}
 
-			var entry map[string]interface{}
-			if err := json.Unmarshal([]byte(jsonLine), &entry); err != nil {
-				fmt.Fprintf(os.Stderr, "ошибка парсинга JSON: %v\n", err)
-				continue
-			}
+// Разбор вывода systemctl list-units --no-legend --plain
+func ParseListUnits(output string) []UnitInfo {
+	var units []UnitInfo
 
-			if message, ok := entry["MESSAGE"].(string); ok {
-				fmt.Println(message)
-			}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
 		}
 
-		if err := scanner.Err(); err != nil {
-			fmt.Fprintf(os.Stderr, "ошибка чтения вывода: %v\n", err)
+		units = append(units, UnitInfo{
+			Name:        strings.TrimSuffix(fields[0], ".service"),
+			LoadState:   fields[1],
+			ActiveState: fields[2],
+			SubState:    fields[3],
+			Description: strings.Join(fields[4:], " "),
+		})
+	}
+
+	return units
+}
+
+// Разбор вывода systemctl list-unit-files --no-legend
+func ParseListUnitFiles(output string) map[string]string {
+	states := make(map[string]string)
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
 		}
+		states[strings.TrimSuffix(fields[0], ".service")] = fields[1]
+	}
 
-		close(done)
-	}()
+	return states
+}
+
+// Дополнить список загруженных сервисов статусом автозапуска и сервисами,
+// которые есть только в виде unit-файлов
+func MergeUnitFileStates(units []UnitInfo, fileStates map[string]string) []UnitInfo {
+	seen := make(map[string]bool, len(units))
+
+	for i := range units {
+		units[i].UnitFileState = fileStates[units[i].Name]
+		seen[units[i].Name] = true
+	}
 
-	select {
-	case <-done:
-		return cmd.Wait()
-	case <-ctx.Done():
-		return cmd.Process.Kill()
+	for name, state := range fileStates {
+		if seen[name] {
+			continue
+		}
+		units = append(units, UnitInfo{
+			Name:          name,
+			ActiveState:   "inactive",
+			SubState:      "dead",
+			UnitFileState: state,
+		})
 	}
+
+	slices.SortFunc(units, func(a, b UnitInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return units
 }
 
 // Полностью установить сервис (создать файл, reload, enable, start)
-func InstallService(config ServiceConfig, actions UserActions) (string, error) {
+func InstallService(ctx context.Context, b Backend, config ServiceConfig, actions UserActions) (string, error) {
 	var resultMessages []string
 
-	// 1. Создаем unit-файл
-	unitFilePath := filepath.Join(config.UnitFilePath, config.ServiceName+".service")
-	err := CreateUnitFile(config, actions.Overwrite)
+	// 1. Создаем unit-файлы
+	units, err := CreateUnitFile(config, actions.Overwrite)
 	if err != nil {
 		return "", fmt.Errorf("ошибка при создании unit-файла: %w", err)
 	}
 
-	// Получаем абсолютный путь
-	absPath, err := filepath.Abs(unitFilePath)
-	if err != nil {
-		absPath = unitFilePath
+	for i, unit := range units {
+		// Получаем абсолютный путь
+		absPath, err := filepath.Abs(unit.Path)
+		if err != nil {
+			absPath = unit.Path
+		}
+		if i == 0 {
+			resultMessages = append(resultMessages, "\n\nSystemd unit файл создан: "+absPath)
+		} else {
+			resultMessages = append(resultMessages, "Systemd unit файл создан: "+absPath)
+		}
+	}
+
+	// Секреты - в отдельный файл, доступный только пользователю сервиса
+	if len(config.Secrets) > 0 {
+		path, err := WriteSecretsFile(config)
+		if err != nil {
+			return strings.Join(resultMessages, "\n"), err
+		}
+		resultMessages = append(resultMessages, "Файл секретов записан: "+path)
 	}
-	resultMessages = append(resultMessages, "\n\nSystemd unit файл создан: "+absPath)
 
 	// 2. Если выбрано, выполняем daemon-reload
 	if actions.ReloadDaemon {
-		output, err := ReloadDaemon()
+		if err := b.DaemonReload(ctx); err != nil {
+			return strings.Join(resultMessages, "\n"), err
+		}
+		resultMessages = append(resultMessages, "Systemd daemon перезагружен")
+	}
+
+	// Сервис с таймером не активируется сам: его запускает таймер. Вместо
+	// шаблона активируются его экземпляры.
+	for _, unit := range units {
+		for _, name := range unit.ActivateNames() {
+			// 3. Если выбрано, выполняем enable
+			if actions.EnableService {
+				if err := b.Enable(ctx, name); err != nil {
+					return strings.Join(resultMessages, "\n"), err
+				}
+				resultMessages = append(resultMessages, unitActionMessage(config, name, "активирован (enabled)"))
+			}
+
+			// 4. Если выбрано, выполняем start
+			if actions.StartService {
+				if err := b.Start(ctx, name); err != nil {
+					return strings.Join(resultMessages, "\n"), err
+				}
+				resultMessages = append(resultMessages, unitActionMessage(config, name, "запущен (started)"))
+			}
+		}
+	}
+
+	resultMessages = append(resultMessages, "Установка успешно завершена")
+	return strings.Join(resultMessages, "\n"), nil
+}
+
+// Сообщение о действии с unit-файлом: для самого сервиса сохраняем
+// прежнюю формулировку
+func unitActionMessage(config ServiceConfig, name, action string) string {
+	if name == config.ServiceName {
+		return "Сервис " + action
+	}
+	return "Unit " + name + " " + action
+}
+
+// Обновить существующий сервис (перезаписать файл, reload, restart)
+func UpdateService(ctx context.Context, b Backend, config ServiceConfig, content string, actions UserActions) (string, error) {
+	unitFilePath := filepath.Join(config.UnitFilePath, config.ServiceName+".service")
+	if err := WriteUnitFile(unitFilePath, content); err != nil {
+		return "", fmt.Errorf("ошибка при обновлении unit-файла: %w", err)
+	}
+
+	resultMessages := []string{"\n\nSystemd unit файл обновлен: " + unitFilePath}
+	if len(config.Secrets) > 0 {
+		path, err := WriteSecretsFile(config)
 		if err != nil {
 			return strings.Join(resultMessages, "\n"), err
 		}
-		if output != "" {
-			resultMessages = append(resultMessages, output)
+		resultMessages = append(resultMessages, "Файл секретов записан: "+path)
+	}
+
+	return applyServiceChanges(ctx, b, config.ServiceName, actions, resultMessages...)
+}
+
+// Записать изменения сервиса в drop-in файл sdmanager (reload, restart)
+func ApplyDropIn(ctx context.Context, b Backend, config ServiceConfig, content string, actions UserActions) (string, error) {
+	path, err := WriteDropIn(DefaultUnitDir, config.ServiceName, DefaultDropInPriority, content)
+	if err != nil {
+		return "", err
+	}
+
+	resultMessages := []string{"\n\nDrop-in файл записан: " + path}
+	if len(config.Secrets) > 0 {
+		secretsPath, err := WriteSecretsFile(config)
+		if err != nil {
+			return strings.Join(resultMessages, "\n"), err
+		}
+		resultMessages = append(resultMessages, "Файл секретов записан: "+secretsPath)
+	}
+
+	return applyServiceChanges(ctx, b, config.ServiceName, actions, resultMessages...)
+}
+
+// Выполнить daemon-reload и restart после изменения файлов сервиса
+func applyServiceChanges(ctx context.Context, b Backend, serviceName string, actions UserActions, resultMessages ...string) (string, error) {
+	// Если выбрано, выполняем daemon-reload
+	if actions.ReloadDaemon {
+		if err := b.DaemonReload(ctx); err != nil {
+			return strings.Join(resultMessages, "\n"), err
 		}
 		resultMessages = append(resultMessages, "Systemd daemon перезагружен")
 	}
 
-	// 3. Если выбрано, выполняем enable
-	if actions.EnableService {
-		output, err := EnableService(config.ServiceName)
+	// Если выбрано, выполняем restart
+	if actions.RestartService {
+		output, err := RestartService(ctx, b, serviceName)
 		if err != nil {
 			return strings.Join(resultMessages, "\n"), err
 		}
-		if output != "" {
-			resultMessages = append(resultMessages, output)
-		}
-		resultMessages = append(resultMessages, "Сервис активирован (enabled)")
+		resultMessages = append(resultMessages, output)
 	}
 
-	// 4. Если выбрано, выполняем start
-	if actions.StartService {
-		output, err := ExecuteCommand("systemctl", "start", config.ServiceName)
+	resultMessages = append(resultMessages, "Изменения успешно применены")
+	return strings.Join(resultMessages, "\n"), nil
+}
+
+// Типы unit-файлов, которые создаются вместе с сервисом и удаляются с ним
+var companionUnitTypes = []string{".timer", ".socket", ".path"}
+
+// Удалить сервис (stop, disable, удалить файл, reload)
+func UninstallService(ctx context.Context, b Backend, unitFilePath, serviceName string) (string, error) {
+	var resultMessages []string
+
+	path := filepath.Join(unitFilePath, serviceName+".service")
+	if !FileExists(path) {
+		// Шаблон и сервис сокета с Accept=yes установлены как name@.service
+		template := filepath.Join(unitFilePath, serviceName+"@.service")
+		if !FileExists(template) {
+			return "", fmt.Errorf("%s: %w", path, ErrUnitNotFound)
+		}
+		path = template
+
+		// Экземпляры удаляемого шаблона больше не смогут работать
+		messages, err := stopTemplateInstances(ctx, b, serviceName)
+		resultMessages = append(resultMessages, messages...)
 		if err != nil {
 			return strings.Join(resultMessages, "\n"), err
 		}
-		if output != "" {
-			resultMessages = append(resultMessages, output)
+	}
+
+	// Сначала удаляем таймер, сокет и path-unit, чтобы они не запустили
+	// сервис снова
+	for _, unitType := range companionUnitTypes {
+		name := serviceName + unitType
+		companionPath := filepath.Join(unitFilePath, name)
+		if !FileExists(companionPath) {
+			continue
+		}
+		if err := b.Stop(ctx, name); err == nil {
+			resultMessages = append(resultMessages, name+" остановлен (stopped)")
+		}
+		if err := b.Disable(ctx, name); err == nil {
+			resultMessages = append(resultMessages, name+" деактивирован (disabled)")
 		}
-		resultMessages = append(resultMessages, "Сервис запущен (started)")
+		if err := os.Remove(companionPath); err != nil {
+			return strings.Join(resultMessages, "\n"), fmt.Errorf("ошибка при удалении unit-файла: %w", err)
+		}
+		resultMessages = append(resultMessages, "Systemd unit файл удален: "+companionPath)
 	}
 
-	resultMessages = append(resultMessages, "Установка успешно завершена")
+	// Остановленный или не активированный сервис не считаем ошибкой
+	if err := b.Stop(ctx, serviceName); err == nil {
+		resultMessages = append(resultMessages, "Сервис остановлен (stopped)")
+	}
+	if err := b.Disable(ctx, serviceName); err == nil {
+		resultMessages = append(resultMessages, "Сервис деактивирован (disabled)")
+	}
+
+	if err := os.Remove(path); err != nil {
+		return strings.Join(resultMessages, "\n"), fmt.Errorf("ошибка при удалении unit-файла: %w", err)
+	}
+	resultMessages = append(resultMessages, "Systemd unit файл удален: "+path)
+
+	// Секреты удаленного сервиса больше не нужны
+	if secretsPath := SecretsFilePath(serviceName); FileExists(secretsPath) {
+		if err := os.Remove(secretsPath); err != nil {
+			return strings.Join(resultMessages, "\n"), fmt.Errorf("ошибка при удалении файла секретов: %w", err)
+		}
+		resultMessages = append(resultMessages, "Файл секретов удален: "+secretsPath)
+	}
+
+	if err := b.DaemonReload(ctx); err != nil {
+		return strings.Join(resultMessages, "\n"), err
+	}
+	resultMessages = append(resultMessages, "Systemd daemon перезагружен")
+
 	return strings.Join(resultMessages, "\n"), nil
 }
 
@@ -429,11 +934,11 @@ func removeMultipleLines(input string) string {
 	var result []string
 	prevBlank := false
 
-	for i, line := range lines {
+	for _, line := range lines {
 		trimmed := strings.TrimSpace(line)
 		if trimmed == "" {
-			// Если предыдущая строка (из исходного массива) начинается с "[", пропускаем пустую строку.
-			if i > 0 && strings.HasPrefix(strings.TrimSpace(lines[i-1]), "[") {
+			// Если предыдущая строка результата начинается с "[", пропускаем пустую строку.
+			if len(result) > 0 && strings.HasPrefix(strings.TrimSpace(result[len(result)-1]), "[") {
 				continue
 			}
 			// Добавляем пустую строку, если предыдущей не было.
@@ -2,6 +2,8 @@ package sdmanager
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/textinput"
@@ -9,7 +11,7 @@ import (
 )
 
 // Инициализация модели ввода имени сервиса
-func NewServiceInputModel(action string) ServiceInputModel {
+func NewServiceInputModel(appOptions AppOptions, action string) ServiceInputModel {
 	ti := textinput.New()
 	ti.Placeholder = "myservice"
 	ti.Focus()
@@ -24,6 +26,8 @@ func NewServiceInputModel(action string) ServiceInputModel {
 		message = "Введите имя сервиса для остановки:"
 	case ActionRestart:
 		message = "Введите имя сервиса для перезапуска:"
+	case ActionReload:
+		message = "Введите имя сервиса, конфигурацию которого нужно перечитать (reload):"
 	case ActionViewLog:
 		message = "Введите имя сервиса для просмотра логов:"
 	default:
@@ -37,6 +41,7 @@ func NewServiceInputModel(action string) ServiceInputModel {
 		Error:     "",
 		ResultMsg: "",
 		Quitting:  false,
+		backend:   appOptions.backend,
 	}
 }
 
@@ -44,6 +49,10 @@ func NewServiceInputModel(action string) ServiceInputModel {
 func UpdateServiceInput(ctx context.Context, msg tea.Msg, model ServiceInputModel) (ServiceInputModel, tea.Cmd, error) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if model.ConfirmRestart {
+			return confirmServiceInputRestart(ctx, msg, model)
+		}
+
 		switch msg.Type {
 		case tea.KeyEnter:
 
@@ -70,13 +79,27 @@ func UpdateServiceInput(ctx context.Context, msg tea.Msg, model ServiceInputMode
 			var err error
 			switch model.Action {
 			case ActionStart:
-				result, err = StartService(serviceName)
+				result, err = StartService(ctx, model.backend, serviceName)
 			case ActionStop:
-				result, err = StopService(serviceName)
+				result, err = StopService(ctx, model.backend, serviceName)
 			case ActionRestart:
-				result, err = RestartService(serviceName)
+				result, err = RestartService(ctx, model.backend, serviceName)
+			case ActionReload:
+				result, err = ReloadService(ctx, model.backend, serviceName)
+				// Без ExecReload конфигурацию можно применить только перезапуском
+				if errors.Is(err, ErrReloadUnsupported) {
+					model.ConfirmRestart = true
+					model.Message = fmt.Sprintf("Сервис %s не поддерживает reload (не задан ExecReload). Перезапустить его? (y/n)", serviceName)
+					return model, nil, nil
+				}
 			case ActionViewLog:
-				err = ViewServiceLogs(ctx, serviceName)
+				var entries []JournalEntry
+				entries, err = model.backend.Logs(ctx, []string{serviceName}, LogQuery{Lines: 50})
+				lines := make([]string, 0, len(entries))
+				for _, entry := range entries {
+					lines = append(lines, RenderJournalEntry(entry))
+				}
+				result = strings.Join(lines, "\n")
 			}
 
 			if err != nil {
@@ -106,10 +129,35 @@ func UpdateServiceInput(ctx context.Context, msg tea.Msg, model ServiceInputMode
 	return model, cmd, nil
 }
 
+// Обработка ответа на вопрос о перезапуске сервиса вместо reload
+func confirmServiceInputRestart(ctx context.Context, msg tea.KeyMsg, model ServiceInputModel) (ServiceInputModel, tea.Cmd, error) {
+	switch msg.String() {
+	case "y", "Y":
+		result, err := RestartService(ctx, model.backend, model.Input.Value())
+		if err != nil {
+			model.ConfirmRestart = false
+			model.Error = err.Error()
+			return model, nil, nil
+		}
+		model.ResultMsg = result
+	case "n", "N", "esc", "ctrl+c":
+		model.ResultMsg = "Перезапуск отменен"
+	default:
+		return model, nil, nil
+	}
+
+	model.Quitting = true
+	return model, tea.Quit, nil
+}
+
 // Отрисовка формы ввода имени сервиса
 func ViewServiceInput(model ServiceInputModel) string {
 	var s strings.Builder
 
+	if model.ConfirmRestart {
+		return model.Message + "\n"
+	}
+
 	s.WriteString(model.Message + "\n\n")
 	s.WriteString(model.Input.View() + "\n\n")
 
@@ -0,0 +1,134 @@
+package sdmanager
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Ввести имя сервиса и нажать Enter
+func submitServiceInput(t *testing.T, model ServiceInputModel, serviceName string) ServiceInputModel {
+	t.Helper()
+
+	model.Input.SetValue(serviceName)
+	model, _, err := UpdateServiceInput(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+	if err != nil {
+		t.Fatalf("UpdateServiceInput: %v", err)
+	}
+
+	return model
+}
+
+func TestUpdateServiceInputActions(t *testing.T) {
+	tests := []struct {
+		action      string
+		activeState string
+		result      string
+	}{
+		{action: ActionStart, activeState: "active", result: "успешно запущен"},
+		{action: ActionStop, activeState: "inactive", result: "успешно остановлен"},
+		{action: ActionRestart, activeState: "active", result: "успешно перезапущен"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			backend := NewFakeBackend(UnitInfo{Name: "api"})
+			model := NewServiceInputModel(AppOptions{backend: backend}, tt.action)
+
+			model = submitServiceInput(t, model, "api")
+
+			if model.Error != "" {
+				t.Fatalf("unexpected error: %s", model.Error)
+			}
+			if !model.Quitting {
+				t.Error("model should quit after the action")
+			}
+			if !strings.Contains(model.ResultMsg, tt.result) {
+				t.Errorf("ResultMsg = %q, want it to contain %q", model.ResultMsg, tt.result)
+			}
+
+			unit, _ := backend.Unit("api")
+			if unit.ActiveState != tt.activeState {
+				t.Errorf("ActiveState = %q, want %q", unit.ActiveState, tt.activeState)
+			}
+		})
+	}
+}
+
+func TestUpdateServiceInputLogs(t *testing.T) {
+	backend := NewFakeBackend(UnitInfo{Name: "api"})
+	backend.SetLogs("api", "first", "second")
+	model := NewServiceInputModel(AppOptions{backend: backend}, ActionViewLog)
+
+	model = submitServiceInput(t, model, "api")
+
+	if model.ResultMsg != "first\nsecond" {
+		t.Errorf("ResultMsg = %q", model.ResultMsg)
+	}
+}
+
+func TestUpdateServiceInputErrors(t *testing.T) {
+	backend := NewFakeBackend(UnitInfo{Name: "api"})
+	backend.FailOn("start", "api", errors.New("access denied"))
+
+	tests := []struct {
+		name        string
+		serviceName string
+		want        string
+	}{
+		{name: "empty", serviceName: "", want: "не может быть пустым"},
+		{name: "invalid", serviceName: "bad/name", want: "недопустимый символ"},
+		{name: "unknown", serviceName: "missing", want: ErrUnitNotFound.Error()},
+		{name: "backend", serviceName: "api", want: "access denied"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := NewServiceInputModel(AppOptions{backend: backend}, ActionStart)
+
+			model = submitServiceInput(t, model, tt.serviceName)
+
+			if !strings.Contains(model.Error, tt.want) {
+				t.Errorf("Error = %q, want it to contain %q", model.Error, tt.want)
+			}
+			if model.Quitting {
+				t.Error("model should not quit on error")
+			}
+
+			// Повторный Enter только сбрасывает ошибку
+			model, _, _ = UpdateServiceInput(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+			if model.Error != "" {
+				t.Errorf("Error was not cleared: %q", model.Error)
+			}
+		})
+	}
+}
+
+func TestUpdateServiceInputReload(t *testing.T) {
+	backend := NewFakeBackend(UnitInfo{Name: "api", ActiveState: "active", SubState: "running"}, UnitInfo{Name: "web", ActiveState: "active", SubState: "running"})
+	backend.SetProperties("api", map[string]string{"CanReload": "yes"})
+
+	model := submitServiceInput(t, NewServiceInputModel(AppOptions{backend: backend}, ActionReload), "api")
+	if !model.Quitting || !strings.Contains(model.ResultMsg, "перечитана") {
+		t.Fatalf("reload: ResultMsg = %q, Error = %q", model.ResultMsg, model.Error)
+	}
+
+	// Без ExecReload сервис перезапускается только после подтверждения
+	model = submitServiceInput(t, NewServiceInputModel(AppOptions{backend: backend}, ActionReload), "web")
+	if !model.ConfirmRestart || model.Quitting {
+		t.Fatalf("restart was not offered: %+v", model)
+	}
+	model, _, _ = UpdateServiceInput(context.Background(), tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")}, model)
+	if !strings.Contains(model.ResultMsg, "успешно перезапущен") {
+		t.Errorf("ResultMsg = %q", model.ResultMsg)
+	}
+
+	want := []string{"show api", "reload api", "show web", "restart web"}
+	if calls := backend.Calls(); !slices.Equal(calls, want) {
+		t.Errorf("calls = %q, want %q", calls, want)
+	}
+}
@@ -0,0 +1,138 @@
+package sdmanager
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// Допустимые значения BindIPv6Only
+var BindIPv6OnlyValues = []string{"default", "both", "ipv6-only"}
+
+// Права доступа к файлу сокета: 3 или 4 восьмеричные цифры
+var socketModePattern = regexp.MustCompile(`^[0-7]?[0-7]{3}$`)
+
+// Настройки сокета (.socket), который запускает сервис при первом
+// подключении
+type SocketConfig struct {
+	// Адреса в формате systemd: порт, адрес:порт, [ipv6]:порт, путь к
+	// unix-сокету или @абстрактное имя
+	ListenStream   []string `json:"listen_stream,omitempty" yaml:"listen_stream,omitempty"`
+	ListenDatagram []string `json:"listen_datagram,omitempty" yaml:"listen_datagram,omitempty"`
+	// Отдельный экземпляр сервиса на каждое подключение (name@.service)
+	Accept       bool   `json:"accept,omitempty" yaml:"accept,omitempty"`
+	SocketUser   string `json:"socket_user,omitempty" yaml:"socket_user,omitempty"`
+	SocketMode   string `json:"socket_mode,omitempty" yaml:"socket_mode,omitempty"`
+	BindIPv6Only string `json:"bind_ipv6_only,omitempty" yaml:"bind_ipv6_only,omitempty"`
+}
+
+// Проверить настройки сокета: нужен хотя бы один адрес, адреса и права
+// доступа должны быть корректными
+func ValidateSocketConfig(socket SocketConfig) error {
+	if len(socket.ListenStream) == 0 && len(socket.ListenDatagram) == 0 {
+		return errors.New("сокету нужен хотя бы один адрес: ListenStream или ListenDatagram")
+	}
+
+	for _, address := range socket.ListenStream {
+		if err := ValidateListenAddress(address); err != nil {
+			return fmt.Errorf("ListenStream: %w", err)
+		}
+	}
+	for _, address := range socket.ListenDatagram {
+		if err := ValidateListenAddress(address); err != nil {
+			return fmt.Errorf("ListenDatagram: %w", err)
+		}
+	}
+
+	if err := IsValidUserName(socket.SocketUser); err != nil {
+		return fmt.Errorf("SocketUser: %w", err)
+	}
+
+	if socket.SocketMode != "" && !socketModePattern.MatchString(socket.SocketMode) {
+		return fmt.Errorf("SocketMode: ожидаются восьмеричные права доступа, например 0660, получено %q", socket.SocketMode)
+	}
+
+	if socket.BindIPv6Only != "" && !slices.Contains(BindIPv6OnlyValues, socket.BindIPv6Only) {
+		return fmt.Errorf("BindIPv6Only: допустимые значения: %s", strings.Join(BindIPv6OnlyValues, ", "))
+	}
+
+	return nil
+}
+
+// Проверить адрес ListenStream/ListenDatagram
+func ValidateListenAddress(address string) error {
+	switch {
+	case address == "":
+		return errors.New("пустой адрес")
+	case strings.HasPrefix(address, "/"):
+		// Путь к unix-сокету
+		return nil
+	case strings.HasPrefix(address, "@"):
+		// Абстрактный unix-сокет
+		if len(address) == 1 {
+			return errors.New("пустое имя абстрактного сокета")
+		}
+		return nil
+	}
+
+	port := address
+	if strings.Contains(address, ":") {
+		host, p, err := net.SplitHostPort(address)
+		if err != nil {
+			return fmt.Errorf("некорректный адрес %q: ожидается порт, адрес:порт, [ipv6]:порт или путь", address)
+		}
+		if net.ParseIP(host) == nil {
+			return fmt.Errorf("некорректный IP-адрес %q", host)
+		}
+		port = p
+	}
+
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("некорректный порт %q: ожидается число от 1 до 65535", port)
+	}
+	return nil
+}
+
+// Разобрать список адресов, разделенных пробелами или запятыми
+func ParseListenList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' })
+}
+
+// Сформировать содержимое .socket для сервиса
+func GenerateSocketUnit(serviceName string, socket SocketConfig) string {
+	var sb strings.Builder
+
+	sb.WriteString("[Unit]\n")
+	sb.WriteString("Description=" + cases.Title(language.English).String(serviceName) + " Socket\n\n")
+
+	sb.WriteString("[Socket]\n")
+	for _, address := range socket.ListenStream {
+		sb.WriteString("ListenStream=" + address + "\n")
+	}
+	for _, address := range socket.ListenDatagram {
+		sb.WriteString("ListenDatagram=" + address + "\n")
+	}
+	if socket.Accept {
+		sb.WriteString("Accept=yes\n")
+	}
+	if socket.SocketUser != "" {
+		sb.WriteString("SocketUser=" + socket.SocketUser + "\n")
+	}
+	if socket.SocketMode != "" {
+		sb.WriteString("SocketMode=" + socket.SocketMode + "\n")
+	}
+	if socket.BindIPv6Only != "" {
+		sb.WriteString("BindIPv6Only=" + socket.BindIPv6Only + "\n")
+	}
+	sb.WriteString("\n[Install]\nWantedBy=sockets.target\n")
+
+	return sb.String()
+}
@@ -0,0 +1,84 @@
+package sdmanager
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateSocketConfig(t *testing.T) {
+	valid := []SocketConfig{
+		{ListenStream: []string{"8080"}},
+		{ListenStream: []string{"127.0.0.1:8080", "[::1]:8080", "/run/app.sock", "@app"}},
+		{ListenDatagram: []string{"514"}, SocketMode: "0660", BindIPv6Only: "both"},
+		{ListenStream: []string{"9000"}, SocketUser: "www-data", SocketMode: "660"},
+	}
+	for _, socket := range valid {
+		if err := ValidateSocketConfig(socket); err != nil {
+			t.Errorf("ValidateSocketConfig(%+v): %v", socket, err)
+		}
+	}
+
+	invalid := []SocketConfig{
+		{},
+		{ListenStream: []string{"0"}},
+		{ListenStream: []string{"70000"}},
+		{ListenStream: []string{"localhost:80"}},
+		{ListenStream: []string{"::1:80"}},
+		{ListenStream: []string{"@"}},
+		{ListenStream: []string{"80"}, SocketMode: "0999"},
+		{ListenStream: []string{"80"}, BindIPv6Only: "yes"},
+	}
+	for _, socket := range invalid {
+		if err := ValidateSocketConfig(socket); err == nil {
+			t.Errorf("ValidateSocketConfig(%+v) succeeded", socket)
+		}
+	}
+}
+
+func TestGenerateSocketUnits(t *testing.T) {
+	config := ServiceConfig{
+		ServiceName:  "echo",
+		ExecStart:    "/usr/bin/echo-server",
+		UnitFilePath: "/etc/systemd/system",
+		Socket:       &SocketConfig{ListenStream: []string{"7777"}, SocketMode: "0660"},
+	}
+
+	units, err := GenerateUnits(config)
+	if err != nil {
+		t.Fatalf("GenerateUnits: %v", err)
+	}
+	if len(units) != 2 || units[0].Activate || !units[1].Activate || units[1].Name != "echo.socket" {
+		t.Fatalf("units = %+v", units)
+	}
+
+	// Сервис запускается сокетом, поэтому не устанавливается в multi-user.target
+	if strings.Contains(units[0].Content, "WantedBy=multi-user.target") || !strings.Contains(units[0].Content, "Restart=always") {
+		t.Errorf("service:\n%s", units[0].Content)
+	}
+	for _, line := range []string{"ListenStream=7777", "SocketMode=0660", "WantedBy=sockets.target"} {
+		if !strings.Contains(units[1].Content, line) {
+			t.Errorf("socket does not contain %q:\n%s", line, units[1].Content)
+		}
+	}
+
+	// С Accept=yes на каждое подключение запускается экземпляр шаблона
+	config.Socket.Accept = true
+	units, _ = GenerateUnits(config)
+	if units[0].Path != "/etc/systemd/system/echo@.service" || strings.Contains(units[0].Content, "Restart=") {
+		t.Errorf("accept service = %s:\n%s", units[0].Path, units[0].Content)
+	}
+
+	// Файлы в предпросмотре выводятся рядом, а в узком окне - друг под другом
+	preview := RenderUnitsSideBySide(units, 100)
+	if lines := strings.Split(preview, "\n"); !strings.Contains(lines[0], "echo@.service") || !strings.Contains(lines[0], "echo.socket") {
+		t.Errorf("side by side preview:\n%s", preview)
+	}
+	if preview := RenderUnitsSideBySide(units, 40); preview != GenerateUnitsPreview(units) {
+		t.Errorf("narrow preview:\n%s", preview)
+	}
+
+	config.Timer = &TimerConfig{OnBootSec: "5min"}
+	if err := ValidateServiceConfig(config); err == nil {
+		t.Error("expected error for service with both timer and socket")
+	}
+}
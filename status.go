@@ -0,0 +1,337 @@
+package sdmanager
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Формат временных меток systemctl show
+const systemdTimestampLayout = "Mon 2006-01-02 15:04:05 MST"
+
+// Количество строк журнала на экране статуса
+const StatusLogLines = 10
+
+// Состояния экрана статуса
+const (
+	StatusStateServiceName = iota
+	StatusStateView
+)
+
+// Свойства systemctl show, из которых строится ServiceStatus
+var ServiceStatusProperties = []string{
+	"Id",
+	"Description",
+	"LoadState",
+	"ActiveState",
+	"SubState",
+	"MainPID",
+	"ExecMainStartTimestamp",
+	"NRestarts",
+	"MemoryCurrent",
+	"CPUUsageNSec",
+	"TasksCurrent",
+	"Result",
+	"UnitFileState",
+	"FragmentPath",
+	"DropInPaths",
+}
+
+// Подробное состояние сервиса. Нулевые значения счетчиков означают, что
+// systemd их не предоставляет (сервис остановлен или учет отключен).
+type ServiceStatus struct {
+	Name          string
+	Description   string
+	LoadState     string
+	ActiveState   string
+	SubState      string
+	MainPID       int
+	StartedAt     time.Time
+	NRestarts     int
+	MemoryCurrent uint64
+	CPUUsage      time.Duration
+	TasksCurrent  uint64
+	Result        string
+	UnitFileState string
+	FragmentPath  string
+	DropInPaths   []string
+}
+
+// Разобрать вывод systemctl show --property=... в ServiceStatus
+func ParseServiceStatus(output string) (ServiceStatus, error) {
+	return ServiceStatusFromProperties(ParseProperties(output))
+}
+
+// Собрать ServiceStatus из свойств в формате systemctl show
+func ServiceStatusFromProperties(props map[string]string) (ServiceStatus, error) {
+	status := ServiceStatus{
+		Name:          strings.TrimSuffix(props["Id"], ".service"),
+		Description:   props["Description"],
+		LoadState:     props["LoadState"],
+		ActiveState:   props["ActiveState"],
+		SubState:      props["SubState"],
+		Result:        props["Result"],
+		UnitFileState: props["UnitFileState"],
+		FragmentPath:  props["FragmentPath"],
+		DropInPaths:   strings.Fields(props["DropInPaths"]),
+	}
+
+	var err error
+
+	if status.MainPID, err = parseIntProperty(props, "MainPID"); err != nil {
+		return ServiceStatus{}, err
+	}
+	if status.NRestarts, err = parseIntProperty(props, "NRestarts"); err != nil {
+		return ServiceStatus{}, err
+	}
+	if status.MemoryCurrent, err = parseUintProperty(props, "MemoryCurrent"); err != nil {
+		return ServiceStatus{}, err
+	}
+	if status.TasksCurrent, err = parseUintProperty(props, "TasksCurrent"); err != nil {
+		return ServiceStatus{}, err
+	}
+
+	cpu, err := parseUintProperty(props, "CPUUsageNSec")
+	if err != nil {
+		return ServiceStatus{}, err
+	}
+	status.CPUUsage = time.Duration(min(cpu, math.MaxInt64))
+
+	if value := props["ExecMainStartTimestamp"]; value != "" && value != "n/a" {
+		status.StartedAt, err = time.ParseInLocation(systemdTimestampLayout, value, time.Local)
+		if err != nil {
+			return ServiceStatus{}, fmt.Errorf("ExecMainStartTimestamp: %w", err)
+		}
+	}
+
+	return status, nil
+}
+
+// Числовое свойство со знаком; пустое значение считается нулем
+func parseIntProperty(props map[string]string, name string) (int, error) {
+	value := props[name]
+	if value == "" || value == "[not set]" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: некорректное значение %q", name, value)
+	}
+	return n, nil
+}
+
+// Беззнаковое свойство-счетчик. systemd возвращает максимальное значение
+// uint64 или "[not set]", если счетчик недоступен.
+func parseUintProperty(props map[string]string, name string) (uint64, error) {
+	value := props[name]
+	if value == "" || value == "[not set]" || value == "[not available]" {
+		return 0, nil
+	}
+
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: некорректное значение %q", name, value)
+	}
+	if n == math.MaxUint64 {
+		return 0, nil
+	}
+	return n, nil
+}
+
+// Получить подробное состояние сервиса
+func GetServiceStatus(ctx context.Context, b Backend, serviceName string) (ServiceStatus, error) {
+	props, err := b.Properties(ctx, serviceName, ServiceStatusProperties...)
+	if err != nil {
+		return ServiceStatus{}, err
+	}
+
+	if props["LoadState"] == "not-found" {
+		return ServiceStatus{}, fmt.Errorf("%s: %w", serviceName, ErrUnitNotFound)
+	}
+
+	return ServiceStatusFromProperties(props)
+}
+
+// Размер в байтах в удобном для чтения виде
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+
+	div, exp := uint64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%c", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// Строки с описанием состояния сервиса: пары "название: значение"
+func (s ServiceStatus) Rows(now time.Time) [][2]string {
+	active := StateStyle(s.ActiveState).Render(s.ActiveState + " (" + s.SubState + ")")
+	if !s.StartedAt.IsZero() && s.ActiveState == "active" {
+		active += " с " + s.StartedAt.Format("2006-01-02 15:04:05") + ", " + now.Sub(s.StartedAt).Round(time.Second).String()
+	}
+
+	rows := [][2]string{
+		{"Описание", s.Description},
+		{"Состояние", active},
+		{"Результат", s.Result},
+		{"Автозапуск", s.UnitFileState},
+	}
+
+	if s.MainPID > 0 {
+		rows = append(rows, [2]string{"Main PID", strconv.Itoa(s.MainPID)})
+	}
+	if s.TasksCurrent > 0 {
+		rows = append(rows, [2]string{"Задачи", strconv.FormatUint(s.TasksCurrent, 10)})
+	}
+	if s.MemoryCurrent > 0 {
+		rows = append(rows, [2]string{"Память", formatBytes(s.MemoryCurrent)})
+	}
+	if s.CPUUsage > 0 {
+		rows = append(rows, [2]string{"CPU", s.CPUUsage.Round(time.Millisecond).String()})
+	}
+
+	rows = append(rows, [2]string{"Перезапуски", strconv.Itoa(s.NRestarts)})
+	rows = append(rows, [2]string{"Unit-файл", s.FragmentPath})
+	for i, path := range s.DropInPaths {
+		title := ""
+		if i == 0 {
+			title = "Drop-in"
+		}
+		rows = append(rows, [2]string{title, path})
+	}
+
+	return rows
+}
+
+// Создать модель экрана статуса
+func NewStatusModel(appOptions AppOptions) StatusModel {
+	ti := textinput.New()
+	ti.Placeholder = "myservice"
+	ti.Focus()
+	ti.CharLimit = 255
+	ti.Width = 80
+
+	return StatusModel{
+		State:   StatusStateServiceName,
+		Input:   ti,
+		backend: appOptions.backend,
+	}
+}
+
+// Загрузить состояние сервиса и последние строки журнала
+func LoadStatus(ctx context.Context, model StatusModel, serviceName string) StatusModel {
+	status, err := GetServiceStatus(ctx, model.backend, serviceName)
+	if err != nil {
+		model.Error = err.Error()
+		return model
+	}
+
+	// Отсутствие журнала не мешает показать состояние
+	logs, err := model.backend.Logs(ctx, []string{serviceName}, LogQuery{Lines: StatusLogLines})
+	if err != nil {
+		logs = []JournalEntry{{Priority: PriorityErr, Message: err.Error()}}
+	}
+
+	model.ServiceName = serviceName
+	model.Status = status
+	model.Logs = logs
+	model.State = StatusStateView
+	model.Error = ""
+	return model
+}
+
+// Обработка событий экрана статуса
+func UpdateStatus(ctx context.Context, msg tea.Msg, model StatusModel) (StatusModel, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+
+	if model.State == StatusStateView {
+		if !ok {
+			return model, nil
+		}
+
+		switch keyMsg.String() {
+		case "r":
+			return LoadStatus(ctx, model, model.ServiceName), nil
+		case "q", "esc", "ctrl+c":
+			model.Quitting = true
+		}
+		return model, nil
+	}
+
+	if ok {
+		switch keyMsg.Type {
+		case tea.KeyEnter:
+			// Если есть предыдущая ошибка, просто очищаем её
+			if model.Error != "" {
+				model.Error = ""
+				return model, nil
+			}
+
+			serviceName := model.Input.Value()
+			if err := IsValidServiceName(serviceName); err != nil {
+				model.Error = err.Error()
+				return model, nil
+			}
+
+			return LoadStatus(ctx, model, serviceName), nil
+
+		case tea.KeyEsc, tea.KeyCtrlC:
+			model.Quitting = true
+			return model, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	model.Input, cmd = model.Input.Update(msg)
+	return model, cmd
+}
+
+// Отрисовка экрана статуса
+func ViewStatus(model StatusModel) string {
+	var s strings.Builder
+
+	if model.State == StatusStateServiceName {
+		s.WriteString("Введите имя сервиса для просмотра статуса:\n\n")
+		s.WriteString(model.Input.View() + "\n\n")
+		if model.Error != "" {
+			s.WriteString(FormatError(model.Error) + "\n\n")
+		}
+		s.WriteString("Нажмите Enter для подтверждения, Esc для возврата в меню\n")
+		return s.String()
+	}
+
+	var panel strings.Builder
+	panel.WriteString(TitleStyle.Render(model.ServiceName) + "\n\n")
+
+	label := lipgloss.NewStyle().Width(14).Foreground(lipgloss.Color("245"))
+	for _, row := range model.Status.Rows(time.Now()) {
+		panel.WriteString(label.Render(row[0]) + row[1] + "\n")
+	}
+
+	s.WriteString(PanelStyle.Render(strings.TrimRight(panel.String(), "\n")) + "\n\n")
+
+	s.WriteString("Последние записи журнала:\n")
+	for _, entry := range model.Logs {
+		s.WriteString("  " + RenderJournalEntry(entry) + "\n")
+	}
+
+	if model.Error != "" {
+		s.WriteString("\n" + FormatError(model.Error) + "\n")
+	}
+
+	s.WriteString("\nr - обновить, Esc - назад\n")
+
+	return s.String()
+}
@@ -0,0 +1,95 @@
+package sdmanager
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestParseServiceStatus(t *testing.T) {
+	status, err := ParseServiceStatus(readFixture(t, "show_running.txt"))
+	if err != nil {
+		t.Fatalf("ParseServiceStatus: %v", err)
+	}
+
+	started := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	if status.Name != "nginx" || status.ActiveState != "active" || status.SubState != "running" {
+		t.Errorf("state = %s %s/%s", status.Name, status.ActiveState, status.SubState)
+	}
+	if status.MainPID != 1234 || status.NRestarts != 2 || status.TasksCurrent != 5 {
+		t.Errorf("counters = pid %d, restarts %d, tasks %d", status.MainPID, status.NRestarts, status.TasksCurrent)
+	}
+	if status.MemoryCurrent != 15*1024*1024 || formatBytes(status.MemoryCurrent) != "15.0M" {
+		t.Errorf("MemoryCurrent = %d (%s)", status.MemoryCurrent, formatBytes(status.MemoryCurrent))
+	}
+	if status.CPUUsage != 1234567890*time.Nanosecond {
+		t.Errorf("CPUUsage = %s", status.CPUUsage)
+	}
+	if !status.StartedAt.Equal(started) {
+		t.Errorf("StartedAt = %s, want %s", status.StartedAt, started)
+	}
+	if len(status.DropInPaths) != 2 || status.FragmentPath != "/usr/lib/systemd/system/nginx.service" {
+		t.Errorf("paths = %s %v", status.FragmentPath, status.DropInPaths)
+	}
+
+	// Недоступные счетчики systemd отдает как [not set] или максимальное значение uint64
+	status, err = ParseServiceStatus(readFixture(t, "show_stopped.txt"))
+	if err != nil {
+		t.Fatalf("ParseServiceStatus: %v", err)
+	}
+	if status.MemoryCurrent != 0 || status.TasksCurrent != 0 || status.CPUUsage != 0 || !status.StartedAt.IsZero() {
+		t.Errorf("unavailable counters = %+v", status)
+	}
+	if status.Result != "exit-code" || len(status.DropInPaths) != 0 {
+		t.Errorf("status = %+v", status)
+	}
+
+	if _, err := ParseServiceStatus("MainPID=abc"); err == nil {
+		t.Error("expected error for invalid MainPID")
+	}
+}
+
+func TestStatusScreen(t *testing.T) {
+	backend := NewFakeBackend(UnitInfo{Name: "api", ActiveState: "active", SubState: "running"})
+	backend.SetProperties("api", map[string]string{"MainPID": "42", "MemoryCurrent": "1048576"})
+	backend.SetLogs("api", "started", "listening on :8080")
+
+	model := NewStatusModel(AppOptions{backend: backend})
+	model.Input.SetValue("api")
+	model, _ = UpdateStatus(context.Background(), tea.KeyMsg{Type: tea.KeyEnter}, model)
+
+	if model.State != StatusStateView || model.Error != "" {
+		t.Fatalf("state = %d, error = %q", model.State, model.Error)
+	}
+	if model.Status.MainPID != 42 || model.Status.MemoryCurrent != 1<<20 {
+		t.Errorf("status = %+v", model.Status)
+	}
+	if !slices.Equal(logMessages(model.Logs), []string{"started", "listening on :8080"}) {
+		t.Errorf("logs = %v", model.Logs)
+	}
+
+	model = NewStatusModel(AppOptions{backend: backend})
+	model = LoadStatus(context.Background(), model, "missing")
+	if model.State != StatusStateServiceName || model.Error == "" {
+		t.Errorf("missing unit: state = %d, error = %q", model.State, model.Error)
+	}
+
+	if _, err := GetServiceStatus(context.Background(), backend, "missing"); !errors.Is(err, ErrUnitNotFound) {
+		t.Errorf("GetServiceStatus(missing) = %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+package sdmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Максимальное количество экземпляров шаблона, которое можно задать
+const MaxTemplateInstances = 100
+
+// Разобрать имя шаблона: "worker@" означает шаблон worker@.service
+func ParseTemplateName(name string) (string, bool) {
+	if base, ok := strings.CutSuffix(name, "@"); ok && base != "" {
+		return base, true
+	}
+	return name, false
+}
+
+// Привести конфигурацию с именем вида "worker@" к шаблону: имя без "@" и
+// хотя бы один экземпляр
+func NormalizeTemplateConfig(config ServiceConfig) ServiceConfig {
+	if base, ok := ParseTemplateName(config.ServiceName); ok {
+		config.ServiceName = base
+		if config.Instances == 0 {
+			config.Instances = 1
+		}
+	}
+	return config
+}
+
+// Имена экземпляров шаблона: name@1 .. name@count
+func TemplateInstanceNames(template string, count int) []string {
+	names := make([]string, 0, count)
+	for i := 1; i <= count; i++ {
+		names = append(names, template+"@"+strconv.Itoa(i))
+	}
+	return names
+}
+
+// Проверить количество экземпляров шаблона
+func ValidateInstanceCount(count int) error {
+	if count < 0 || count > MaxTemplateInstances {
+		return fmt.Errorf("количество экземпляров должно быть от 0 до %d", MaxTemplateInstances)
+	}
+	return nil
+}
+
+// Идентификатор экземпляра шаблона: "worker@3" -> "3"
+func templateInstance(unitName, template string) (string, bool) {
+	instance, ok := strings.CutPrefix(unitName, template+"@")
+	return instance, ok && instance != ""
+}
+
+// Работающий экземпляр
+func instanceRunning(unit UnitInfo) bool {
+	switch unit.ActiveState {
+	case "active", "activating", "reloading":
+		return true
+	}
+	return false
+}
+
+// Работающий или активированный экземпляр, в том числе упавший или
+// остановленный после активации
+func instanceInUse(unit UnitInfo) bool {
+	switch unit.UnitFileState {
+	case "enabled", "enabled-runtime":
+		return true
+	}
+	return instanceRunning(unit)
+}
+
+// Запущенные или активированные экземпляры шаблона. Числовые экземпляры
+// идут по возрастанию номера, остальные - после них по имени.
+func TemplateInstances(ctx context.Context, b Backend, template string) ([]UnitInfo, error) {
+	units, err := b.ListUnits(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []UnitInfo
+	for _, unit := range units {
+		if _, ok := templateInstance(unit.Name, template); !ok {
+			continue
+		}
+
+		// list-unit-files показывает только шаблон worker@.service, поэтому
+		// автозапуск экземпляра узнаем из его собственного UnitFileState
+		props, err := b.Properties(ctx, unit.Name, "UnitFileState")
+		if err != nil && !errors.Is(err, ErrUnitNotFound) {
+			return nil, err
+		}
+		unit.UnitFileState = props["UnitFileState"]
+
+		if instanceInUse(unit) {
+			instances = append(instances, unit)
+		}
+	}
+
+	slices.SortFunc(instances, func(a, c UnitInfo) int {
+		ai, _ := templateInstance(a.Name, template)
+		ci, _ := templateInstance(c.Name, template)
+		an, aErr := strconv.Atoi(ai)
+		cn, cErr := strconv.Atoi(ci)
+		switch {
+		case aErr == nil && cErr == nil:
+			return an - cn
+		case aErr == nil:
+			return -1
+		case cErr == nil:
+			return 1
+		}
+		return strings.Compare(ai, ci)
+	})
+
+	return instances, nil
+}
+
+// Изменить количество экземпляров шаблона: активировать и запустить
+// недостающие name@1 .. name@count, остановить и деактивировать экземпляры
+// с большими номерами. Экземпляры с нечисловыми именами не затрагиваются.
+func ScaleTemplate(ctx context.Context, b Backend, template string, count int) (string, error) {
+	resultMessages, err := scaleTemplate(ctx, b, template, count)
+	if err != nil {
+		return strings.Join(resultMessages, "\n"), err
+	}
+
+	if len(resultMessages) == 0 {
+		return fmt.Sprintf("Количество экземпляров %s@ не изменилось: %d", template, count), nil
+	}
+	return strings.Join(resultMessages, "\n"), nil
+}
+
+// Масштабировать шаблон; возвращает сообщения о запущенных и остановленных
+// экземплярах
+func scaleTemplate(ctx context.Context, b Backend, template string, count int) ([]string, error) {
+	if err := ValidateInstanceCount(count); err != nil {
+		return nil, err
+	}
+
+	instances, err := TemplateInstances(ctx, b, template)
+	if err != nil {
+		return nil, err
+	}
+
+	running := make(map[string]bool, len(instances))
+	for _, unit := range instances {
+		running[unit.Name] = true
+	}
+
+	var resultMessages []string
+
+	for _, name := range TemplateInstanceNames(template, count) {
+		if running[name] {
+			continue
+		}
+		if err := b.Enable(ctx, name); err != nil {
+			return resultMessages, err
+		}
+		if err := b.Start(ctx, name); err != nil {
+			return resultMessages, err
+		}
+		resultMessages = append(resultMessages, fmt.Sprintf("Экземпляр %s запущен", name))
+	}
+
+	// Лишние экземпляры останавливаем с конца
+	for _, unit := range slices.Backward(instances) {
+		instance, _ := templateInstance(unit.Name, template)
+		n, err := strconv.Atoi(instance)
+		if err != nil || n <= count {
+			continue
+		}
+		if err := b.Stop(ctx, unit.Name); err != nil {
+			return resultMessages, err
+		}
+		if err := b.Disable(ctx, unit.Name); err != nil {
+			return resultMessages, err
+		}
+		resultMessages = append(resultMessages, fmt.Sprintf("Экземпляр %s остановлен", unit.Name))
+	}
+
+	return resultMessages, nil
+}
+
+// Остановить и деактивировать все экземпляры шаблона перед его удалением
+func stopTemplateInstances(ctx context.Context, b Backend, template string) ([]string, error) {
+	instances, err := TemplateInstances(ctx, b, template)
+	if err != nil {
+		return nil, err
+	}
+
+	var resultMessages []string
+	for _, unit := range instances {
+		if err := b.Stop(ctx, unit.Name); err != nil && !errors.Is(err, ErrUnitNotFound) {
+			return resultMessages, err
+		}
+		// Экземпляр, запущенный сокетом, не активирован - это не ошибка
+		_ = b.Disable(ctx, unit.Name)
+		resultMessages = append(resultMessages, fmt.Sprintf("Экземпляр %s остановлен", unit.Name))
+	}
+	return resultMessages, nil
+}
@@ -0,0 +1,142 @@
+package sdmanager
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTemplateConfig(t *testing.T) {
+	config := NormalizeTemplateConfig(ServiceConfig{ServiceName: "worker@"})
+	if config.ServiceName != "worker" || config.Instances != 1 {
+		t.Errorf("config = %+v", config)
+	}
+
+	config = NormalizeTemplateConfig(ServiceConfig{ServiceName: "worker@", Instances: 4})
+	if config.Instances != 4 {
+		t.Errorf("Instances = %d, want 4", config.Instances)
+	}
+
+	if config := NormalizeTemplateConfig(ServiceConfig{ServiceName: "web"}); config.Instances != 0 {
+		t.Errorf("plain service became template: %+v", config)
+	}
+}
+
+func TestGenerateTemplateUnit(t *testing.T) {
+	config := ServiceConfig{
+		ServiceName:      "worker",
+		ExecStart:        "/usr/bin/worker --id %i",
+		WorkingDirectory: "/srv/worker/%i",
+		UnitFilePath:     "/etc/systemd/system",
+		Instances:        3,
+	}
+
+	units, err := GenerateUnits(config)
+	if err != nil {
+		t.Fatalf("GenerateUnits: %v", err)
+	}
+	if len(units) != 1 || units[0].Path != "/etc/systemd/system/worker@.service" {
+		t.Fatalf("units = %+v", units)
+	}
+	for _, line := range []string{"Description=Worker Service %i", "ExecStart=/usr/bin/worker --id %i", "WorkingDirectory=/srv/worker/%i"} {
+		if !strings.Contains(units[0].Content, line) {
+			t.Errorf("unit does not contain %q:\n%s", line, units[0].Content)
+		}
+	}
+	if names := units[0].ActivateNames(); !slices.Equal(names, []string{"worker@1", "worker@2", "worker@3"}) {
+		t.Errorf("ActivateNames = %v", names)
+	}
+
+	config.Timer = &TimerConfig{OnCalendar: []string{"daily"}}
+	if err := ValidateServiceConfig(config); err == nil {
+		t.Error("expected error for template with timer")
+	}
+}
+
+func TestScaleTemplate(t *testing.T) {
+	ctx := context.Background()
+	backend := NewFakeBackend(UnitInfo{Name: "worker@"})
+
+	if _, err := ScaleTemplate(ctx, backend, "worker", 3); err != nil {
+		t.Fatalf("ScaleTemplate: %v", err)
+	}
+	if _, err := ScaleTemplate(ctx, backend, "worker", 1); err != nil {
+		t.Fatalf("ScaleTemplate: %v", err)
+	}
+
+	want := []string{
+		"list-units",
+		"enable worker@1", "start worker@1",
+		"enable worker@2", "start worker@2",
+		"enable worker@3", "start worker@3",
+		"list-units", "show worker@1", "show worker@2", "show worker@3",
+		"stop worker@3", "disable worker@3",
+		"stop worker@2", "disable worker@2",
+	}
+	if calls := backend.Calls(); !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+
+	instances, err := TemplateInstances(ctx, backend, "worker")
+	if err != nil || len(instances) != 1 || instances[0].Name != "worker@1" {
+		t.Errorf("instances = %+v, err = %v", instances, err)
+	}
+
+	message, _ := ScaleTemplate(ctx, backend, "worker", 1)
+	if !strings.Contains(message, "не изменилось") {
+		t.Errorf("message = %q", message)
+	}
+
+	if _, err := ScaleTemplate(ctx, backend, "worker", MaxTemplateInstances+1); err == nil {
+		t.Error("expected error for too many instances")
+	}
+}
+
+func TestScaleTemplateFailedInstances(t *testing.T) {
+	ctx := context.Background()
+	backend := NewFakeBackend(
+		UnitInfo{Name: "worker@"},
+		UnitInfo{Name: "worker@1", ActiveState: "active", SubState: "running", UnitFileState: "enabled"},
+		UnitInfo{Name: "worker@2", ActiveState: "failed", SubState: "failed", UnitFileState: "enabled"},
+		UnitInfo{Name: "worker@3", UnitFileState: "enabled"},
+		UnitInfo{Name: "worker@4", ActiveState: "failed", SubState: "failed"},
+	)
+
+	// Упавший и остановленный экземпляры активированы и остаются в работе,
+	// хотя list-units не показывает автозапуск экземпляров
+	instances, err := TemplateInstances(ctx, backend, "worker")
+	if err != nil {
+		t.Fatalf("TemplateInstances: %v", err)
+	}
+	var names []string
+	for _, unit := range instances {
+		names = append(names, unit.Name)
+	}
+	if !slices.Equal(names, []string{"worker@1", "worker@2", "worker@3"}) {
+		t.Errorf("instances = %v", names)
+	}
+
+	if _, err := ScaleTemplate(ctx, backend, "worker", 1); err != nil {
+		t.Fatalf("ScaleTemplate: %v", err)
+	}
+	for _, name := range []string{"worker@2", "worker@3"} {
+		if unit, _ := backend.Unit(name); unit.UnitFileState != "disabled" {
+			t.Errorf("%s: UnitFileState = %s", name, unit.UnitFileState)
+		}
+	}
+	if slices.Contains(backend.Calls(), "disable worker@4") {
+		t.Error("inactive instance without autostart was disabled")
+	}
+
+	// При удалении шаблона упавший экземпляр тоже деактивируется
+	backend.Enable(ctx, "worker@2")
+	if _, err := stopTemplateInstances(ctx, backend, "worker"); err != nil {
+		t.Fatalf("stopTemplateInstances: %v", err)
+	}
+	for _, name := range []string{"worker@1", "worker@2"} {
+		if unit, _ := backend.Unit(name); unit.UnitFileState != "disabled" || unit.ActiveState != "inactive" {
+			t.Errorf("%s: %s/%s", name, unit.ActiveState, unit.UnitFileState)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package sdmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// Количество ближайших срабатываний таймера в предпросмотре
+const TimerPreviewElapses = 5
+
+// Настройки таймера (.timer), запускающего сервис по расписанию
+type TimerConfig struct {
+	// Календарные выражения, например "Mon..Fri 09:00" или "daily"
+	OnCalendar []string `json:"on_calendar,omitempty" yaml:"on_calendar,omitempty"`
+	// Интервалы в формате systemd ("15min", "1h 30min")
+	OnBootSec          string `json:"on_boot_sec,omitempty" yaml:"on_boot_sec,omitempty"`
+	OnUnitActiveSec    string `json:"on_unit_active_sec,omitempty" yaml:"on_unit_active_sec,omitempty"`
+	RandomizedDelaySec string `json:"randomized_delay_sec,omitempty" yaml:"randomized_delay_sec,omitempty"`
+	AccuracySec        string `json:"accuracy_sec,omitempty" yaml:"accuracy_sec,omitempty"`
+	// Запустить пропущенное срабатывание после включения машины
+	Persistent bool `json:"persistent,omitempty" yaml:"persistent,omitempty"`
+}
+
+// Проверить настройки таймера: нужен хотя бы один триггер, календарные
+// выражения и интервалы должны разбираться
+func ValidateTimerConfig(ctx context.Context, timer TimerConfig) error {
+	if len(timer.OnCalendar) == 0 && timer.OnBootSec == "" && timer.OnUnitActiveSec == "" {
+		return errors.New("таймеру нужен хотя бы один триггер: OnCalendar, OnBootSec или OnUnitActiveSec")
+	}
+
+	for _, expr := range timer.OnCalendar {
+		if _, err := CalendarNextElapses(ctx, expr, time.Now(), 1); err != nil {
+			return fmt.Errorf("OnCalendar: %w", err)
+		}
+	}
+
+	for _, field := range []struct{ name, value string }{
+		{"OnBootSec", timer.OnBootSec},
+		{"OnUnitActiveSec", timer.OnUnitActiveSec},
+		{"RandomizedDelaySec", timer.RandomizedDelaySec},
+		{"AccuracySec", timer.AccuracySec},
+	} {
+		if field.value == "" {
+			continue
+		}
+		if _, err := ParseTimespan(field.value); err != nil {
+			return fmt.Errorf("%s: %w", field.name, err)
+		}
+	}
+
+	if timer.Persistent && len(timer.OnCalendar) == 0 {
+		return errors.New("Persistent работает только вместе с OnCalendar")
+	}
+
+	return nil
+}
+
+// Разобрать список календарных выражений, разделенных ";"
+func ParseCalendarList(value string) []string {
+	var exprs []string
+	for _, expr := range strings.Split(value, ";") {
+		if expr = strings.TrimSpace(expr); expr != "" {
+			exprs = append(exprs, expr)
+		}
+	}
+	return exprs
+}
+
+// Сформировать содержимое .timer для сервиса
+func GenerateTimerUnit(serviceName string, timer TimerConfig) string {
+	var sb strings.Builder
+
+	sb.WriteString("[Unit]\n")
+	sb.WriteString("Description=" + cases.Title(language.English).String(serviceName) + " Timer\n\n")
+
+	sb.WriteString("[Timer]\n")
+	for _, expr := range timer.OnCalendar {
+		sb.WriteString("OnCalendar=" + expr + "\n")
+	}
+	if timer.OnBootSec != "" {
+		sb.WriteString("OnBootSec=" + timer.OnBootSec + "\n")
+	}
+	if timer.OnUnitActiveSec != "" {
+		sb.WriteString("OnUnitActiveSec=" + timer.OnUnitActiveSec + "\n")
+	}
+	if timer.RandomizedDelaySec != "" {
+		sb.WriteString("RandomizedDelaySec=" + timer.RandomizedDelaySec + "\n")
+	}
+	if timer.AccuracySec != "" {
+		sb.WriteString("AccuracySec=" + timer.AccuracySec + "\n")
+	}
+	if timer.Persistent {
+		sb.WriteString("Persistent=true\n")
+	}
+	sb.WriteString("\n[Install]\nWantedBy=timers.target\n")
+
+	return sb.String()
+}
+
+// Описание ближайших срабатываний календарных выражений таймера
+func TimerElapsesPreview(ctx context.Context, timer TimerConfig, now time.Time) string {
+	var sb strings.Builder
+
+	for _, expr := range timer.OnCalendar {
+		sb.WriteString("Ближайшие срабатывания " + expr + ":\n")
+		elapses, err := CalendarNextElapses(ctx, expr, now, TimerPreviewElapses)
+		if err != nil {
+			sb.WriteString("  " + err.Error() + "\n")
+			continue
+		}
+		for _, t := range elapses {
+			sb.WriteString("  " + t.Format(systemdTimestampLayout) + "\n")
+		}
+	}
+
+	if timer.OnBootSec != "" {
+		sb.WriteString("После загрузки через " + timer.OnBootSec + "\n")
+	}
+	if timer.OnUnitActiveSec != "" {
+		sb.WriteString("Повторно через " + timer.OnUnitActiveSec + " после предыдущего запуска\n")
+	}
+
+	return sb.String()
+}
@@ -3,6 +3,9 @@ package sdmanager
 import (
 	"fmt"
 	"io"
+	"maps"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/list"
@@ -12,7 +15,9 @@ import (
 
 // Константы для стилей UI
 const (
-	ListHeight = 14
+	ListHeight = 16
+	// Минимальная ширина колонки в предпросмотре нескольких unit-файлов
+	MinUnitColumnWidth = 30
 )
 
 // Стили для UI
@@ -25,6 +30,8 @@ var (
 	QuitTextStyle     = lipgloss.NewStyle().Margin(1, 0, 2, 4)
 	InfoStyle         = lipgloss.NewStyle().PaddingLeft(2).Foreground(lipgloss.Color("86"))
 	ErrorStyle        = lipgloss.NewStyle().PaddingLeft(2).Foreground(lipgloss.Color("196"))
+	WarningStyle      = lipgloss.NewStyle().PaddingLeft(2).Foreground(lipgloss.Color("214"))
+	UnitHeaderStyle   = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("170"))
 	ViewportStyle     = lipgloss.NewStyle().BorderStyle(lipgloss.RoundedBorder()).Padding(1).BorderForeground(lipgloss.Color("62"))
 )
 
@@ -56,11 +63,56 @@ func FormatError(err string) string {
 	return ErrorStyle.Render("Ошибка: " + err)
 }
 
+// Функция для форматирования предупреждений
+func FormatWarning(warning string) string {
+	return WarningStyle.Render("Внимание: " + warning)
+}
+
 // Функция для форматирования информационных сообщений
 func FormatInfo(info string) string {
 	return InfoStyle.Render(info)
 }
 
+// Полоса прогресса вида [=====     ] 50% (5/10)
+func RenderProgress(done, total, width int) string {
+	if total <= 0 {
+		return "[" + strings.Repeat(" ", width) + "]"
+	}
+
+	done = min(done, total)
+	filled := width * done / total
+	return fmt.Sprintf("[%s%s] %d%% (%d/%d)", strings.Repeat("=", filled), strings.Repeat(" ", width-filled), 100*done/total, done, total)
+}
+
+// Отобразить переменные окружения сервиса; значения секретов скрыты
+func RenderEnvironment(env, secrets map[string]string) string {
+	if len(env) == 0 && len(secrets) == 0 {
+		return "    (переменные не заданы)\n"
+	}
+
+	var sb strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(env)) {
+		sb.WriteString("    " + name + "=" + env[name] + "\n")
+	}
+	for _, name := range slices.Sorted(maps.Keys(secrets)) {
+		sb.WriteString("    " + name + "=******** " + InfoStyle.Render("(секрет)") + "\n")
+	}
+	return sb.String()
+}
+
+// Отобразить команды жизненного цикла в редакторе мастера
+func RenderCommands(commands []string) string {
+	if len(commands) == 0 {
+		return "    (команды не заданы)\n"
+	}
+
+	var sb strings.Builder
+	for i, command := range commands {
+		sb.WriteString(fmt.Sprintf("    %d. %s\n", i+1, command))
+	}
+	return sb.String()
+}
+
 // Отобразить список опций с выбором
 func RenderOptionsList(options []Option, currentOption int) string {
 	var sb strings.Builder
@@ -85,7 +137,7 @@ func RenderOptionsList(options []Option, currentOption int) string {
 }
 
 // Отобразить выбранные опции
-func RenderSelectedOptions(actions UserActions) string {
+func RenderSelectedOptions(actions UserActions, target string) string {
 	var sb strings.Builder
 
 	sb.WriteString("Выбранные опции:\n")
@@ -93,11 +145,37 @@ func RenderSelectedOptions(actions UserActions) string {
 		sb.WriteString("✓ Перезагрузить systemd daemon\n")
 	}
 	if actions.EnableService {
-		sb.WriteString("✓ Активировать (enable) сервис\n")
+		sb.WriteString("✓ Активировать (enable) " + target + "\n")
 	}
 	if actions.StartService {
-		sb.WriteString("✓ Запустить (start) сервис\n")
+		sb.WriteString("✓ Запустить (start) " + target + "\n")
+	}
+	if actions.RestartService {
+		sb.WriteString("✓ Перезапустить (restart) сервис\n")
 	}
 
 	return sb.String()
 }
+
+// Отрисовать unit-файлы колонками рядом друг с другом. Если колонки не
+// помещаются в ширину, файлы выводятся друг под другом.
+func RenderUnitsSideBySide(units []GeneratedUnit, width int) string {
+	const gap = 2
+
+	columnWidth := (width - gap*(len(units)-1)) / len(units)
+	if len(units) == 1 || columnWidth < MinUnitColumnWidth {
+		return GenerateUnitsPreview(units)
+	}
+
+	columns := make([]string, 0, len(units))
+	for i, unit := range units {
+		style := lipgloss.NewStyle().Width(columnWidth)
+		if i < len(units)-1 {
+			style = style.MarginRight(gap)
+		}
+		header := UnitHeaderStyle.Render(filepath.Base(unit.Path))
+		columns = append(columns, style.Render(header+"\n\n"+strings.TrimRight(unit.Content, "\n")))
+	}
+
+	return lipgloss.JoinHorizontal(lipgloss.Top, columns...)
+}
@@ -0,0 +1,666 @@
+package sdmanager
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Тип строки unit-файла
+type UnitLineKind int
+
+const (
+	UnitLineBlank UnitLineKind = iota
+	UnitLineComment
+	UnitLineDirective
+)
+
+// Строка unit-файла. Raw хранит исходный текст (вместе со строками
+// продолжения), поэтому неизмененные строки записываются обратно как есть.
+type UnitLine struct {
+	Kind  UnitLineKind
+	Key   string
+	Value string
+	Raw   string
+}
+
+// Секция unit-файла ([Unit], [Service], [Install] и т.д.)
+type UnitSection struct {
+	Name  string
+	Raw   string
+	Lines []*UnitLine
+}
+
+// Разобранный unit-файл
+type UnitFile struct {
+	Preamble []*UnitLine
+	Sections []*UnitSection
+
+	trailingNewline bool
+}
+
+// Разбор unit-файла в формате INI, принятом в systemd
+func ParseUnitFile(r io.Reader) (*UnitFile, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения unit-файла: %w", err)
+	}
+
+	text := string(data)
+	unit := &UnitFile{trailingNewline: strings.HasSuffix(text, "\n")}
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return unit, nil
+	}
+
+	var (
+		section *UnitSection
+		lines   = strings.Split(text, "\n")
+	)
+
+	appendLine := func(line *UnitLine) {
+		if section == nil {
+			unit.Preamble = append(unit.Preamble, line)
+			return
+		}
+		section.Lines = append(section.Lines, line)
+	}
+
+	for i := 0; i < len(lines); i++ {
+		raw := lines[i]
+		trimmed := strings.TrimSpace(raw)
+
+		switch {
+		case trimmed == "":
+			appendLine(&UnitLine{Kind: UnitLineBlank, Raw: raw})
+
+		case trimmed[0] == '#' || trimmed[0] == ';':
+			appendLine(&UnitLine{Kind: UnitLineComment, Raw: raw})
+
+		case trimmed[0] == '[':
+			if !strings.HasSuffix(trimmed, "]") || len(trimmed) < 3 {
+				return nil, fmt.Errorf("строка %d: некорректный заголовок секции: %s", i+1, trimmed)
+			}
+			section = &UnitSection{Name: trimmed[1 : len(trimmed)-1], Raw: raw}
+			unit.Sections = append(unit.Sections, section)
+
+		default:
+			if section == nil {
+				return nil, fmt.Errorf("строка %d: директива вне секции: %s", i+1, trimmed)
+			}
+
+			// Склеиваем строки продолжения, оканчивающиеся на "\"
+			start := i
+			rawLines := []string{raw}
+			parts := []string{trimmed}
+			for strings.HasSuffix(parts[len(parts)-1], "\\") && i+1 < len(lines) {
+				i++
+				rawLines = append(rawLines, lines[i])
+
+				next := strings.TrimSpace(lines[i])
+				// Комментарии внутри продолжения systemd пропускает
+				if next != "" && (next[0] == '#' || next[0] == ';') {
+					continue
+				}
+
+				last := len(parts) - 1
+				parts[last] = strings.TrimSpace(strings.TrimSuffix(parts[last], "\\"))
+				parts = append(parts, next)
+			}
+			value := strings.Join(parts, " ")
+
+			key, val, ok := strings.Cut(value, "=")
+			if !ok {
+				return nil, fmt.Errorf("строка %d: ожидалось Key=Value: %s", start+1, trimmed)
+			}
+
+			key = strings.TrimSpace(key)
+			if key == "" {
+				return nil, fmt.Errorf("строка %d: пустое имя директивы", start+1)
+			}
+
+			appendLine(&UnitLine{
+				Kind:  UnitLineDirective,
+				Key:   key,
+				Value: strings.TrimSpace(val),
+				Raw:   strings.Join(rawLines, "\n"),
+			})
+		}
+	}
+
+	return unit, nil
+}
+
+// Чтение и разбор unit-файла с диска
+func ReadUnitFile(path string) (*UnitFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при открытии unit-файла: %w", err)
+	}
+	defer file.Close()
+
+	return ParseUnitFile(bufio.NewReader(file))
+}
+
+// Сериализация unit-файла обратно в текст
+func (u *UnitFile) String() string {
+	var lines []string
+
+	for _, line := range u.Preamble {
+		lines = append(lines, line.Raw)
+	}
+
+	for _, section := range u.Sections {
+		lines = append(lines, section.Raw)
+		for _, line := range section.Lines {
+			lines = append(lines, line.Raw)
+		}
+	}
+
+	result := strings.Join(lines, "\n")
+	if u.trailingNewline || len(lines) == 0 {
+		result += "\n"
+	}
+
+	return result
+}
+
+// Найти первую секцию с указанным именем
+func (u *UnitFile) Section(name string) *UnitSection {
+	for _, section := range u.Sections {
+		if section.Name == name {
+			return section
+		}
+	}
+	return nil
+}
+
+// Получить значение директивы. Как и в systemd, побеждает последнее присваивание
+func (u *UnitFile) Get(section, key string) (string, bool) {
+	values := u.GetAll(section, key)
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[len(values)-1], true
+}
+
+// Получить все значения повторяющейся директивы с учетом сброса пустым
+// присваиванием (например, "ExecStart=")
+func (u *UnitFile) GetAll(section, key string) []string {
+	var values []string
+
+	for _, s := range u.Sections {
+		if s.Name != section {
+			continue
+		}
+		for _, line := range s.Lines {
+			if line.Kind != UnitLineDirective || line.Key != key {
+				continue
+			}
+			if line.Value == "" {
+				values = nil
+				continue
+			}
+			values = append(values, line.Value)
+		}
+	}
+
+	return values
+}
+
+// Установить единственное значение директивы
+func (u *UnitFile) Set(section, key, value string) {
+	u.SetAll(section, key, []string{value})
+}
+
+// Заменить все значения директивы. Существующие строки переиспользуются по
+// порядку, лишние удаляются, недостающие добавляются после последней директивы
+// секции. Пустой список удаляет директиву. Последнее пустое присваивание
+// (сброс, например "ExecStart=") и строки перед ним остаются на месте: новые
+// значения записываются после него.
+func (u *UnitFile) SetAll(section, key string, values []string) {
+	if current := u.GetAll(section, key); slices.Equal(current, values) {
+		return
+	}
+
+	reset, count := -1, 0
+	for _, s := range u.Sections {
+		if s.Name != section {
+			continue
+		}
+		for _, line := range s.Lines {
+			if line.Kind == UnitLineDirective && line.Key == key {
+				if line.Value == "" {
+					reset = count
+				}
+				count++
+			}
+		}
+	}
+
+	var (
+		target *UnitSection
+		index  int
+	)
+
+	count = 0
+	for _, s := range u.Sections {
+		if s.Name != section {
+			continue
+		}
+		target = s
+
+		kept := s.Lines[:0]
+		for _, line := range s.Lines {
+			if line.Kind == UnitLineDirective && line.Key == key {
+				count++
+				if count <= reset+1 {
+					kept = append(kept, line)
+					continue
+				}
+				if index < len(values) {
+					line.Value = values[index]
+					line.Raw = key + "=" + values[index]
+					index++
+					kept = append(kept, line)
+				}
+				continue
+			}
+			kept = append(kept, line)
+		}
+		s.Lines = kept
+	}
+
+	if index >= len(values) {
+		return
+	}
+
+	if target == nil {
+		if len(u.Sections) > 0 {
+			u.appendBlankToLastSection()
+		}
+		target = &UnitSection{Name: section, Raw: "[" + section + "]"}
+		u.Sections = append(u.Sections, target)
+	}
+
+	// Вставляем после последней директивы секции, чтобы не отрывать
+	// добавленные строки от остальных пустыми строками и комментариями
+	pos := 0
+	for i, line := range target.Lines {
+		if line.Kind == UnitLineDirective {
+			pos = i + 1
+		}
+	}
+
+	var added []*UnitLine
+	for _, value := range values[index:] {
+		added = append(added, &UnitLine{Kind: UnitLineDirective, Key: key, Value: value, Raw: key + "=" + value})
+	}
+
+	target.Lines = append(target.Lines[:pos], append(added, target.Lines[pos:]...)...)
+}
+
+// Удалить все вхождения директивы
+func (u *UnitFile) Del(section, key string) {
+	u.SetAll(section, key, nil)
+}
+
+// Добавить пустую строку-разделитель в конец последней секции
+func (u *UnitFile) appendBlankToLastSection() {
+	last := u.Sections[len(u.Sections)-1]
+	if n := len(last.Lines); n > 0 && last.Lines[n-1].Kind == UnitLineBlank {
+		return
+	}
+	last.Lines = append(last.Lines, &UnitLine{Kind: UnitLineBlank})
+}
+
+// Заполнить ServiceConfig значениями из разобранного unit-файла
+func ServiceConfigFromUnit(unit *UnitFile, serviceName, unitFilePath string) (ServiceConfig, error) {
+	config := ServiceConfig{
+		ServiceName:  serviceName,
+		UnitFilePath: unitFilePath,
+	}
+
+	config.UserName, _ = unit.Get("Service", "User")
+	config.WorkingDirectory, _ = unit.Get("Service", "WorkingDirectory")
+	if execStart := unit.GetAll("Service", "ExecStart"); len(execStart) > 0 {
+		config.ExecStart, config.ExecStartNext = execStart[0], execStart[1:]
+	}
+	config.StandardOutput, _ = unit.Get("Service", "StandardOutput")
+	config.StandardError, _ = unit.Get("Service", "StandardError")
+	config.SyslogIdentifier, _ = unit.Get("Service", "SyslogIdentifier")
+	config.AllowedCPUs, _ = unit.Get("Service", "AllowedCPUs")
+	config.AllowedMemoryNodes, _ = unit.Get("Service", "AllowedMemoryNodes")
+	config.TasksMax, _ = unit.Get("Service", "TasksMax")
+	config.IODeviceReadBandwidthMax = unit.GetAll("Service", "IODeviceReadBandwidthMax")
+	config.IODeviceWriteBandwidthMax = unit.GetAll("Service", "IODeviceWriteBandwidthMax")
+	config.LimitNOFILE, _ = unit.Get("Service", "LimitNOFILE")
+	config.LimitNPROC, _ = unit.Get("Service", "LimitNPROC")
+	config.LimitCORE, _ = unit.Get("Service", "LimitCORE")
+	config.Hardening = detectHardening(unit, config)
+	config.Type, _ = unit.Get("Service", "Type")
+	config.Restart, _ = unit.Get("Service", "Restart")
+	config.RestartSec, _ = unit.Get("Service", "RestartSec")
+	config.TimeoutStartSec, _ = unit.Get("Service", "TimeoutStartSec")
+	config.TimeoutStopSec, _ = unit.Get("Service", "TimeoutStopSec")
+	config.KillMode, _ = unit.Get("Service", "KillMode")
+	config.KillSignal, _ = unit.Get("Service", "KillSignal")
+	config.StartLimitIntervalSec, _ = unit.Get("Unit", "StartLimitIntervalSec")
+	config.EnvironmentFiles = unit.GetAll("Service", "EnvironmentFile")
+	for _, dep := range dependencies(config) {
+		*dependencyField(&config, dep.Key) = unitWords(unit.GetAll(dep.Section, dep.Key))
+	}
+	config.ExecStartPre = unit.GetAll("Service", "ExecStartPre")
+	config.ExecStartPost = unit.GetAll("Service", "ExecStartPost")
+	config.ExecReload = unit.GetAll("Service", "ExecReload")
+	config.ExecStop = unit.GetAll("Service", "ExecStop")
+	config.ExecStopPost = unit.GetAll("Service", "ExecStopPost")
+
+	var err error
+	if config.Environment, err = ParseEnvironment(unit.GetAll("Service", "Environment")); err != nil {
+		return config, fmt.Errorf("Environment: %w", err)
+	}
+
+	if value, ok := unit.Get("Service", "RemainAfterExit"); ok {
+		if config.RemainAfterExit, err = parseUnitBool(value); err != nil {
+			return config, fmt.Errorf("RemainAfterExit: %w", err)
+		}
+	}
+
+	if value, ok := unit.Get("Unit", "StartLimitBurst"); ok {
+		if config.StartLimitBurst, err = strconv.Atoi(value); err != nil {
+			return config, fmt.Errorf("StartLimitBurst: некорректное значение: %s", value)
+		}
+	}
+
+	for key, field := range map[string]*MemorySize{
+		"MemoryHigh":    &config.MemoryHigh,
+		"MemoryMax":     &config.MemoryMax,
+		"MemorySwapMax": &config.MemorySwapMax,
+	} {
+		if value, ok := unit.Get("Service", key); ok {
+			if *field, err = memorySizeFromUnit(value); err != nil {
+				return config, fmt.Errorf("%s: %w", key, err)
+			}
+		}
+	}
+
+	for key, field := range map[string]*int{"CPUWeight": &config.CPUWeight, "IOWeight": &config.IOWeight} {
+		if value, ok := unit.Get("Service", key); ok {
+			if *field, err = strconv.Atoi(value); err != nil {
+				return config, fmt.Errorf("%s: некорректное значение: %s", key, value)
+			}
+		}
+	}
+
+	if value, ok := unit.Get("Service", "Nice"); ok {
+		nice, err := strconv.Atoi(value)
+		if err != nil {
+			return config, fmt.Errorf("Nice: некорректное значение: %s", value)
+		}
+		config.Nice = &nice
+	}
+
+	if value, ok := unit.Get("Service", "CPUQuota"); ok {
+		if config.CPUQuota, err = ParseCPUQuota(value); err != nil {
+			return config, fmt.Errorf("CPUQuota: некорректное значение: %s", value)
+		}
+	}
+
+	return config, nil
+}
+
+// Загрузить конфигурацию сервиса из существующего unit-файла
+func LoadServiceConfig(unitFilePath, serviceName string) (ServiceConfig, *UnitFile, error) {
+	path := filepath.Join(unitFilePath, serviceName+".service")
+
+	unit, err := ReadUnitFile(path)
+	if err != nil {
+		return ServiceConfig{}, nil, err
+	}
+
+	config, err := ServiceConfigFromUnit(unit, serviceName, unitFilePath)
+	if err != nil {
+		return ServiceConfig{}, nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return config, unit, nil
+}
+
+// Директива unit-файла, которой управляет sdmanager
+type serviceDirective struct {
+	// Секция директивы; пустая означает [Service]
+	Section string
+	Key     string
+	Values  []string
+	// Размер памяти: сравнивается по значению, а не по записи
+	Size bool
+	// Логическое значение: "true" и "yes" равны, "no" равно отсутствию
+	Bool bool
+	// Переменные окружения: сравниваются по значениям, а не по записи
+	Env bool
+	// Список, который в drop-in нужно сначала очистить пустым присваиванием
+	Reset bool
+	// Список unit: сравнивается по именам, а drop-in может его только
+	// дополнить - systemd не позволяет сбросить зависимости
+	Units bool
+	// Не менять директиву: профиль защиты не выбран, и значение в unit-файле
+	// задано вручную
+	Keep bool
+}
+
+// Директивы секции [Service], формируемые из ServiceConfig
+func serviceDirectives(config ServiceConfig) []serviceDirective {
+	single := func(value string) []string {
+		if value == "" {
+			return nil
+		}
+		return []string{value}
+	}
+
+	directives := []serviceDirective{
+		{Key: "User", Values: single(config.UserName)},
+		{Key: "WorkingDirectory", Values: single(config.WorkingDirectory)},
+		{Key: "Environment", Values: FormatEnvironment(config.Environment), Env: true, Reset: true},
+		{Key: "EnvironmentFile", Values: environmentFiles(config), Reset: true},
+		{Key: "ExecStartPre", Values: config.ExecStartPre, Reset: true},
+		{Key: "ExecStart", Values: append(single(config.ExecStart), config.ExecStartNext...), Reset: true},
+		{Key: "ExecStartPost", Values: config.ExecStartPost, Reset: true},
+		{Key: "ExecReload", Values: config.ExecReload, Reset: true},
+		{Key: "ExecStop", Values: config.ExecStop, Reset: true},
+		{Key: "ExecStopPost", Values: config.ExecStopPost, Reset: true},
+		{Key: "Type", Values: single(config.Type)},
+		{Key: "RemainAfterExit", Values: single(formatUnitBool(config.RemainAfterExit)), Bool: true},
+		{Key: "Restart", Values: single(config.Restart)},
+		{Key: "RestartSec", Values: single(config.RestartSec)},
+		{Key: "TimeoutStartSec", Values: single(config.TimeoutStartSec)},
+		{Key: "TimeoutStopSec", Values: single(config.TimeoutStopSec)},
+		{Key: "KillMode", Values: single(config.KillMode)},
+		{Key: "KillSignal", Values: single(config.KillSignal)},
+		{Section: "Unit", Key: "After", Values: config.After, Units: true},
+		{Section: "Unit", Key: "Before", Values: config.Before, Units: true},
+		{Section: "Unit", Key: "Wants", Values: config.Wants, Units: true},
+		{Section: "Unit", Key: "Requires", Values: config.Requires, Units: true},
+		{Section: "Unit", Key: "BindsTo", Values: config.BindsTo, Units: true},
+		{Section: "Unit", Key: "PartOf", Values: config.PartOf, Units: true},
+		{Section: "Unit", Key: "Conflicts", Values: config.Conflicts, Units: true},
+		{Section: "Install", Key: "WantedBy", Values: config.WantedBy, Units: true},
+		{Section: "Install", Key: "RequiredBy", Values: config.RequiredBy, Units: true},
+		{Section: "Unit", Key: "StartLimitIntervalSec", Values: single(config.StartLimitIntervalSec)},
+		{Section: "Unit", Key: "StartLimitBurst", Values: single(formatPositive(config.StartLimitBurst))},
+		{Key: "StandardOutput", Values: single(config.StandardOutput)},
+		{Key: "StandardError", Values: single(config.StandardError)},
+		{Key: "SyslogIdentifier", Values: single(config.SyslogIdentifier)},
+		{Key: "MemoryMax", Values: single(config.MemoryMax.UnitValue()), Size: true},
+		{Key: "MemoryHigh", Values: single(config.MemoryHigh.UnitValue()), Size: true},
+		{Key: "CPUQuota", Values: single(formatPercent(config.CPUQuota))},
+		{Key: "AllowedCPUs", Values: single(config.AllowedCPUs)},
+		{Key: "MemorySwapMax", Values: single(config.MemorySwapMax.UnitValue()), Size: true},
+		{Key: "CPUWeight", Values: single(formatPositive(config.CPUWeight))},
+		{Key: "AllowedMemoryNodes", Values: single(config.AllowedMemoryNodes)},
+		{Key: "TasksMax", Values: single(config.TasksMax)},
+		{Key: "IOWeight", Values: single(formatPositive(config.IOWeight))},
+		{Key: "IODeviceReadBandwidthMax", Values: config.IODeviceReadBandwidthMax, Reset: true},
+		{Key: "IODeviceWriteBandwidthMax", Values: config.IODeviceWriteBandwidthMax, Reset: true},
+		{Key: "LimitNOFILE", Values: single(config.LimitNOFILE)},
+		{Key: "LimitNPROC", Values: single(config.LimitNPROC)},
+		{Key: "LimitCORE", Values: single(config.LimitCORE)},
+		{Key: "Nice", Values: single(formatNice(config.Nice))},
+	}
+
+	for _, key := range hardeningKeys {
+		directives = append(directives, serviceDirective{
+			Key:    key.Key,
+			Values: single(hardeningValue(config, key)),
+			Reset:  key.List,
+			Keep:   config.Hardening == "",
+		})
+	}
+
+	return directives
+}
+
+// Секция unit-файла, в которой находится директива
+func (d serviceDirective) section() string {
+	if d.Section == "" {
+		return "Service"
+	}
+	return d.Section
+}
+
+// Проверить, совпадают ли значения директивы с учетом размеров памяти
+func (d serviceDirective) equal(values []string) bool {
+	if d.Keep {
+		return true
+	}
+
+	if d.Bool {
+		return unitBoolValue(d.Values) == unitBoolValue(values)
+	}
+
+	if d.Units {
+		return slices.Equal(unitWords(d.Values), unitWords(values))
+	}
+
+	if d.Env {
+		a, errA := ParseEnvironment(d.Values)
+		b, errB := ParseEnvironment(values)
+		return errA == nil && errB == nil && maps.Equal(a, b)
+	}
+
+	if !d.Size || len(d.Values) != len(values) {
+		return slices.Equal(d.Values, values)
+	}
+
+	// Размеры сравниваем по значению, чтобы не терять исходную запись
+	// вида "1.5G" или "infinity"
+	for i := range values {
+		a, errA := unitSizeBytes(d.Values[i], 0, false)
+		b, errB := unitSizeBytes(values[i], 0, false)
+		if errA != nil || errB != nil {
+			// Проценты сравниваются по записи
+			if d.Values[i] != values[i] {
+				return false
+			}
+		} else if a != b {
+			return false
+		}
+	}
+	return true
+}
+
+// Перенести значения ServiceConfig в разобранный unit-файл, сохранив все
+// директивы, которыми sdmanager не управляет
+func ApplyServiceConfig(unit *UnitFile, config ServiceConfig) {
+	for _, directive := range serviceDirectives(config) {
+		if directive.equal(unit.GetAll(directive.section(), directive.Key)) {
+			continue
+		}
+		unit.SetAll(directive.section(), directive.Key, directive.Values)
+	}
+}
+
+// Имена unit из значений директив: в одной строке их может быть несколько
+func unitWords(values []string) []string {
+	var words []string
+	for _, value := range values {
+		words = append(words, strings.Fields(value)...)
+	}
+	return words
+}
+
+// Поле ServiceConfig с unit директивы зависимостей
+func dependencyField(config *ServiceConfig, key string) *[]string {
+	switch key {
+	case "After":
+		return &config.After
+	case "Before":
+		return &config.Before
+	case "Wants":
+		return &config.Wants
+	case "Requires":
+		return &config.Requires
+	case "BindsTo":
+		return &config.BindsTo
+	case "PartOf":
+		return &config.PartOf
+	case "Conflicts":
+		return &config.Conflicts
+	case "WantedBy":
+		return &config.WantedBy
+	default:
+		return &config.RequiredBy
+	}
+}
+
+// Разбор логического значения unit-файла (yes/no, true/false, on/off, 1/0)
+func parseUnitBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "yes", "true", "on", "1":
+		return true, nil
+	case "no", "false", "off", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("некорректное логическое значение: %s", value)
+}
+
+// Итоговое логическое значение директивы; отсутствие означает false
+func unitBoolValue(values []string) bool {
+	if len(values) == 0 {
+		return false
+	}
+	value, _ := parseUnitBool(values[len(values)-1])
+	return value
+}
+
+// Форматирование логического значения: false не записывается
+func formatUnitBool(value bool) string {
+	if !value {
+		return ""
+	}
+	return "yes"
+}
+
+// Форматирование положительного числа: 0 не записывается
+func formatPositive(value int) string {
+	if value <= 0 {
+		return ""
+	}
+	return strconv.Itoa(value)
+}
+
+// Форматирование процентов в значение для unit-файла. systemd хранит
+// проценты с точностью до сотых.
+func formatPercent(value float64) string {
+	if value <= 0 {
+		return ""
+	}
+	return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64) + "%"
+}
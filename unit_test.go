@@ -0,0 +1,396 @@
+package sdmanager
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestUnitFileRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		key     string
+		want    []string
+	}{
+		{
+			name:    "комментарии и пустые строки",
+			content: "# Создано вручную\n\n[Unit]\n; описание\nDescription=API\n\n[Service]\n  # отступ\nExecStart=/usr/bin/api\n",
+			key:     "ExecStart",
+			want:    []string{"/usr/bin/api"},
+		},
+		{
+			name:    "строки продолжения",
+			content: "[Service]\nExecStart=/usr/bin/api \\\n    --port 8080 \\\n# комментарий внутри\n    --verbose\nUser=www\n",
+			key:     "ExecStart",
+			want:    []string{"/usr/bin/api --port 8080 --verbose"},
+		},
+		{
+			name:    "повторяющиеся директивы и сброс",
+			content: "[Service]\nExecStartPre=/bin/a\nExecStartPre=\nExecStartPre=/bin/b\nExecStartPre=/bin/c\n",
+			key:     "ExecStartPre",
+			want:    []string{"/bin/b", "/bin/c"},
+		},
+		{
+			name:    "неизвестные директивы и пробелы вокруг =",
+			content: "[Service]\nX-Custom = value\nFooBar=1\n[X-Vendor]\nKey=v",
+			key:     "X-Custom",
+			want:    []string{"value"},
+		},
+	}
+
+	for _, tt := range tests {
+		unit, err := ParseUnitFile(strings.NewReader(tt.content))
+		if err != nil {
+			t.Errorf("%s: ParseUnitFile: %v", tt.name, err)
+			continue
+		}
+		if got := unit.String(); got != tt.content {
+			t.Errorf("%s: String() = %q, want %q", tt.name, got, tt.content)
+		}
+		if got := unit.GetAll("Service", tt.key); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: GetAll(%s) = %q, want %q", tt.name, tt.key, got, tt.want)
+		}
+	}
+
+	for _, content := range []string{"Key=value\n", "[Service]\nno equals\n", "[Service\n", "[Service]\n=value\n"} {
+		if _, err := ParseUnitFile(strings.NewReader(content)); err == nil {
+			t.Errorf("ParseUnitFile(%q) succeeded", content)
+		}
+	}
+}
+
+func TestUnitFileSetAll(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		key     string
+		values  []string
+		want    string
+	}{
+		{
+			name:    "замена с сохранением соседних строк",
+			content: "[Service]\n# запуск\nExecStart=/bin/a\nUser=www\n",
+			key:     "ExecStart",
+			values:  []string{"/bin/b"},
+			want:    "[Service]\n# запуск\nExecStart=/bin/b\nUser=www\n",
+		},
+		{
+			name:    "сброс остается перед новыми значениями",
+			content: "[Service]\nExecStart=\nExecStart=/bin/a\n",
+			key:     "ExecStart",
+			values:  []string{"/bin/b"},
+			want:    "[Service]\nExecStart=\nExecStart=/bin/b\n",
+		},
+		{
+			name:    "сброс без значений",
+			content: "[Service]\nExecStart=\nUser=www\n",
+			key:     "ExecStart",
+			values:  []string{"/bin/b"},
+			want:    "[Service]\nExecStart=\nUser=www\nExecStart=/bin/b\n",
+		},
+		{
+			name:    "значения до сброса не трогаются",
+			content: "[Service]\nExecStartPre=/bin/a\nExecStartPre=\nExecStartPre=/bin/b\n",
+			key:     "ExecStartPre",
+			values:  []string{"/bin/c", "/bin/d"},
+			want:    "[Service]\nExecStartPre=/bin/a\nExecStartPre=\nExecStartPre=/bin/c\nExecStartPre=/bin/d\n",
+		},
+		{
+			name:    "удаление лишних значений",
+			content: "[Service]\nEnvironment=A=1\nEnvironment=B=2\nUser=www\n",
+			key:     "Environment",
+			values:  []string{"A=1"},
+			want:    "[Service]\nEnvironment=A=1\nUser=www\n",
+		},
+		{
+			name:    "новая директива после последней, а не после комментария",
+			content: "[Service]\nUser=www\n\n# конец\n",
+			key:     "Group",
+			values:  []string{"www"},
+			want:    "[Service]\nUser=www\nGroup=www\n\n# конец\n",
+		},
+		{
+			name:    "новая секция",
+			content: "[Unit]\nDescription=API\n",
+			key:     "User",
+			values:  []string{"www"},
+			want:    "[Unit]\nDescription=API\n\n[Service]\nUser=www\n",
+		},
+		{
+			name:    "неизменные значения не переписываются",
+			content: "[Service]\nExecStart=/bin/a \\\n  --flag\n",
+			key:     "ExecStart",
+			values:  []string{"/bin/a --flag"},
+			want:    "[Service]\nExecStart=/bin/a \\\n  --flag\n",
+		},
+	}
+
+	for _, tt := range tests {
+		unit, err := ParseUnitFile(strings.NewReader(tt.content))
+		if err != nil {
+			t.Fatalf("%s: ParseUnitFile: %v", tt.name, err)
+		}
+		unit.SetAll("Service", tt.key, tt.values)
+		if got := unit.String(); got != tt.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", tt.name, got, tt.want)
+		}
+		if got := unit.GetAll("Service", tt.key); !slices.Equal(got, tt.values) {
+			t.Errorf("%s: GetAll = %q, want %q", tt.name, got, tt.values)
+		}
+	}
+
+	// Удаление директивы с последним сбросом сохраняет сброс
+	unit, _ := ParseUnitFile(strings.NewReader("[Service]\nExecStart=\nExecStart=/bin/a\n"))
+	unit.Del("Service", "ExecStart")
+	if got := unit.String(); got != "[Service]\nExecStart=\n" {
+		t.Errorf("Del = %q", got)
+	}
+}
+
+func TestApplyServiceConfigKeepsUnknown(t *testing.T) {
+	// Неизвестные sdmanager директивы, размеры в других единицах и
+	// комментарии переживают загрузку и повторную запись без изменений
+	content := "[Unit]\nDescription=API\n\n[Service]\n# лимиты\nMemoryMax=512K\nMemoryHigh=infinity\nExecStart=/usr/bin/api\nX-Custom=1\n\n[Install]\nWantedBy=multi-user.target\n"
+
+	unit, err := ParseUnitFile(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("ParseUnitFile: %v", err)
+	}
+	config, err := ServiceConfigFromUnit(unit, "api", "")
+	if err != nil {
+		t.Fatalf("ServiceConfigFromUnit: %v", err)
+	}
+	ApplyServiceConfig(unit, config)
+	if got := unit.String(); got != content {
+		t.Errorf("unit changed:\n%s", got)
+	}
+}
+
+// Конфигурация сервиса проходит генерацию unit-файла, чтение обратно и
+// drop-in без потерь. Каждая группа директив добавляет сюда свои случаи.
+func TestServiceConfigRoundTrip(t *testing.T) {
+	base := ServiceConfig{ServiceName: "api", ExecStart: "/usr/bin/api"}
+	with := func(change func(*ServiceConfig)) ServiceConfig {
+		config := base
+		change(&config)
+		return config
+	}
+	deps := with(func(c *ServiceConfig) {
+		c.After = []string{"network-online.target", "postgresql.service", "data.mount"}
+		c.Wants = []string{"network-online.target"}
+		c.Requires = []string{"postgresql.service", "data.mount"}
+		c.RequiredBy = []string{"app.target"}
+	})
+
+	tests := []struct {
+		name     string
+		config   ServiceConfig
+		contains []string
+		excludes []string
+		// Изменение для drop-in и ожидаемый drop-in; пустой - ожидается ошибка
+		change func(*ServiceConfig)
+		dropIn string
+	}{
+		{
+			name:     "без настроек unit-файл такой же, как раньше",
+			config:   base,
+			contains: []string{"ExecStart=/usr/bin/api\nRestart=always\nRestartSec=10\nOOMPolicy=restart\n"},
+			excludes: []string{"Type="},
+		},
+		{
+			name: "тип и политика перезапуска, StartLimit* в [Unit]",
+			config: with(func(c *ServiceConfig) {
+				c.Type = "notify"
+				c.Restart = "on-failure"
+				c.StartLimitIntervalSec = "5min"
+				c.StartLimitBurst = 3
+				c.TimeoutStopSec = "30s"
+				c.KillSignal = "SIGINT"
+			}),
+			contains: []string{
+				"After=network.target\nStartLimitIntervalSec=5min\nStartLimitBurst=3\n\n[Service]",
+				"ExecStart=/usr/bin/api\nType=notify\nRestart=on-failure\nRestartSec=10\nTimeoutStopSec=30s\nKillSignal=SIGINT\n",
+			},
+			excludes: []string{"OOMPolicy"},
+			change: func(c *ServiceConfig) {
+				c.StartLimitBurst = 5
+				c.Restart = "always"
+			},
+			dropIn: "# Создано sdmanager\n[Unit]\nStartLimitBurst=5\n\n[Service]\nRestart=always\n",
+		},
+		{
+			// Все команды oneshot сохраняются, drop-in задает список заново
+			name: "несколько ExecStart у oneshot",
+			config: with(func(c *ServiceConfig) {
+				c.Type = "oneshot"
+				c.ExecStartNext = []string{"/usr/bin/api cleanup"}
+			}),
+			contains: []string{"ExecStart=/usr/bin/api\nExecStart=/usr/bin/api cleanup\nType=oneshot\n"},
+			change:   func(c *ServiceConfig) { c.ExecStart = "/usr/bin/api --once" },
+			dropIn:   "# Создано sdmanager\n[Service]\nExecStart=\nExecStart=/usr/bin/api --once\nExecStart=/usr/bin/api cleanup\n",
+		},
+		{
+			// systemd принимает CPUQuota с точностью до сотых процента
+			name:     "дробный CPUQuota",
+			config:   with(func(c *ServiceConfig) { c.CPUQuota = 12.5 }),
+			contains: []string{"\nCPUQuota=12.5%\n"},
+			change:   func(c *ServiceConfig) { c.CPUQuota = 0.25 },
+			dropIn:   "# Создано sdmanager\n[Service]\nCPUQuota=0.25%\n",
+		},
+		{
+			// Спецификатор экземпляра остается спецификатором
+			name:     "спецификаторы в переменных шаблона",
+			config:   with(func(c *ServiceConfig) { c.Environment = map[string]string{"INST": "%i", "RATIO": "50%%"} }),
+			contains: []string{"Environment=INST=%i\nEnvironment=RATIO=50%%\n"},
+			change:   func(c *ServiceConfig) { c.Environment = map[string]string{"INST": "worker-%i", "RATIO": "50%%"} },
+			dropIn:   "# Создано sdmanager\n[Service]\nEnvironment=\nEnvironment=INST=worker-%i\nEnvironment=RATIO=50%%\n",
+		},
+		{
+			name: "переменные окружения и файлы",
+			config: with(func(c *ServiceConfig) {
+				c.Environment = map[string]string{"PORT": "8080", "NAME": "my api"}
+				c.EnvironmentFiles = []string{"-/etc/default/api"}
+			}),
+			contains: []string{"Environment=\"NAME=my api\"\nEnvironment=PORT=8080\nEnvironmentFile=-/etc/default/api\nExecStart="},
+			// Список переменных сбрасывается и задается заново
+			change: func(c *ServiceConfig) { c.Environment = map[string]string{"PORT": "9090"} },
+			dropIn: "# Создано sdmanager\n[Service]\nEnvironment=\nEnvironment=PORT=9090\n",
+		},
+		{
+			name: "команды жизненного цикла",
+			config: with(func(c *ServiceConfig) {
+				c.ExecStartPre = []string{"-/usr/bin/mkdir -p /run/api", "/usr/bin/api migrate"}
+				c.ExecStartPost = []string{"/usr/bin/api warmup"}
+				c.ExecReload = []string{"/bin/kill -HUP $MAINPID"}
+				c.ExecStopPost = []string{"+/usr/bin/rm -f /run/api/lock"}
+			}),
+			contains: []string{"ExecStartPre=-/usr/bin/mkdir -p /run/api\nExecStartPre=/usr/bin/api migrate\nExecStart=/usr/bin/api\n" +
+				"ExecStartPost=/usr/bin/api warmup\nExecReload=/bin/kill -HUP $MAINPID\nExecStopPost=+/usr/bin/rm -f /run/api/lock\n"},
+			excludes: []string{"ExecStop="},
+			// Список команд заменяется целиком
+			change: func(c *ServiceConfig) { c.ExecStartPre = []string{"/usr/bin/api migrate"} },
+			dropIn: "# Создано sdmanager\n[Service]\nExecStartPre=\nExecStartPre=/usr/bin/api migrate\n",
+		},
+		{
+			name:     "зависимости по умолчанию",
+			config:   base,
+			contains: []string{"Description=Api Service\nAfter=network.target\n\n", "[Install]\nWantedBy=multi-user.target\n"},
+		},
+		{
+			// Список в одной строке читается по именам и не переписывается
+			name:   "зависимости и порядок запуска",
+			config: deps,
+			contains: []string{
+				"After=network-online.target postgresql.service data.mount\nWants=network-online.target\nRequires=postgresql.service data.mount\n",
+				"[Install]\nRequiredBy=app.target\n",
+			},
+			excludes: []string{"WantedBy"},
+			// Drop-in может только добавить зависимости
+			change: func(c *ServiceConfig) { c.After = append(slices.Clone(c.After), "redis.service") },
+			dropIn: "# Создано sdmanager\n[Unit]\nAfter=redis.service\n",
+		},
+		{
+			name:   "drop-in не удаляет зависимости",
+			config: deps,
+			change: func(c *ServiceConfig) { c.After = []string{"redis.service"} },
+		},
+		{
+			name: "профиль защиты strict",
+			config: with(func(c *ServiceConfig) {
+				c.WorkingDirectory = "/srv/api"
+				c.Hardening = "strict"
+			}),
+			contains: []string{
+				"NoNewPrivileges=yes\nProtectSystem=strict\nProtectHome=yes\n",
+				"RestrictAddressFamilies=AF_UNIX AF_INET AF_INET6\n",
+				"CapabilityBoundingSet=CAP_NET_BIND_SERVICE\n",
+				"SystemCallFilter=@system-service\n",
+				"ReadWritePaths=/srv/api\n\n[Install]",
+			},
+			// Переход на basic убирает директивы strict и меняет общие, не
+			// трогая одинаковые в обоих профилях
+			change: func(c *ServiceConfig) { c.Hardening = "basic" },
+			dropIn: "# Создано sdmanager\n[Service]\nProtectSystem=full\nProtectHome=read-only\n" +
+				"ProtectKernelLogs=\nProtectClock=\nProtectHostname=\nRestrictAddressFamilies=\nRestrictNamespaces=\n" +
+				"RestrictRealtime=\nRestrictSUIDSGID=\nLockPersonality=\nCapabilityBoundingSet=\nSystemCallFilter=\n" +
+				"SystemCallArchitectures=\nUMask=\n",
+		},
+		{
+			name: "ограничения ресурсов",
+			config: with(func(c *ServiceConfig) {
+				nice := -5
+				c.MemoryMax = "1G"
+				c.MemorySwapMax = "0M"
+				c.TasksMax = "infinity"
+				c.IODeviceReadBandwidthMax = []string{"/dev/sda 10M", "/dev/sdb 5M"}
+				c.LimitNOFILE = "65536"
+				c.Nice = &nice
+			}),
+			contains: []string{
+				"MemoryMax=1G\n",
+				"MemorySwapMax=0M\nTasksMax=infinity\nIODeviceReadBandwidthMax=/dev/sda 10M\nIODeviceReadBandwidthMax=/dev/sdb 5M\nLimitNOFILE=65536\nNice=-5\n",
+			},
+			// Та же величина в другой записи не считается изменением
+			change: func(c *ServiceConfig) { c.MemoryMax = "1024" },
+		},
+	}
+
+	for _, tt := range tests {
+		content, err := GenerateUnitPreview(tt.config)
+		if err != nil {
+			t.Errorf("%s: GenerateUnitPreview: %v", tt.name, err)
+			continue
+		}
+		for _, part := range tt.contains {
+			if !strings.Contains(content, part) {
+				t.Errorf("%s: unit does not contain %q:\n%s", tt.name, part, content)
+			}
+		}
+		for _, part := range tt.excludes {
+			if strings.Contains(content, part) {
+				t.Errorf("%s: unit contains %q:\n%s", tt.name, part, content)
+			}
+		}
+
+		// Заданные в конфигурации поля читаются обратно, а запись прочитанной
+		// конфигурации не меняет unit-файл
+		unit, err := ParseUnitFile(strings.NewReader(content))
+		if err != nil {
+			t.Errorf("%s: ParseUnitFile: %v", tt.name, err)
+			continue
+		}
+		loaded, err := ServiceConfigFromUnit(unit, tt.config.ServiceName, "")
+		if err != nil {
+			t.Errorf("%s: ServiceConfigFromUnit: %v", tt.name, err)
+			continue
+		}
+		want, got := reflect.ValueOf(tt.config), reflect.ValueOf(loaded)
+		for i := range want.NumField() {
+			if field := want.Field(i); !field.IsZero() && !reflect.DeepEqual(field.Interface(), got.Field(i).Interface()) {
+				t.Errorf("%s: loaded %s = %v, want %v", tt.name, want.Type().Field(i).Name, got.Field(i), field)
+			}
+		}
+		ApplyServiceConfig(unit, loaded)
+		if got := unit.String(); got != content {
+			t.Errorf("%s: ApplyServiceConfig changed unit:\n%s", tt.name, got)
+		}
+
+		if tt.change == nil {
+			continue
+		}
+		changed := loaded
+		tt.change(&changed)
+		dropIn, err := GenerateDropIn(loaded, changed)
+		if tt.dropIn == "" {
+			if err == nil {
+				t.Errorf("%s: GenerateDropIn succeeded:\n%s", tt.name, dropIn)
+			}
+			continue
+		}
+		if err != nil || dropIn != tt.dropIn {
+			t.Errorf("%s: GenerateDropIn = %q, %v, want %q", tt.name, dropIn, err, tt.dropIn)
+		}
+	}
+}